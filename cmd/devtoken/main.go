@@ -0,0 +1,263 @@
+// Command devtoken mints signed access tokens for local and test environments, to be verified by
+// a server configured with KEYCLOAK_JWKS_FILE or KEYCLOAK_PUBLIC_KEY_FILES. It refuses to run
+// when ENVIRONMENT is production.
+//
+// Generate a signing key and the matching JWKS once:
+//
+//	go run ./cmd/devtoken -genkey -key dev-signing.pem -jwks dev-jwks.json
+//
+// Then mint tokens:
+//
+//	go run ./cmd/devtoken -key dev-signing.pem -tenant <tenant uuid> -roles ops-user,ops-finance \
+//		-branches <branch uuid>,<branch uuid>
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"frego-operations/internal/auth"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, "devtoken:", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	var (
+		keyFile       = flag.String("key", "dev-signing.pem", "PEM private key (RSA or EC P-256) to sign with")
+		genKey        = flag.Bool("genkey", false, "generate a new RSA signing key at -key and its JWKS at -jwks, then exit")
+		jwksFile      = flag.String("jwks", "dev-jwks.json", "JWKS file written by -genkey")
+		kid           = flag.String("kid", "dev", "key ID placed in the token header and JWKS")
+		issuer        = flag.String("issuer", envOr("KEYCLOAK_ISSUER", "http://localhost:8080/realms/frego"), "token issuer")
+		audience      = flag.String("aud", envOr("KEYCLOAK_AUDIENCE", "frego-app"), "comma-separated token audiences")
+		tenant        = flag.String("tenant", "", "tenant ID (required)")
+		tenantClaim   = flag.String("tenant-claim", envOr("KEYCLOAK_TENANT_CLAIM", "tenant_id"), "claim carrying the tenant ID")
+		roles         = flag.String("roles", "ops-user", "comma-separated realm roles")
+		scopes        = flag.String("scope", "", "space-separated token scopes")
+		branches      = flag.String("branches", "", "comma-separated branch IDs; omit for no branch claim")
+		branchClaim   = flag.String("branch-claim", envOr("KEYCLOAK_BRANCH_CLAIM", "branch_ids"), "claim carrying the branch IDs")
+		employee      = flag.String("employee", "", "employee ID; omit for no employee claim")
+		employeeClaim = flag.String("employee-claim", envOr("KEYCLOAK_EMPLOYEE_CLAIM", "employee_id"), "claim carrying the employee ID")
+		subject       = flag.String("sub", "", "token subject (default: a random UUID)")
+		username      = flag.String("username", "dev-user", "preferred_username claim")
+		email         = flag.String("email", "", "email claim")
+		ttl           = flag.Duration("ttl", time.Hour, "token lifetime")
+	)
+	flag.Parse()
+
+	if strings.EqualFold(os.Getenv("ENVIRONMENT"), "production") {
+		return errors.New("refusing to mint tokens when ENVIRONMENT is production")
+	}
+	if *genKey {
+		return generateKey(*keyFile, *jwksFile, *kid)
+	}
+
+	if _, err := uuid.Parse(*tenant); err != nil {
+		return fmt.Errorf("-tenant must be a UUID: %w", err)
+	}
+	branchIDs := splitList(*branches, ",")
+	for _, b := range branchIDs {
+		if _, err := uuid.Parse(b); err != nil {
+			return fmt.Errorf("branch %q must be a UUID: %w", b, err)
+		}
+	}
+	if *employee != "" {
+		if _, err := uuid.Parse(*employee); err != nil {
+			return fmt.Errorf("-employee must be a UUID: %w", err)
+		}
+	}
+	if *subject == "" {
+		*subject = uuid.NewString()
+	}
+
+	key, err := loadPrivateKey(*keyFile)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	claims := map[string]any{
+		"iss":                *issuer,
+		"sub":                *subject,
+		"aud":                splitList(*audience, ","),
+		"iat":                now.Unix(),
+		"nbf":                now.Unix(),
+		"exp":                now.Add(*ttl).Unix(),
+		"jti":                uuid.NewString(),
+		"preferred_username": *username,
+		"realm_access":       map[string]any{"roles": splitList(*roles, ",")},
+		*tenantClaim:         *tenant,
+	}
+	if *email != "" {
+		claims["email"] = *email
+	}
+	if s := strings.Join(strings.Fields(*scopes), " "); s != "" {
+		claims["scope"] = s
+	}
+	if *branches != "" {
+		claims[*branchClaim] = branchIDs
+	}
+	if *employee != "" {
+		claims[*employeeClaim] = *employee
+	}
+
+	token, err := sign(key, *kid, claims)
+	if err != nil {
+		return err
+	}
+	fmt.Println(token)
+	return nil
+}
+
+// generateKey writes a new RSA private key to keyFile and the JWKS holding its public half to
+// jwksFile. Existing files are not overwritten.
+func generateKey(keyFile, jwksFile, kid string) error {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return fmt.Errorf("generate key: %w", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return fmt.Errorf("encode key: %w", err)
+	}
+	jwk, err := auth.PublicJWK(&key.PublicKey, kid)
+	if err != nil {
+		return err
+	}
+	jwks, err := json.MarshalIndent(auth.JWKS{Keys: []auth.JWK{jwk}}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode jwks: %w", err)
+	}
+
+	if err := writeNew(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		return err
+	}
+	if err := writeNew(jwksFile, append(jwks, '\n'), 0o644); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "wrote %s and %s; start the server with KEYCLOAK_JWKS_FILE=%s\n", keyFile, jwksFile, jwksFile)
+	return nil
+}
+
+func writeNew(path string, data []byte, perm os.FileMode) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func loadPrivateKey(path string) (crypto.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read signing key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM block found", path)
+	}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("%s: unsupported private key type %T", path, key)
+		}
+		return signer, nil
+	default:
+		return nil, fmt.Errorf("%s: unsupported PEM block %q", path, block.Type)
+	}
+}
+
+// sign encodes claims as a compact JWS signed with RS256 or ES256.
+func sign(key crypto.Signer, kid string, claims map[string]any) (string, error) {
+	var alg string
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		alg = "RS256"
+	case *ecdsa.PrivateKey:
+		if k.Curve != elliptic.P256() {
+			return "", errors.New("only P-256 EC keys are supported")
+		}
+		alg = "ES256"
+	default:
+		return "", fmt.Errorf("unsupported signing key type %T", key)
+	}
+
+	header, err := json.Marshal(map[string]string{"alg": alg, "typ": "JWT", "kid": kid})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+
+	var sig []byte
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		sig, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+	case *ecdsa.PrivateKey:
+		// JWS wants the raw r||s encoding rather than ASN.1.
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, k, digest[:])
+		if err == nil {
+			sig = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("sign token: %w", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+func splitList(value, sep string) []string {
+	var out []string
+	for _, part := range strings.Split(value, sep) {
+		if trimmed := strings.TrimSpace(part); trimmed != "" {
+			out = append(out, trimmed)
+		}
+	}
+	return out
+}
+
+func envOr(key, fallback string) string {
+	if v := strings.TrimSpace(os.Getenv(key)); v != "" {
+		return v
+	}
+	return fallback
+}
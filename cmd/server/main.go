@@ -2,20 +2,25 @@ package main
 
 import (
 	"context"
+	"expvar"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
 	"strings"
 	"syscall"
+	"time"
 
 	"frego-operations/internal/api"
 	"frego-operations/internal/auth"
+	"frego-operations/internal/authz"
 	"frego-operations/internal/config"
 	"frego-operations/internal/db"
 	"frego-operations/internal/logging"
+	"frego-operations/internal/ratelimit"
 	operationsrepo "frego-operations/internal/repository/operations"
 	tenantrepo "frego-operations/internal/repository/tenant"
+	"frego-operations/internal/screening"
 	"frego-operations/internal/server"
 	operationsservice "frego-operations/internal/service/operations"
 	tenantservice "frego-operations/internal/service/tenant"
@@ -43,11 +48,18 @@ func main() {
 		slog.String("version", "1.0.0"),
 	)
 
-	authenticator, err := auth.NewAuthenticator(ctx, auth.Config{
-		Issuer:      cfg.Security.KeycloakIssuer,
-		Audiences:   cfg.Security.KeycloakAudience,
-		TenantClaim: cfg.Security.KeycloakTenantClaim,
-	})
+	authConfig := auth.Config{
+		Issuer:         cfg.Security.KeycloakIssuer,
+		Audiences:      cfg.Security.KeycloakAudience,
+		TenantClaim:    cfg.Security.KeycloakTenantClaim,
+		JWKSFile:       cfg.Security.KeycloakJWKSFile,
+		PublicKeyFiles: cfg.Security.KeycloakPublicKeys,
+	}
+	if authConfig.Offline() && cfg.Environment == "production" {
+		logger.Error("offline token verification keys are not allowed in production")
+		os.Exit(1)
+	}
+	authenticator, err := auth.NewAuthenticator(ctx, authConfig)
 	if err != nil {
 		logger.Error("failed to init keycloak authenticator", slog.Any("error", err))
 		os.Exit(1)
@@ -69,8 +81,26 @@ func main() {
 	}
 	defer operationsPool.Close()
 
-	// Create tenant session manager with both pools
-	tenantSessions := db.NewTenantSessionManager(tenantPool, operationsPool, "operations")
+	// Further operations databases hold the tenants the registry assigns to them
+	shardURLs, err := db.ParseShardURLs(cfg.Shards.URLs)
+	if err != nil {
+		logger.Error("invalid operations database shards", slog.Any("error", err))
+		os.Exit(1)
+	}
+	shards := db.NewShardPools(operationsPool, shardURLs, db.ShardLimits{
+		MaxConns:          int32(cfg.Shards.MaxConns),
+		ConnMaxLifetime:   cfg.Shards.ConnMaxLifetime,
+		ConnMaxIdleTime:   cfg.Shards.ConnMaxIdleTime,
+		PreferSimpleProto: cfg.Database.PreferSimpleProto,
+	})
+	defer shards.Close()
+	logger.Info("operations database shards configured", slog.Any("shards", shards.Keys()))
+
+	// Create tenant session manager with the registry pool and the shards
+	tenantSessions := db.NewShardedTenantSessionManager(tenantPool, shards, "operations")
+	go tenantSessions.ListenForTenantChanges(ctx, logger)
+	expvar.Publish("tenant_cache", expvar.Func(func() any { return tenantSessions.CacheStats() }))
+	expvar.Publish("shard_pools", expvar.Func(func() any { return shards.Stats() }))
 
 	var documentUploader storage.DocumentUploader
 	if strings.TrimSpace(cfg.Storage.Bucket) == "" || strings.TrimSpace(cfg.Storage.Region) == "" {
@@ -92,14 +122,25 @@ func main() {
 		}
 		documentUploader = uploader
 	}
-	_ = documentUploader
+
+	var screener *screening.Screener
+	if strings.TrimSpace(cfg.Screening.ListDir) == "" {
+		logger.Warn("restricted-party screening disabled; missing list directory")
+	} else {
+		screener, err = screening.Open(cfg.Screening.ListDir, cfg.Screening.MatchThreshold)
+		if err != nil {
+			logger.Error("failed to load denied-party lists", slog.Any("error", err))
+			os.Exit(1)
+		}
+		logger.Info("loaded denied-party lists", slog.Int("lists", len(screener.Lists())))
+	}
 
 	operationsRepo := operationsrepo.NewWithSessions(tenantSessions)
-	operationsService := operationsservice.New(operationsRepo)
-	_ = operationsService // Silence unused variable error until handler is wired
+	operationsService := operationsservice.New(operationsRepo, documentUploader, screener)
 
 	tenantRepo := tenantrepo.New(tenantPool, operationsPool, cfg.Database.User)
-	tenantService := tenantservice.New(tenantRepo)
+	authorizer := authz.New(authz.DefaultPolicy(), operationsService.RoleGrants, time.Minute)
+	tenantService := tenantservice.New(tenantRepo, authorizer)
 
 	// operationsHandler := api.NewOperationsHandler(logger, operationsService, tenantService, cfg.InternalSecret)
 	// strictServer := api.NewStrictHandler(operationsHandler, nil)
@@ -110,10 +151,81 @@ func main() {
 	// 	},
 	// })
 
-	// Tenant provisioning handler (for backend-to-operations communication)
-	tenantHandler := api.NewTenantHandler(logger, tenantService, cfg.InternalSecret)
-	tenantRouter := chi.NewRouter()
-	tenantHandler.RegisterRoutes(tenantRouter)
+	// Hand-written operations routes served alongside the generated API
+	exportHandler := api.NewExportHandler(logger, operationsService, cfg.EDI.SenderID)
+	documentHandler := api.NewDocumentHandler(logger, operationsService)
+	exchangeRateHandler := api.NewExchangeRateHandler(logger, operationsService)
+	profitabilityHandler := api.NewProfitabilityHandler(logger, operationsService)
+	chargeTemplateHandler := api.NewChargeTemplateHandler(logger, operationsService)
+	incotermHandler := api.NewIncotermHandler(logger, operationsService)
+	invoiceHandler := api.NewInvoiceHandler(logger, operationsService)
+	accrualHandler := api.NewAccrualHandler(logger, operationsService)
+	approvalHandler := api.NewApprovalHandler(logger, operationsService)
+	jobStatusHandler := api.NewJobStatusHandler(logger, operationsService)
+	creditHandler := api.NewCreditHandler(logger, operationsService)
+	screeningHandler := api.NewScreeningHandler(logger, operationsService)
+	roleHandler := api.NewRoleHandler(logger, operationsService, authorizer)
+	apiKeyHandler := api.NewAPIKeyHandler(logger, tenantService)
+	apiRouter := chi.NewRouter()
+	apiRouter.Use(logging.InjectMiddleware(logger))
+	if cfg.RateLimit.Enabled {
+		limiter := ratelimit.New(tenantService.RateLimitRules, cfg.RateLimit.RefreshInterval)
+		apiRouter.Use(ratelimit.Middleware(limiter, api.RouteClasses(apiRouter)))
+	}
+	apiRouter.Use(authz.ScopeMiddleware(authz.ScopeClaims{
+		Branches: cfg.Security.KeycloakBranchClaim,
+		Employee: cfg.Security.KeycloakEmployeeClaim,
+	}))
+	apiRouter.Use(api.AuthorizeRoutes(apiRouter, authorizer))
+	exportHandler.RegisterRoutes(apiRouter)
+	documentHandler.RegisterRoutes(apiRouter)
+	exchangeRateHandler.RegisterRoutes(apiRouter)
+	profitabilityHandler.RegisterRoutes(apiRouter)
+	chargeTemplateHandler.RegisterRoutes(apiRouter)
+	incotermHandler.RegisterRoutes(apiRouter)
+	invoiceHandler.RegisterRoutes(apiRouter)
+	accrualHandler.RegisterRoutes(apiRouter)
+	approvalHandler.RegisterRoutes(apiRouter)
+	jobStatusHandler.RegisterRoutes(apiRouter)
+	creditHandler.RegisterRoutes(apiRouter)
+	screeningHandler.RegisterRoutes(apiRouter)
+	roleHandler.RegisterRoutes(apiRouter)
+	apiKeyHandler.RegisterRoutes(apiRouter)
+	if missing := api.UnauthorizedRoutes(apiRouter); len(missing) > 0 {
+		logger.Error("routes without an authorization policy", slog.Any("routes", missing))
+		os.Exit(1)
+	}
+
+	// Tenant provisioning handler (for backend-to-operations communication). Internal routes are
+	// only mounted when service-to-service auth is configured.
+	serviceKeys, err := auth.ParseServiceKeys(cfg.ServiceAuth.Keys)
+	if err != nil {
+		logger.Error("invalid internal service keys", slog.Any("error", err))
+		os.Exit(1)
+	}
+	var tenantRouter http.Handler
+	var serviceMiddleware func(http.Handler) http.Handler
+	if len(serviceKeys) > 0 || cfg.ServiceAuth.Audience != "" {
+		serviceAuthenticator, err := auth.NewServiceAuthenticator(auth.ServiceConfig{
+			Keys:     serviceKeys,
+			MaxSkew:  cfg.ServiceAuth.MaxSkew,
+			Audience: cfg.ServiceAuth.Audience,
+			Clients:  cfg.ServiceAuth.Clients,
+		}, authenticator)
+		if err != nil {
+			logger.Error("failed to init service authenticator", slog.Any("error", err))
+			os.Exit(1)
+		}
+		serviceMiddleware = auth.ServiceMiddleware(logger, serviceAuthenticator)
+
+		tenantHandler := api.NewTenantHandler(logger, tenantService)
+		internalRouter := chi.NewRouter()
+		tenantHandler.RegisterRoutes(internalRouter)
+		internalRouter.Handle("/debug/vars", expvar.Handler())
+		tenantRouter = internalRouter
+	} else {
+		logger.Warn("internal service routes disabled: no INTERNAL_SERVICE_KEYS or INTERNAL_SERVICE_AUDIENCE configured")
+	}
 
 	corsOrigins := append([]string{}, cfg.Security.AllowedOrigins...)
 	corsOrigins = append(corsOrigins, "https://dev.myfrego.com", "http://localhost:3000")
@@ -133,7 +245,6 @@ func main() {
 			"Content-Type",
 			"X-Requested-With",
 			"X-Tenant-ID",
-			"Secret",
 		},
 		AllowCredentials: true,
 		MaxAge:           300,
@@ -141,11 +252,17 @@ func main() {
 
 	router := server.BuildRouter(
 		logger,
-		nil, // apiHandler is commented out until generated code exists
+		apiRouter,
 		tenantRouter,
 		corsMiddleware,
-		auth.Middleware(logger, authenticator),
-		server.TenantMiddleware(logger, tenantPool, cfg.Security.DefaultTenant),
+		serviceMiddleware,
+		auth.Middleware(logger, authenticator, tenantService),
+		server.TenantMiddleware(logger, tenantPool, tenantSessions, server.TenantOptions{
+			DefaultTenant:   cfg.Security.DefaultTenant,
+			AllowDefault:    cfg.Security.AllowDefaultTenant && cfg.Environment == "development",
+			CrossTenantRole: cfg.Security.CrossTenantRole,
+			Module:          "operations",
+		}),
 	)
 
 	httpServer := server.New(logger, cfg.HTTPAddress, router, cfg.GracefulDelay)
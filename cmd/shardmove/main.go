@@ -0,0 +1,360 @@
+// Command shardmove moves a tenant's operations schema from its current database shard to
+// another one and points the tenant registry at the new shard.
+//
+// It reads TENANT_DB_URL, DB_URL and DB_SHARDS like the server, and needs pg_dump and psql on
+// PATH, at least as new as the databases. The target database must already have the extensions
+// and shared objects of the primary. While the move runs the registry marks the tenant as moving
+// and the service answers its requests with 503.
+//
+//	go run ./cmd/shardmove -tenant <tenant uuid> -to <shard key>
+//
+// The old schema is kept, renamed with a "_moved_<timestamp>" suffix, unless -drop-source is set.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"frego-operations/internal/config"
+	"frego-operations/internal/db"
+)
+
+const module = "operations"
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, "shardmove:", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	var (
+		tenant      = flag.String("tenant", "", "tenant ID (required)")
+		to          = flag.String("to", "", "shard key to move the tenant to, or \"primary\" (required)")
+		drain       = flag.Duration("drain", 15*time.Second, "how long to wait for in-flight requests after marking the tenant as moving")
+		lockTimeout = flag.Duration("lock-timeout", 30*time.Second, "how long to wait for the tenant's tables to be free of writers")
+		dropSource  = flag.Bool("drop-source", false, "drop the old schema instead of renaming it")
+		actor       = flag.String("actor", envOr("USER", "shardmove"), "recorded as modified_by in the registry")
+	)
+	flag.Parse()
+
+	tenantID, err := uuid.Parse(*tenant)
+	if err != nil {
+		return fmt.Errorf("-tenant must be a UUID: %w", err)
+	}
+	if *to == "" {
+		return errors.New("-to is required")
+	}
+
+	cfg, err := config.LoadDatabases()
+	if err != nil {
+		return err
+	}
+	urls, err := db.ParseShardURLs(cfg.Shards.URLs)
+	if err != nil {
+		return err
+	}
+	urls[db.PrimaryShard] = cfg.Database.URL
+	targetURL, ok := urls[*to]
+	if !ok {
+		return fmt.Errorf("%w: %q is not in DB_SHARDS", db.ErrUnknownShard, *to)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	registry, err := pgxpool.New(ctx, cfg.TenantDatabase.URL)
+	if err != nil {
+		return fmt.Errorf("connect to tenant registry: %w", err)
+	}
+	defer registry.Close()
+
+	schema, from, movingTo, err := tenantShard(ctx, registry, tenantID)
+	if err != nil {
+		return err
+	}
+	switch {
+	case from == *to:
+		return fmt.Errorf("tenant %s is already on shard %q", tenantID, *to)
+	case movingTo != "" && movingTo != *to:
+		return fmt.Errorf("tenant %s is already moving to shard %q", tenantID, movingTo)
+	}
+	sourceURL, ok := urls[from]
+	if !ok {
+		return fmt.Errorf("%w: tenant %s is on %q, which is not in DB_SHARDS", db.ErrUnknownShard, tenantID, from)
+	}
+
+	m := &move{
+		registry: registry,
+		tenantID: tenantID,
+		schema:   schema,
+		from:     from,
+		to:       *to,
+		actor:    *actor,
+	}
+	return m.run(ctx, sourceURL, targetURL, *drain, *lockTimeout, *dropSource)
+}
+
+type move struct {
+	registry *pgxpool.Pool
+	tenantID uuid.UUID
+	schema   string
+	from, to string
+	actor    string
+}
+
+func (m *move) run(ctx context.Context, sourceURL, targetURL string, drain, lockTimeout time.Duration, dropSource bool) (err error) {
+	// Services stop serving the tenant once the registry change notification reaches them.
+	if err := m.markMoving(ctx); err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if clearErr := m.clearMoving(context.Background()); clearErr != nil {
+				err = errors.Join(err, fmt.Errorf("tenant is still marked as moving: %w", clearErr))
+			}
+		}
+	}()
+	fmt.Printf("tenant %s marked as moving from %q to %q; draining for %s\n", m.tenantID, m.from, m.to, drain)
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(drain):
+	}
+
+	source, err := pgx.Connect(ctx, sourceURL)
+	if err != nil {
+		return fmt.Errorf("connect to shard %q: %w", m.from, err)
+	}
+	defer source.Close(context.Background())
+	target, err := pgx.Connect(ctx, targetURL)
+	if err != nil {
+		return fmt.Errorf("connect to shard %q: %w", m.to, err)
+	}
+	defer target.Close(context.Background())
+
+	var exists bool
+	if err := target.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM pg_namespace WHERE nspname = $1)`, m.schema).Scan(&exists); err != nil {
+		return fmt.Errorf("check target schema: %w", err)
+	}
+	if exists {
+		return fmt.Errorf("schema %q already exists on shard %q", m.schema, m.to)
+	}
+
+	// The source transaction holds SHARE locks, which let the dump read but keep writers out, and
+	// exports its snapshot so the dump sees exactly what is counted below.
+	tx, err := source.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead})
+	if err != nil {
+		return fmt.Errorf("begin source transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(context.Background()) }()
+
+	if _, err := tx.Exec(ctx, fmt.Sprintf("SET LOCAL lock_timeout = %d", lockTimeout.Milliseconds())); err != nil {
+		return fmt.Errorf("set lock timeout: %w", err)
+	}
+	tables, err := m.tables(ctx, tx)
+	if err != nil {
+		return err
+	}
+	for _, table := range tables {
+		if _, err := tx.Exec(ctx, "LOCK TABLE "+m.qualified(table)+" IN SHARE MODE"); err != nil {
+			return fmt.Errorf("lock %s: %w", table, err)
+		}
+	}
+	var snapshot string
+	if err := tx.QueryRow(ctx, `SELECT pg_export_snapshot()`).Scan(&snapshot); err != nil {
+		return fmt.Errorf("export snapshot: %w", err)
+	}
+
+	fmt.Printf("copying schema %q (%d tables)\n", m.schema, len(tables))
+	if err := m.copySchema(ctx, sourceURL, targetURL, snapshot); err != nil {
+		return errors.Join(err, m.dropTarget(target))
+	}
+	if err := m.verify(ctx, tx, target, tables); err != nil {
+		return errors.Join(err, m.dropTarget(target))
+	}
+
+	// Retire the old schema in the locked transaction, then switch the registry. Should the switch
+	// fail, the rollback restores the old schema and the copy is dropped.
+	retired := ""
+	if dropSource {
+		_, err = tx.Exec(ctx, "DROP SCHEMA "+pgx.Identifier{m.schema}.Sanitize()+" CASCADE")
+	} else {
+		retired = fmt.Sprintf("%s_moved_%s", m.schema, time.Now().UTC().Format("20060102150405"))
+		_, err = tx.Exec(ctx, "ALTER SCHEMA "+pgx.Identifier{m.schema}.Sanitize()+" RENAME TO "+pgx.Identifier{retired}.Sanitize())
+	}
+	if err != nil {
+		return errors.Join(fmt.Errorf("retire source schema: %w", err), m.dropTarget(target))
+	}
+	if err := m.switchShard(ctx); err != nil {
+		return errors.Join(err, m.dropTarget(target))
+	}
+	if err := tx.Commit(ctx); err != nil {
+		// The tenant is served from the new shard; only the old copy was left in place.
+		fmt.Fprintf(os.Stderr, "warning: schema %q on shard %q was not retired: %v\n", m.schema, m.from, err)
+		retired = m.schema
+	}
+
+	fmt.Printf("tenant %s moved to shard %q\n", m.tenantID, m.to)
+	if retired != "" {
+		fmt.Printf("old schema kept on shard %q as %q\n", m.from, retired)
+	}
+	return nil
+}
+
+// tenantShard returns the tenant's operations schema, its shard, and the shard it is moving to.
+func tenantShard(ctx context.Context, registry *pgxpool.Pool, tenantID uuid.UUID) (schema, shard, movingTo string, err error) {
+	err = registry.QueryRow(ctx, `
+		SELECT COALESCE(r.operations_schema, ''), COALESCE(s.shard_key, $3), COALESCE(s.moving_to, '')
+		FROM registry.tenant_registry r
+		LEFT JOIN registry.tenant_module_shard s ON s.tenant_id = r.tenant_id AND s.module_name = $2
+		WHERE r.tenant_id = $1
+	`, tenantID, module, db.PrimaryShard).Scan(&schema, &shard, &movingTo)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", "", "", fmt.Errorf("%w: %s", db.ErrTenantNotFound, tenantID)
+	}
+	if err != nil {
+		return "", "", "", fmt.Errorf("query tenant registry: %w", err)
+	}
+	if schema == "" {
+		return "", "", "", fmt.Errorf("tenant %s has no %s schema", tenantID, module)
+	}
+	return schema, shard, movingTo, nil
+}
+
+func (m *move) markMoving(ctx context.Context) error {
+	_, err := m.registry.Exec(ctx, `
+		INSERT INTO registry.tenant_module_shard (tenant_id, module_name, shard_key, moving_to, modified_by)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (tenant_id, module_name) DO UPDATE SET
+			moving_to = EXCLUDED.moving_to,
+			modified_at = now(),
+			modified_by = EXCLUDED.modified_by
+	`, m.tenantID, module, m.from, m.to, m.actor)
+	if err != nil {
+		return fmt.Errorf("mark tenant as moving: %w", err)
+	}
+	return nil
+}
+
+func (m *move) clearMoving(ctx context.Context) error {
+	_, err := m.registry.Exec(ctx, `
+		UPDATE registry.tenant_module_shard
+		SET moving_to = NULL, modified_at = now(), modified_by = $3
+		WHERE tenant_id = $1 AND module_name = $2
+	`, m.tenantID, module, m.actor)
+	return err
+}
+
+func (m *move) switchShard(ctx context.Context) error {
+	_, err := m.registry.Exec(ctx, `
+		UPDATE registry.tenant_module_shard
+		SET shard_key = $3, moving_to = NULL, modified_at = now(), modified_by = $4
+		WHERE tenant_id = $1 AND module_name = $2
+	`, m.tenantID, module, m.to, m.actor)
+	if err != nil {
+		return fmt.Errorf("switch tenant shard: %w", err)
+	}
+	return nil
+}
+
+func (m *move) tables(ctx context.Context, tx pgx.Tx) ([]string, error) {
+	rows, err := tx.Query(ctx, `SELECT tablename FROM pg_tables WHERE schemaname = $1 ORDER BY tablename`, m.schema)
+	if err != nil {
+		return nil, fmt.Errorf("list tables: %w", err)
+	}
+	tables, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("list tables: %w", err)
+	}
+	return tables, nil
+}
+
+func (m *move) qualified(table string) string {
+	return pgx.Identifier{m.schema, table}.Sanitize()
+}
+
+// copySchema pipes pg_dump of the schema at snapshot into psql on the target, which applies it in
+// one transaction.
+func (m *move) copySchema(ctx context.Context, sourceURL, targetURL, snapshot string) error {
+	dump := exec.CommandContext(ctx, "pg_dump",
+		"--dbname="+sourceURL,
+		"--schema="+m.schema,
+		"--snapshot="+snapshot,
+		"--no-owner",
+		"--no-privileges",
+	)
+	restore := exec.CommandContext(ctx, "psql",
+		"--dbname="+targetURL,
+		"--single-transaction",
+		"--set=ON_ERROR_STOP=1",
+		"--quiet",
+	)
+	pipe, err := dump.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	restore.Stdin = pipe
+	restore.Stdout = io.Discard
+	dump.Stderr = os.Stderr
+	restore.Stderr = os.Stderr
+
+	if err := restore.Start(); err != nil {
+		return fmt.Errorf("start psql: %w", err)
+	}
+	if err := dump.Run(); err != nil {
+		_ = restore.Wait()
+		return fmt.Errorf("pg_dump: %w", err)
+	}
+	if err := restore.Wait(); err != nil {
+		return fmt.Errorf("psql: %w", err)
+	}
+	return nil
+}
+
+// verify compares every table's row count on the source snapshot and the copy.
+func (m *move) verify(ctx context.Context, source pgx.Tx, target *pgx.Conn, tables []string) error {
+	for _, table := range tables {
+		query := "SELECT count(*) FROM " + m.qualified(table)
+		var want, got int64
+		if err := source.QueryRow(ctx, query).Scan(&want); err != nil {
+			return fmt.Errorf("count %s on shard %q: %w", table, m.from, err)
+		}
+		if err := target.QueryRow(ctx, query).Scan(&got); err != nil {
+			return fmt.Errorf("count %s on shard %q: %w", table, m.to, err)
+		}
+		if want != got {
+			return fmt.Errorf("table %s has %d rows on shard %q but %d on %q", table, want, m.from, got, m.to)
+		}
+	}
+	return nil
+}
+
+// dropTarget removes a partial or unverified copy from the target shard.
+func (m *move) dropTarget(target *pgx.Conn) error {
+	_, err := target.Exec(context.Background(), "DROP SCHEMA IF EXISTS "+pgx.Identifier{m.schema}.Sanitize()+" CASCADE")
+	if err != nil {
+		return fmt.Errorf("drop copied schema on shard %q: %w", m.to, err)
+	}
+	return nil
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
@@ -49,13 +49,74 @@ ORDER BY priority_id;
 -- name: ListActivityLookups :many
 SELECT
     activity_type,
-    activity_code
+    activity_code,
+    cost_segment,
+    iata_charge_code,
+    iata_charge_entitlement,
+    iata_rate_class
 FROM activity_lu
 WHERE is_active = true
 ORDER BY activity_type, activity_code;
 
 
 
+-- name: ListIncotermLookups :many
+SELECT
+    id,
+    code,
+    name,
+    version,
+    origin_handling,
+    export_customs,
+    main_carriage,
+    insurance,
+    import_customs,
+    destination_delivery
+FROM incoterm_lu
+WHERE is_active = true
+ORDER BY code;
+
+-- name: GetIncoterm :one
+SELECT
+    id,
+    code,
+    name,
+    version,
+    origin_handling,
+    export_customs,
+    main_carriage,
+    insurance,
+    import_customs,
+    destination_delivery
+FROM incoterm_lu
+WHERE code = upper(sqlc.arg(code))
+  AND is_active;
+
+-- name: GetTaxRate :one
+SELECT rate_percent
+FROM tax_code_lu
+WHERE tax_code = sqlc.arg(tax_code)
+  AND is_active;
+
+-- name: GetPrimaryCurrency :one
+SELECT primary_currency_code
+FROM tenant_setting
+LIMIT 1;
+
+-- name: GetAccrualTolerance :one
+SELECT primary_currency_code, accrual_tolerance_percent, accrual_tolerance_amount
+FROM tenant_setting
+LIMIT 1;
+
+-- name: UpdateAccrualTolerance :one
+UPDATE tenant_setting
+SET
+    accrual_tolerance_percent = sqlc.narg(accrual_tolerance_percent),
+    accrual_tolerance_amount = sqlc.narg(accrual_tolerance_amount),
+    modified_at = now(),
+    modified_by = sqlc.arg(actor)
+RETURNING primary_currency_code, accrual_tolerance_percent, accrual_tolerance_amount;
+
 -- ============================================================
 -- JOB CRUD QUERIES
 -- ============================================================
@@ -112,6 +173,8 @@ WHERE j.is_active
   AND (sqlc.narg(status)::text IS NULL OR j.status = sqlc.narg(status))
   AND (sqlc.narg(customer_id)::uuid IS NULL OR j.customer_id = sqlc.narg(customer_id))
   AND (sqlc.narg(job_type)::text IS NULL OR j.job_type = sqlc.narg(job_type))
+  AND (sqlc.narg(scope_branches)::uuid[] IS NULL OR j.branch_id = ANY(sqlc.narg(scope_branches)::uuid[]))
+  AND (sqlc.narg(scope_sales_executive_id)::uuid IS NULL OR j.sales_executive_id = sqlc.narg(scope_sales_executive_id))
 ORDER BY j.created_at DESC
 LIMIT sqlc.arg(row_limit);
 
@@ -169,7 +232,9 @@ LEFT JOIN party_master agent ON agent.id = j.agent_id
 LEFT JOIN employee_master se ON se.id = j.sales_executive_id
 LEFT JOIN employee_master oe ON oe.id = j.operations_exec_id
 LEFT JOIN employee_master ce ON ce.id = j.cs_executive_id
-WHERE j.id = sqlc.arg(id);
+WHERE j.id = sqlc.arg(id)
+  AND (sqlc.narg(scope_branches)::uuid[] IS NULL OR j.branch_id = ANY(sqlc.narg(scope_branches)::uuid[]))
+  AND (sqlc.narg(scope_sales_executive_id)::uuid IS NULL OR j.sales_executive_id = sqlc.narg(scope_sales_executive_id));
 
 -- name: CreateJob :one
 INSERT INTO ops_job (
@@ -280,15 +345,102 @@ SET
     modified_at = now(),
     modified_by = sqlc.arg(actor)
 WHERE id = sqlc.arg(id)
+  AND status IS DISTINCT FROM 'Closed'
 RETURNING *;
 
--- name: ArchiveJob :exec
+-- name: LockJob :one
+SELECT id, job_code, status
+FROM ops_job
+WHERE id = sqlc.arg(id) AND is_active
+FOR UPDATE;
+
+-- name: ReopenJob :execrows
+UPDATE ops_job
+SET
+    status = 'Active',
+    modified_at = now(),
+    modified_by = sqlc.arg(actor)
+WHERE id = sqlc.arg(id)
+  AND status = 'Closed';
+
+-- name: CreateJobStatusHistory :one
+INSERT INTO ops_job_status_history (
+    job_id,
+    from_status,
+    to_status,
+    reason,
+    changed_at,
+    changed_by
+) VALUES (
+    sqlc.arg(job_id),
+    sqlc.narg(from_status),
+    sqlc.narg(to_status),
+    sqlc.narg(reason),
+    now(),
+    sqlc.arg(actor)
+) RETURNING *;
+
+-- name: ListJobStatusHistory :many
+SELECT *
+FROM ops_job_status_history
+WHERE job_id = sqlc.arg(job_id)
+ORDER BY changed_at;
+
+-- name: ArchiveJob :execrows
 UPDATE ops_job
 SET 
     is_active = false,
     modified_at = now(),
     modified_by = sqlc.arg(actor)
-WHERE id = sqlc.arg(id);
+WHERE id = sqlc.arg(id)
+  AND status IS DISTINCT FROM 'Closed';
+
+-- name: ListHouseJobs :many
+SELECT
+    j.id,
+    j.job_code,
+    j.commodity,
+    c.origin_port_station,
+    c.destination_port_station,
+    c.transport_document_reference,
+    d.house_doc_number,
+    pk.no_of_packages,
+    pk.gross_weight_kg,
+    shp.name AS shipper_name,
+    shp.human_id AS shipper_human_id,
+    cne.name AS consignee_name,
+    cne.human_id AS consignee_human_id
+FROM ops_job j
+LEFT JOIN LATERAL (
+    SELECT origin_port_station, destination_port_station, transport_document_reference
+    FROM ops_carrier
+    WHERE job_id = j.id AND is_active
+    ORDER BY created_at
+    LIMIT 1
+) c ON true
+LEFT JOIN LATERAL (
+    SELECT house_doc_number
+    FROM ops_job_document
+    WHERE job_id = j.id AND is_active AND house_doc_number IS NOT NULL
+    ORDER BY created_at
+    LIMIT 1
+) d ON true
+LEFT JOIN LATERAL (
+    SELECT
+        sum(no_of_packages)::numeric AS no_of_packages,
+        sum(gross_weight_kg)::numeric AS gross_weight_kg
+    FROM ops_package
+    WHERE job_id = j.id AND is_active
+) pk ON true
+LEFT JOIN ops_party p ON p.job_id = j.id
+LEFT JOIN party_master shp ON shp.id = p.shipper_id
+LEFT JOIN party_master cne ON cne.id = p.consignee_id
+WHERE j.parent_job_id = sqlc.arg(parent_job_id)
+  AND j.is_active
+  AND j.status IS DISTINCT FROM 'Cancelled'
+  AND (sqlc.narg(scope_branches)::uuid[] IS NULL OR j.branch_id = ANY(sqlc.narg(scope_branches)::uuid[]))
+  AND (sqlc.narg(scope_sales_executive_id)::uuid IS NULL OR j.sales_executive_id = sqlc.narg(scope_sales_executive_id))
+ORDER BY j.job_code;
 
 -- ============================================================
 -- JOB PACKAGE QUERIES
@@ -376,6 +528,7 @@ INSERT INTO ops_carrier (
     destination_country,
     accounting_info,
     handling_info,
+    charges_code,
     transport_document_reference,
     supporting_doc_url,
     file_region,
@@ -404,6 +557,7 @@ INSERT INTO ops_carrier (
     sqlc.narg(destination_country),
     sqlc.narg(accounting_info),
     sqlc.narg(handling_info),
+    sqlc.narg(charges_code),
     sqlc.narg(transport_document_reference),
     sqlc.narg(doc_urls),
     sqlc.narg(file_region),
@@ -435,6 +589,7 @@ SET
     destination_country = COALESCE(sqlc.narg(destination_country), destination_country),
     accounting_info = COALESCE(sqlc.narg(accounting_info), accounting_info),
     handling_info = COALESCE(sqlc.narg(handling_info), handling_info),
+    charges_code = COALESCE(sqlc.narg(charges_code), charges_code),
     transport_document_reference = COALESCE(sqlc.narg(transport_document_reference), transport_document_reference),
     supporting_doc_url = COALESCE(sqlc.narg(doc_urls), supporting_doc_url),
     file_region = COALESCE(sqlc.narg(file_region), file_region),
@@ -506,6 +661,152 @@ INSERT INTO ops_job_document (
     true
 ) RETURNING *;
 
+-- name: GetNextDocumentSequence :one
+SELECT COALESCE(MAX(
+    CASE
+        WHEN doc_number ~ ('^' || sqlc.arg(prefix) || '-[0-9]+$')
+        THEN CAST(SUBSTRING(doc_number FROM LENGTH(sqlc.arg(prefix)) + 2) AS INTEGER)
+        ELSE 0
+    END
+), 0) + 1 AS next_seq
+FROM ops_job_document
+WHERE doc_number LIKE sqlc.arg(prefix) || '%';
+
+-- ============================================================
+-- DOCUMENT TEMPLATE QUERIES
+-- ============================================================
+
+-- name: GetDocumentType :one
+SELECT code, label
+FROM document_type_lu
+WHERE code = sqlc.arg(code)
+  AND is_active;
+
+-- name: GetActiveDocTemplate :one
+SELECT *
+FROM doc_template
+WHERE doc_type_code = sqlc.arg(doc_type_code)
+  AND is_active
+ORDER BY version DESC
+LIMIT 1;
+
+-- name: ListDocTemplates :many
+SELECT *
+FROM doc_template
+WHERE (sqlc.narg(doc_type_code)::text IS NULL OR doc_type_code = sqlc.narg(doc_type_code))
+ORDER BY doc_type_code, version DESC;
+
+-- name: CreateDocTemplate :one
+INSERT INTO doc_template (
+    doc_type_code,
+    version,
+    name,
+    body_html,
+    created_at,
+    created_by,
+    is_active
+) VALUES (
+    sqlc.arg(doc_type_code),
+    (SELECT COALESCE(MAX(version), 0) + 1 FROM doc_template WHERE doc_type_code = sqlc.arg(doc_type_code)),
+    sqlc.narg(name),
+    sqlc.arg(body_html),
+    now(),
+    sqlc.arg(actor),
+    true
+) RETURNING *;
+
+-- ============================================================
+-- CHARGE TEMPLATE QUERIES
+-- ============================================================
+
+-- name: ListChargeTemplates :many
+SELECT *
+FROM charge_template
+WHERE is_active
+  AND (sqlc.narg(transport_mode)::text IS NULL OR transport_mode = sqlc.narg(transport_mode))
+ORDER BY transport_mode, service_type NULLS FIRST, service_subcategory NULLS FIRST, inco_term_code NULLS FIRST, name;
+
+-- name: ListChargeTemplateLines :many
+SELECT *
+FROM charge_template_line
+WHERE template_id = ANY(sqlc.arg(template_ids)::uuid[])
+ORDER BY template_id, sort_order, line_kind, activity_type, activity_code;
+
+-- name: GetMatchingChargeTemplate :one
+SELECT *
+FROM charge_template
+WHERE is_active
+  AND transport_mode = sqlc.arg(transport_mode)
+  AND (service_type IS NULL OR service_type = sqlc.narg(service_type))
+  AND (service_subcategory IS NULL OR service_subcategory = sqlc.narg(service_subcategory))
+  AND (inco_term_code IS NULL OR inco_term_code = sqlc.narg(inco_term_code))
+ORDER BY (service_type IS NOT NULL)::int
+       + (service_subcategory IS NOT NULL)::int
+       + (inco_term_code IS NOT NULL)::int DESC,
+         created_at DESC
+LIMIT 1;
+
+-- name: CreateChargeTemplate :one
+INSERT INTO charge_template (
+    name,
+    transport_mode,
+    service_type,
+    service_subcategory,
+    inco_term_code,
+    created_at,
+    created_by,
+    is_active
+) VALUES (
+    sqlc.arg(name),
+    sqlc.arg(transport_mode),
+    sqlc.narg(service_type),
+    sqlc.narg(service_subcategory),
+    sqlc.narg(inco_term_code),
+    now(),
+    sqlc.arg(actor),
+    true
+) RETURNING *;
+
+-- name: CreateChargeTemplateLine :one
+INSERT INTO charge_template_line (
+    template_id,
+    line_kind,
+    activity_type,
+    activity_code,
+    quantity_basis,
+    party_role,
+    currency_code,
+    unit_price,
+    tax_code,
+    description,
+    sort_order,
+    created_at,
+    created_by
+) VALUES (
+    sqlc.arg(template_id),
+    sqlc.arg(line_kind),
+    sqlc.arg(activity_type),
+    sqlc.arg(activity_code),
+    sqlc.arg(quantity_basis),
+    sqlc.narg(party_role),
+    sqlc.narg(currency_code),
+    sqlc.narg(unit_price),
+    sqlc.narg(tax_code),
+    sqlc.narg(description),
+    sqlc.arg(sort_order),
+    now(),
+    sqlc.arg(actor)
+) RETURNING *;
+
+-- name: DeactivateChargeTemplate :execrows
+UPDATE charge_template
+SET
+    is_active = false,
+    modified_at = now(),
+    modified_by = sqlc.arg(actor)
+WHERE id = sqlc.arg(id)
+  AND is_active;
+
 -- ============================================================
 -- JOB PARTY QUERIES
 -- ============================================================
@@ -558,6 +859,15 @@ SET
     is_active = true
 RETURNING *;
 
+-- name: ListPartiesByIDs :many
+SELECT
+    id,
+    name,
+    human_id,
+    status
+FROM party_master
+WHERE id = ANY(sqlc.arg(ids)::uuid[]);
+
 -- ============================================================
 -- JOB BILLING QUERIES
 -- ============================================================
@@ -621,6 +931,25 @@ INSERT INTO ops_billing (
     true
 ) RETURNING *;
 
+-- name: UpdateJobBillingRate :execrows
+UPDATE ops_billing
+SET
+    exchange_rate = sqlc.arg(exchange_rate),
+    amount_primary_currency = sqlc.arg(amount_primary_currency),
+    modified_at = now(),
+    modified_by = sqlc.arg(actor)
+WHERE id = sqlc.arg(id)
+  AND rate_locked_at IS NULL;
+
+-- name: LockJobBillingRates :execrows
+UPDATE ops_billing
+SET
+    rate_locked_at = now(),
+    rate_locked_by = sqlc.arg(actor)
+WHERE job_id = sqlc.arg(job_id)
+  AND is_active
+  AND rate_locked_at IS NULL;
+
 -- ============================================================
 -- JOB PROVISION QUERIES
 -- ============================================================
@@ -690,6 +1019,33 @@ INSERT INTO ops_provision (
     true
 ) RETURNING *;
 
+-- name: UpdateJobProvisionProfit :exec
+UPDATE ops_provision
+SET
+    profit = sqlc.narg(profit),
+    modified_at = now(),
+    modified_by = sqlc.arg(actor)
+WHERE id = sqlc.arg(id);
+
+-- name: UpdateJobProvisionRate :execrows
+UPDATE ops_provision
+SET
+    exchange_rate = sqlc.arg(exchange_rate),
+    amount_primary_currency = sqlc.arg(amount_primary_currency),
+    modified_at = now(),
+    modified_by = sqlc.arg(actor)
+WHERE id = sqlc.arg(id)
+  AND rate_locked_at IS NULL;
+
+-- name: LockJobProvisionRates :execrows
+UPDATE ops_provision
+SET
+    rate_locked_at = now(),
+    rate_locked_by = sqlc.arg(actor)
+WHERE job_id = sqlc.arg(job_id)
+  AND is_active
+  AND rate_locked_at IS NULL;
+
 -- ============================================================
 -- JOB TRACKING QUERIES
 -- ============================================================
@@ -746,3 +1102,769 @@ DO UPDATE SET
     modified_by = EXCLUDED.created_by
 RETURNING *;
 
+-- ============================================================
+-- EXCHANGE RATE QUERIES
+-- ============================================================
+
+-- name: GetExchangeRateInForce :one
+SELECT rate, effective_date
+FROM exchange_rate
+WHERE from_currency = sqlc.arg(from_currency)
+  AND to_currency = sqlc.arg(to_currency)
+  AND effective_date <= sqlc.arg(on_date)
+  AND is_active
+ORDER BY effective_date DESC
+LIMIT 1;
+
+-- name: ListExchangeRates :many
+SELECT *
+FROM exchange_rate
+WHERE is_active
+  AND (sqlc.narg(from_currency)::char(3) IS NULL OR from_currency = sqlc.narg(from_currency))
+  AND (sqlc.narg(to_currency)::char(3) IS NULL OR to_currency = sqlc.narg(to_currency))
+ORDER BY effective_date DESC, from_currency, to_currency
+LIMIT sqlc.arg(row_limit);
+
+-- name: UpsertExchangeRate :one
+INSERT INTO exchange_rate (
+    from_currency,
+    to_currency,
+    effective_date,
+    rate,
+    source,
+    created_at,
+    created_by,
+    is_active
+) VALUES (
+    sqlc.arg(from_currency),
+    sqlc.arg(to_currency),
+    sqlc.arg(effective_date),
+    sqlc.arg(rate),
+    sqlc.narg(source),
+    now(),
+    sqlc.arg(actor),
+    true
+)
+ON CONFLICT (from_currency, to_currency, effective_date)
+DO UPDATE SET
+    rate = EXCLUDED.rate,
+    source = EXCLUDED.source,
+    is_active = true,
+    modified_at = now(),
+    modified_by = EXCLUDED.created_by
+RETURNING *;
+
+-- ============================================================
+-- PROFITABILITY QUERIES
+-- ============================================================
+
+-- name: ListJobProfitability :many
+WITH revenue AS (
+    SELECT job_id, SUM(amount_primary_currency) AS amount
+    FROM ops_billing
+    WHERE is_active
+    GROUP BY job_id
+), cost AS (
+    SELECT job_id, SUM(amount_primary_currency) AS amount
+    FROM ops_provision
+    WHERE is_active
+    GROUP BY job_id
+)
+SELECT
+    j.id,
+    j.job_code,
+    j.job_type,
+    j.transport_mode,
+    j.customer_id,
+    cust.name AS customer_name,
+    j.branch_id,
+    j.branch_name,
+    j.sales_executive_id,
+    se.name AS sales_executive_name,
+    j.status,
+    j.created_at,
+    COALESCE(r.amount, 0)::numeric AS revenue,
+    COALESCE(c.amount, 0)::numeric AS cost
+FROM ops_job j
+LEFT JOIN revenue r ON r.job_id = j.id
+LEFT JOIN cost c ON c.job_id = j.id
+LEFT JOIN party_master cust ON cust.id = j.customer_id
+LEFT JOIN employee_master se ON se.id = j.sales_executive_id
+WHERE j.is_active
+  AND (sqlc.narg(job_id)::uuid IS NULL OR j.id = sqlc.narg(job_id))
+  AND (sqlc.narg(branch_id)::uuid IS NULL OR j.branch_id = sqlc.narg(branch_id))
+  AND (sqlc.narg(sales_executive_id)::uuid IS NULL OR j.sales_executive_id = sqlc.narg(sales_executive_id))
+  AND (sqlc.narg(customer_id)::uuid IS NULL OR j.customer_id = sqlc.narg(customer_id))
+  AND (sqlc.narg(transport_mode)::text IS NULL OR j.transport_mode = sqlc.narg(transport_mode))
+  AND (sqlc.narg(period_from)::timestamptz IS NULL OR j.created_at >= sqlc.narg(period_from))
+  AND (sqlc.narg(period_to)::timestamptz IS NULL OR j.created_at < sqlc.narg(period_to))
+  AND (sqlc.narg(scope_branches)::uuid[] IS NULL OR j.branch_id = ANY(sqlc.narg(scope_branches)::uuid[]))
+  AND (sqlc.narg(scope_sales_executive_id)::uuid IS NULL OR j.sales_executive_id = sqlc.narg(scope_sales_executive_id))
+ORDER BY j.created_at DESC
+LIMIT sqlc.arg(row_limit);
+
+-- name: ListActivityProfitability :many
+WITH lines AS (
+    SELECT job_id, activity_type, activity_code, amount_primary_currency AS revenue, 0::numeric AS cost
+    FROM ops_billing
+    WHERE is_active
+    UNION ALL
+    SELECT job_id, activity_type, activity_code, 0::numeric AS revenue, amount_primary_currency AS cost
+    FROM ops_provision
+    WHERE is_active
+)
+SELECT
+    l.activity_type,
+    l.activity_code,
+    COUNT(DISTINCT l.job_id)::int AS job_count,
+    COALESCE(SUM(l.revenue), 0)::numeric AS revenue,
+    COALESCE(SUM(l.cost), 0)::numeric AS cost
+FROM lines l
+JOIN ops_job j ON j.id = l.job_id
+WHERE j.is_active
+  AND (sqlc.narg(job_id)::uuid IS NULL OR j.id = sqlc.narg(job_id))
+  AND (sqlc.narg(branch_id)::uuid IS NULL OR j.branch_id = sqlc.narg(branch_id))
+  AND (sqlc.narg(sales_executive_id)::uuid IS NULL OR j.sales_executive_id = sqlc.narg(sales_executive_id))
+  AND (sqlc.narg(customer_id)::uuid IS NULL OR j.customer_id = sqlc.narg(customer_id))
+  AND (sqlc.narg(transport_mode)::text IS NULL OR j.transport_mode = sqlc.narg(transport_mode))
+  AND (sqlc.narg(period_from)::timestamptz IS NULL OR j.created_at >= sqlc.narg(period_from))
+  AND (sqlc.narg(period_to)::timestamptz IS NULL OR j.created_at < sqlc.narg(period_to))
+  AND (sqlc.narg(scope_branches)::uuid[] IS NULL OR j.branch_id = ANY(sqlc.narg(scope_branches)::uuid[]))
+  AND (sqlc.narg(scope_sales_executive_id)::uuid IS NULL OR j.sales_executive_id = sqlc.narg(scope_sales_executive_id))
+GROUP BY l.activity_type, l.activity_code
+ORDER BY l.activity_type, l.activity_code;
+
+-- ============================================================
+-- INVOICE QUERIES
+-- ============================================================
+
+-- name: ListUninvoicedJobBilling :many
+SELECT *
+FROM ops_billing
+WHERE job_id = sqlc.arg(job_id)
+  AND is_active
+  AND invoice_id IS NULL
+ORDER BY created_at;
+
+-- name: NextInvoiceNumber :one
+INSERT INTO ops_invoice_sequence (prefix, last_value)
+VALUES (sqlc.arg(prefix), 1)
+ON CONFLICT (prefix) DO UPDATE
+SET last_value = ops_invoice_sequence.last_value + 1
+RETURNING (prefix || lpad(last_value::text, 6, '0'))::text AS invoice_number;
+
+-- name: CreateInvoice :one
+INSERT INTO ops_invoice (
+    invoice_number,
+    job_id,
+    billing_party_id,
+    currency_code,
+    status,
+    subtotal,
+    tax_total,
+    total,
+    created_at,
+    created_by,
+    is_active
+) VALUES (
+    sqlc.arg(invoice_number),
+    sqlc.arg(job_id),
+    sqlc.narg(billing_party_id),
+    sqlc.arg(currency_code),
+    sqlc.arg(status),
+    sqlc.arg(subtotal),
+    sqlc.arg(tax_total),
+    sqlc.arg(total),
+    now(),
+    sqlc.arg(actor),
+    true
+) RETURNING *;
+
+-- name: MarkBillingInvoiced :execrows
+UPDATE ops_billing
+SET
+    invoice_id = sqlc.arg(invoice_id),
+    invoiced_at = now(),
+    rate_locked_at = COALESCE(rate_locked_at, now()),
+    rate_locked_by = COALESCE(rate_locked_by, sqlc.arg(actor)),
+    modified_at = now(),
+    modified_by = sqlc.arg(actor)
+WHERE id = ANY(sqlc.arg(billing_ids)::uuid[])
+  AND invoice_id IS NULL
+  AND is_active;
+
+-- name: GetInvoice :one
+SELECT
+    i.*,
+    j.job_code,
+    p.name AS billing_party_name
+FROM ops_invoice i
+JOIN ops_job j ON j.id = i.job_id
+LEFT JOIN party_master p ON p.id = i.billing_party_id
+WHERE i.id = sqlc.arg(id)
+  AND i.is_active;
+
+-- name: ListJobInvoices :many
+SELECT
+    i.*,
+    j.job_code,
+    p.name AS billing_party_name
+FROM ops_invoice i
+JOIN ops_job j ON j.id = i.job_id
+LEFT JOIN party_master p ON p.id = i.billing_party_id
+WHERE i.job_id = sqlc.arg(job_id)
+  AND i.is_active
+ORDER BY i.invoice_number;
+
+-- name: ListInvoiceBilling :many
+SELECT *
+FROM ops_billing
+WHERE invoice_id = ANY(sqlc.arg(invoice_ids)::uuid[])
+ORDER BY invoice_id, created_at;
+
+-- ============================================================
+-- EVENT OUTBOX QUERIES
+-- ============================================================
+
+-- name: CreateOutboxEvent :exec
+INSERT INTO ops_event_outbox (
+    event_type,
+    aggregate_type,
+    aggregate_id,
+    payload,
+    created_at,
+    created_by
+) VALUES (
+    sqlc.arg(event_type),
+    sqlc.arg(aggregate_type),
+    sqlc.arg(aggregate_id),
+    sqlc.arg(payload),
+    now(),
+    sqlc.arg(actor)
+);
+
+-- ============================================================
+-- ACCRUAL RECONCILIATION QUERIES
+-- ============================================================
+
+-- name: GetProvision :one
+SELECT
+    p.*,
+    j.job_code
+FROM ops_provision p
+JOIN ops_job j ON j.id = p.job_id
+WHERE p.id = sqlc.arg(id)
+  AND p.is_active;
+
+-- name: DeactivateProvisionReconciliation :many
+UPDATE ops_provision_reconciliation
+SET
+    is_active = false,
+    modified_at = now(),
+    modified_by = sqlc.arg(actor)
+WHERE provision_id = sqlc.arg(provision_id)
+  AND is_active
+RETURNING id;
+
+-- name: CreateProvisionReconciliation :one
+INSERT INTO ops_provision_reconciliation (
+    provision_id,
+    job_id,
+    supplier_invoice_number,
+    supplier_invoice_date,
+    currency_code,
+    amount_without_tax,
+    tax_amount,
+    total_amount,
+    exchange_rate,
+    expected_amount,
+    variance,
+    variance_percent,
+    variance_primary_currency,
+    status,
+    notes,
+    created_at,
+    created_by,
+    is_active
+) VALUES (
+    sqlc.arg(provision_id),
+    sqlc.arg(job_id),
+    sqlc.arg(supplier_invoice_number),
+    sqlc.narg(supplier_invoice_date),
+    sqlc.arg(currency_code),
+    sqlc.arg(amount_without_tax),
+    sqlc.narg(tax_amount),
+    sqlc.narg(total_amount),
+    sqlc.narg(exchange_rate),
+    sqlc.arg(expected_amount),
+    sqlc.arg(variance),
+    sqlc.narg(variance_percent),
+    sqlc.narg(variance_primary_currency),
+    sqlc.arg(status),
+    sqlc.narg(notes),
+    now(),
+    sqlc.arg(actor),
+    true
+) RETURNING *;
+
+-- name: UpdateProvisionSupplierInvoice :exec
+UPDATE ops_provision
+SET
+    invoice_number = sqlc.arg(invoice_number),
+    invoice_date = sqlc.narg(invoice_date),
+    modified_at = now(),
+    modified_by = sqlc.arg(actor)
+WHERE id = sqlc.arg(id);
+
+-- name: GetProvisionReconciliation :one
+SELECT
+    r.*,
+    j.job_code,
+    p.activity_type,
+    p.activity_code,
+    p.cost_party_id,
+    p.currency_code AS provision_currency_code,
+    pm.name AS cost_party_name
+FROM ops_provision_reconciliation r
+JOIN ops_provision p ON p.id = r.provision_id
+JOIN ops_job j ON j.id = r.job_id
+LEFT JOIN party_master pm ON pm.id = p.cost_party_id
+WHERE r.id = sqlc.arg(id);
+
+-- name: ListJobReconciliations :many
+SELECT
+    r.*,
+    j.job_code,
+    p.activity_type,
+    p.activity_code,
+    p.cost_party_id,
+    p.currency_code AS provision_currency_code,
+    pm.name AS cost_party_name
+FROM ops_provision_reconciliation r
+JOIN ops_provision p ON p.id = r.provision_id
+JOIN ops_job j ON j.id = r.job_id
+LEFT JOIN party_master pm ON pm.id = p.cost_party_id
+WHERE r.job_id = sqlc.arg(job_id)
+  AND r.is_active
+ORDER BY r.created_at;
+
+-- name: ListReconciliationsByStatus :many
+SELECT
+    r.*,
+    j.job_code,
+    p.activity_type,
+    p.activity_code,
+    p.cost_party_id,
+    p.currency_code AS provision_currency_code,
+    pm.name AS cost_party_name
+FROM ops_provision_reconciliation r
+JOIN ops_provision p ON p.id = r.provision_id
+JOIN ops_job j ON j.id = r.job_id
+LEFT JOIN party_master pm ON pm.id = p.cost_party_id
+WHERE r.status = sqlc.arg(status)
+  AND r.is_active
+  AND (sqlc.narg(scope_branches)::uuid[] IS NULL OR j.branch_id = ANY(sqlc.narg(scope_branches)::uuid[]))
+  AND (sqlc.narg(scope_sales_executive_id)::uuid IS NULL OR j.sales_executive_id = sqlc.narg(scope_sales_executive_id))
+ORDER BY r.created_at
+LIMIT sqlc.arg(row_limit);
+
+-- name: ListOpenAccruals :many
+-- Open accruals are active provisions with no supplier invoice recorded against them, oldest first.
+SELECT
+    p.id,
+    p.job_id,
+    j.job_code,
+    p.activity_type,
+    p.activity_code,
+    p.cost_party_id,
+    pm.name AS cost_party_name,
+    p.currency_code,
+    p.amount_without_tax,
+    p.amount_primary_currency,
+    p.po_number,
+    p.created_at,
+    (current_date - p.created_at::date)::int AS age_days
+FROM ops_provision p
+JOIN ops_job j ON j.id = p.job_id
+LEFT JOIN party_master pm ON pm.id = p.cost_party_id
+WHERE p.is_active
+  AND NOT EXISTS (
+      SELECT 1
+      FROM ops_provision_reconciliation r
+      WHERE r.provision_id = p.id
+        AND r.is_active
+  )
+  AND (sqlc.narg(cost_party_id)::uuid IS NULL OR p.cost_party_id = sqlc.narg(cost_party_id))
+  AND (sqlc.narg(min_age_days)::int IS NULL OR p.created_at::date <= current_date - sqlc.narg(min_age_days)::int)
+  AND (sqlc.narg(scope_branches)::uuid[] IS NULL OR j.branch_id = ANY(sqlc.narg(scope_branches)::uuid[]))
+  AND (sqlc.narg(scope_sales_executive_id)::uuid IS NULL OR j.sales_executive_id = sqlc.narg(scope_sales_executive_id))
+ORDER BY p.created_at
+LIMIT sqlc.arg(row_limit);
+
+-- ============================================================
+-- APPROVAL QUERIES
+-- ============================================================
+
+-- name: ListProvisionApprovalRules :many
+SELECT *
+FROM provision_approval_rule
+WHERE is_active
+ORDER BY name;
+
+-- name: CreateProvisionApprovalRule :one
+INSERT INTO provision_approval_rule (
+    name,
+    currency_code,
+    min_amount,
+    payment_priority,
+    min_vendor_risk,
+    approver_role,
+    created_at,
+    created_by,
+    is_active
+) VALUES (
+    sqlc.arg(name),
+    sqlc.narg(currency_code),
+    sqlc.narg(min_amount),
+    sqlc.narg(payment_priority),
+    sqlc.narg(min_vendor_risk),
+    sqlc.arg(approver_role),
+    now(),
+    sqlc.arg(actor),
+    true
+) RETURNING *;
+
+-- name: DeactivateProvisionApprovalRule :execrows
+UPDATE provision_approval_rule
+SET
+    is_active = false,
+    modified_at = now(),
+    modified_by = sqlc.arg(actor)
+WHERE id = sqlc.arg(id)
+  AND is_active;
+
+-- name: GetPartyRisk :one
+SELECT risk_level, status
+FROM party_master
+WHERE id = sqlc.arg(id);
+
+-- name: CreateApproval :one
+INSERT INTO ops_approval (
+    subject_type,
+    subject_id,
+    job_id,
+    rule_id,
+    required_role,
+    reason,
+    status,
+    requested_at,
+    requested_by
+) VALUES (
+    sqlc.arg(subject_type),
+    sqlc.arg(subject_id),
+    sqlc.arg(job_id),
+    sqlc.narg(rule_id),
+    sqlc.arg(required_role),
+    sqlc.arg(reason),
+    'Pending',
+    now(),
+    sqlc.arg(actor)
+) RETURNING *;
+
+-- name: CancelPendingApprovals :exec
+UPDATE ops_approval
+SET
+    status = 'Cancelled',
+    decided_at = now(),
+    decided_by = sqlc.arg(actor)
+WHERE subject_type = sqlc.arg(subject_type)
+  AND subject_id = ANY(sqlc.arg(subject_ids)::uuid[])
+  AND status = 'Pending';
+
+-- name: GetApproval :one
+SELECT *
+FROM ops_approval
+WHERE id = sqlc.arg(id);
+
+-- name: DecideApproval :execrows
+UPDATE ops_approval
+SET
+    status = sqlc.arg(status),
+    decided_at = now(),
+    decided_by = sqlc.arg(actor),
+    comment = sqlc.narg(comment)
+WHERE id = sqlc.arg(id)
+  AND status = 'Pending';
+
+-- name: SetProvisionApprovalStatus :exec
+UPDATE ops_provision
+SET
+    approval_status = sqlc.arg(approval_status),
+    modified_at = now(),
+    modified_by = sqlc.arg(actor)
+WHERE id = sqlc.arg(id);
+
+-- name: SetReconciliationStatus :exec
+UPDATE ops_provision_reconciliation
+SET
+    status = sqlc.arg(status),
+    modified_at = now(),
+    modified_by = sqlc.arg(actor)
+WHERE id = sqlc.arg(id);
+
+-- name: ListPendingApprovals :many
+SELECT
+    a.*,
+    j.job_code,
+    p.id AS provision_id,
+    p.activity_type,
+    p.activity_code,
+    p.cost_party_id,
+    pm.name AS cost_party_name,
+    p.currency_code,
+    p.total_amount,
+    p.payment_priority,
+    r.variance,
+    r.variance_percent
+FROM ops_approval a
+JOIN ops_job j ON j.id = a.job_id
+LEFT JOIN ops_provision_reconciliation r ON a.subject_type = 'reconciliation' AND r.id = a.subject_id
+LEFT JOIN ops_provision p ON p.id = CASE WHEN a.subject_type = 'provision' THEN a.subject_id ELSE r.provision_id END
+LEFT JOIN party_master pm ON pm.id = p.cost_party_id
+WHERE a.status = 'Pending'
+  AND a.required_role = ANY(sqlc.arg(roles)::text[])
+  AND (sqlc.narg(scope_branches)::uuid[] IS NULL OR j.branch_id = ANY(sqlc.narg(scope_branches)::uuid[]))
+  AND (sqlc.narg(scope_sales_executive_id)::uuid IS NULL OR j.sales_executive_id = sqlc.narg(scope_sales_executive_id))
+ORDER BY a.requested_at
+LIMIT sqlc.arg(row_limit);
+
+-- ============================================================
+-- PARTY ELIGIBILITY AND CREDIT QUERIES
+-- ============================================================
+
+-- name: ListPartyStatuses :many
+SELECT id, name, status
+FROM party_master
+WHERE id = ANY(sqlc.arg(ids)::uuid[]);
+
+-- name: ListJobPartyStatuses :many
+SELECT 'customer'::text AS party_role, pm.id, pm.name, pm.status
+FROM ops_job j
+JOIN party_master pm ON pm.id = j.customer_id
+WHERE j.id = sqlc.arg(job_id)
+UNION
+SELECT 'agent'::text, pm.id, pm.name, pm.status
+FROM ops_job j
+JOIN party_master pm ON pm.id = j.agent_id
+WHERE j.id = sqlc.arg(job_id)
+UNION
+SELECT 'carrier'::text, pm.id, pm.name, pm.status
+FROM ops_carrier c
+JOIN party_master pm ON pm.id = c.carrier_party_id
+WHERE c.job_id = sqlc.arg(job_id)
+  AND c.is_active
+UNION
+SELECT 'billing party'::text, pm.id, pm.name, pm.status
+FROM ops_billing b
+JOIN party_master pm ON pm.id = b.billing_party_id
+WHERE b.job_id = sqlc.arg(job_id)
+  AND b.is_active
+UNION
+SELECT 'cost party'::text, pm.id, pm.name, pm.status
+FROM ops_provision p
+JOIN party_master pm ON pm.id = p.cost_party_id
+WHERE p.job_id = sqlc.arg(job_id)
+  AND p.is_active;
+
+-- name: GetPartyCreditLimit :one
+SELECT *
+FROM party_credit_limit
+WHERE party_id = sqlc.arg(party_id);
+
+-- name: UpsertPartyCreditLimit :one
+INSERT INTO party_credit_limit (
+    party_id,
+    credit_limit,
+    created_at,
+    created_by
+) VALUES (
+    sqlc.arg(party_id),
+    sqlc.arg(credit_limit),
+    now(),
+    sqlc.arg(actor)
+)
+ON CONFLICT (party_id) DO UPDATE
+SET
+    credit_limit = EXCLUDED.credit_limit,
+    modified_at = now(),
+    modified_by = EXCLUDED.created_by
+RETURNING *;
+
+-- name: DeletePartyCreditLimit :execrows
+DELETE FROM party_credit_limit
+WHERE party_id = sqlc.arg(party_id);
+
+-- Open billed lines sit on an invoice that is not cancelled; every other line is unbilled.
+-- name: GetCustomerCreditExposure :one
+SELECT
+    COALESCE(SUM(b.amount_primary_currency) FILTER (WHERE i.id IS NOT NULL AND i.status <> 'Cancelled'), 0)::numeric AS open_billed,
+    COALESCE(SUM(b.amount_primary_currency) FILTER (WHERE i.id IS NULL OR i.status = 'Cancelled'), 0)::numeric AS unbilled
+FROM ops_billing b
+JOIN ops_job j ON j.id = b.job_id
+LEFT JOIN ops_invoice i ON i.id = b.invoice_id
+WHERE j.customer_id = sqlc.arg(customer_id)
+  AND j.is_active
+  AND b.is_active
+  AND (j.status = 'Active' OR j.id = sqlc.narg(job_id));
+
+-- name: CreateCreditOverride :exec
+INSERT INTO ops_credit_override (
+    job_id,
+    party_id,
+    credit_limit,
+    exposure,
+    currency_code,
+    overridden_at,
+    overridden_by
+) VALUES (
+    sqlc.arg(job_id),
+    sqlc.arg(party_id),
+    sqlc.arg(credit_limit),
+    sqlc.arg(exposure),
+    sqlc.arg(currency_code),
+    now(),
+    sqlc.arg(actor)
+);
+
+-- name: ListPartyCreditOverrides :many
+SELECT o.*, j.job_code
+FROM ops_credit_override o
+JOIN ops_job j ON j.id = o.job_id
+WHERE o.party_id = sqlc.arg(party_id)
+ORDER BY o.overridden_at DESC;
+
+-- ============================================================
+-- RESTRICTED-PARTY SCREENING QUERIES
+-- ============================================================
+
+-- name: ListJobScreeningParties :many
+SELECT r.party_role, pm.id, pm.name
+FROM (
+    SELECT 'customer'::text AS party_role, j.customer_id AS party_id FROM ops_job j WHERE j.id = sqlc.arg(job_id)
+    UNION ALL SELECT 'agent', j.agent_id FROM ops_job j WHERE j.id = sqlc.arg(job_id)
+    UNION ALL SELECT 'shipper', p.shipper_id FROM ops_party p WHERE p.job_id = sqlc.arg(job_id) AND p.is_active
+    UNION ALL SELECT 'consignee', p.consignee_id FROM ops_party p WHERE p.job_id = sqlc.arg(job_id) AND p.is_active
+    UNION ALL SELECT 'notify party', p.notify_party_id FROM ops_party p WHERE p.job_id = sqlc.arg(job_id) AND p.is_active
+    UNION ALL SELECT 'switch B/L shipper', p.switch_bl_shipper_id FROM ops_party p WHERE p.job_id = sqlc.arg(job_id) AND p.is_active
+    UNION ALL SELECT 'switch B/L consignee', p.switch_bl_consignee_id FROM ops_party p WHERE p.job_id = sqlc.arg(job_id) AND p.is_active
+    UNION ALL SELECT 'switch B/L notify party', p.switch_bl_notify_party_id FROM ops_party p WHERE p.job_id = sqlc.arg(job_id) AND p.is_active
+    UNION ALL SELECT 'origin agent', p.origin_agent_id FROM ops_party p WHERE p.job_id = sqlc.arg(job_id) AND p.is_active
+    UNION ALL SELECT 'destination agent', p.destination_agent_id FROM ops_party p WHERE p.job_id = sqlc.arg(job_id) AND p.is_active
+) r
+JOIN party_master pm ON pm.id = r.party_id;
+
+-- name: SupersedeJobScreenings :exec
+UPDATE ops_party_screening
+SET
+    status = 'Superseded',
+    resolved_at = now(),
+    resolved_by = sqlc.arg(actor)
+WHERE job_id = sqlc.arg(job_id)
+  AND status IN ('Clear', 'PotentialMatch');
+
+-- name: ListResolvedScreeningMatches :many
+SELECT party_id, list_name, entry_id, status
+FROM ops_party_screening
+WHERE job_id = sqlc.arg(job_id)
+  AND status IN ('Cleared', 'Confirmed');
+
+-- name: CreatePartyScreening :one
+INSERT INTO ops_party_screening (
+    job_id,
+    party_id,
+    party_role,
+    party_name,
+    list_name,
+    list_version,
+    entry_id,
+    entry_name,
+    matched_name,
+    program,
+    score,
+    status,
+    screened_at,
+    screened_by
+) VALUES (
+    sqlc.arg(job_id),
+    sqlc.arg(party_id),
+    sqlc.arg(party_role),
+    sqlc.arg(party_name),
+    sqlc.arg(list_name),
+    sqlc.arg(list_version),
+    sqlc.narg(entry_id),
+    sqlc.narg(entry_name),
+    sqlc.narg(matched_name),
+    sqlc.narg(program),
+    sqlc.narg(score),
+    sqlc.arg(status),
+    now(),
+    sqlc.arg(actor)
+) RETURNING *;
+
+-- name: ListJobScreenings :many
+SELECT *
+FROM ops_party_screening
+WHERE job_id = sqlc.arg(job_id)
+  AND status <> 'Superseded'
+ORDER BY party_role, list_name, score DESC NULLS LAST;
+
+-- name: GetPartyScreening :one
+SELECT *
+FROM ops_party_screening
+WHERE id = sqlc.arg(id);
+
+-- name: ResolvePartyScreening :execrows
+UPDATE ops_party_screening
+SET
+    status = sqlc.arg(status),
+    resolved_at = now(),
+    resolved_by = sqlc.arg(actor),
+    resolution_note = sqlc.arg(note)
+WHERE id = sqlc.arg(id)
+  AND status = 'PotentialMatch';
+
+-- name: CountBlockingScreenings :one
+SELECT count(*)
+FROM ops_party_screening
+WHERE job_id = sqlc.arg(job_id)
+  AND status IN ('PotentialMatch', 'Confirmed');
+
+-- ============================================================
+-- ROLE PERMISSION QUERIES
+-- ============================================================
+
+-- name: ListRolePermissions :many
+SELECT
+    rd.role_id,
+    rd.role_name,
+    rp.permission
+FROM ops_role_permission rp
+JOIN role_details_lu rd ON rd.role_id = rp.role_id
+WHERE COALESCE(rd.is_active, true)
+ORDER BY rd.role_id, rp.permission;
+
+-- name: GetRoleDetails :one
+SELECT
+    role_id,
+    role_name,
+    role_desc
+FROM role_details_lu
+WHERE role_id = sqlc.arg(role_id);
+
+-- name: DeleteRolePermissions :exec
+DELETE FROM ops_role_permission
+WHERE role_id = sqlc.arg(role_id);
+
+-- name: CreateRolePermission :exec
+INSERT INTO ops_role_permission (role_id, permission, created_by)
+VALUES (sqlc.arg(role_id), sqlc.arg(permission), sqlc.arg(actor));
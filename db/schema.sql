@@ -56,6 +56,16 @@ CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
     is_active   boolean DEFAULT true
   );
 
+  -- Operations permissions a tenant grants to a role, on top of the built-in policy. The role is
+  -- matched to token roles by role_details_lu.role_name.
+  CREATE TABLE IF NOT EXISTS ops_role_permission (
+    role_id     smallint NOT NULL REFERENCES role_details_lu(role_id) ON DELETE CASCADE,
+    permission  text NOT NULL,
+    created_at  timestamptz NOT NULL DEFAULT now(),
+    created_by  text,
+    PRIMARY KEY (role_id, permission)
+  );
+
   CREATE TABLE IF NOT EXISTS priority_lu (
     priority_id     smallint PRIMARY KEY,
     priority_label  text,
@@ -76,6 +86,11 @@ CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
     is_active    boolean DEFAULT true
   );
 
+  -- Customer invoices are rendered through the document template machinery.
+  INSERT INTO document_type_lu (code, label, created_at, created_by)
+  VALUES ('INV', 'Customer Invoice', now(), 'system')
+  ON CONFLICT DO NOTHING;
+
   CREATE TABLE IF NOT EXISTS container_type_lu (
     label       text NOT NULL,
     value       text PRIMARY KEY,
@@ -119,12 +134,102 @@ CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
   CREATE TABLE IF NOT EXISTS activity_lu (
     activity_type text NOT NULL,
     activity_code text NOT NULL,
+    cost_segment  text CHECK (cost_segment IN ('origin_handling','export_customs','main_carriage','insurance','import_customs','destination_delivery')),
+    -- Air waybill codes: the IATA rate class (e.g. N, Q, M) of a freight activity, or the IATA
+    -- other charge code (e.g. AW, MY) of any other activity with whether it is due agent (A) or
+    -- due carrier (C)
+    iata_charge_code text CHECK (iata_charge_code ~ '^[A-Z]{2}$'),
+    iata_charge_entitlement text CHECK (iata_charge_entitlement IN ('A','C')),
+    iata_rate_class  text CHECK (iata_rate_class IN ('M','N','Q','B','K','C','R','S','U','E','X','Y','W')),
     created_at    timestamptz,
     created_by    text,
     modified_at   timestamptz,
     modified_by   text,
     is_active     boolean DEFAULT true,
-    PRIMARY KEY (activity_type, activity_code)
+    PRIMARY KEY (activity_type, activity_code),
+    CHECK ((iata_charge_code IS NULL) = (iata_charge_entitlement IS NULL)),
+    CHECK (iata_charge_code IS NULL OR iata_rate_class IS NULL)
+  );
+
+  -- Incoterms catalogue. Each cost segment column names the party that bears it:
+  -- the shipper (seller) or the consignee (buyer).
+  CREATE TABLE IF NOT EXISTS incoterm_lu (
+    id                   uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    code                 text NOT NULL UNIQUE,
+    name                 text NOT NULL,
+    version              integer NOT NULL,
+    origin_handling      text NOT NULL CHECK (origin_handling IN ('shipper','consignee')),
+    export_customs       text NOT NULL CHECK (export_customs IN ('shipper','consignee')),
+    main_carriage        text NOT NULL CHECK (main_carriage IN ('shipper','consignee')),
+    insurance            text NOT NULL CHECK (insurance IN ('shipper','consignee')),
+    import_customs       text NOT NULL CHECK (import_customs IN ('shipper','consignee')),
+    destination_delivery text NOT NULL CHECK (destination_delivery IN ('shipper','consignee')),
+    created_at           timestamptz,
+    created_by           text,
+    modified_at          timestamptz,
+    modified_by          text,
+    is_active            boolean DEFAULT true
+  );
+
+  INSERT INTO incoterm_lu (code, name, version, origin_handling, export_customs, main_carriage, insurance, import_customs, destination_delivery, created_at, created_by)
+  VALUES
+    ('EXW', 'Ex Works',                       2020, 'consignee', 'consignee', 'consignee', 'consignee', 'consignee', 'consignee', now(), 'system'),
+    ('FCA', 'Free Carrier',                   2020, 'shipper',   'shipper',   'consignee', 'consignee', 'consignee', 'consignee', now(), 'system'),
+    ('FAS', 'Free Alongside Ship',            2020, 'shipper',   'shipper',   'consignee', 'consignee', 'consignee', 'consignee', now(), 'system'),
+    ('FOB', 'Free On Board',                  2020, 'shipper',   'shipper',   'consignee', 'consignee', 'consignee', 'consignee', now(), 'system'),
+    ('CFR', 'Cost and Freight',               2020, 'shipper',   'shipper',   'shipper',   'consignee', 'consignee', 'consignee', now(), 'system'),
+    ('CIF', 'Cost, Insurance and Freight',    2020, 'shipper',   'shipper',   'shipper',   'shipper',   'consignee', 'consignee', now(), 'system'),
+    ('CPT', 'Carriage Paid To',               2020, 'shipper',   'shipper',   'shipper',   'consignee', 'consignee', 'consignee', now(), 'system'),
+    ('CIP', 'Carriage and Insurance Paid To', 2020, 'shipper',   'shipper',   'shipper',   'shipper',   'consignee', 'consignee', now(), 'system'),
+    ('DAP', 'Delivered at Place',             2020, 'shipper',   'shipper',   'shipper',   'shipper',   'consignee', 'shipper',   now(), 'system'),
+    ('DPU', 'Delivered at Place Unloaded',    2020, 'shipper',   'shipper',   'shipper',   'shipper',   'consignee', 'shipper',   now(), 'system'),
+    ('DDP', 'Delivered Duty Paid',            2020, 'shipper',   'shipper',   'shipper',   'shipper',   'shipper',   'shipper',   now(), 'system')
+  ON CONFLICT (code) DO NOTHING;
+
+  CREATE TABLE IF NOT EXISTS tax_code_lu (
+    tax_code     text PRIMARY KEY,
+    tax_desc     text,
+    rate_percent numeric(7,4) NOT NULL CHECK (rate_percent >= 0),
+    created_at   timestamptz,
+    created_by   text,
+    modified_at  timestamptz,
+    modified_by  text,
+    is_active    boolean DEFAULT true
+  );
+
+  -- ============================================================
+  --  TENANT SETTINGS (single row)
+  -- ============================================================
+
+  CREATE TABLE IF NOT EXISTS tenant_setting (
+    id                    boolean PRIMARY KEY DEFAULT true CHECK (id),
+    primary_currency_code char(3) NOT NULL,
+    -- Supplier invoice variances within both tolerances match automatically; an unset tolerance is not applied.
+    accrual_tolerance_percent numeric(7,4) CHECK (accrual_tolerance_percent >= 0),
+    accrual_tolerance_amount  numeric(20,3) CHECK (accrual_tolerance_amount >= 0), -- in the primary currency
+    created_at            timestamptz DEFAULT now(),
+    created_by            text,
+    modified_at           timestamptz,
+    modified_by           text
+  );
+
+  -- ============================================================
+  --  EXCHANGE RATES (rate sheets keyed by pair and effective date)
+  -- ============================================================
+
+  CREATE TABLE IF NOT EXISTS exchange_rate (
+    id             uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    from_currency  char(3) NOT NULL,
+    to_currency    char(3) NOT NULL,
+    effective_date date NOT NULL,
+    rate           numeric(18,8) NOT NULL CHECK (rate > 0),
+    source         text,
+    created_at     timestamptz DEFAULT now(),
+    created_by     text,
+    modified_at    timestamptz,
+    modified_by    text,
+    is_active      boolean DEFAULT true,
+    UNIQUE (from_currency, to_currency, effective_date)
   );
 
   -- ============================================================
@@ -164,6 +269,7 @@ CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
     owner_id_number      text,
     owner_id_expiry      date,
     status               text CHECK (status IN ('Active','Inactive','Blacklisted')) DEFAULT 'Active',
+    risk_level           text CHECK (risk_level IN ('Low','Medium','High')),
     created_at           timestamptz DEFAULT now(),
     created_by           text,
     modified_at          timestamptz,
@@ -171,6 +277,16 @@ CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
     is_active            boolean DEFAULT true
   );
 
+  -- Customer credit limits, in the tenant's primary currency. Customers without a row are not limited.
+  CREATE TABLE IF NOT EXISTS party_credit_limit (
+    party_id     uuid PRIMARY KEY REFERENCES party_master(id) ON DELETE CASCADE,
+    credit_limit numeric(20,3) NOT NULL CHECK (credit_limit >= 0),
+    created_at   timestamptz DEFAULT now(),
+    created_by   text,
+    modified_at  timestamptz,
+    modified_by  text
+  );
+
   -- ============================================================
   --  OPERATIONS MODULE
   -- ============================================================
@@ -217,6 +333,58 @@ CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
 
   CREATE INDEX IF NOT EXISTS idx_ops_job_job_code ON ops_job(job_code);
 
+  -- Job status transitions. Reopening a closed job requires a reason.
+  CREATE TABLE IF NOT EXISTS ops_job_status_history (
+    id          uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    job_id      uuid NOT NULL REFERENCES ops_job(id) ON DELETE CASCADE,
+    from_status text,
+    to_status   text,
+    reason      text,
+    changed_at  timestamptz DEFAULT now(),
+    changed_by  text
+  );
+
+  CREATE INDEX IF NOT EXISTS idx_ops_job_status_history_job ON ops_job_status_history(job_id, changed_at);
+
+  -- Jobs created or activated past the customer's credit limit by a user holding the override role.
+  CREATE TABLE IF NOT EXISTS ops_credit_override (
+    id            uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    job_id        uuid NOT NULL REFERENCES ops_job(id) ON DELETE CASCADE,
+    party_id      uuid NOT NULL,
+    credit_limit  numeric(20,3) NOT NULL,
+    exposure      numeric(20,3) NOT NULL, -- open billed plus unbilled, including the job
+    currency_code char(3) NOT NULL,
+    overridden_at timestamptz DEFAULT now(),
+    overridden_by text
+  );
+
+  CREATE INDEX IF NOT EXISTS idx_ops_credit_override_party ON ops_credit_override(party_id, overridden_at);
+
+  -- Restricted-party screening results: one row per potential match, or a Clear row when a party
+  -- has none on a list. Rescreening supersedes Clear and PotentialMatch rows; decisions stand.
+  CREATE TABLE IF NOT EXISTS ops_party_screening (
+    id              uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    job_id          uuid NOT NULL REFERENCES ops_job(id) ON DELETE CASCADE,
+    party_id        uuid NOT NULL,
+    party_role      text NOT NULL,
+    party_name      text NOT NULL,
+    list_name       text NOT NULL,
+    list_version    text NOT NULL,
+    entry_id        text,
+    entry_name      text,
+    matched_name    text,
+    program         text,
+    score           numeric(5,4),
+    status          text NOT NULL CHECK (status IN ('Clear','PotentialMatch','Cleared','Confirmed','Superseded')),
+    screened_at     timestamptz DEFAULT now(),
+    screened_by     text,
+    resolved_at     timestamptz,
+    resolved_by     text,
+    resolution_note text
+  );
+
+  CREATE INDEX IF NOT EXISTS idx_ops_party_screening_job ON ops_party_screening(job_id, status);
+
   CREATE TABLE IF NOT EXISTS ops_package (
     id                         uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
     job_id                     uuid NOT NULL REFERENCES ops_job(id) ON DELETE CASCADE,
@@ -263,6 +431,7 @@ CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
     destination_country   text,
     accounting_info       text,
     handling_info         text,
+    charges_code          text CHECK (charges_code IN ('PP','CC')), -- Air waybill charges prepaid or collect
     transport_document_reference text,
     supporting_doc_url    text[],
     file_region           text,
@@ -319,6 +488,32 @@ CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
     is_active       boolean DEFAULT true
   );
 
+  -- Customer invoices drafted from a job's billing lines, one per billing party and currency.
+  CREATE TABLE IF NOT EXISTS ops_invoice (
+    id               uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    invoice_number   text NOT NULL UNIQUE,
+    job_id           uuid NOT NULL REFERENCES ops_job(id),
+    billing_party_id uuid, -- Soft FK
+    currency_code    char(3) NOT NULL,
+    status           text NOT NULL DEFAULT 'Draft' CHECK (status IN ('Draft','Issued','Cancelled')),
+    subtotal         numeric(20,3) NOT NULL,
+    tax_total        numeric(20,3) NOT NULL,
+    total            numeric(20,3) NOT NULL,
+    created_at       timestamptz DEFAULT now(),
+    created_by       text,
+    modified_at      timestamptz,
+    modified_by      text,
+    is_active        boolean DEFAULT true
+  );
+
+  CREATE INDEX IF NOT EXISTS idx_ops_invoice_job ON ops_invoice(job_id);
+
+  -- Per-prefix invoice counters; the row lock serialises numbering within the tenant.
+  CREATE TABLE IF NOT EXISTS ops_invoice_sequence (
+    prefix     text PRIMARY KEY,
+    last_value integer NOT NULL
+  );
+
   CREATE TABLE IF NOT EXISTS ops_billing (
     id                      uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
     job_id                  uuid REFERENCES ops_job(id) ON DELETE CASCADE,
@@ -334,13 +529,17 @@ CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
     amount_without_tax      numeric(30,3),
     tax_code                text, -- Global Lookup
     tax_amount              numeric(10,3),
-    exchange_rate           numeric(10,3),
+    exchange_rate           numeric(18,8),
     total_amount            numeric(20,3),
     description             text,
     notes                   text,
     supporting_doc_url      text[],
     file_region             text,
     amount_primary_currency numeric(20,3),
+    rate_locked_at          timestamptz,
+    rate_locked_by          text,
+    invoice_id              uuid REFERENCES ops_invoice(id),
+    invoiced_at             timestamptz,
     created_at              timestamptz DEFAULT now(),
     created_by              text,
     modified_at             timestamptz,
@@ -366,13 +565,16 @@ CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
     total_amount            numeric(20,3),
     po_number               text,
     po_date                 timestamptz,
-    exchange_rate           numeric(10,3),
+    exchange_rate           numeric(18,8),
     payment_priority        text,
     notes                   text,
     supporting_doc_url      text[],
     file_region             text,
     amount_primary_currency numeric(14,2),
     profit                  numeric(14,2),
+    rate_locked_at          timestamptz,
+    rate_locked_by          text,
+    approval_status         text NOT NULL DEFAULT 'NotRequired' CHECK (approval_status IN ('NotRequired','PendingApproval','Approved','Rejected')),
     created_at              timestamptz DEFAULT now(),
     created_by              text,
     modified_at             timestamptz,
@@ -380,6 +582,75 @@ CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
     is_active               boolean DEFAULT true
   );
 
+  -- Supplier invoices recorded against provisions (accruals). One active row per provision;
+  -- re-recording an invoice retires the previous row.
+  CREATE TABLE IF NOT EXISTS ops_provision_reconciliation (
+    id                        uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    provision_id              uuid NOT NULL REFERENCES ops_provision(id) ON DELETE CASCADE,
+    job_id                    uuid NOT NULL REFERENCES ops_job(id) ON DELETE CASCADE,
+    supplier_invoice_number   text NOT NULL,
+    supplier_invoice_date     timestamptz,
+    currency_code             char(3) NOT NULL,
+    amount_without_tax        numeric(20,3) NOT NULL,
+    tax_amount                numeric(20,3),
+    total_amount              numeric(20,3),
+    exchange_rate             numeric(18,8), -- invoice currency to provision currency
+    expected_amount           numeric(20,3) NOT NULL, -- provision amount_without_tax at recording time
+    variance                  numeric(20,3) NOT NULL, -- in the provision currency, actual minus expected
+    variance_percent          numeric(12,4),
+    variance_primary_currency numeric(20,3),
+    status                    text NOT NULL CHECK (status IN ('Matched','PendingApproval','Approved','Rejected')),
+    notes                     text,
+    created_at                timestamptz DEFAULT now(),
+    created_by                text,
+    modified_at               timestamptz,
+    modified_by               text,
+    is_active                 boolean DEFAULT true
+  );
+
+  CREATE UNIQUE INDEX IF NOT EXISTS idx_ops_provision_reconciliation_active ON ops_provision_reconciliation(provision_id) WHERE is_active;
+  CREATE INDEX IF NOT EXISTS idx_ops_provision_reconciliation_status ON ops_provision_reconciliation(status) WHERE is_active;
+
+  -- Tenant rules that send new provisions for approval. A rule matches when every condition it
+  -- sets holds; the amount threshold applies to provisions in the rule's currency.
+  CREATE TABLE IF NOT EXISTS provision_approval_rule (
+    id               uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name             text NOT NULL,
+    currency_code    char(3),
+    min_amount       numeric(20,3) CHECK (min_amount >= 0),
+    payment_priority text,
+    min_vendor_risk  text CHECK (min_vendor_risk IN ('Low','Medium','High')),
+    approver_role    text NOT NULL,
+    created_at       timestamptz DEFAULT now(),
+    created_by       text,
+    modified_at      timestamptz,
+    modified_by      text,
+    is_active        boolean DEFAULT true,
+    CHECK (min_amount IS NULL OR currency_code IS NOT NULL),
+    CHECK (min_amount IS NOT NULL OR payment_priority IS NOT NULL OR min_vendor_risk IS NOT NULL)
+  );
+
+  -- Approval requests for provisions and for supplier invoice variances outside tolerance.
+  -- Only a principal holding required_role may decide a request.
+  CREATE TABLE IF NOT EXISTS ops_approval (
+    id            uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    subject_type  text NOT NULL CHECK (subject_type IN ('provision','reconciliation')),
+    subject_id    uuid NOT NULL,
+    job_id        uuid NOT NULL REFERENCES ops_job(id) ON DELETE CASCADE,
+    rule_id       uuid REFERENCES provision_approval_rule(id),
+    required_role text NOT NULL,
+    reason        text NOT NULL,
+    status        text NOT NULL DEFAULT 'Pending' CHECK (status IN ('Pending','Approved','Rejected','Cancelled')),
+    requested_at  timestamptz DEFAULT now(),
+    requested_by  text,
+    decided_at    timestamptz,
+    decided_by    text,
+    comment       text
+  );
+
+  CREATE UNIQUE INDEX IF NOT EXISTS idx_ops_approval_pending_subject ON ops_approval(subject_type, subject_id) WHERE status = 'Pending';
+  CREATE INDEX IF NOT EXISTS idx_ops_approval_pending_role ON ops_approval(required_role, requested_at) WHERE status = 'Pending';
+
   CREATE TABLE IF NOT EXISTS ops_tracking (
     id               uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
     job_id           uuid REFERENCES ops_job(id) ON DELETE CASCADE,
@@ -399,6 +670,72 @@ CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
     is_active        boolean DEFAULT true
   );
 
+  -- Versioned printable document templates. Rows are immutable; saving a template
+  -- inserts the next version and generation always uses the newest active one.
+  CREATE TABLE IF NOT EXISTS doc_template (
+    id              uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    doc_type_code   text NOT NULL REFERENCES document_type_lu(code),
+    version         integer NOT NULL,
+    name            text,
+    body_html       text NOT NULL,
+    created_at      timestamptz DEFAULT now(),
+    created_by      text,
+    modified_at     timestamptz,
+    modified_by     text,
+    is_active       boolean DEFAULT true,
+    UNIQUE (doc_type_code, version)
+  );
+
+  -- Charge templates propose the recurring billing and provision lines for a service
+  -- combination. NULL keys match any value; the most specific active template wins.
+  CREATE TABLE IF NOT EXISTS charge_template (
+    id                  uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name                text NOT NULL,
+    transport_mode      text NOT NULL,
+    service_type        text,
+    service_subcategory text,
+    inco_term_code      text, -- Global Lookup (Soft FK)
+    created_at          timestamptz DEFAULT now(),
+    created_by          text,
+    modified_at         timestamptz,
+    modified_by         text,
+    is_active           boolean DEFAULT true
+  );
+
+  CREATE TABLE IF NOT EXISTS charge_template_line (
+    id             uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    template_id    uuid NOT NULL REFERENCES charge_template(id) ON DELETE CASCADE,
+    line_kind      text NOT NULL CHECK (line_kind IN ('billing','provision')),
+    activity_type  text NOT NULL,
+    activity_code  text NOT NULL,
+    FOREIGN KEY (activity_type, activity_code) REFERENCES activity_lu (activity_type, activity_code),
+    quantity_basis text NOT NULL CHECK (quantity_basis IN ('per_container','per_kg','per_shipment')),
+    party_role     text CHECK (party_role IN ('customer','agent','carrier','shipper','consignee','notify_party','origin_agent','destination_agent')),
+    currency_code  char(3), -- Global Lookup
+    unit_price     numeric(10,3),
+    tax_code       text, -- Global Lookup
+    description    text,
+    sort_order     integer NOT NULL DEFAULT 0,
+    created_at     timestamptz DEFAULT now(),
+    created_by     text
+  );
+
+  CREATE INDEX IF NOT EXISTS idx_charge_template_line_template ON charge_template_line(template_id);
+
+  -- Transactional outbox of integration events for other modules (finance polls it).
+  CREATE TABLE IF NOT EXISTS ops_event_outbox (
+    id             uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    event_type     text NOT NULL,
+    aggregate_type text NOT NULL,
+    aggregate_id   uuid NOT NULL,
+    payload        jsonb NOT NULL,
+    created_at     timestamptz DEFAULT now(),
+    created_by     text,
+    published_at   timestamptz
+  );
+
+  CREATE INDEX IF NOT EXISTS idx_ops_event_outbox_pending ON ops_event_outbox(created_at) WHERE published_at IS NULL;
+
 -- ============================================================
 --  ORDERS (PRICING TOOL)
 -- ============================================================
@@ -56,6 +56,111 @@ CREATE INDEX IF NOT EXISTS idx_module_log_tenant ON tenant_module_log(tenant_id)
 CREATE INDEX IF NOT EXISTS idx_module_log_module ON tenant_module_log(module_name);
 CREATE INDEX IF NOT EXISTS idx_module_log_status ON tenant_module_log(status);
 
+-- ============================================================
+--  CROSS-TENANT ACCESS AUDIT
+-- ============================================================
+
+-- One row per request a super-admin makes into a tenant other than the one in their token.
+CREATE TABLE IF NOT EXISTS tenant_access_audit (
+  id                uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  tenant_id         uuid NOT NULL REFERENCES tenant_registry(tenant_id),
+  home_tenant_id    text,
+  subject           text NOT NULL,
+  username          text,
+  module_name       text NOT NULL,
+  method            text NOT NULL,
+  path              text NOT NULL,
+  request_id        text,
+  accessed_at       timestamptz DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_tenant_access_audit_tenant ON tenant_access_audit(tenant_id, accessed_at);
+
+-- ============================================================
+--  API KEYS
+-- ============================================================
+
+-- Tenant-scoped keys for machine clients. Only the SHA-256 hash of the secret is stored; the
+-- prefix identifies the key and is shown to admins. A rotated key points at its replacement and
+-- keeps working until its shortened expiry. A key sees the jobs of branch_ids, or of every branch
+-- when all_branches is set; a key with neither sees none.
+CREATE TABLE IF NOT EXISTS tenant_api_key (
+  id                uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  tenant_id         uuid NOT NULL REFERENCES tenant_registry(tenant_id),
+  name              text NOT NULL,
+  key_prefix        text NOT NULL UNIQUE,
+  key_hash          bytea NOT NULL,
+  scopes            text[] NOT NULL DEFAULT '{}',
+  branch_ids        uuid[] NOT NULL DEFAULT '{}',
+  all_branches      boolean NOT NULL DEFAULT false,
+  expires_at        timestamptz,
+  last_used_at      timestamptz,
+  replaced_by       uuid REFERENCES tenant_api_key(id),
+  created_at        timestamptz NOT NULL DEFAULT now(),
+  created_by        text,
+  revoked_at        timestamptz,
+  revoked_by        text
+);
+
+-- Registries created before keys carried branches. Their existing keys see no jobs; issue
+-- replacements with branches and revoke them.
+ALTER TABLE tenant_api_key ADD COLUMN IF NOT EXISTS branch_ids uuid[] NOT NULL DEFAULT '{}';
+ALTER TABLE tenant_api_key ADD COLUMN IF NOT EXISTS all_branches boolean NOT NULL DEFAULT false;
+
+CREATE INDEX IF NOT EXISTS idx_tenant_api_key_tenant ON tenant_api_key(tenant_id, created_at);
+
+-- ============================================================
+--  RATE LIMITS
+-- ============================================================
+
+-- Token-bucket limits per route class ('read', 'write', 'export'). Rows with a NULL tenant_id
+-- are defaults for every tenant; a tenant row overrides them. 'tenant' limits a whole tenant,
+-- 'principal' each user or client within it, and 'api_key' one API key in place of 'principal'.
+-- Services reload these every minute, so changes apply without a redeploy.
+CREATE TABLE IF NOT EXISTS tenant_rate_limit (
+  id                  uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  tenant_id           uuid REFERENCES tenant_registry(tenant_id),
+  api_key_id          uuid REFERENCES tenant_api_key(id),
+  route_class         text NOT NULL,
+  limit_level         text NOT NULL,
+  requests_per_minute integer NOT NULL,
+  burst               integer NOT NULL,
+  modified_at         timestamptz DEFAULT now(),
+  modified_by         text,
+
+  CONSTRAINT valid_route_class CHECK (route_class IN ('read', 'write', 'export')),
+  CONSTRAINT valid_limit_level CHECK (limit_level IN ('tenant', 'principal', 'api_key')),
+  CONSTRAINT api_key_level CHECK ((limit_level = 'api_key') = (api_key_id IS NOT NULL)),
+  CONSTRAINT positive_limit CHECK (requests_per_minute > 0 AND burst > 0)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tenant_rate_limit_rule ON tenant_rate_limit(
+  COALESCE(tenant_id, '00000000-0000-0000-0000-000000000000'::uuid),
+  COALESCE(api_key_id, '00000000-0000-0000-0000-000000000000'::uuid),
+  route_class, limit_level
+);
+
+-- ============================================================
+--  DATABASE SHARDS
+-- ============================================================
+
+-- Which service database holds a tenant's module schema. Services map shard keys to connection
+-- URLs in their own configuration (DB_SHARDS); a tenant without a row lives on the service's
+-- primary database. moving_to is set while the schema is copied to another shard, and services
+-- refuse the tenant's requests until the move completes.
+CREATE TABLE IF NOT EXISTS tenant_module_shard (
+  tenant_id         uuid NOT NULL REFERENCES tenant_registry(tenant_id),
+  module_name       text NOT NULL,
+  shard_key         text NOT NULL DEFAULT 'primary',
+  moving_to         text,
+  modified_at       timestamptz DEFAULT now(),
+  modified_by       text,
+
+  PRIMARY KEY (tenant_id, module_name),
+  CONSTRAINT valid_shard_module CHECK (module_name IN ('operations', 'finance')),
+  CONSTRAINT moving_elsewhere CHECK (moving_to IS DISTINCT FROM shard_key)
+);
+
 -- ============================================================
 --  HELPER FUNCTIONS
 -- ============================================================
@@ -97,6 +202,29 @@ BEGIN
 END;
 $$ LANGUAGE plpgsql STABLE;
 
+-- ============================================================
+--  CHANGE NOTIFICATIONS
+-- ============================================================
+
+-- Services cache tenant lookups and drop a tenant's entry when its row, or its shard, changes.
+CREATE OR REPLACE FUNCTION notify_tenant_registry_change()
+RETURNS trigger AS $$
+BEGIN
+  PERFORM pg_notify('tenant_registry_changed', COALESCE(NEW.tenant_id, OLD.tenant_id)::text);
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS tenant_registry_changed ON tenant_registry;
+CREATE TRIGGER tenant_registry_changed
+  AFTER INSERT OR UPDATE OR DELETE ON tenant_registry
+  FOR EACH ROW EXECUTE FUNCTION notify_tenant_registry_change();
+
+DROP TRIGGER IF EXISTS tenant_module_shard_changed ON tenant_module_shard;
+CREATE TRIGGER tenant_module_shard_changed
+  AFTER INSERT OR UPDATE OR DELETE ON tenant_module_shard
+  FOR EACH ROW EXECUTE FUNCTION notify_tenant_registry_change();
+
 -- ============================================================
 --  STORED PROCEDURES
 -- ============================================================
@@ -0,0 +1,367 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"frego-operations/internal/decimal"
+	operationsdto "frego-operations/internal/dto/operations"
+	"frego-operations/internal/logging"
+	operationsservice "frego-operations/internal/service/operations"
+)
+
+const (
+	defaultAccrualRows = 200
+	maxAccrualRows     = 1000
+)
+
+// AccrualHandler reconciles provisions (accruals) with supplier invoices.
+type AccrualHandler struct {
+	logger            *slog.Logger
+	operationsService *operationsservice.Service
+}
+
+// NewAccrualHandler creates a new accrual handler
+func NewAccrualHandler(logger *slog.Logger, operationsService *operationsservice.Service) *AccrualHandler {
+	return &AccrualHandler{
+		logger:            logger,
+		operationsService: operationsService,
+	}
+}
+
+// RegisterRoutes registers accrual reconciliation routes
+func (h *AccrualHandler) RegisterRoutes(r chi.Router) {
+	r.Get("/settings/accrual-tolerance", h.GetTolerance)
+	r.Put("/settings/accrual-tolerance", h.UpdateTolerance)
+	r.Post("/provisions/{provisionID}/supplier-invoice", h.RecordSupplierInvoice)
+	r.Get("/jobs/{jobID}/reconciliations", h.ListJobReconciliations)
+	r.Get("/accruals/open", h.OpenAccruals)
+	r.Get("/accruals/variances", h.ListVariances)
+}
+
+// AccrualToleranceRequest replaces the tenant's tolerance; omitted fields are not applied
+type AccrualToleranceRequest struct {
+	Percent *decimal.Decimal `json:"percent,omitempty"`
+	Amount  *decimal.Decimal `json:"amount,omitempty"`
+}
+
+// AccrualToleranceResponse describes the tenant's tolerance; amount is in the primary currency
+type AccrualToleranceResponse struct {
+	Currency string           `json:"currency"`
+	Percent  *decimal.Decimal `json:"percent,omitempty"`
+	Amount   *decimal.Decimal `json:"amount,omitempty"`
+}
+
+// SupplierInvoiceRequest records a supplier invoice against a provision
+type SupplierInvoiceRequest struct {
+	InvoiceNumber    string           `json:"invoiceNumber"`
+	InvoiceDate      *time.Time       `json:"invoiceDate,omitempty"`
+	CurrencyCode     *string          `json:"currencyCode,omitempty"`
+	AmountWithoutTax *decimal.Decimal `json:"amountWithoutTax"`
+	TaxAmount        *decimal.Decimal `json:"taxAmount,omitempty"`
+	TotalAmount      *decimal.Decimal `json:"totalAmount,omitempty"`
+	Notes            *string          `json:"notes,omitempty"`
+}
+
+// ReconciliationResponse compares a supplier invoice with its provision
+type ReconciliationResponse struct {
+	ID                      string           `json:"id"`
+	ProvisionID             string           `json:"provisionId"`
+	JobID                   string           `json:"jobId"`
+	JobCode                 *string          `json:"jobCode,omitempty"`
+	ActivityType            *string          `json:"activityType,omitempty"`
+	ActivityCode            *string          `json:"activityCode,omitempty"`
+	CostPartyID             *string          `json:"costPartyId,omitempty"`
+	CostPartyName           *string          `json:"costPartyName,omitempty"`
+	SupplierInvoiceNumber   string           `json:"supplierInvoiceNumber"`
+	SupplierInvoiceDate     *time.Time       `json:"supplierInvoiceDate,omitempty"`
+	CurrencyCode            string           `json:"currencyCode"`
+	AmountWithoutTax        decimal.Decimal  `json:"amountWithoutTax"`
+	TaxAmount               *decimal.Decimal `json:"taxAmount,omitempty"`
+	TotalAmount             *decimal.Decimal `json:"totalAmount,omitempty"`
+	ExchangeRate            *decimal.Decimal `json:"exchangeRate,omitempty"`
+	ProvisionCurrency       *string          `json:"provisionCurrency,omitempty"`
+	ExpectedAmount          decimal.Decimal  `json:"expectedAmount"`
+	Variance                decimal.Decimal  `json:"variance"`
+	VariancePercent         *decimal.Decimal `json:"variancePercent,omitempty"`
+	VariancePrimaryCurrency *decimal.Decimal `json:"variancePrimaryCurrency,omitempty"`
+	Status                  string           `json:"status"`
+	Notes                   *string          `json:"notes,omitempty"`
+	CreatedAt               *time.Time       `json:"createdAt,omitempty"`
+	CreatedBy               *string          `json:"createdBy,omitempty"`
+}
+
+// OpenAccrualResponse is a provision awaiting its supplier invoice
+type OpenAccrualResponse struct {
+	ProvisionID           string           `json:"provisionId"`
+	JobID                 *string          `json:"jobId,omitempty"`
+	JobCode               string           `json:"jobCode"`
+	ActivityType          *string          `json:"activityType,omitempty"`
+	ActivityCode          *string          `json:"activityCode,omitempty"`
+	CostPartyID           *string          `json:"costPartyId,omitempty"`
+	CostPartyName         *string          `json:"costPartyName,omitempty"`
+	CurrencyCode          *string          `json:"currencyCode,omitempty"`
+	AmountWithoutTax      *decimal.Decimal `json:"amountWithoutTax,omitempty"`
+	AmountPrimaryCurrency *decimal.Decimal `json:"amountPrimaryCurrency,omitempty"`
+	PONumber              *string          `json:"poNumber,omitempty"`
+	CreatedAt             *time.Time       `json:"createdAt,omitempty"`
+	AgeDays               int32            `json:"ageDays"`
+}
+
+// VendorAccrualAgingResponse ages one vendor's open accruals in the primary currency
+type VendorAccrualAgingResponse struct {
+	CostPartyID   *string         `json:"costPartyId,omitempty"`
+	CostPartyName *string         `json:"costPartyName,omitempty"`
+	Count         int             `json:"count"`
+	Unpriced      int             `json:"unpriced"`
+	Days0To30     decimal.Decimal `json:"days0To30"`
+	Days31To60    decimal.Decimal `json:"days31To60"`
+	Days61To90    decimal.Decimal `json:"days61To90"`
+	Over90Days    decimal.Decimal `json:"over90Days"`
+	Total         decimal.Decimal `json:"total"`
+}
+
+// OpenAccrualReportResponse lists open accruals and their aging per vendor
+type OpenAccrualReportResponse struct {
+	Currency string                       `json:"currency"`
+	Accruals []OpenAccrualResponse        `json:"accruals"`
+	Vendors  []VendorAccrualAgingResponse `json:"vendors"`
+}
+
+// GetTolerance returns the tenant's supplier invoice variance tolerance.
+func (h *AccrualHandler) GetTolerance(w http.ResponseWriter, r *http.Request) {
+	tolerance, err := h.operationsService.GetAccrualTolerance(r.Context())
+	if err != nil {
+		h.writeAccrualError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, AccrualToleranceResponse(tolerance))
+}
+
+// UpdateTolerance replaces the tenant's supplier invoice variance tolerance.
+func (h *AccrualHandler) UpdateTolerance(w http.ResponseWriter, r *http.Request) {
+	var req AccrualToleranceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_body", "request body must be valid JSON")
+		return
+	}
+
+	tolerance, err := h.operationsService.UpdateAccrualTolerance(r.Context(), operationsdto.AccrualToleranceInput{
+		Percent:   req.Percent,
+		Amount:    req.Amount,
+		UpdatedBy: actorFromRequest(r),
+	})
+	if err != nil {
+		h.writeAccrualError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, AccrualToleranceResponse(tolerance))
+}
+
+// RecordSupplierInvoice records a supplier invoice against a provision and reports the variance.
+func (h *AccrualHandler) RecordSupplierInvoice(w http.ResponseWriter, r *http.Request) {
+	provisionID, err := uuid.Parse(chi.URLParam(r, "provisionID"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_provision_id", "provision id must be a UUID")
+		return
+	}
+
+	var req SupplierInvoiceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_body", "request body must be valid JSON")
+		return
+	}
+	if strings.TrimSpace(req.InvoiceNumber) == "" || req.AmountWithoutTax == nil {
+		writeError(w, http.StatusBadRequest, "invalid_body", "invoiceNumber and amountWithoutTax are required")
+		return
+	}
+
+	reconciliation, err := h.operationsService.RecordSupplierInvoice(r.Context(), provisionID, operationsdto.SupplierInvoiceInput{
+		InvoiceNumber:    req.InvoiceNumber,
+		InvoiceDate:      req.InvoiceDate,
+		CurrencyCode:     req.CurrencyCode,
+		AmountWithoutTax: *req.AmountWithoutTax,
+		TaxAmount:        req.TaxAmount,
+		TotalAmount:      req.TotalAmount,
+		Notes:            req.Notes,
+		RecordedBy:       actorFromRequest(r),
+	})
+	if err != nil {
+		h.writeAccrualError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, reconciliationResponse(reconciliation))
+}
+
+// ListJobReconciliations returns the supplier invoices recorded against a job's provisions.
+func (h *AccrualHandler) ListJobReconciliations(w http.ResponseWriter, r *http.Request) {
+	jobID, ok := jobIDParam(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid_job_id", "job id must be a UUID")
+		return
+	}
+
+	reconciliations, err := h.operationsService.ListJobReconciliations(r.Context(), jobID)
+	if err != nil {
+		h.writeAccrualError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, reconciliationResponses(reconciliations))
+}
+
+// ListVariances returns reconciliations in a state, by default those pending approval (?status=, ?limit=).
+func (h *AccrualHandler) ListVariances(w http.ResponseWriter, r *http.Request) {
+	limit, ok := accrualLimit(w, r)
+	if !ok {
+		return
+	}
+	status := operationsservice.ReconciliationPendingApproval
+	if raw := optionalQuery(r, "status"); raw != nil {
+		status = *raw
+	}
+
+	reconciliations, err := h.operationsService.ListReconciliationsByStatus(r.Context(), status, limit)
+	if err != nil {
+		h.writeAccrualError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, reconciliationResponses(reconciliations))
+}
+
+// OpenAccruals lists provisions still awaiting a supplier invoice, filtered by ?vendorId=,
+// ?minAgeDays= and ?limit=, with totals per vendor by age.
+func (h *AccrualHandler) OpenAccruals(w http.ResponseWriter, r *http.Request) {
+	filter := operationsdto.OpenAccrualFilter{}
+
+	var ok bool
+	if filter.CostPartyID, ok = optionalUUIDQuery(w, r, "vendorId"); !ok {
+		return
+	}
+	if raw := r.URL.Query().Get("minAgeDays"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, "invalid_minAgeDays", "minAgeDays must be a non-negative integer")
+			return
+		}
+		days := int32(n)
+		filter.MinAgeDays = &days
+	}
+	if filter.Limit, ok = accrualLimit(w, r); !ok {
+		return
+	}
+
+	report, err := h.operationsService.OpenAccruals(r.Context(), filter)
+	if err != nil {
+		h.writeAccrualError(w, r, err)
+		return
+	}
+
+	resp := OpenAccrualReportResponse{
+		Currency: report.Currency,
+		Accruals: make([]OpenAccrualResponse, 0, len(report.Accruals)),
+		Vendors:  make([]VendorAccrualAgingResponse, 0, len(report.Vendors)),
+	}
+	for _, a := range report.Accruals {
+		resp.Accruals = append(resp.Accruals, OpenAccrualResponse{
+			ProvisionID:           a.ProvisionID.String(),
+			JobID:                 uuidString(a.JobID),
+			JobCode:               a.JobCode,
+			ActivityType:          a.ActivityType,
+			ActivityCode:          a.ActivityCode,
+			CostPartyID:           uuidString(a.CostPartyID),
+			CostPartyName:         a.CostPartyName,
+			CurrencyCode:          a.CurrencyCode,
+			AmountWithoutTax:      a.AmountWithoutTax,
+			AmountPrimaryCurrency: a.AmountPrimaryCurrency,
+			PONumber:              a.PONumber,
+			CreatedAt:             a.CreatedAt,
+			AgeDays:               a.AgeDays,
+		})
+	}
+	for _, v := range report.Vendors {
+		resp.Vendors = append(resp.Vendors, VendorAccrualAgingResponse{
+			CostPartyID:   uuidString(v.CostPartyID),
+			CostPartyName: v.CostPartyName,
+			Count:         v.Count,
+			Unpriced:      v.Unpriced,
+			Days0To30:     v.Days0To30,
+			Days31To60:    v.Days31To60,
+			Days61To90:    v.Days61To90,
+			Over90Days:    v.Over90Days,
+			Total:         v.Total,
+		})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *AccrualHandler) writeAccrualError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		writeError(w, http.StatusNotFound, "not_found", "provision not found")
+	case errors.Is(err, operationsservice.ErrInvalidReconciliation), errors.Is(err, operationsservice.ErrInvalidCharge):
+		writeError(w, http.StatusUnprocessableEntity, "invalid_reconciliation", err.Error())
+	default:
+		logging.FromContext(r.Context()).Error("accrual request failed", slog.Any("error", err))
+		writeError(w, http.StatusInternalServerError, "internal_error", "accrual request failed")
+	}
+}
+
+// accrualLimit parses ?limit=, writing a 400 and returning false when it is out of range.
+func accrualLimit(w http.ResponseWriter, r *http.Request) (int32, bool) {
+	raw := r.URL.Query().Get("limit")
+	if raw == "" {
+		return defaultAccrualRows, true
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 1 || n > maxAccrualRows {
+		writeError(w, http.StatusBadRequest, "invalid_limit", "limit must be between 1 and 1000")
+		return 0, false
+	}
+	return int32(n), true
+}
+
+func reconciliationResponses(reconciliations []operationsdto.ProvisionReconciliation) []ReconciliationResponse {
+	resp := make([]ReconciliationResponse, 0, len(reconciliations))
+	for _, rec := range reconciliations {
+		resp = append(resp, reconciliationResponse(rec))
+	}
+	return resp
+}
+
+func reconciliationResponse(rec operationsdto.ProvisionReconciliation) ReconciliationResponse {
+	return ReconciliationResponse{
+		ID:                      rec.ID.String(),
+		ProvisionID:             rec.ProvisionID.String(),
+		JobID:                   rec.JobID.String(),
+		JobCode:                 rec.JobCode,
+		ActivityType:            rec.ActivityType,
+		ActivityCode:            rec.ActivityCode,
+		CostPartyID:             uuidString(rec.CostPartyID),
+		CostPartyName:           rec.CostPartyName,
+		SupplierInvoiceNumber:   rec.SupplierInvoiceNumber,
+		SupplierInvoiceDate:     rec.SupplierInvoiceDate,
+		CurrencyCode:            rec.CurrencyCode,
+		AmountWithoutTax:        rec.AmountWithoutTax,
+		TaxAmount:               rec.TaxAmount,
+		TotalAmount:             rec.TotalAmount,
+		ExchangeRate:            rec.ExchangeRate,
+		ProvisionCurrency:       rec.ProvisionCurrency,
+		ExpectedAmount:          rec.ExpectedAmount,
+		Variance:                rec.Variance,
+		VariancePercent:         rec.VariancePercent,
+		VariancePrimaryCurrency: rec.VariancePrimaryCurrency,
+		Status:                  rec.Status,
+		Notes:                   rec.Notes,
+		CreatedAt:               rec.CreatedAt,
+		CreatedBy:               rec.CreatedBy,
+	}
+}
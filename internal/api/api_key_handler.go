@@ -0,0 +1,219 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	tenantdto "frego-operations/internal/dto/tenant"
+	"frego-operations/internal/logging"
+	"frego-operations/internal/server"
+	"frego-operations/internal/service/tenant"
+)
+
+// APIKeyHandler lets tenant admins issue, rotate and revoke API keys for machine clients.
+type APIKeyHandler struct {
+	logger        *slog.Logger
+	tenantService *tenant.Service
+}
+
+// NewAPIKeyHandler creates a new API key handler
+func NewAPIKeyHandler(logger *slog.Logger, tenantService *tenant.Service) *APIKeyHandler {
+	return &APIKeyHandler{
+		logger:        logger,
+		tenantService: tenantService,
+	}
+}
+
+// RegisterRoutes registers API key routes
+func (h *APIKeyHandler) RegisterRoutes(r chi.Router) {
+	r.Get("/api-keys", h.List)
+	r.Post("/api-keys", h.Issue)
+	r.Post("/api-keys/{keyID}/rotate", h.Rotate)
+	r.Delete("/api-keys/{keyID}", h.Revoke)
+}
+
+// IssueAPIKeyRequest issues a new API key. The key sees the jobs of branchIds, or of every
+// branch with allBranches.
+type IssueAPIKeyRequest struct {
+	Name        string      `json:"name"`
+	Scopes      []string    `json:"scopes"`
+	BranchIDs   []uuid.UUID `json:"branchIds,omitempty"`
+	AllBranches bool        `json:"allBranches,omitempty"`
+	ExpiresAt   *time.Time  `json:"expiresAt,omitempty"`
+}
+
+// RotateAPIKeyRequest sets how long the old key keeps working, as a Go duration such as "24h".
+type RotateAPIKeyRequest struct {
+	GracePeriod *string `json:"gracePeriod,omitempty"`
+}
+
+// APIKeyResponse describes an API key without its secret
+type APIKeyResponse struct {
+	ID          string      `json:"id"`
+	Name        string      `json:"name"`
+	Prefix      string      `json:"prefix"`
+	Scopes      []string    `json:"scopes"`
+	BranchIDs   []uuid.UUID `json:"branchIds"`
+	AllBranches bool        `json:"allBranches"`
+	ExpiresAt   *time.Time  `json:"expiresAt,omitempty"`
+	LastUsedAt  *time.Time  `json:"lastUsedAt,omitempty"`
+	ReplacedBy  *string     `json:"replacedBy,omitempty"`
+	CreatedAt   time.Time   `json:"createdAt"`
+	CreatedBy   *string     `json:"createdBy,omitempty"`
+	RevokedAt   *time.Time  `json:"revokedAt,omitempty"`
+	RevokedBy   *string     `json:"revokedBy,omitempty"`
+}
+
+// IssuedAPIKeyResponse carries a new key's secret, which is only ever returned here
+type IssuedAPIKeyResponse struct {
+	APIKeyResponse
+	Key string `json:"key"`
+}
+
+// List returns the tenant's API keys.
+func (h *APIKeyHandler) List(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := server.GetTenantID(r.Context())
+	if err != nil {
+		writeError(w, http.StatusForbidden, "tenant_missing", "tenant context missing")
+		return
+	}
+	keys, err := h.tenantService.ListAPIKeys(r.Context(), tenantID)
+	if err != nil {
+		h.writeAPIKeyError(w, r, err)
+		return
+	}
+	resp := make([]APIKeyResponse, 0, len(keys))
+	for _, k := range keys {
+		resp = append(resp, apiKeyResponse(k))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// Issue creates an API key and returns its secret once.
+func (h *APIKeyHandler) Issue(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := server.GetTenantID(r.Context())
+	if err != nil {
+		writeError(w, http.StatusForbidden, "tenant_missing", "tenant context missing")
+		return
+	}
+	var req IssueAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_body", "request body must be valid JSON")
+		return
+	}
+
+	issued, err := h.tenantService.IssueAPIKey(r.Context(), tenantID, tenantdto.APIKeyInput{
+		Name:        req.Name,
+		Scopes:      req.Scopes,
+		BranchIDs:   req.BranchIDs,
+		AllBranches: req.AllBranches,
+		ExpiresAt:   req.ExpiresAt,
+	}, actorFromRequest(r))
+	if err != nil {
+		h.writeAPIKeyError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, IssuedAPIKeyResponse{APIKeyResponse: apiKeyResponse(issued.APIKey), Key: issued.Key})
+}
+
+// Rotate issues a replacement key; the old key keeps working for the grace period.
+func (h *APIKeyHandler) Rotate(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := server.GetTenantID(r.Context())
+	if err != nil {
+		writeError(w, http.StatusForbidden, "tenant_missing", "tenant context missing")
+		return
+	}
+	keyID, err := uuid.Parse(chi.URLParam(r, "keyID"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_key_id", "key id must be a UUID")
+		return
+	}
+	var req RotateAPIKeyRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_body", "request body must be valid JSON")
+			return
+		}
+	}
+	grace := tenant.DefaultAPIKeyGrace
+	if req.GracePeriod != nil {
+		grace, err = time.ParseDuration(*req.GracePeriod)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_body", "gracePeriod must be a duration such as 24h")
+			return
+		}
+	}
+
+	issued, err := h.tenantService.RotateAPIKey(r.Context(), tenantID, keyID, grace, actorFromRequest(r))
+	if err != nil {
+		h.writeAPIKeyError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, IssuedAPIKeyResponse{APIKeyResponse: apiKeyResponse(issued.APIKey), Key: issued.Key})
+}
+
+// Revoke stops a key from authenticating.
+func (h *APIKeyHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := server.GetTenantID(r.Context())
+	if err != nil {
+		writeError(w, http.StatusForbidden, "tenant_missing", "tenant context missing")
+		return
+	}
+	keyID, err := uuid.Parse(chi.URLParam(r, "keyID"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_key_id", "key id must be a UUID")
+		return
+	}
+
+	key, err := h.tenantService.RevokeAPIKey(r.Context(), tenantID, keyID, actorFromRequest(r))
+	if err != nil {
+		h.writeAPIKeyError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, apiKeyResponse(key))
+}
+
+func (h *APIKeyHandler) writeAPIKeyError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		writeError(w, http.StatusNotFound, "not_found", "api key not found")
+	case errors.Is(err, tenant.ErrInvalidAPIKeyInput):
+		writeError(w, http.StatusUnprocessableEntity, "invalid_api_key", err.Error())
+	case errors.Is(err, tenant.ErrAPIKeyInactive):
+		writeError(w, http.StatusConflict, "api_key_inactive", err.Error())
+	case errors.Is(err, tenant.ErrAPIKeyScopeForbidden):
+		writeError(w, http.StatusForbidden, "scope_forbidden", err.Error())
+	default:
+		logging.FromContext(r.Context()).Error("api key request failed", slog.Any("error", err))
+		writeError(w, http.StatusInternalServerError, "internal_error", "api key request failed")
+	}
+}
+
+func apiKeyResponse(k tenantdto.APIKey) APIKeyResponse {
+	resp := APIKeyResponse{
+		ID:          k.ID.String(),
+		Name:        k.Name,
+		Prefix:      k.Prefix,
+		Scopes:      k.Scopes,
+		BranchIDs:   k.BranchIDs,
+		AllBranches: k.AllBranches,
+		ExpiresAt:   k.ExpiresAt,
+		LastUsedAt:  k.LastUsedAt,
+		CreatedAt:   k.CreatedAt,
+		CreatedBy:   k.CreatedBy,
+		RevokedAt:   k.RevokedAt,
+		RevokedBy:   k.RevokedBy,
+	}
+	if k.ReplacedBy != nil {
+		replacedBy := k.ReplacedBy.String()
+		resp.ReplacedBy = &replacedBy
+	}
+	return resp
+}
@@ -0,0 +1,259 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"frego-operations/internal/decimal"
+	operationsdto "frego-operations/internal/dto/operations"
+	"frego-operations/internal/logging"
+	operationsservice "frego-operations/internal/service/operations"
+)
+
+// ApprovalHandler manages provision approval rules and the approvals inbox.
+type ApprovalHandler struct {
+	logger            *slog.Logger
+	operationsService *operationsservice.Service
+}
+
+// NewApprovalHandler creates a new approval handler
+func NewApprovalHandler(logger *slog.Logger, operationsService *operationsservice.Service) *ApprovalHandler {
+	return &ApprovalHandler{
+		logger:            logger,
+		operationsService: operationsService,
+	}
+}
+
+// RegisterRoutes registers approval routes
+func (h *ApprovalHandler) RegisterRoutes(r chi.Router) {
+	r.Get("/approval-rules", h.ListRules)
+	r.Post("/approval-rules", h.CreateRule)
+	r.Delete("/approval-rules/{ruleID}", h.DeactivateRule)
+	r.Get("/approvals/inbox", h.Inbox)
+	r.Post("/approvals/{approvalID}/approve", h.Approve)
+	r.Post("/approvals/{approvalID}/reject", h.Reject)
+}
+
+// ApprovalRuleRequest defines a provision approval rule; every condition given must hold
+type ApprovalRuleRequest struct {
+	Name            string           `json:"name"`
+	CurrencyCode    *string          `json:"currencyCode,omitempty"`
+	MinAmount       *decimal.Decimal `json:"minAmount,omitempty"`
+	PaymentPriority *string          `json:"paymentPriority,omitempty"`
+	MinVendorRisk   *string          `json:"minVendorRisk,omitempty"`
+	ApproverRole    *string          `json:"approverRole,omitempty"`
+}
+
+// ApprovalRuleResponse describes a provision approval rule
+type ApprovalRuleResponse struct {
+	ID              string           `json:"id"`
+	Name            string           `json:"name"`
+	CurrencyCode    *string          `json:"currencyCode,omitempty"`
+	MinAmount       *decimal.Decimal `json:"minAmount,omitempty"`
+	PaymentPriority *string          `json:"paymentPriority,omitempty"`
+	MinVendorRisk   *string          `json:"minVendorRisk,omitempty"`
+	ApproverRole    string           `json:"approverRole"`
+	CreatedAt       *time.Time       `json:"createdAt,omitempty"`
+	CreatedBy       *string          `json:"createdBy,omitempty"`
+}
+
+// ApprovalDecisionRequest carries the approver's comment; rejections require one
+type ApprovalDecisionRequest struct {
+	Comment *string `json:"comment,omitempty"`
+}
+
+// ApprovalResponse is an approval request on a provision or a supplier invoice variance
+type ApprovalResponse struct {
+	ID              string           `json:"id"`
+	SubjectType     string           `json:"subjectType"`
+	SubjectID       string           `json:"subjectId"`
+	JobID           string           `json:"jobId"`
+	JobCode         *string          `json:"jobCode,omitempty"`
+	RuleID          *string          `json:"ruleId,omitempty"`
+	RequiredRole    string           `json:"requiredRole"`
+	Reason          string           `json:"reason"`
+	Status          string           `json:"status"`
+	ProvisionID     *string          `json:"provisionId,omitempty"`
+	ActivityType    *string          `json:"activityType,omitempty"`
+	ActivityCode    *string          `json:"activityCode,omitempty"`
+	CostPartyID     *string          `json:"costPartyId,omitempty"`
+	CostPartyName   *string          `json:"costPartyName,omitempty"`
+	CurrencyCode    *string          `json:"currencyCode,omitempty"`
+	TotalAmount     *decimal.Decimal `json:"totalAmount,omitempty"`
+	PaymentPriority *string          `json:"paymentPriority,omitempty"`
+	Variance        *decimal.Decimal `json:"variance,omitempty"`
+	VariancePercent *decimal.Decimal `json:"variancePercent,omitempty"`
+	RequestedAt     *time.Time       `json:"requestedAt,omitempty"`
+	RequestedBy     *string          `json:"requestedBy,omitempty"`
+	DecidedAt       *time.Time       `json:"decidedAt,omitempty"`
+	DecidedBy       *string          `json:"decidedBy,omitempty"`
+	Comment         *string          `json:"comment,omitempty"`
+}
+
+// ListRules returns the tenant's active provision approval rules.
+func (h *ApprovalHandler) ListRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.operationsService.ListApprovalRules(r.Context())
+	if err != nil {
+		h.writeApprovalError(w, r, err)
+		return
+	}
+	resp := make([]ApprovalRuleResponse, 0, len(rules))
+	for _, rule := range rules {
+		resp = append(resp, approvalRuleResponse(rule))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// CreateRule adds a provision approval rule.
+func (h *ApprovalHandler) CreateRule(w http.ResponseWriter, r *http.Request) {
+	var req ApprovalRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_body", "request body must be valid JSON")
+		return
+	}
+
+	rule, err := h.operationsService.CreateApprovalRule(r.Context(), operationsdto.ProvisionApprovalRuleInput{
+		Name:            req.Name,
+		CurrencyCode:    req.CurrencyCode,
+		MinAmount:       req.MinAmount,
+		PaymentPriority: req.PaymentPriority,
+		MinVendorRisk:   req.MinVendorRisk,
+		ApproverRole:    req.ApproverRole,
+		CreatedBy:       actorFromRequest(r),
+	})
+	if err != nil {
+		h.writeApprovalError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, approvalRuleResponse(rule))
+}
+
+// DeactivateRule retires a provision approval rule.
+func (h *ApprovalHandler) DeactivateRule(w http.ResponseWriter, r *http.Request) {
+	ruleID, err := uuid.Parse(chi.URLParam(r, "ruleID"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_rule_id", "rule id must be a UUID")
+		return
+	}
+	if err := h.operationsService.DeactivateApprovalRule(r.Context(), ruleID, actorFromRequest(r)); err != nil {
+		h.writeApprovalError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Inbox lists the pending approvals the caller's roles allow them to decide (?limit=).
+func (h *ApprovalHandler) Inbox(w http.ResponseWriter, r *http.Request) {
+	limit, ok := accrualLimit(w, r)
+	if !ok {
+		return
+	}
+	approvals, err := h.operationsService.ApprovalInbox(r.Context(), limit)
+	if err != nil {
+		h.writeApprovalError(w, r, err)
+		return
+	}
+	resp := make([]ApprovalResponse, 0, len(approvals))
+	for _, a := range approvals {
+		resp = append(resp, approvalResponse(a))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// Approve approves a pending request.
+func (h *ApprovalHandler) Approve(w http.ResponseWriter, r *http.Request) {
+	h.decide(w, r, true)
+}
+
+// Reject rejects a pending request; the comment is required.
+func (h *ApprovalHandler) Reject(w http.ResponseWriter, r *http.Request) {
+	h.decide(w, r, false)
+}
+
+func (h *ApprovalHandler) decide(w http.ResponseWriter, r *http.Request, approve bool) {
+	approvalID, err := uuid.Parse(chi.URLParam(r, "approvalID"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_approval_id", "approval id must be a UUID")
+		return
+	}
+	var req ApprovalDecisionRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_body", "request body must be valid JSON")
+			return
+		}
+	}
+
+	approval, err := h.operationsService.DecideApproval(r.Context(), approvalID, approve, req.Comment, actorFromRequest(r))
+	if err != nil {
+		h.writeApprovalError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, approvalResponse(approval))
+}
+
+func (h *ApprovalHandler) writeApprovalError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		writeError(w, http.StatusNotFound, "not_found", "approval or rule not found")
+	case errors.Is(err, operationsservice.ErrApprovalForbidden):
+		writeError(w, http.StatusForbidden, "forbidden", err.Error())
+	case errors.Is(err, operationsservice.ErrApprovalDecided):
+		writeError(w, http.StatusConflict, "already_decided", err.Error())
+	case errors.Is(err, operationsservice.ErrInvalidApprovalRule):
+		writeError(w, http.StatusUnprocessableEntity, "invalid_approval", err.Error())
+	default:
+		logging.FromContext(r.Context()).Error("approval request failed", slog.Any("error", err))
+		writeError(w, http.StatusInternalServerError, "internal_error", "approval request failed")
+	}
+}
+
+func approvalRuleResponse(rule operationsdto.ProvisionApprovalRule) ApprovalRuleResponse {
+	return ApprovalRuleResponse{
+		ID:              rule.ID.String(),
+		Name:            rule.Name,
+		CurrencyCode:    rule.CurrencyCode,
+		MinAmount:       rule.MinAmount,
+		PaymentPriority: rule.PaymentPriority,
+		MinVendorRisk:   rule.MinVendorRisk,
+		ApproverRole:    rule.ApproverRole,
+		CreatedAt:       rule.CreatedAt,
+		CreatedBy:       rule.CreatedBy,
+	}
+}
+
+func approvalResponse(a operationsdto.Approval) ApprovalResponse {
+	return ApprovalResponse{
+		ID:              a.ID.String(),
+		SubjectType:     a.SubjectType,
+		SubjectID:       a.SubjectID.String(),
+		JobID:           a.JobID.String(),
+		JobCode:         a.JobCode,
+		RuleID:          uuidString(a.RuleID),
+		RequiredRole:    a.RequiredRole,
+		Reason:          a.Reason,
+		Status:          a.Status,
+		ProvisionID:     uuidString(a.ProvisionID),
+		ActivityType:    a.ActivityType,
+		ActivityCode:    a.ActivityCode,
+		CostPartyID:     uuidString(a.CostPartyID),
+		CostPartyName:   a.CostPartyName,
+		CurrencyCode:    a.CurrencyCode,
+		TotalAmount:     a.TotalAmount,
+		PaymentPriority: a.PaymentPriority,
+		Variance:        a.Variance,
+		VariancePercent: a.VariancePercent,
+		RequestedAt:     a.RequestedAt,
+		RequestedBy:     a.RequestedBy,
+		DecidedAt:       a.DecidedAt,
+		DecidedBy:       a.DecidedBy,
+		Comment:         a.Comment,
+	}
+}
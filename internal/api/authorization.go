@@ -0,0 +1,125 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"frego-operations/internal/authz"
+	"frego-operations/internal/logging"
+)
+
+// routePermissions declares the permission every operations route needs, keyed by method and
+// route pattern. Routes missing from it are refused.
+var routePermissions = map[string]authz.Permission{
+	// Jobs
+	"GET /jobs/{jobID}/exports/iftmin":                authz.ViewJobs,
+	"GET /jobs/{jobID}/exports/fwb":                   authz.ViewJobs,
+	"GET /jobs/{jobID}/exports/fhl":                   authz.ViewJobs,
+	"POST /jobs/{jobID}/documents/{docType}/generate": authz.EditJobs,
+	"POST /jobs/{jobID}/reopen":                       authz.EditJobs,
+	"GET /jobs/{jobID}/status-history":                authz.ViewJobs,
+	"POST /jobs/{jobID}/screening":                    authz.EditJobs,
+	"GET /jobs/{jobID}/screening":                     authz.ViewJobs,
+	"POST /screenings/{screeningID}/clear":            authz.EditJobs,
+	"POST /screenings/{screeningID}/confirm":          authz.EditJobs,
+	"GET /screening/lists":                            authz.ViewJobs,
+
+	// Financials
+	"GET /jobs/{jobID}/charge-proposal":               authz.ViewFinancials,
+	"GET /jobs/{jobID}/billing/incoterm-check":        authz.ViewFinancials,
+	"GET /jobs/{jobID}/profitability":                 authz.ViewFinancials,
+	"GET /reports/profitability":                      authz.ViewFinancials,
+	"GET /exchange-rates":                             authz.ViewFinancials,
+	"GET /exchange-rates/lookup":                      authz.ViewFinancials,
+	"POST /jobs/{jobID}/exchange-rates/refresh":       authz.EditFinancials,
+	"POST /jobs/{jobID}/exchange-rates/lock":          authz.EditFinancials,
+	"POST /jobs/{jobID}/invoices/draft":               authz.EditFinancials,
+	"GET /jobs/{jobID}/invoices":                      authz.ViewFinancials,
+	"GET /invoices/{invoiceID}":                       authz.ViewFinancials,
+	"GET /invoices/{invoiceID}/export":                authz.ViewFinancials,
+	"POST /provisions/{provisionID}/supplier-invoice": authz.EditFinancials,
+	"GET /jobs/{jobID}/reconciliations":               authz.ViewFinancials,
+	"GET /accruals/open":                              authz.ViewFinancials,
+	"GET /accruals/variances":                         authz.ViewFinancials,
+	"GET /settings/accrual-tolerance":                 authz.ViewFinancials,
+	"GET /parties/{partyID}/credit":                   authz.ViewFinancials,
+	"PUT /parties/{partyID}/credit-limit":             authz.EditFinancials,
+	"DELETE /parties/{partyID}/credit-limit":          authz.EditFinancials,
+	"GET /parties/{partyID}/credit-overrides":         authz.ViewFinancials,
+	"GET /approval-rules":                             authz.ViewFinancials,
+
+	// Approvals
+	"GET /approvals/inbox":                 authz.DecideApprovals,
+	"POST /approvals/{approvalID}/approve": authz.DecideApprovals,
+	"POST /approvals/{approvalID}/reject":  authz.DecideApprovals,
+
+	// Lookups and settings
+	"GET /charge-templates":                 authz.ViewJobs,
+	"POST /charge-templates":                authz.ManageLookups,
+	"DELETE /charge-templates/{templateID}": authz.ManageLookups,
+	"GET /document-templates":               authz.ViewJobs,
+	"POST /document-templates":              authz.ManageLookups,
+	"GET /incoterms":                        authz.ViewJobs,
+	"POST /exchange-rates/import":           authz.ManageLookups,
+	"PUT /settings/accrual-tolerance":       authz.ManageLookups,
+	"POST /approval-rules":                  authz.ManageLookups,
+	"DELETE /approval-rules/{ruleID}":       authz.ManageLookups,
+	"POST /screening/lists/reload":          authz.ManageLookups,
+	"GET /roles/permissions":                authz.ManageLookups,
+	"PUT /roles/{roleID}/permissions":       authz.ManageLookups,
+
+	// API keys
+	"GET /api-keys":                 authz.ManageAPIKeys,
+	"POST /api-keys":                authz.ManageAPIKeys,
+	"POST /api-keys/{keyID}/rotate": authz.ManageAPIKeys,
+	"DELETE /api-keys/{keyID}":      authz.ManageAPIKeys,
+}
+
+// AuthorizeRoutes returns middleware for router that refuses requests whose principal lacks the
+// permission routePermissions declares for the matched route. Unmatched requests pass through to
+// the router's not-found handling.
+func AuthorizeRoutes(router chi.Routes, authorizer *authz.Authorizer) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path := r.URL.Path
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePath != "" {
+				path = rctx.RoutePath
+			}
+			match := chi.NewRouteContext()
+			if !router.Match(match, r.Method, path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			perm, ok := routePermissions[r.Method+" "+match.RoutePattern()]
+			if !ok {
+				writeError(w, http.StatusForbidden, "forbidden", "no authorization policy covers this operation")
+				return
+			}
+			if err := authorizer.Authorize(r.Context(), perm); err != nil {
+				logging.FromContext(r.Context()).Info("request forbidden",
+					slog.String("route", r.Method+" "+match.RoutePattern()),
+					slog.String("permission", string(perm)),
+				)
+				writeError(w, http.StatusForbidden, "forbidden", err.Error())
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// UnauthorizedRoutes lists the routes of router that routePermissions does not cover, so a
+// route added without a policy is caught at startup.
+func UnauthorizedRoutes(router chi.Routes) []string {
+	var missing []string
+	_ = chi.Walk(router, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		if _, ok := routePermissions[method+" "+route]; !ok {
+			missing = append(missing, method+" "+route)
+		}
+		return nil
+	})
+	return missing
+}
@@ -0,0 +1,284 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"frego-operations/internal/decimal"
+	operationsdto "frego-operations/internal/dto/operations"
+	"frego-operations/internal/logging"
+	operationsservice "frego-operations/internal/service/operations"
+)
+
+// ChargeTemplateHandler serves tenant charge templates and the charges they propose for jobs.
+type ChargeTemplateHandler struct {
+	logger            *slog.Logger
+	operationsService *operationsservice.Service
+}
+
+// NewChargeTemplateHandler creates a new charge template handler
+func NewChargeTemplateHandler(logger *slog.Logger, operationsService *operationsservice.Service) *ChargeTemplateHandler {
+	return &ChargeTemplateHandler{
+		logger:            logger,
+		operationsService: operationsService,
+	}
+}
+
+// RegisterRoutes registers charge template routes
+func (h *ChargeTemplateHandler) RegisterRoutes(r chi.Router) {
+	r.Get("/charge-templates", h.ListTemplates)
+	r.Post("/charge-templates", h.CreateTemplate)
+	r.Delete("/charge-templates/{templateID}", h.DeactivateTemplate)
+	r.Get("/jobs/{jobID}/charge-proposal", h.ProposeCharges)
+}
+
+// ChargeTemplateLineRequest defines one line of a charge template
+type ChargeTemplateLineRequest struct {
+	LineKind      string           `json:"lineKind"`
+	ActivityType  string           `json:"activityType"`
+	ActivityCode  string           `json:"activityCode"`
+	QuantityBasis string           `json:"quantityBasis"`
+	PartyRole     *string          `json:"partyRole,omitempty"`
+	CurrencyCode  *string          `json:"currencyCode,omitempty"`
+	UnitPrice     *decimal.Decimal `json:"unitPrice,omitempty"`
+	TaxCode       *string          `json:"taxCode,omitempty"`
+	Description   *string          `json:"description,omitempty"`
+}
+
+// CreateChargeTemplateRequest defines the request body for creating a charge template
+type CreateChargeTemplateRequest struct {
+	Name               string                      `json:"name"`
+	TransportMode      string                      `json:"transportMode"`
+	ServiceType        *string                     `json:"serviceType,omitempty"`
+	ServiceSubcategory *string                     `json:"serviceSubcategory,omitempty"`
+	IncotermCode       *string                     `json:"incotermCode,omitempty"`
+	Lines              []ChargeTemplateLineRequest `json:"lines"`
+}
+
+// ChargeTemplateLineResponse describes one line of a charge template
+type ChargeTemplateLineResponse struct {
+	ID            string           `json:"id"`
+	LineKind      string           `json:"lineKind"`
+	ActivityType  string           `json:"activityType"`
+	ActivityCode  string           `json:"activityCode"`
+	QuantityBasis string           `json:"quantityBasis"`
+	PartyRole     *string          `json:"partyRole,omitempty"`
+	CurrencyCode  *string          `json:"currencyCode,omitempty"`
+	UnitPrice     *decimal.Decimal `json:"unitPrice,omitempty"`
+	TaxCode       *string          `json:"taxCode,omitempty"`
+	Description   *string          `json:"description,omitempty"`
+	SortOrder     int32            `json:"sortOrder"`
+}
+
+// ChargeTemplateResponse describes a charge template and its lines
+type ChargeTemplateResponse struct {
+	ID                 string                       `json:"id"`
+	Name               string                       `json:"name"`
+	TransportMode      string                       `json:"transportMode"`
+	ServiceType        *string                      `json:"serviceType,omitempty"`
+	ServiceSubcategory *string                      `json:"serviceSubcategory,omitempty"`
+	IncotermCode       *string                      `json:"incotermCode,omitempty"`
+	Lines              []ChargeTemplateLineResponse `json:"lines"`
+	CreatedAt          *time.Time                   `json:"createdAt,omitempty"`
+	CreatedBy          *string                      `json:"createdBy,omitempty"`
+}
+
+// ProposedChargeResponse is a proposed billing or provision line, ready to be sent back with the job
+type ProposedChargeResponse struct {
+	ActivityType *string          `json:"activityType,omitempty"`
+	ActivityCode *string          `json:"activityCode,omitempty"`
+	PartyID      *string          `json:"partyId,omitempty"`
+	CurrencyCode *string          `json:"currencyCode,omitempty"`
+	Quantity     *decimal.Decimal `json:"quantity,omitempty"`
+	UnitPrice    *decimal.Decimal `json:"unitPrice,omitempty"`
+	TaxCode      *string          `json:"taxCode,omitempty"`
+	Description  *string          `json:"description,omitempty"`
+}
+
+// ChargeProposalResponse lists the charges a template proposes for a job
+type ChargeProposalResponse struct {
+	TemplateID   string                   `json:"templateId"`
+	TemplateName string                   `json:"templateName"`
+	Billing      []ProposedChargeResponse `json:"billing"`
+	Provisions   []ProposedChargeResponse `json:"provisions"`
+	Gaps         []string                 `json:"gaps,omitempty"`
+}
+
+// ListTemplates returns the active charge templates, optionally filtered by ?transportMode=.
+func (h *ChargeTemplateHandler) ListTemplates(w http.ResponseWriter, r *http.Request) {
+	templates, err := h.operationsService.ListChargeTemplates(r.Context(), optionalQuery(r, "transportMode"))
+	if err != nil {
+		h.writeChargeTemplateError(w, r, err)
+		return
+	}
+
+	resp := make([]ChargeTemplateResponse, 0, len(templates))
+	for _, t := range templates {
+		resp = append(resp, chargeTemplateResponse(t))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// CreateTemplate validates and stores a charge template.
+func (h *ChargeTemplateHandler) CreateTemplate(w http.ResponseWriter, r *http.Request) {
+	var req CreateChargeTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_body", "request body must be valid JSON")
+		return
+	}
+	if strings.TrimSpace(req.Name) == "" || strings.TrimSpace(req.TransportMode) == "" || len(req.Lines) == 0 {
+		writeError(w, http.StatusBadRequest, "invalid_body", "name, transportMode and lines are required")
+		return
+	}
+
+	input := operationsdto.ChargeTemplateInput{
+		Name:               req.Name,
+		TransportMode:      req.TransportMode,
+		ServiceType:        req.ServiceType,
+		ServiceSubcategory: req.ServiceSubcategory,
+		IncotermCode:       req.IncotermCode,
+		Lines:              make([]operationsdto.ChargeTemplateLineInput, 0, len(req.Lines)),
+		CreatedBy:          actorFromRequest(r),
+	}
+	for _, line := range req.Lines {
+		input.Lines = append(input.Lines, operationsdto.ChargeTemplateLineInput{
+			LineKind:      line.LineKind,
+			ActivityType:  line.ActivityType,
+			ActivityCode:  line.ActivityCode,
+			QuantityBasis: line.QuantityBasis,
+			PartyRole:     line.PartyRole,
+			CurrencyCode:  line.CurrencyCode,
+			UnitPrice:     line.UnitPrice,
+			TaxCode:       line.TaxCode,
+			Description:   line.Description,
+		})
+	}
+
+	template, err := h.operationsService.CreateChargeTemplate(r.Context(), input)
+	if err != nil {
+		h.writeChargeTemplateError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, chargeTemplateResponse(template))
+}
+
+// DeactivateTemplate retires a charge template.
+func (h *ChargeTemplateHandler) DeactivateTemplate(w http.ResponseWriter, r *http.Request) {
+	templateID, err := uuid.Parse(chi.URLParam(r, "templateID"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_template_id", "template id must be a UUID")
+		return
+	}
+
+	if err := h.operationsService.DeactivateChargeTemplate(r.Context(), templateID, actorFromRequest(r)); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			writeError(w, http.StatusNotFound, "not_found", "charge template not found")
+			return
+		}
+		h.writeChargeTemplateError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ProposeCharges returns the billing and provision lines the job's charge template proposes.
+func (h *ChargeTemplateHandler) ProposeCharges(w http.ResponseWriter, r *http.Request) {
+	jobID, ok := jobIDParam(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid_job_id", "job id must be a UUID")
+		return
+	}
+
+	proposal, err := h.operationsService.ProposeJobCharges(r.Context(), jobID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			writeError(w, http.StatusNotFound, "not_found", "job not found or no charge template matches it")
+			return
+		}
+		h.writeChargeTemplateError(w, r, err)
+		return
+	}
+
+	resp := ChargeProposalResponse{
+		TemplateID:   proposal.TemplateID.String(),
+		TemplateName: proposal.TemplateName,
+		Billing:      make([]ProposedChargeResponse, 0, len(proposal.Billing)),
+		Provisions:   make([]ProposedChargeResponse, 0, len(proposal.Provisions)),
+		Gaps:         proposal.Gaps,
+	}
+	for _, b := range proposal.Billing {
+		resp.Billing = append(resp.Billing, ProposedChargeResponse{
+			ActivityType: b.ActivityType,
+			ActivityCode: b.ActivityCode,
+			PartyID:      uuidString(b.BillingPartyID),
+			CurrencyCode: b.CurrencyCode,
+			Quantity:     b.Quantity,
+			UnitPrice:    b.UnitPrice,
+			TaxCode:      b.TaxCode,
+			Description:  b.Description,
+		})
+	}
+	for _, p := range proposal.Provisions {
+		resp.Provisions = append(resp.Provisions, ProposedChargeResponse{
+			ActivityType: p.ActivityType,
+			ActivityCode: p.ActivityCode,
+			PartyID:      uuidString(p.CostPartyID),
+			CurrencyCode: p.CurrencyCode,
+			Quantity:     p.Quantity,
+			UnitPrice:    p.UnitPrice,
+			TaxCode:      p.TaxCode,
+			Description:  p.Notes,
+		})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *ChargeTemplateHandler) writeChargeTemplateError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, operationsservice.ErrInvalidChargeTemplate):
+		writeError(w, http.StatusUnprocessableEntity, "invalid_charge_template", err.Error())
+	default:
+		logging.FromContext(r.Context()).Error("charge template request failed", slog.Any("error", err))
+		writeError(w, http.StatusInternalServerError, "internal_error", "charge template request failed")
+	}
+}
+
+func chargeTemplateResponse(t operationsdto.ChargeTemplate) ChargeTemplateResponse {
+	resp := ChargeTemplateResponse{
+		ID:                 t.ID.String(),
+		Name:               t.Name,
+		TransportMode:      t.TransportMode,
+		ServiceType:        t.ServiceType,
+		ServiceSubcategory: t.ServiceSubcategory,
+		IncotermCode:       t.IncotermCode,
+		Lines:              make([]ChargeTemplateLineResponse, 0, len(t.Lines)),
+		CreatedAt:          t.CreatedAt,
+		CreatedBy:          t.CreatedBy,
+	}
+	for _, line := range t.Lines {
+		resp.Lines = append(resp.Lines, ChargeTemplateLineResponse{
+			ID:            line.ID.String(),
+			LineKind:      line.LineKind,
+			ActivityType:  line.ActivityType,
+			ActivityCode:  line.ActivityCode,
+			QuantityBasis: line.QuantityBasis,
+			PartyRole:     line.PartyRole,
+			CurrencyCode:  line.CurrencyCode,
+			UnitPrice:     line.UnitPrice,
+			TaxCode:       line.TaxCode,
+			Description:   line.Description,
+			SortOrder:     line.SortOrder,
+		})
+	}
+	return resp
+}
@@ -0,0 +1,180 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"frego-operations/internal/decimal"
+	operationsdto "frego-operations/internal/dto/operations"
+	"frego-operations/internal/logging"
+	operationsservice "frego-operations/internal/service/operations"
+)
+
+// CreditHandler manages customer credit limits and their override audit.
+type CreditHandler struct {
+	logger            *slog.Logger
+	operationsService *operationsservice.Service
+}
+
+// NewCreditHandler creates a new credit handler
+func NewCreditHandler(logger *slog.Logger, operationsService *operationsservice.Service) *CreditHandler {
+	return &CreditHandler{
+		logger:            logger,
+		operationsService: operationsService,
+	}
+}
+
+// RegisterRoutes registers customer credit routes
+func (h *CreditHandler) RegisterRoutes(r chi.Router) {
+	r.Get("/parties/{partyID}/credit", h.GetCredit)
+	r.Put("/parties/{partyID}/credit-limit", h.SetCreditLimit)
+	r.Delete("/parties/{partyID}/credit-limit", h.RemoveCreditLimit)
+	r.Get("/parties/{partyID}/credit-overrides", h.ListOverrides)
+}
+
+// CreditLimitRequest sets a customer's credit limit in the primary currency
+type CreditLimitRequest struct {
+	CreditLimit *decimal.Decimal `json:"creditLimit"`
+}
+
+// CustomerCreditResponse is a customer's exposure against their credit limit
+type CustomerCreditResponse struct {
+	PartyID     string           `json:"partyId"`
+	Currency    string           `json:"currency"`
+	CreditLimit *decimal.Decimal `json:"creditLimit,omitempty"`
+	OpenBilled  decimal.Decimal  `json:"openBilled"`
+	Unbilled    decimal.Decimal  `json:"unbilled"`
+	Exposure    decimal.Decimal  `json:"exposure"`
+	Available   *decimal.Decimal `json:"available,omitempty"`
+}
+
+// CreditOverrideResponse is a job created or activated past the customer's credit limit
+type CreditOverrideResponse struct {
+	ID           string          `json:"id"`
+	JobID        string          `json:"jobId"`
+	JobCode      string          `json:"jobCode"`
+	CreditLimit  decimal.Decimal `json:"creditLimit"`
+	Exposure     decimal.Decimal `json:"exposure"`
+	Currency     string          `json:"currency"`
+	OverriddenAt *time.Time      `json:"overriddenAt,omitempty"`
+	OverriddenBy *string         `json:"overriddenBy,omitempty"`
+}
+
+// GetCredit returns the customer's credit limit and current exposure.
+func (h *CreditHandler) GetCredit(w http.ResponseWriter, r *http.Request) {
+	partyID, ok := partyIDParam(w, r)
+	if !ok {
+		return
+	}
+	credit, err := h.operationsService.GetCustomerCredit(r.Context(), partyID)
+	if err != nil {
+		h.writeCreditError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, customerCreditResponse(credit))
+}
+
+// SetCreditLimit sets the customer's credit limit.
+func (h *CreditHandler) SetCreditLimit(w http.ResponseWriter, r *http.Request) {
+	partyID, ok := partyIDParam(w, r)
+	if !ok {
+		return
+	}
+	var req CreditLimitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_body", "request body must be valid JSON")
+		return
+	}
+	if req.CreditLimit == nil {
+		writeError(w, http.StatusBadRequest, "invalid_body", "creditLimit is required")
+		return
+	}
+
+	credit, err := h.operationsService.SetCreditLimit(r.Context(), partyID, *req.CreditLimit, actorFromRequest(r))
+	if err != nil {
+		h.writeCreditError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, customerCreditResponse(credit))
+}
+
+// RemoveCreditLimit lifts the customer's credit limit.
+func (h *CreditHandler) RemoveCreditLimit(w http.ResponseWriter, r *http.Request) {
+	partyID, ok := partyIDParam(w, r)
+	if !ok {
+		return
+	}
+	if err := h.operationsService.RemoveCreditLimit(r.Context(), partyID); err != nil {
+		h.writeCreditError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListOverrides returns the jobs created or activated past the customer's limit.
+func (h *CreditHandler) ListOverrides(w http.ResponseWriter, r *http.Request) {
+	partyID, ok := partyIDParam(w, r)
+	if !ok {
+		return
+	}
+	overrides, err := h.operationsService.ListCreditOverrides(r.Context(), partyID)
+	if err != nil {
+		h.writeCreditError(w, r, err)
+		return
+	}
+	resp := make([]CreditOverrideResponse, 0, len(overrides))
+	for _, o := range overrides {
+		resp = append(resp, CreditOverrideResponse{
+			ID:           o.ID.String(),
+			JobID:        o.JobID.String(),
+			JobCode:      o.JobCode,
+			CreditLimit:  o.CreditLimit,
+			Exposure:     o.Exposure,
+			Currency:     o.Currency,
+			OverriddenAt: o.OverriddenAt,
+			OverriddenBy: o.OverriddenBy,
+		})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *CreditHandler) writeCreditError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		writeError(w, http.StatusNotFound, "not_found", "credit limit not found")
+	case errors.Is(err, operationsservice.ErrInvalidCreditLimit), errors.Is(err, operationsservice.ErrInvalidCharge):
+		writeError(w, http.StatusUnprocessableEntity, "invalid_credit_limit", err.Error())
+	default:
+		logging.FromContext(r.Context()).Error("credit request failed", slog.Any("error", err))
+		writeError(w, http.StatusInternalServerError, "internal_error", "credit request failed")
+	}
+}
+
+// partyIDParam parses {partyID}, writing a 400 and returning false when it is not a UUID.
+func partyIDParam(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	partyID, err := uuid.Parse(chi.URLParam(r, "partyID"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_party_id", "party id must be a UUID")
+		return uuid.Nil, false
+	}
+	return partyID, true
+}
+
+func customerCreditResponse(c operationsdto.CustomerCredit) CustomerCreditResponse {
+	return CustomerCreditResponse{
+		PartyID:     c.PartyID.String(),
+		Currency:    c.Currency,
+		CreditLimit: c.CreditLimit,
+		OpenBilled:  c.OpenBilled,
+		Unbilled:    c.Unbilled,
+		Exposure:    c.Exposure,
+		Available:   c.Available,
+	}
+}
@@ -0,0 +1,189 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+
+	"frego-operations/internal/common"
+	"frego-operations/internal/docrender"
+	operationsdto "frego-operations/internal/dto/operations"
+	"frego-operations/internal/logging"
+	operationsservice "frego-operations/internal/service/operations"
+	"frego-operations/internal/storage"
+)
+
+// DocumentHandler serves printable document templates and generation for jobs.
+type DocumentHandler struct {
+	logger            *slog.Logger
+	operationsService *operationsservice.Service
+}
+
+// NewDocumentHandler creates a new document handler
+func NewDocumentHandler(logger *slog.Logger, operationsService *operationsservice.Service) *DocumentHandler {
+	return &DocumentHandler{
+		logger:            logger,
+		operationsService: operationsService,
+	}
+}
+
+// RegisterRoutes registers document routes
+func (h *DocumentHandler) RegisterRoutes(r chi.Router) {
+	r.Get("/document-templates", h.ListTemplates)
+	r.Post("/document-templates", h.CreateTemplate)
+	r.Post("/jobs/{jobID}/documents/{docType}/generate", h.GenerateDocument)
+}
+
+// CreateDocumentTemplateRequest defines the request body for uploading a template version
+type CreateDocumentTemplateRequest struct {
+	DocTypeCode string  `json:"docTypeCode"`
+	Name        *string `json:"name,omitempty"`
+	BodyHTML    string  `json:"bodyHtml"`
+}
+
+// DocumentTemplateResponse describes one stored template version
+type DocumentTemplateResponse struct {
+	ID          string     `json:"id"`
+	DocTypeCode string     `json:"docTypeCode"`
+	Version     int32      `json:"version"`
+	Name        *string    `json:"name,omitempty"`
+	BodyHTML    string     `json:"bodyHtml"`
+	CreatedAt   *time.Time `json:"createdAt,omitempty"`
+	CreatedBy   *string    `json:"createdBy,omitempty"`
+	IsActive    bool       `json:"isActive"`
+}
+
+// GeneratedDocumentResponse describes a document generated and attached to a job
+type GeneratedDocumentResponse struct {
+	ID              string     `json:"id"`
+	DocTypeCode     *string    `json:"docTypeCode,omitempty"`
+	DocNumber       *string    `json:"docNumber,omitempty"`
+	IssuedDate      *time.Time `json:"issuedDate,omitempty"`
+	Description     *string    `json:"description,omitempty"`
+	FileKey         *string    `json:"fileKey,omitempty"`
+	FileRegion      *string    `json:"fileRegion,omitempty"`
+	TemplateVersion int32      `json:"templateVersion"`
+}
+
+// ListTemplates returns the stored template versions, optionally filtered by ?docType=.
+func (h *DocumentHandler) ListTemplates(w http.ResponseWriter, r *http.Request) {
+	var docType *string
+	if v := strings.TrimSpace(r.URL.Query().Get("docType")); v != "" {
+		v = strings.ToUpper(v)
+		docType = &v
+	}
+
+	templates, err := h.operationsService.ListDocumentTemplates(r.Context(), docType)
+	if err != nil {
+		h.writeDocumentError(w, r, err)
+		return
+	}
+
+	resp := make([]DocumentTemplateResponse, 0, len(templates))
+	for _, t := range templates {
+		resp = append(resp, documentTemplateResponse(t))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// CreateTemplate validates and stores a new template version for a document type.
+func (h *DocumentHandler) CreateTemplate(w http.ResponseWriter, r *http.Request) {
+	var req CreateDocumentTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_body", "request body must be valid JSON")
+		return
+	}
+	if strings.TrimSpace(req.DocTypeCode) == "" || strings.TrimSpace(req.BodyHTML) == "" {
+		writeError(w, http.StatusBadRequest, "invalid_body", "docTypeCode and bodyHtml are required")
+		return
+	}
+
+	template, err := h.operationsService.CreateDocumentTemplate(r.Context(), operationsdto.DocumentTemplateInput{
+		DocTypeCode: req.DocTypeCode,
+		Name:        req.Name,
+		BodyHTML:    req.BodyHTML,
+		CreatedBy:   actorFromRequest(r),
+	})
+	if err != nil {
+		h.writeDocumentError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, documentTemplateResponse(template))
+}
+
+// GenerateDocument renders a document for the job and attaches the PDF to it.
+func (h *DocumentHandler) GenerateDocument(w http.ResponseWriter, r *http.Request) {
+	jobID, ok := jobIDParam(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid_job_id", "job id must be a UUID")
+		return
+	}
+
+	generated, err := h.operationsService.GenerateJobDocument(r.Context(), jobID, chi.URLParam(r, "docType"), actorFromRequest(r))
+	if err != nil {
+		h.writeDocumentError(w, r, err)
+		return
+	}
+
+	doc := generated.Document
+	writeJSON(w, http.StatusCreated, GeneratedDocumentResponse{
+		ID:              doc.ID.String(),
+		DocTypeCode:     doc.DocTypeCode,
+		DocNumber:       doc.DocNumber,
+		IssuedDate:      doc.IssuedDate,
+		Description:     doc.Description,
+		FileKey:         doc.FileKey,
+		FileRegion:      doc.FileRegion,
+		TemplateVersion: generated.TemplateVersion,
+	})
+}
+
+func (h *DocumentHandler) writeDocumentError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		writeError(w, http.StatusNotFound, "not_found", "job not found")
+	case errors.Is(err, operationsservice.ErrJobClosed):
+		writeError(w, http.StatusConflict, "job_closed", err.Error())
+	case errors.Is(err, operationsservice.ErrUnknownDocumentType), errors.Is(err, docrender.ErrNoTemplate):
+		writeError(w, http.StatusNotFound, "unknown_document_type", err.Error())
+	case errors.Is(err, docrender.ErrInvalidTemplate):
+		writeError(w, http.StatusUnprocessableEntity, "invalid_template", err.Error())
+	case errors.Is(err, storage.ErrUploaderDisabled):
+		writeError(w, http.StatusServiceUnavailable, "storage_disabled", "document storage is not configured")
+	default:
+		logging.FromContext(r.Context()).Error("document request failed", slog.Any("error", err))
+		writeError(w, http.StatusInternalServerError, "internal_error", "document request failed")
+	}
+}
+
+func documentTemplateResponse(t operationsdto.DocumentTemplate) DocumentTemplateResponse {
+	return DocumentTemplateResponse{
+		ID:          t.ID.String(),
+		DocTypeCode: t.DocTypeCode,
+		Version:     t.Version,
+		Name:        t.Name,
+		BodyHTML:    t.BodyHTML,
+		CreatedAt:   t.CreatedAt,
+		CreatedBy:   t.CreatedBy,
+		IsActive:    t.IsActive,
+	}
+}
+
+// actorFromRequest names the caller for audit columns.
+func actorFromRequest(r *http.Request) string {
+	if principal, ok := common.PrincipalFromContext(r.Context()); ok {
+		for _, name := range []string{principal.Username, principal.Email, principal.Subject} {
+			if name != "" {
+				return name
+			}
+		}
+	}
+	return "system"
+}
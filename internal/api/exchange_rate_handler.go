@@ -0,0 +1,240 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	operationsdto "frego-operations/internal/dto/operations"
+	"frego-operations/internal/logging"
+	operationsservice "frego-operations/internal/service/operations"
+)
+
+const (
+	maxRateSheetBytes     = 5 << 20
+	defaultExchangeRates  = 100
+	maxExchangeRatesLimit = 1000
+)
+
+// ExchangeRateHandler serves the tenant's exchange rate sheets and job rate locking.
+type ExchangeRateHandler struct {
+	logger            *slog.Logger
+	operationsService *operationsservice.Service
+}
+
+// NewExchangeRateHandler creates a new exchange rate handler
+func NewExchangeRateHandler(logger *slog.Logger, operationsService *operationsservice.Service) *ExchangeRateHandler {
+	return &ExchangeRateHandler{
+		logger:            logger,
+		operationsService: operationsService,
+	}
+}
+
+// RegisterRoutes registers exchange rate routes
+func (h *ExchangeRateHandler) RegisterRoutes(r chi.Router) {
+	r.Get("/exchange-rates", h.ListRates)
+	r.Get("/exchange-rates/lookup", h.LookupRate)
+	r.Post("/exchange-rates/import", h.ImportRates)
+	r.Post("/jobs/{jobID}/exchange-rates/refresh", h.RefreshJobRates)
+	r.Post("/jobs/{jobID}/exchange-rates/lock", h.LockJobRates)
+}
+
+// ExchangeRateResponse describes one rate sheet entry
+type ExchangeRateResponse struct {
+	ID            string     `json:"id"`
+	FromCurrency  string     `json:"fromCurrency"`
+	ToCurrency    string     `json:"toCurrency"`
+	EffectiveDate string     `json:"effectiveDate"`
+	Rate          string     `json:"rate"`
+	Source        *string    `json:"source,omitempty"`
+	CreatedAt     *time.Time `json:"createdAt,omitempty"`
+	CreatedBy     *string    `json:"createdBy,omitempty"`
+}
+
+// ExchangeRateQuoteResponse describes the rate in force for a pair on a date
+type ExchangeRateQuoteResponse struct {
+	FromCurrency  string `json:"fromCurrency"`
+	ToCurrency    string `json:"toCurrency"`
+	Date          string `json:"date"`
+	EffectiveDate string `json:"effectiveDate"`
+	Rate          string `json:"rate"`
+	Inverted      bool   `json:"inverted"`
+}
+
+// ExchangeRateImportResponse summarises an imported rate sheet
+type ExchangeRateImportResponse struct {
+	Source   string `json:"source,omitempty"`
+	Imported int    `json:"imported"`
+}
+
+// JobRateUpdateResponse reports how many job lines a rate operation touched
+type JobRateUpdateResponse struct {
+	JobID string `json:"jobId"`
+	Lines int64  `json:"lines"`
+}
+
+// ListRates returns rate sheet entries, filtered by ?from= and ?to=.
+func (h *ExchangeRateHandler) ListRates(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	limit := int32(defaultExchangeRates)
+	if raw := query.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxExchangeRatesLimit {
+			writeError(w, http.StatusBadRequest, "invalid_limit", "limit must be between 1 and 1000")
+			return
+		}
+		limit = int32(n)
+	}
+
+	rates, err := h.operationsService.ListExchangeRates(r.Context(), optionalQuery(r, "from"), optionalQuery(r, "to"), limit)
+	if err != nil {
+		h.writeRateError(w, r, err)
+		return
+	}
+
+	resp := make([]ExchangeRateResponse, 0, len(rates))
+	for _, rate := range rates {
+		resp = append(resp, ExchangeRateResponse{
+			ID:            rate.ID.String(),
+			FromCurrency:  rate.FromCurrency,
+			ToCurrency:    rate.ToCurrency,
+			EffectiveDate: rate.EffectiveDate.Format(time.DateOnly),
+			Rate:          rate.Rate.String(),
+			Source:        rate.Source,
+			CreatedAt:     rate.CreatedAt,
+			CreatedBy:     rate.CreatedBy,
+		})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// LookupRate returns the rate in force for ?from= and ?to= on ?date= (default today).
+func (h *ExchangeRateHandler) LookupRate(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	from, to := query.Get("from"), query.Get("to")
+	if from == "" || to == "" {
+		writeError(w, http.StatusBadRequest, "invalid_pair", "from and to are required")
+		return
+	}
+	on := time.Now().UTC()
+	if raw := query.Get("date"); raw != "" {
+		parsed, err := time.Parse(time.DateOnly, raw)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_date", "date must be YYYY-MM-DD")
+			return
+		}
+		on = parsed
+	}
+
+	quote, err := h.operationsService.LookupExchangeRate(r.Context(), from, to, on)
+	if err != nil {
+		h.writeRateError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ExchangeRateQuoteResponse{
+		FromCurrency:  quote.FromCurrency,
+		ToCurrency:    quote.ToCurrency,
+		Date:          quote.OnDate.Format(time.DateOnly),
+		EffectiveDate: quote.EffectiveDate.Format(time.DateOnly),
+		Rate:          quote.Rate.String(),
+		Inverted:      quote.Inverted,
+	})
+}
+
+// ImportRates imports a CSV rate sheet sent either as the raw body or as the "file" part of a
+// multipart form. ?source= names the sheet; the uploaded file name is used otherwise.
+func (h *ExchangeRateHandler) ImportRates(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRateSheetBytes)
+	source := strings.TrimSpace(r.URL.Query().Get("source"))
+
+	var sheet io.Reader = r.Body
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_body", "multipart upload must include a file part")
+			return
+		}
+		defer file.Close()
+		sheet = file
+		if source == "" {
+			source = header.Filename
+		}
+	}
+
+	result, err := h.operationsService.ImportExchangeRates(r.Context(), sheet, source, actorFromRequest(r))
+	if err != nil {
+		h.writeRateError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, ExchangeRateImportResponse{Source: result.Source, Imported: result.Imported})
+}
+
+// RefreshJobRates reprices the job's unlocked lines with the rates currently in force.
+func (h *ExchangeRateHandler) RefreshJobRates(w http.ResponseWriter, r *http.Request) {
+	h.updateJobRates(w, r, h.operationsService.RefreshJobRates)
+}
+
+// LockJobRates freezes the exchange rate on all of the job's lines.
+func (h *ExchangeRateHandler) LockJobRates(w http.ResponseWriter, r *http.Request) {
+	h.updateJobRates(w, r, h.operationsService.LockJobRates)
+}
+
+func (h *ExchangeRateHandler) updateJobRates(
+	w http.ResponseWriter,
+	r *http.Request,
+	update func(ctx context.Context, jobID uuid.UUID, actor string) (operationsdto.JobRateUpdate, error),
+) {
+	jobID, ok := jobIDParam(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid_job_id", "job id must be a UUID")
+		return
+	}
+
+	result, err := update(r.Context(), jobID, actorFromRequest(r))
+	if err != nil {
+		h.writeRateError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, JobRateUpdateResponse{JobID: result.JobID.String(), Lines: result.Lines})
+}
+
+func (h *ExchangeRateHandler) writeRateError(w http.ResponseWriter, r *http.Request, err error) {
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		writeError(w, http.StatusRequestEntityTooLarge, "rate_sheet_too_large", "rate sheet exceeds 5 MB")
+	case errors.Is(err, pgx.ErrNoRows):
+		writeError(w, http.StatusNotFound, "not_found", "job not found")
+	case errors.Is(err, operationsservice.ErrNoExchangeRate):
+		writeError(w, http.StatusNotFound, "no_exchange_rate", err.Error())
+	case errors.Is(err, operationsservice.ErrJobClosed):
+		writeError(w, http.StatusConflict, "job_closed", err.Error())
+	case errors.Is(err, operationsservice.ErrInvalidRateSheet), errors.Is(err, operationsservice.ErrInvalidCharge):
+		writeError(w, http.StatusUnprocessableEntity, "invalid_rates", err.Error())
+	default:
+		logging.FromContext(r.Context()).Error("exchange rate request failed", slog.Any("error", err))
+		writeError(w, http.StatusInternalServerError, "internal_error", "exchange rate request failed")
+	}
+}
+
+// optionalQuery returns a trimmed query parameter, or nil when it is absent.
+func optionalQuery(r *http.Request, name string) *string {
+	v := strings.TrimSpace(r.URL.Query().Get(name))
+	if v == "" {
+		return nil
+	}
+	return &v
+}
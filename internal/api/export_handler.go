@@ -0,0 +1,123 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	operationsdto "frego-operations/internal/dto/operations"
+	"frego-operations/internal/edifact"
+	"frego-operations/internal/iata"
+	"frego-operations/internal/logging"
+	operationsservice "frego-operations/internal/service/operations"
+)
+
+// ExportHandler serves carrier-facing document and message exports for jobs.
+type ExportHandler struct {
+	logger            *slog.Logger
+	operationsService *operationsservice.Service
+	ediSenderID       string
+}
+
+// NewExportHandler creates a new export handler
+func NewExportHandler(logger *slog.Logger, operationsService *operationsservice.Service, ediSenderID string) *ExportHandler {
+	return &ExportHandler{
+		logger:            logger,
+		operationsService: operationsService,
+		ediSenderID:       ediSenderID,
+	}
+}
+
+// RegisterRoutes registers export routes
+func (h *ExportHandler) RegisterRoutes(r chi.Router) {
+	r.Get("/jobs/{jobID}/exports/iftmin", h.ExportIFTMIN)
+	r.Get("/jobs/{jobID}/exports/fwb", h.ExportFWB)
+	r.Get("/jobs/{jobID}/exports/fhl", h.ExportFHL)
+}
+
+// ExportIFTMIN returns the job's IFTMIN booking request as an .edi file.
+func (h *ExportHandler) ExportIFTMIN(w http.ResponseWriter, r *http.Request) {
+	jobID, ok := jobIDParam(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid_job_id", "job id must be a UUID")
+		return
+	}
+
+	file, err := h.operationsService.ExportIFTMIN(r.Context(), jobID, operationsdto.EDIExportOptions{
+		SenderID:      h.ediSenderID,
+		RecipientID:   strings.TrimSpace(r.URL.Query().Get("recipient")),
+		TestIndicator: r.URL.Query().Get("test") == "true",
+	})
+	if err != nil {
+		h.writeExportError(w, r, err)
+		return
+	}
+
+	writeFile(w, file.FileName, file.ContentType, file.Data)
+}
+
+// ExportFWB returns the job's master air waybill as Cargo-IMP text or Cargo-XML.
+func (h *ExportHandler) ExportFWB(w http.ResponseWriter, r *http.Request) {
+	h.exportAirWaybill(w, r, h.operationsService.ExportFWB)
+}
+
+// ExportFHL returns the house manifest of a consolidated air job as Cargo-IMP text or Cargo-XML.
+func (h *ExportHandler) ExportFHL(w http.ResponseWriter, r *http.Request) {
+	h.exportAirWaybill(w, r, h.operationsService.ExportFHL)
+}
+
+func (h *ExportHandler) exportAirWaybill(
+	w http.ResponseWriter,
+	r *http.Request,
+	export func(context.Context, uuid.UUID, operationsdto.EDIExportOptions) (operationsdto.ExportFile, error),
+) {
+	jobID, ok := jobIDParam(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid_job_id", "job id must be a UUID")
+		return
+	}
+
+	format := iata.Format(strings.ToLower(strings.TrimSpace(r.URL.Query().Get("format"))))
+	switch format {
+	case "":
+		format = iata.FormatCargoIMP
+	case iata.FormatCargoIMP, iata.FormatCargoXML:
+	default:
+		writeError(w, http.StatusBadRequest, "invalid_format", "format must be cargo-imp or cargo-xml")
+		return
+	}
+
+	file, err := export(r.Context(), jobID, operationsdto.EDIExportOptions{
+		SenderID:    h.ediSenderID,
+		RecipientID: strings.TrimSpace(r.URL.Query().Get("recipient")),
+		Format:      string(format),
+	})
+	if err != nil {
+		h.writeExportError(w, r, err)
+		return
+	}
+
+	writeFile(w, file.FileName, file.ContentType, file.Data)
+}
+
+func (h *ExportHandler) writeExportError(w http.ResponseWriter, r *http.Request, err error) {
+	var validationErr *edifact.ValidationError
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		writeError(w, http.StatusNotFound, "not_found", "job not found")
+	case errors.Is(err, operationsservice.ErrUnsupportedJobType):
+		writeError(w, http.StatusUnprocessableEntity, "unsupported_job_type", err.Error())
+	case errors.As(err, &validationErr), errors.Is(err, edifact.ErrMissingBookingData),
+		errors.Is(err, iata.ErrInvalidWaybill), errors.Is(err, iata.ErrInvalidAWB):
+		writeError(w, http.StatusUnprocessableEntity, "export_invalid", err.Error())
+	default:
+		logging.FromContext(r.Context()).Error("export failed", slog.Any("error", err))
+		writeError(w, http.StatusInternalServerError, "internal_error", "export failed")
+	}
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+
+	"frego-operations/internal/logging"
+	operationsservice "frego-operations/internal/service/operations"
+)
+
+// IncotermHandler serves the incoterm catalogue and checks billing against it.
+type IncotermHandler struct {
+	logger            *slog.Logger
+	operationsService *operationsservice.Service
+}
+
+// NewIncotermHandler creates a new incoterm handler
+func NewIncotermHandler(logger *slog.Logger, operationsService *operationsservice.Service) *IncotermHandler {
+	return &IncotermHandler{
+		logger:            logger,
+		operationsService: operationsService,
+	}
+}
+
+// RegisterRoutes registers incoterm routes
+func (h *IncotermHandler) RegisterRoutes(r chi.Router) {
+	r.Get("/incoterms", h.ListIncoterms)
+	r.Get("/jobs/{jobID}/billing/incoterm-check", h.CheckJobBilling)
+}
+
+// IncotermResponse describes one incoterm and who bears each cost segment
+type IncotermResponse struct {
+	ID               string            `json:"id"`
+	Code             string            `json:"code"`
+	Name             string            `json:"name"`
+	Version          int32             `json:"version"`
+	Responsibilities map[string]string `json:"responsibilities"`
+}
+
+// IncotermBillingLineResponse is the verdict for one billing line
+type IncotermBillingLineResponse struct {
+	BillingID       string  `json:"billingId"`
+	ActivityType    *string `json:"activityType,omitempty"`
+	ActivityCode    *string `json:"activityCode,omitempty"`
+	CostSegment     *string `json:"costSegment,omitempty"`
+	Bearer          *string `json:"bearer,omitempty"`
+	BillingPartyID  *string `json:"billingPartyId,omitempty"`
+	ExpectedPartyID *string `json:"expectedPartyId,omitempty"`
+	Status          string  `json:"status"`
+}
+
+// IncotermBillingCheckResponse compares a job's billing parties with its incoterm
+type IncotermBillingCheckResponse struct {
+	JobID       string                        `json:"jobId"`
+	Incoterm    string                        `json:"incoterm"`
+	ShipperID   *string                       `json:"shipperId,omitempty"`
+	ConsigneeID *string                       `json:"consigneeId,omitempty"`
+	Lines       []IncotermBillingLineResponse `json:"lines"`
+}
+
+// ListIncoterms returns the incoterm catalogue.
+func (h *IncotermHandler) ListIncoterms(w http.ResponseWriter, r *http.Request) {
+	incoterms, err := h.operationsService.ListIncoterms(r.Context())
+	if err != nil {
+		h.writeIncotermError(w, r, err)
+		return
+	}
+
+	resp := make([]IncotermResponse, 0, len(incoterms))
+	for _, t := range incoterms {
+		resp = append(resp, IncotermResponse{
+			ID:               t.ID.String(),
+			Code:             t.Code,
+			Name:             t.Name,
+			Version:          t.Version,
+			Responsibilities: t.Responsibilities,
+		})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// CheckJobBilling reports, per billing line, whether it is billed to the party its incoterm makes responsible.
+func (h *IncotermHandler) CheckJobBilling(w http.ResponseWriter, r *http.Request) {
+	jobID, ok := jobIDParam(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid_job_id", "job id must be a UUID")
+		return
+	}
+
+	check, err := h.operationsService.CheckJobBillingIncoterm(r.Context(), jobID)
+	if err != nil {
+		h.writeIncotermError(w, r, err)
+		return
+	}
+
+	resp := IncotermBillingCheckResponse{
+		JobID:       check.JobID.String(),
+		Incoterm:    check.Incoterm,
+		ShipperID:   uuidString(check.ShipperID),
+		ConsigneeID: uuidString(check.ConsigneeID),
+		Lines:       make([]IncotermBillingLineResponse, 0, len(check.Lines)),
+	}
+	for _, line := range check.Lines {
+		resp.Lines = append(resp.Lines, IncotermBillingLineResponse{
+			BillingID:       line.BillingID.String(),
+			ActivityType:    line.ActivityType,
+			ActivityCode:    line.ActivityCode,
+			CostSegment:     line.CostSegment,
+			Bearer:          line.Bearer,
+			BillingPartyID:  uuidString(line.BillingPartyID),
+			ExpectedPartyID: uuidString(line.ExpectedPartyID),
+			Status:          line.Status,
+		})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *IncotermHandler) writeIncotermError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		writeError(w, http.StatusNotFound, "not_found", "job not found")
+	case errors.Is(err, operationsservice.ErrUnknownIncoterm):
+		writeError(w, http.StatusUnprocessableEntity, "unknown_incoterm", err.Error())
+	default:
+		logging.FromContext(r.Context()).Error("incoterm request failed", slog.Any("error", err))
+		writeError(w, http.StatusInternalServerError, "internal_error", "incoterm request failed")
+	}
+}
@@ -0,0 +1,242 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"frego-operations/internal/decimal"
+	operationsdto "frego-operations/internal/dto/operations"
+	"frego-operations/internal/logging"
+	operationsservice "frego-operations/internal/service/operations"
+)
+
+// InvoiceHandler drafts customer invoices from job billing and exports them.
+type InvoiceHandler struct {
+	logger            *slog.Logger
+	operationsService *operationsservice.Service
+}
+
+// NewInvoiceHandler creates a new invoice handler
+func NewInvoiceHandler(logger *slog.Logger, operationsService *operationsservice.Service) *InvoiceHandler {
+	return &InvoiceHandler{
+		logger:            logger,
+		operationsService: operationsService,
+	}
+}
+
+// RegisterRoutes registers invoice routes
+func (h *InvoiceHandler) RegisterRoutes(r chi.Router) {
+	r.Post("/jobs/{jobID}/invoices/draft", h.DraftInvoices)
+	r.Get("/jobs/{jobID}/invoices", h.ListJobInvoices)
+	r.Get("/invoices/{invoiceID}", h.GetInvoice)
+	r.Get("/invoices/{invoiceID}/export", h.ExportInvoice)
+}
+
+// InvoiceLineResponse is a billing line as it appears on an invoice
+type InvoiceLineResponse struct {
+	BillingID        string           `json:"billingId"`
+	ActivityType     *string          `json:"activityType,omitempty"`
+	ActivityCode     *string          `json:"activityCode,omitempty"`
+	Description      *string          `json:"description,omitempty"`
+	Quantity         *decimal.Decimal `json:"quantity,omitempty"`
+	UnitPrice        *decimal.Decimal `json:"unitPrice,omitempty"`
+	TaxCode          *string          `json:"taxCode,omitempty"`
+	AmountWithoutTax decimal.Decimal  `json:"amountWithoutTax"`
+	TaxAmount        decimal.Decimal  `json:"taxAmount"`
+	TotalAmount      decimal.Decimal  `json:"totalAmount"`
+}
+
+// InvoiceTaxSummaryResponse totals an invoice's lines for one tax code
+type InvoiceTaxSummaryResponse struct {
+	TaxCode     string           `json:"taxCode"`
+	RatePercent *decimal.Decimal `json:"ratePercent,omitempty"`
+	Taxable     decimal.Decimal  `json:"taxable"`
+	Tax         decimal.Decimal  `json:"tax"`
+}
+
+// InvoiceResponse describes a customer invoice
+type InvoiceResponse struct {
+	ID               string                      `json:"id"`
+	InvoiceNumber    string                      `json:"invoiceNumber"`
+	JobID            string                      `json:"jobId"`
+	JobCode          string                      `json:"jobCode"`
+	BillingPartyID   *string                     `json:"billingPartyId,omitempty"`
+	BillingPartyName *string                     `json:"billingPartyName,omitempty"`
+	CurrencyCode     string                      `json:"currencyCode"`
+	Status           string                      `json:"status"`
+	Subtotal         decimal.Decimal             `json:"subtotal"`
+	TaxTotal         decimal.Decimal             `json:"taxTotal"`
+	Total            decimal.Decimal             `json:"total"`
+	Lines            []InvoiceLineResponse       `json:"lines"`
+	Taxes            []InvoiceTaxSummaryResponse `json:"taxes"`
+	CreatedAt        *time.Time                  `json:"createdAt,omitempty"`
+	CreatedBy        *string                     `json:"createdBy,omitempty"`
+}
+
+// DraftInvoicesResponse lists the drafted invoices and the billing lines left uninvoiced
+type DraftInvoicesResponse struct {
+	Invoices          []InvoiceResponse `json:"invoices"`
+	SkippedBillingIDs []string          `json:"skippedBillingIds,omitempty"`
+}
+
+// DraftInvoices drafts one invoice per billing party and currency from the job's uninvoiced billing lines.
+func (h *InvoiceHandler) DraftInvoices(w http.ResponseWriter, r *http.Request) {
+	jobID, ok := jobIDParam(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid_job_id", "job id must be a UUID")
+		return
+	}
+
+	drafts, err := h.operationsService.CreateDraftInvoices(r.Context(), jobID, actorFromRequest(r))
+	if err != nil {
+		h.writeInvoiceError(w, r, err)
+		return
+	}
+
+	resp := DraftInvoicesResponse{Invoices: make([]InvoiceResponse, 0, len(drafts.Invoices))}
+	for _, invoice := range drafts.Invoices {
+		resp.Invoices = append(resp.Invoices, invoiceResponse(invoice))
+	}
+	for _, id := range drafts.Skipped {
+		resp.SkippedBillingIDs = append(resp.SkippedBillingIDs, id.String())
+	}
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+// ListJobInvoices returns the job's invoices.
+func (h *InvoiceHandler) ListJobInvoices(w http.ResponseWriter, r *http.Request) {
+	jobID, ok := jobIDParam(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid_job_id", "job id must be a UUID")
+		return
+	}
+
+	invoices, err := h.operationsService.ListJobInvoices(r.Context(), jobID)
+	if err != nil {
+		h.writeInvoiceError(w, r, err)
+		return
+	}
+
+	resp := make([]InvoiceResponse, 0, len(invoices))
+	for _, invoice := range invoices {
+		resp = append(resp, invoiceResponse(invoice))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// GetInvoice returns one invoice.
+func (h *InvoiceHandler) GetInvoice(w http.ResponseWriter, r *http.Request) {
+	invoiceID, ok := invoiceIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	invoice, err := h.operationsService.GetInvoice(r.Context(), invoiceID)
+	if err != nil {
+		h.writeInvoiceError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, invoiceResponse(invoice))
+}
+
+// ExportInvoice returns the invoice as a JSON document (?format=json, the default) or a PDF (?format=pdf).
+func (h *InvoiceHandler) ExportInvoice(w http.ResponseWriter, r *http.Request) {
+	invoiceID, ok := invoiceIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	var (
+		file operationsdto.ExportFile
+		err  error
+	)
+	switch r.URL.Query().Get("format") {
+	case "", "json":
+		file, err = h.operationsService.ExportInvoiceJSON(r.Context(), invoiceID)
+	case "pdf":
+		file, err = h.operationsService.RenderInvoicePDF(r.Context(), invoiceID)
+	default:
+		writeError(w, http.StatusBadRequest, "invalid_format", "format must be json or pdf")
+		return
+	}
+	if err != nil {
+		h.writeInvoiceError(w, r, err)
+		return
+	}
+
+	writeFile(w, file.FileName, file.ContentType, file.Data)
+}
+
+func (h *InvoiceHandler) writeInvoiceError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		writeError(w, http.StatusNotFound, "not_found", "job or invoice not found")
+	case errors.Is(err, operationsservice.ErrNothingToInvoice):
+		writeError(w, http.StatusUnprocessableEntity, "nothing_to_invoice", err.Error())
+	case errors.Is(err, operationsservice.ErrAlreadyInvoiced):
+		writeError(w, http.StatusConflict, "already_invoiced", err.Error())
+	default:
+		logging.FromContext(r.Context()).Error("invoice request failed", slog.Any("error", err))
+		writeError(w, http.StatusInternalServerError, "internal_error", "invoice request failed")
+	}
+}
+
+// invoiceIDParam parses the {invoiceID} path parameter, writing a 400 and returning false when it is malformed.
+func invoiceIDParam(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "invoiceID"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_invoice_id", "invoice id must be a UUID")
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func invoiceResponse(invoice operationsdto.Invoice) InvoiceResponse {
+	resp := InvoiceResponse{
+		ID:               invoice.ID.String(),
+		InvoiceNumber:    invoice.InvoiceNumber,
+		JobID:            invoice.JobID.String(),
+		JobCode:          invoice.JobCode,
+		BillingPartyID:   uuidString(invoice.BillingPartyID),
+		BillingPartyName: invoice.BillingPartyName,
+		CurrencyCode:     invoice.CurrencyCode,
+		Status:           invoice.Status,
+		Subtotal:         invoice.Subtotal,
+		TaxTotal:         invoice.TaxTotal,
+		Total:            invoice.Total,
+		Lines:            make([]InvoiceLineResponse, 0, len(invoice.Lines)),
+		Taxes:            make([]InvoiceTaxSummaryResponse, 0, len(invoice.Taxes)),
+		CreatedAt:        invoice.CreatedAt,
+		CreatedBy:        invoice.CreatedBy,
+	}
+	for _, line := range invoice.Lines {
+		resp.Lines = append(resp.Lines, InvoiceLineResponse{
+			BillingID:        line.BillingID.String(),
+			ActivityType:     line.ActivityType,
+			ActivityCode:     line.ActivityCode,
+			Description:      line.Description,
+			Quantity:         line.Quantity,
+			UnitPrice:        line.UnitPrice,
+			TaxCode:          line.TaxCode,
+			AmountWithoutTax: line.AmountWithoutTax,
+			TaxAmount:        line.TaxAmount,
+			TotalAmount:      line.TotalAmount,
+		})
+	}
+	for _, tax := range invoice.Taxes {
+		resp.Taxes = append(resp.Taxes, InvoiceTaxSummaryResponse{
+			TaxCode:     tax.TaxCode,
+			RatePercent: tax.RatePercent,
+			Taxable:     tax.Taxable,
+			Tax:         tax.Tax,
+		})
+	}
+	return resp
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+
+	operationsdto "frego-operations/internal/dto/operations"
+	"frego-operations/internal/logging"
+	operationsservice "frego-operations/internal/service/operations"
+)
+
+// JobStatusHandler reopens closed jobs and serves their status history.
+type JobStatusHandler struct {
+	logger            *slog.Logger
+	operationsService *operationsservice.Service
+}
+
+// NewJobStatusHandler creates a new job status handler
+func NewJobStatusHandler(logger *slog.Logger, operationsService *operationsservice.Service) *JobStatusHandler {
+	return &JobStatusHandler{
+		logger:            logger,
+		operationsService: operationsService,
+	}
+}
+
+// RegisterRoutes registers job status routes
+func (h *JobStatusHandler) RegisterRoutes(r chi.Router) {
+	r.Post("/jobs/{jobID}/reopen", h.ReopenJob)
+	r.Get("/jobs/{jobID}/status-history", h.ListStatusHistory)
+}
+
+// ReopenJobRequest gives the reason a closed job is reopened
+type ReopenJobRequest struct {
+	Reason string `json:"reason"`
+}
+
+// JobStatusChangeResponse is one entry of a job's status history
+type JobStatusChangeResponse struct {
+	ID         string     `json:"id"`
+	JobID      string     `json:"jobId"`
+	FromStatus *string    `json:"fromStatus,omitempty"`
+	ToStatus   *string    `json:"toStatus,omitempty"`
+	Reason     *string    `json:"reason,omitempty"`
+	ChangedAt  *time.Time `json:"changedAt,omitempty"`
+	ChangedBy  *string    `json:"changedBy,omitempty"`
+}
+
+// ReopenJob moves a closed job back to Active.
+func (h *JobStatusHandler) ReopenJob(w http.ResponseWriter, r *http.Request) {
+	jobID, ok := jobIDParam(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid_job_id", "job id must be a UUID")
+		return
+	}
+	var req ReopenJobRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_body", "request body must be valid JSON")
+		return
+	}
+	if strings.TrimSpace(req.Reason) == "" {
+		writeError(w, http.StatusBadRequest, "invalid_body", "reason is required")
+		return
+	}
+
+	change, err := h.operationsService.ReopenJob(r.Context(), jobID, req.Reason, actorFromRequest(r))
+	if err != nil {
+		h.writeJobStatusError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, jobStatusChangeResponse(change))
+}
+
+// ListStatusHistory returns the job's status transitions, oldest first.
+func (h *JobStatusHandler) ListStatusHistory(w http.ResponseWriter, r *http.Request) {
+	jobID, ok := jobIDParam(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid_job_id", "job id must be a UUID")
+		return
+	}
+
+	changes, err := h.operationsService.ListJobStatusHistory(r.Context(), jobID)
+	if err != nil {
+		h.writeJobStatusError(w, r, err)
+		return
+	}
+	resp := make([]JobStatusChangeResponse, 0, len(changes))
+	for _, c := range changes {
+		resp = append(resp, jobStatusChangeResponse(c))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *JobStatusHandler) writeJobStatusError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		writeError(w, http.StatusNotFound, "not_found", "job not found")
+	case errors.Is(err, operationsservice.ErrReopenForbidden):
+		writeError(w, http.StatusForbidden, "forbidden", err.Error())
+	case errors.Is(err, operationsservice.ErrJobNotClosed):
+		writeError(w, http.StatusConflict, "job_not_closed", err.Error())
+	case errors.Is(err, operationsservice.ErrInvalidReopen):
+		writeError(w, http.StatusUnprocessableEntity, "invalid_reopen", err.Error())
+	default:
+		logging.FromContext(r.Context()).Error("job status request failed", slog.Any("error", err))
+		writeError(w, http.StatusInternalServerError, "internal_error", "job status request failed")
+	}
+}
+
+func jobStatusChangeResponse(c operationsdto.JobStatusChange) JobStatusChangeResponse {
+	return JobStatusChangeResponse{
+		ID:         c.ID.String(),
+		JobID:      c.JobID.String(),
+		FromStatus: c.FromStatus,
+		ToStatus:   c.ToStatus,
+		Reason:     c.Reason,
+		ChangedAt:  c.ChangedAt,
+		ChangedBy:  c.ChangedBy,
+	}
+}
@@ -0,0 +1,257 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	operationsdto "frego-operations/internal/dto/operations"
+	"frego-operations/internal/logging"
+	operationsservice "frego-operations/internal/service/operations"
+)
+
+const (
+	defaultProfitabilityJobs = 100
+	maxProfitabilityJobs     = 1000
+)
+
+// ProfitabilityHandler serves job profitability and margin reports.
+type ProfitabilityHandler struct {
+	logger            *slog.Logger
+	operationsService *operationsservice.Service
+}
+
+// NewProfitabilityHandler creates a new profitability handler
+func NewProfitabilityHandler(logger *slog.Logger, operationsService *operationsservice.Service) *ProfitabilityHandler {
+	return &ProfitabilityHandler{
+		logger:            logger,
+		operationsService: operationsService,
+	}
+}
+
+// RegisterRoutes registers profitability routes
+func (h *ProfitabilityHandler) RegisterRoutes(r chi.Router) {
+	r.Get("/reports/profitability", h.Report)
+	r.Get("/jobs/{jobID}/profitability", h.JobProfitability)
+}
+
+// ProfitabilityFiguresResponse carries amounts in the primary currency as decimal strings
+type ProfitabilityFiguresResponse struct {
+	Revenue       string  `json:"revenue"`
+	Cost          string  `json:"cost"`
+	GrossProfit   string  `json:"grossProfit"`
+	MarginPercent *string `json:"marginPercent"`
+	Loss          bool    `json:"loss"`
+}
+
+// ActivityProfitabilityResponse is the profitability of one activity
+type ActivityProfitabilityResponse struct {
+	ActivityType *string `json:"activityType"`
+	ActivityCode *string `json:"activityCode"`
+	JobCount     int32   `json:"jobCount"`
+	ProfitabilityFiguresResponse
+}
+
+// JobProfitabilityResponse is the profitability of one job
+type JobProfitabilityResponse struct {
+	JobID              string     `json:"jobId"`
+	JobCode            string     `json:"jobCode"`
+	JobType            *string    `json:"jobType,omitempty"`
+	TransportMode      *string    `json:"transportMode,omitempty"`
+	Status             *string    `json:"status,omitempty"`
+	CustomerID         *string    `json:"customerId,omitempty"`
+	CustomerName       *string    `json:"customerName,omitempty"`
+	BranchID           *string    `json:"branchId,omitempty"`
+	BranchName         *string    `json:"branchName,omitempty"`
+	SalesExecutiveID   *string    `json:"salesExecutiveId,omitempty"`
+	SalesExecutiveName *string    `json:"salesExecutiveName,omitempty"`
+	CreatedAt          *time.Time `json:"createdAt,omitempty"`
+	Currency           string     `json:"currency,omitempty"`
+	ProfitabilityFiguresResponse
+	Activities []ActivityProfitabilityResponse `json:"activities,omitempty"`
+}
+
+// ProfitabilityReportResponse summarises profitability across jobs
+type ProfitabilityReportResponse struct {
+	Currency   string                          `json:"currency"`
+	Totals     ProfitabilityFiguresResponse    `json:"totals"`
+	Jobs       []JobProfitabilityResponse      `json:"jobs"`
+	Activities []ActivityProfitabilityResponse `json:"activities"`
+}
+
+// Report returns profitability across jobs, filtered by ?branchId=, ?salesExecutiveId=,
+// ?customerId=, ?transportMode= and the job creation period ?from= / ?to= (inclusive dates).
+func (h *ProfitabilityHandler) Report(w http.ResponseWriter, r *http.Request) {
+	filter := operationsdto.ProfitabilityFilter{
+		TransportMode: optionalQuery(r, "transportMode"),
+		Limit:         defaultProfitabilityJobs,
+	}
+
+	var ok bool
+	if filter.BranchID, ok = optionalUUIDQuery(w, r, "branchId"); !ok {
+		return
+	}
+	if filter.SalesExecutiveID, ok = optionalUUIDQuery(w, r, "salesExecutiveId"); !ok {
+		return
+	}
+	if filter.CustomerID, ok = optionalUUIDQuery(w, r, "customerId"); !ok {
+		return
+	}
+	if filter.PeriodFrom, ok = optionalDateQuery(w, r, "from"); !ok {
+		return
+	}
+	if filter.PeriodTo, ok = optionalDateQuery(w, r, "to"); !ok {
+		return
+	}
+	if filter.PeriodTo != nil {
+		// The period is half-open; include the whole of the "to" day.
+		end := filter.PeriodTo.AddDate(0, 0, 1)
+		filter.PeriodTo = &end
+	}
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxProfitabilityJobs {
+			writeError(w, http.StatusBadRequest, "invalid_limit", "limit must be between 1 and 1000")
+			return
+		}
+		filter.Limit = int32(n)
+	}
+
+	report, err := h.operationsService.ProfitabilityReport(r.Context(), filter)
+	if err != nil {
+		h.writeProfitabilityError(w, r, err)
+		return
+	}
+
+	resp := ProfitabilityReportResponse{
+		Currency:   report.Currency,
+		Totals:     profitabilityFiguresResponse(report.Totals),
+		Jobs:       make([]JobProfitabilityResponse, 0, len(report.Jobs)),
+		Activities: activityProfitabilityResponses(report.Activities),
+	}
+	for _, job := range report.Jobs {
+		resp.Jobs = append(resp.Jobs, jobProfitabilityResponse(job))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// JobProfitability returns one job's profitability broken down by activity.
+func (h *ProfitabilityHandler) JobProfitability(w http.ResponseWriter, r *http.Request) {
+	jobID, ok := jobIDParam(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid_job_id", "job id must be a UUID")
+		return
+	}
+
+	job, err := h.operationsService.GetJobProfitability(r.Context(), jobID)
+	if err != nil {
+		h.writeProfitabilityError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, jobProfitabilityResponse(job))
+}
+
+func (h *ProfitabilityHandler) writeProfitabilityError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		writeError(w, http.StatusNotFound, "not_found", "job not found")
+	case errors.Is(err, operationsservice.ErrInvalidCharge):
+		writeError(w, http.StatusUnprocessableEntity, "invalid_configuration", err.Error())
+	default:
+		logging.FromContext(r.Context()).Error("profitability request failed", slog.Any("error", err))
+		writeError(w, http.StatusInternalServerError, "internal_error", "profitability request failed")
+	}
+}
+
+func jobProfitabilityResponse(job operationsdto.JobProfitability) JobProfitabilityResponse {
+	resp := JobProfitabilityResponse{
+		JobID:                        job.JobID.String(),
+		JobCode:                      job.JobCode,
+		JobType:                      job.JobType,
+		TransportMode:                job.TransportMode,
+		Status:                       job.Status,
+		CustomerID:                   uuidString(job.CustomerID),
+		CustomerName:                 job.CustomerName,
+		BranchID:                     uuidString(job.BranchID),
+		BranchName:                   job.BranchName,
+		SalesExecutiveID:             uuidString(job.SalesExecutiveID),
+		SalesExecutiveName:           job.SalesExecutiveName,
+		CreatedAt:                    job.CreatedAt,
+		Currency:                     job.Currency,
+		ProfitabilityFiguresResponse: profitabilityFiguresResponse(job.ProfitabilityFigures),
+	}
+	if job.Activities != nil {
+		resp.Activities = activityProfitabilityResponses(job.Activities)
+	}
+	return resp
+}
+
+func activityProfitabilityResponses(activities []operationsdto.ActivityProfitability) []ActivityProfitabilityResponse {
+	resp := make([]ActivityProfitabilityResponse, 0, len(activities))
+	for _, activity := range activities {
+		resp = append(resp, ActivityProfitabilityResponse{
+			ActivityType:                 activity.ActivityType,
+			ActivityCode:                 activity.ActivityCode,
+			JobCount:                     activity.JobCount,
+			ProfitabilityFiguresResponse: profitabilityFiguresResponse(activity.ProfitabilityFigures),
+		})
+	}
+	return resp
+}
+
+func profitabilityFiguresResponse(f operationsdto.ProfitabilityFigures) ProfitabilityFiguresResponse {
+	resp := ProfitabilityFiguresResponse{
+		Revenue:     f.Revenue.String(),
+		Cost:        f.Cost.String(),
+		GrossProfit: f.GrossProfit.String(),
+		Loss:        f.Loss,
+	}
+	if f.MarginPercent != nil {
+		margin := f.MarginPercent.String()
+		resp.MarginPercent = &margin
+	}
+	return resp
+}
+
+// optionalUUIDQuery parses a UUID query parameter, writing a 400 and returning false when it is malformed.
+func optionalUUIDQuery(w http.ResponseWriter, r *http.Request, name string) (*uuid.UUID, bool) {
+	raw := optionalQuery(r, name)
+	if raw == nil {
+		return nil, true
+	}
+	id, err := uuid.Parse(*raw)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_"+name, name+" must be a UUID")
+		return nil, false
+	}
+	return &id, true
+}
+
+// optionalDateQuery parses a YYYY-MM-DD query parameter, writing a 400 and returning false when it is malformed.
+func optionalDateQuery(w http.ResponseWriter, r *http.Request, name string) (*time.Time, bool) {
+	raw := optionalQuery(r, name)
+	if raw == nil {
+		return nil, true
+	}
+	date, err := time.Parse(time.DateOnly, *raw)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_"+name, name+" must be YYYY-MM-DD")
+		return nil, false
+	}
+	return &date, true
+}
+
+func uuidString(id *uuid.UUID) *string {
+	if id == nil {
+		return nil
+	}
+	s := id.String()
+	return &s
+}
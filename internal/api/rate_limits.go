@@ -0,0 +1,40 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"frego-operations/internal/ratelimit"
+)
+
+// exportRoutes are the routes that render documents, files or reports. They are limited as
+// ratelimit.Export; other reads are ratelimit.Read and everything else ratelimit.Write.
+var exportRoutes = map[string]bool{
+	"GET /jobs/{jobID}/exports/iftmin":                true,
+	"GET /jobs/{jobID}/exports/fwb":                   true,
+	"GET /jobs/{jobID}/exports/fhl":                   true,
+	"POST /jobs/{jobID}/documents/{docType}/generate": true,
+	"GET /invoices/{invoiceID}/export":                true,
+	"GET /reports/profitability":                      true,
+}
+
+// RouteClasses returns the rate limit classifier for router's routes.
+func RouteClasses(router chi.Routes) ratelimit.Classifier {
+	return func(r *http.Request) ratelimit.Class {
+		path := r.URL.Path
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePath != "" {
+			path = rctx.RoutePath
+		}
+		match := chi.NewRouteContext()
+		if router.Match(match, r.Method, path) && exportRoutes[r.Method+" "+match.RoutePattern()] {
+			return ratelimit.Export
+		}
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			return ratelimit.Read
+		default:
+			return ratelimit.Write
+		}
+	}
+}
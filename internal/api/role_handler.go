@@ -0,0 +1,109 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+
+	"frego-operations/internal/authz"
+	operationsdto "frego-operations/internal/dto/operations"
+	"frego-operations/internal/logging"
+	operationsservice "frego-operations/internal/service/operations"
+)
+
+// RoleHandler manages the operations permissions tenants grant to their roles.
+type RoleHandler struct {
+	logger            *slog.Logger
+	operationsService *operationsservice.Service
+	authorizer        *authz.Authorizer
+}
+
+// NewRoleHandler creates a new role handler
+func NewRoleHandler(logger *slog.Logger, operationsService *operationsservice.Service, authorizer *authz.Authorizer) *RoleHandler {
+	return &RoleHandler{
+		logger:            logger,
+		operationsService: operationsService,
+		authorizer:        authorizer,
+	}
+}
+
+// RegisterRoutes registers role permission routes
+func (h *RoleHandler) RegisterRoutes(r chi.Router) {
+	r.Get("/roles/permissions", h.ListRolePermissions)
+	r.Put("/roles/{roleID}/permissions", h.SetRolePermissions)
+}
+
+// RolePermissionsRequest replaces the permissions granted to a role
+type RolePermissionsRequest struct {
+	Permissions []string `json:"permissions"`
+}
+
+// RolePermissionsResponse is a role with the operations permissions granted to it
+type RolePermissionsResponse struct {
+	RoleID      int16    `json:"roleId"`
+	RoleName    string   `json:"roleName"`
+	RoleDesc    *string  `json:"roleDesc,omitempty"`
+	Permissions []string `json:"permissions"`
+}
+
+// ListRolePermissions returns every role with its granted permissions.
+func (h *RoleHandler) ListRolePermissions(w http.ResponseWriter, r *http.Request) {
+	roles, err := h.operationsService.ListRolePermissions(r.Context())
+	if err != nil {
+		h.writeRoleError(w, r, err)
+		return
+	}
+	resp := make([]RolePermissionsResponse, 0, len(roles))
+	for _, role := range roles {
+		resp = append(resp, rolePermissionsResponse(role))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// SetRolePermissions replaces the permissions granted to a role.
+func (h *RoleHandler) SetRolePermissions(w http.ResponseWriter, r *http.Request) {
+	roleID, err := strconv.ParseInt(chi.URLParam(r, "roleID"), 10, 16)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_role_id", "role id must be a number")
+		return
+	}
+	var req RolePermissionsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_body", "request body must be valid JSON")
+		return
+	}
+
+	role, err := h.operationsService.SetRolePermissions(r.Context(), int16(roleID), req.Permissions, actorFromRequest(r))
+	if err != nil {
+		h.writeRoleError(w, r, err)
+		return
+	}
+	h.authorizer.Invalidate(r.Context())
+	writeJSON(w, http.StatusOK, rolePermissionsResponse(role))
+}
+
+func (h *RoleHandler) writeRoleError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		writeError(w, http.StatusNotFound, "not_found", "role not found")
+	case errors.Is(err, operationsservice.ErrInvalidRolePermissions):
+		writeError(w, http.StatusUnprocessableEntity, "invalid_permissions", err.Error())
+	default:
+		logging.FromContext(r.Context()).Error("role request failed", slog.Any("error", err))
+		writeError(w, http.StatusInternalServerError, "internal_error", "role request failed")
+	}
+}
+
+func rolePermissionsResponse(role operationsdto.RolePermissions) RolePermissionsResponse {
+	return RolePermissionsResponse{
+		RoleID:      role.RoleID,
+		RoleName:    role.RoleName,
+		RoleDesc:    role.RoleDesc,
+		Permissions: role.Permissions,
+	}
+}
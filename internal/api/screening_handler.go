@@ -0,0 +1,219 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"frego-operations/internal/decimal"
+	operationsdto "frego-operations/internal/dto/operations"
+	"frego-operations/internal/logging"
+	operationsservice "frego-operations/internal/service/operations"
+)
+
+// ScreeningHandler screens job parties against the denied-party lists and records reviews of
+// potential matches.
+type ScreeningHandler struct {
+	logger            *slog.Logger
+	operationsService *operationsservice.Service
+}
+
+// NewScreeningHandler creates a new screening handler
+func NewScreeningHandler(logger *slog.Logger, operationsService *operationsservice.Service) *ScreeningHandler {
+	return &ScreeningHandler{
+		logger:            logger,
+		operationsService: operationsService,
+	}
+}
+
+// RegisterRoutes registers screening routes
+func (h *ScreeningHandler) RegisterRoutes(r chi.Router) {
+	r.Post("/jobs/{jobID}/screening", h.ScreenJob)
+	r.Get("/jobs/{jobID}/screening", h.ListJobScreenings)
+	r.Post("/screenings/{screeningID}/clear", h.Clear)
+	r.Post("/screenings/{screeningID}/confirm", h.Confirm)
+	r.Get("/screening/lists", h.ListLists)
+	r.Post("/screening/lists/reload", h.ReloadLists)
+}
+
+// ScreeningResolutionRequest carries the reviewer's note on a potential match
+type ScreeningResolutionRequest struct {
+	Note string `json:"note"`
+}
+
+// PartyScreeningResponse is a job party's screening result against one list
+type PartyScreeningResponse struct {
+	ID             string           `json:"id"`
+	JobID          string           `json:"jobId"`
+	PartyID        string           `json:"partyId"`
+	PartyRole      string           `json:"partyRole"`
+	PartyName      string           `json:"partyName"`
+	ListName       string           `json:"listName"`
+	ListVersion    string           `json:"listVersion"`
+	EntryID        *string          `json:"entryId,omitempty"`
+	EntryName      *string          `json:"entryName,omitempty"`
+	MatchedName    *string          `json:"matchedName,omitempty"`
+	Program        *string          `json:"program,omitempty"`
+	Score          *decimal.Decimal `json:"score,omitempty"`
+	Status         string           `json:"status"`
+	ScreenedAt     *time.Time       `json:"screenedAt,omitempty"`
+	ScreenedBy     *string          `json:"screenedBy,omitempty"`
+	ResolvedAt     *time.Time       `json:"resolvedAt,omitempty"`
+	ResolvedBy     *string          `json:"resolvedBy,omitempty"`
+	ResolutionNote *string          `json:"resolutionNote,omitempty"`
+}
+
+// ScreeningListResponse describes a loaded denied-party list
+type ScreeningListResponse struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Entries int    `json:"entries"`
+}
+
+// ScreenJob screens the job's parties against the current lists.
+func (h *ScreeningHandler) ScreenJob(w http.ResponseWriter, r *http.Request) {
+	jobID, ok := jobIDParam(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid_job_id", "job id must be a UUID")
+		return
+	}
+	results, err := h.operationsService.ScreenJob(r.Context(), jobID, actorFromRequest(r))
+	if err != nil {
+		h.writeScreeningError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, partyScreeningResponses(results))
+}
+
+// ListJobScreenings returns the job's current screening results.
+func (h *ScreeningHandler) ListJobScreenings(w http.ResponseWriter, r *http.Request) {
+	jobID, ok := jobIDParam(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid_job_id", "job id must be a UUID")
+		return
+	}
+	results, err := h.operationsService.ListJobScreenings(r.Context(), jobID)
+	if err != nil {
+		h.writeScreeningError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, partyScreeningResponses(results))
+}
+
+// Clear resolves a potential match as a false positive.
+func (h *ScreeningHandler) Clear(w http.ResponseWriter, r *http.Request) {
+	h.resolve(w, r, false)
+}
+
+// Confirm resolves a potential match as the listed party.
+func (h *ScreeningHandler) Confirm(w http.ResponseWriter, r *http.Request) {
+	h.resolve(w, r, true)
+}
+
+func (h *ScreeningHandler) resolve(w http.ResponseWriter, r *http.Request, confirm bool) {
+	screeningID, err := uuid.Parse(chi.URLParam(r, "screeningID"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_screening_id", "screening id must be a UUID")
+		return
+	}
+	var req ScreeningResolutionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_body", "request body must be valid JSON")
+		return
+	}
+	if strings.TrimSpace(req.Note) == "" {
+		writeError(w, http.StatusBadRequest, "invalid_body", "note is required")
+		return
+	}
+
+	result, err := h.operationsService.ResolveScreening(r.Context(), screeningID, confirm, req.Note, actorFromRequest(r))
+	if err != nil {
+		h.writeScreeningError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, partyScreeningResponse(result))
+}
+
+// ListLists returns the loaded denied-party lists and their versions.
+func (h *ScreeningHandler) ListLists(w http.ResponseWriter, r *http.Request) {
+	lists, err := h.operationsService.ScreeningLists()
+	if err != nil {
+		h.writeScreeningError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, screeningListResponses(lists))
+}
+
+// ReloadLists re-reads the denied-party lists from disk.
+func (h *ScreeningHandler) ReloadLists(w http.ResponseWriter, r *http.Request) {
+	lists, err := h.operationsService.ReloadScreeningLists(r.Context())
+	if err != nil {
+		h.writeScreeningError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, screeningListResponses(lists))
+}
+
+func (h *ScreeningHandler) writeScreeningError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		writeError(w, http.StatusNotFound, "not_found", "job or screening result not found")
+	case errors.Is(err, operationsservice.ErrScreeningUnavailable):
+		writeError(w, http.StatusServiceUnavailable, "screening_unavailable", err.Error())
+	case errors.Is(err, operationsservice.ErrScreeningForbidden):
+		writeError(w, http.StatusForbidden, "forbidden", err.Error())
+	case errors.Is(err, operationsservice.ErrScreeningResolved):
+		writeError(w, http.StatusConflict, "already_resolved", err.Error())
+	case errors.Is(err, operationsservice.ErrInvalidScreening):
+		writeError(w, http.StatusUnprocessableEntity, "invalid_screening", err.Error())
+	default:
+		logging.FromContext(r.Context()).Error("screening request failed", slog.Any("error", err))
+		writeError(w, http.StatusInternalServerError, "internal_error", "screening request failed")
+	}
+}
+
+func partyScreeningResponses(results []operationsdto.PartyScreening) []PartyScreeningResponse {
+	resp := make([]PartyScreeningResponse, 0, len(results))
+	for _, s := range results {
+		resp = append(resp, partyScreeningResponse(s))
+	}
+	return resp
+}
+
+func partyScreeningResponse(s operationsdto.PartyScreening) PartyScreeningResponse {
+	return PartyScreeningResponse{
+		ID:             s.ID.String(),
+		JobID:          s.JobID.String(),
+		PartyID:        s.PartyID.String(),
+		PartyRole:      s.PartyRole,
+		PartyName:      s.PartyName,
+		ListName:       s.ListName,
+		ListVersion:    s.ListVersion,
+		EntryID:        s.EntryID,
+		EntryName:      s.EntryName,
+		MatchedName:    s.MatchedName,
+		Program:        s.Program,
+		Score:          s.Score,
+		Status:         s.Status,
+		ScreenedAt:     s.ScreenedAt,
+		ScreenedBy:     s.ScreenedBy,
+		ResolvedAt:     s.ResolvedAt,
+		ResolvedBy:     s.ResolvedBy,
+		ResolutionNote: s.ResolutionNote,
+	}
+}
+
+func screeningListResponses(lists []operationsdto.ScreeningList) []ScreeningListResponse {
+	resp := make([]ScreeningListResponse, 0, len(lists))
+	for _, l := range lists {
+		resp = append(resp, ScreeningListResponse{Name: l.Name, Version: l.Version, Entries: l.Entries})
+	}
+	return resp
+}
@@ -13,17 +13,15 @@ import (
 
 // TenantHandler handles tenant provisioning API requests
 type TenantHandler struct {
-	logger         *slog.Logger
-	tenantService  *tenant.Service
-	internalSecret string
+	logger        *slog.Logger
+	tenantService *tenant.Service
 }
 
 // NewTenantHandler creates a new tenant handler
-func NewTenantHandler(logger *slog.Logger, tenantService *tenant.Service, internalSecret string) *TenantHandler {
+func NewTenantHandler(logger *slog.Logger, tenantService *tenant.Service) *TenantHandler {
 	return &TenantHandler{
-		logger:         logger,
-		tenantService:  tenantService,
-		internalSecret: internalSecret,
+		logger:        logger,
+		tenantService: tenantService,
 	}
 }
 
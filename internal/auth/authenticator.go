@@ -11,13 +11,20 @@ import (
 	"frego-operations/internal/common"
 )
 
-// Config drives how the authenticator validates Keycloak-issued tokens.
+// Config drives how the authenticator validates Keycloak-issued tokens. When JWKSFile or
+// PublicKeyFiles is set, tokens are verified offline against those keys instead of keys
+// discovered from the issuer, so no Keycloak needs to be reachable.
 type Config struct {
-	Issuer      string
-	Audiences   []string
-	TenantClaim string
+	Issuer         string
+	Audiences      []string
+	TenantClaim    string
+	JWKSFile       string
+	PublicKeyFiles []string
 }
 
+// offlineSigningAlgs are the algorithms accepted from offline keys.
+var offlineSigningAlgs = []string{oidc.RS256, oidc.RS384, oidc.RS512, oidc.ES256, oidc.ES384, oidc.ES512}
+
 // ErrAudienceMismatch indicates the token was not intended for this API.
 var ErrAudienceMismatch = errors.New("auth: token audience mismatch")
 
@@ -31,7 +38,8 @@ type Authenticator struct {
 	tenantClaim string
 }
 
-// NewAuthenticator builds an Authenticator backed by a cached OIDC provider.
+// NewAuthenticator builds an Authenticator backed by a cached OIDC provider, or by the
+// configured offline keys.
 func NewAuthenticator(ctx context.Context, cfg Config) (*Authenticator, error) {
 	if strings.TrimSpace(cfg.Issuer) == "" {
 		return nil, errors.New("auth: keycloak issuer must be configured")
@@ -40,15 +48,30 @@ func NewAuthenticator(ctx context.Context, cfg Config) (*Authenticator, error) {
 		cfg.TenantClaim = "tenantId"
 	}
 
-	provider, err := oidc.NewProvider(ctx, cfg.Issuer)
-	if err != nil {
-		return nil, fmt.Errorf("auth: init oidc provider: %w", err)
-	}
-
-	oidcCfg := &oidc.Config{
-		SkipClientIDCheck: true,
+	var verifier *oidc.IDTokenVerifier
+	if cfg.Offline() {
+		// Locally minted tokens must be pinned to an audience, or any token signed with the
+		// configured keys would be accepted.
+		if len(normalizeAudiences(cfg.Audiences)) == 0 {
+			return nil, errors.New("auth: KEYCLOAK_AUDIENCE must be configured with offline verification keys")
+		}
+		keys, err := LoadPublicKeys(cfg.JWKSFile, cfg.PublicKeyFiles)
+		if err != nil {
+			return nil, err
+		}
+		verifier = oidc.NewVerifier(cfg.Issuer, &oidc.StaticKeySet{PublicKeys: keys}, &oidc.Config{
+			SkipClientIDCheck:    true,
+			SupportedSigningAlgs: offlineSigningAlgs,
+		})
+	} else {
+		provider, err := oidc.NewProvider(ctx, cfg.Issuer)
+		if err != nil {
+			return nil, fmt.Errorf("auth: init oidc provider: %w", err)
+		}
+		verifier = provider.Verifier(&oidc.Config{
+			SkipClientIDCheck: true,
+		})
 	}
-	verifier := provider.Verifier(oidcCfg)
 
 	return &Authenticator{
 		verifier:    verifier,
@@ -57,6 +80,11 @@ func NewAuthenticator(ctx context.Context, cfg Config) (*Authenticator, error) {
 	}, nil
 }
 
+// Offline reports whether tokens are verified against local key files.
+func (c Config) Offline() bool {
+	return strings.TrimSpace(c.JWKSFile) != "" || len(c.PublicKeyFiles) > 0
+}
+
 // Authenticate verifies the provided raw token, returning the associated principal.
 func (a *Authenticator) Authenticate(ctx context.Context, rawToken string) (common.Principal, error) {
 	idToken, err := a.verifier.Verify(ctx, rawToken)
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"context"
 	"errors"
 	"log/slog"
 	"net/http"
@@ -11,8 +12,20 @@ import (
 
 var errMissingToken = errors.New("auth: bearer token missing")
 
-// Middleware enforces Keycloak authentication across the HTTP surface.
-func Middleware(logger *slog.Logger, authenticator *Authenticator) func(http.Handler) http.Handler {
+// ErrAPIKeyInvalid indicates an API key that is unknown, revoked or expired.
+var ErrAPIKeyInvalid = errors.New("auth: invalid api key")
+
+// apiKeyHeader carries an API key; "Authorization: ApiKey <key>" is also accepted.
+const apiKeyHeader = "X-API-Key"
+
+// APIKeyAuthenticator resolves API keys presented by machine clients instead of a bearer token.
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(ctx context.Context, key string) (common.Principal, error)
+}
+
+// Middleware enforces Keycloak authentication across the HTTP surface. When apiKeys is set,
+// requests may present an API key instead of a bearer token.
+func Middleware(logger *slog.Logger, authenticator *Authenticator, apiKeys APIKeyAuthenticator) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			normalizedPath := strings.TrimSuffix(r.URL.Path, "/")
@@ -22,6 +35,23 @@ func Middleware(logger *slog.Logger, authenticator *Authenticator) func(http.Han
 				return
 			}
 
+			if key, ok := apiKeyFromRequest(r); ok && apiKeys != nil {
+				principal, err := apiKeys.AuthenticateAPIKey(r.Context(), key)
+				if err != nil {
+					if errors.Is(err, ErrAPIKeyInvalid) {
+						logger.Warn("auth: api key rejected", slog.Any("error", err))
+						http.Error(w, "invalid api key", http.StatusUnauthorized)
+					} else {
+						logger.Error("auth: api key lookup failed", slog.Any("error", err))
+						http.Error(w, "authentication unavailable", http.StatusServiceUnavailable)
+					}
+					return
+				}
+				ctx := common.WithPrincipal(r.Context(), principal)
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
 			rawToken, err := bearerToken(r.Header.Get("Authorization"))
 			if err != nil {
 				http.Error(w, "missing bearer token", http.StatusUnauthorized)
@@ -48,6 +78,17 @@ func Middleware(logger *slog.Logger, authenticator *Authenticator) func(http.Han
 	}
 }
 
+func apiKeyFromRequest(r *http.Request) (string, bool) {
+	if key := strings.TrimSpace(r.Header.Get(apiKeyHeader)); key != "" {
+		return key, true
+	}
+	scheme, key, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if found && strings.EqualFold(scheme, "ApiKey") && strings.TrimSpace(key) != "" {
+		return strings.TrimSpace(key), true
+	}
+	return "", false
+}
+
 func bearerToken(header string) (string, error) {
 	if header == "" {
 		return "", errMissingToken
@@ -0,0 +1,314 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"frego-operations/internal/common"
+)
+
+// Headers carrying a signed service-to-service request.
+const (
+	HeaderServiceKeyID     = "X-Frego-Key-Id"
+	HeaderServiceTimestamp = "X-Frego-Timestamp"
+	HeaderServiceNonce     = "X-Frego-Nonce"
+	HeaderServiceSignature = "X-Frego-Signature"
+)
+
+// maxServiceBody caps the request body read to verify a signature.
+const maxServiceBody = 10 << 20
+
+var (
+	// ErrServiceUnauthenticated indicates the request carried no service credentials.
+	ErrServiceUnauthenticated = errors.New("auth: service credentials missing")
+	// ErrServiceSignature indicates the request signature or its key is not valid.
+	ErrServiceSignature = errors.New("auth: invalid service signature")
+	// ErrServiceReplay indicates the request is outside the allowed clock skew or its nonce was
+	// already used.
+	ErrServiceReplay = errors.New("auth: service request replayed or expired")
+)
+
+// ServiceKey is a shared HMAC key held by one calling service. A service may have several active
+// keys during a rotation; each is identified by its ID.
+type ServiceKey struct {
+	ID      string
+	Service string
+	Secret  []byte
+}
+
+// ServiceConfig drives authentication of internal service-to-service calls. Requests are accepted
+// when HMAC-signed with one of Keys, or when they carry a client-credentials token issued for
+// Audience (and, when Clients is set, to one of those clients).
+type ServiceConfig struct {
+	Keys     []ServiceKey
+	MaxSkew  time.Duration
+	Audience string
+	Clients  []string
+}
+
+// ParseServiceKeys parses "service:keyID:secret" entries. Secrets are used as given.
+func ParseServiceKeys(entries []string) ([]ServiceKey, error) {
+	var keys []ServiceKey
+	seen := make(map[string]struct{}, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) != 3 || parts[0] == "" || parts[1] == "" || len(parts[2]) < 32 {
+			return nil, fmt.Errorf("auth: service key entries must be service:keyID:secret with a secret of at least 32 characters")
+		}
+		if _, dup := seen[parts[1]]; dup {
+			return nil, fmt.Errorf("auth: duplicate service key ID %q", parts[1])
+		}
+		seen[parts[1]] = struct{}{}
+		keys = append(keys, ServiceKey{ID: parts[1], Service: parts[0], Secret: []byte(parts[2])})
+	}
+	return keys, nil
+}
+
+// ServiceAuthenticator verifies internal requests from other Frego services.
+type ServiceAuthenticator struct {
+	keys     map[string]ServiceKey
+	maxSkew  time.Duration
+	audience string
+	clients  map[string]struct{}
+	tokens   *Authenticator
+	nonces   *nonceCache
+}
+
+// NewServiceAuthenticator builds a ServiceAuthenticator. tokens verifies client-credentials
+// tokens and may be nil when Audience is empty.
+func NewServiceAuthenticator(cfg ServiceConfig, tokens *Authenticator) (*ServiceAuthenticator, error) {
+	if cfg.Audience != "" && tokens == nil {
+		return nil, errors.New("auth: service token audience needs a token authenticator")
+	}
+	if len(cfg.Keys) == 0 && cfg.Audience == "" {
+		return nil, errors.New("auth: internal routes need service keys or a service token audience")
+	}
+	if cfg.MaxSkew <= 0 {
+		cfg.MaxSkew = 5 * time.Minute
+	}
+
+	keys := make(map[string]ServiceKey, len(cfg.Keys))
+	for _, k := range cfg.Keys {
+		keys[k.ID] = k
+	}
+	var clients map[string]struct{}
+	if len(cfg.Clients) > 0 {
+		clients = make(map[string]struct{}, len(cfg.Clients))
+		for _, c := range normalizeAudiences(cfg.Clients) {
+			clients[c] = struct{}{}
+		}
+	}
+	return &ServiceAuthenticator{
+		keys:     keys,
+		maxSkew:  cfg.MaxSkew,
+		audience: cfg.Audience,
+		clients:  clients,
+		tokens:   tokens,
+		nonces:   newNonceCache(),
+	}, nil
+}
+
+// Authenticate verifies the request's signature or service token and returns the calling
+// service as a principal. The body is read and restored so handlers can still consume it.
+func (s *ServiceAuthenticator) Authenticate(r *http.Request) (common.Principal, error) {
+	if r.Header.Get(HeaderServiceSignature) != "" {
+		return s.verifySignature(r)
+	}
+	if s.audience != "" {
+		if raw, err := bearerToken(r.Header.Get("Authorization")); err == nil {
+			return s.verifyToken(r.Context(), raw)
+		}
+	}
+	return common.Principal{}, ErrServiceUnauthenticated
+}
+
+func (s *ServiceAuthenticator) verifySignature(r *http.Request) (common.Principal, error) {
+	keyID := r.Header.Get(HeaderServiceKeyID)
+	key, ok := s.keys[keyID]
+	if !ok {
+		return common.Principal{}, fmt.Errorf("%w: unknown key %q", ErrServiceSignature, keyID)
+	}
+	signature, err := base64.StdEncoding.DecodeString(r.Header.Get(HeaderServiceSignature))
+	if err != nil {
+		return common.Principal{}, fmt.Errorf("%w: signature is not base64", ErrServiceSignature)
+	}
+
+	timestamp := r.Header.Get(HeaderServiceTimestamp)
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return common.Principal{}, fmt.Errorf("%w: timestamp is not unix seconds", ErrServiceReplay)
+	}
+	if skew := time.Since(time.Unix(unix, 0)); skew > s.maxSkew || skew < -s.maxSkew {
+		return common.Principal{}, fmt.Errorf("%w: timestamp outside the allowed %s skew", ErrServiceReplay, s.maxSkew)
+	}
+	nonce := r.Header.Get(HeaderServiceNonce)
+	if nonce == "" {
+		return common.Principal{}, fmt.Errorf("%w: nonce missing", ErrServiceReplay)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxServiceBody+1))
+	if err != nil {
+		return common.Principal{}, fmt.Errorf("auth: read service request body: %w", err)
+	}
+	if len(body) > maxServiceBody {
+		return common.Principal{}, fmt.Errorf("%w: body too large to verify", ErrServiceSignature)
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	expected := serviceSignature(key.Secret, r.Method, r.URL.EscapedPath(), r.URL.RawQuery, timestamp, nonce, body)
+	if !hmac.Equal(signature, expected) {
+		return common.Principal{}, ErrServiceSignature
+	}
+	// Only a correctly signed nonce is remembered, so forged requests cannot burn real ones.
+	if !s.nonces.add(keyID+"/"+nonce, 2*s.maxSkew) {
+		return common.Principal{}, fmt.Errorf("%w: nonce already used", ErrServiceReplay)
+	}
+
+	return common.Principal{
+		Username: key.Service,
+		Subject:  key.Service,
+		Claims:   map[string]any{"key_id": key.ID, "auth": "hmac"},
+	}, nil
+}
+
+func (s *ServiceAuthenticator) verifyToken(ctx context.Context, raw string) (common.Principal, error) {
+	token, err := s.tokens.verifier.Verify(ctx, raw)
+	if err != nil {
+		return common.Principal{}, fmt.Errorf("auth: verify service token: %w", err)
+	}
+	if !audAllowed(token.Audience, []string{s.audience}) {
+		return common.Principal{}, fmt.Errorf("%w: %v", ErrAudienceMismatch, token.Audience)
+	}
+	var claims map[string]any
+	if err := token.Claims(&claims); err != nil {
+		return common.Principal{}, fmt.Errorf("auth: decode claims: %w", err)
+	}
+	client := stringOrFallback(claims, "azp", "")
+	if client == "" {
+		client = stringOrFallback(claims, "client_id", "")
+	}
+	if s.clients != nil {
+		if _, ok := s.clients[client]; !ok {
+			return common.Principal{}, fmt.Errorf("%w: client %q is not an allowed service", ErrAudienceMismatch, client)
+		}
+	}
+
+	principal := common.Principal{
+		Username: client,
+		Subject:  token.Subject,
+		Roles:    extractRoles(claims),
+		Claims:   claims,
+	}
+	if scope, ok := stringClaim(claims["scope"]); ok {
+		principal.Scopes = fields(scope)
+	}
+	return principal, nil
+}
+
+// SignRequest signs req for an internal route with key. body must be the exact bytes sent as the
+// request body.
+func SignRequest(req *http.Request, key ServiceKey, body []byte, now time.Time) {
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	nonce := uuid.NewString()
+	sig := serviceSignature(key.Secret, req.Method, req.URL.EscapedPath(), req.URL.RawQuery, timestamp, nonce, body)
+	req.Header.Set(HeaderServiceKeyID, key.ID)
+	req.Header.Set(HeaderServiceTimestamp, timestamp)
+	req.Header.Set(HeaderServiceNonce, nonce)
+	req.Header.Set(HeaderServiceSignature, base64.StdEncoding.EncodeToString(sig))
+}
+
+// serviceSignature is the HMAC-SHA256 over the method, path, query, timestamp, nonce and body
+// hash, one per line.
+func serviceSignature(secret []byte, method, path, query, timestamp, nonce string, body []byte) []byte {
+	bodyHash := sha256.Sum256(body)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(strings.Join([]string{
+		strings.ToUpper(method),
+		path,
+		query,
+		timestamp,
+		nonce,
+		hex.EncodeToString(bodyHash[:]),
+	}, "\n")))
+	return mac.Sum(nil)
+}
+
+// ServiceMiddleware restricts the routes it wraps to authenticated Frego services.
+func ServiceMiddleware(logger *slog.Logger, authenticator *ServiceAuthenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, err := authenticator.Authenticate(r)
+			if err != nil {
+				switch {
+				case errors.Is(err, ErrServiceUnauthenticated):
+					http.Error(w, "service credentials required", http.StatusUnauthorized)
+				case errors.Is(err, ErrAudienceMismatch):
+					http.Error(w, "service token not accepted", http.StatusForbidden)
+				default:
+					logger.Warn("auth: service request rejected",
+						slog.String("path", r.URL.Path),
+						slog.String("key_id", r.Header.Get(HeaderServiceKeyID)),
+						slog.Any("error", err),
+					)
+					http.Error(w, "invalid service credentials", http.StatusUnauthorized)
+				}
+				return
+			}
+
+			ctx := common.WithPrincipal(r.Context(), principal)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// nonceCache remembers seen nonces until they expire. It is per instance, so replay protection
+// across replicas relies on the timestamp window.
+type nonceCache struct {
+	mu        sync.Mutex
+	seen      map[string]time.Time
+	lastSweep time.Time
+}
+
+func newNonceCache() *nonceCache {
+	return &nonceCache{seen: make(map[string]time.Time)}
+}
+
+// add records nonce for ttl and reports false when it is already recorded.
+func (c *nonceCache) add(nonce string, ttl time.Duration) bool {
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if now.Sub(c.lastSweep) > ttl {
+		for n, expires := range c.seen {
+			if now.After(expires) {
+				delete(c.seen, n)
+			}
+		}
+		c.lastSweep = now
+	}
+	if expires, ok := c.seen[nonce]; ok && now.Before(expires) {
+		return false
+	}
+	c.seen[nonce] = now.Add(ttl)
+	return true
+}
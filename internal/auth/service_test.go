@@ -0,0 +1,252 @@
+package auth
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+var (
+	testKeyOld = ServiceKey{ID: "billing-2025", Service: "billing", Secret: []byte("0123456789abcdef0123456789abcdef")}
+	testKeyNew = ServiceKey{ID: "billing-2026", Service: "billing", Secret: []byte("fedcba9876543210fedcba9876543210")}
+)
+
+func newTestServiceAuthenticator(t *testing.T, keys ...ServiceKey) *ServiceAuthenticator {
+	t.Helper()
+	s, err := NewServiceAuthenticator(ServiceConfig{Keys: keys, MaxSkew: time.Minute}, nil)
+	if err != nil {
+		t.Fatalf("NewServiceAuthenticator: %v", err)
+	}
+	return s
+}
+
+func signedRequest(key ServiceKey, body string, now time.Time) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/internal/jobs?tenant=acme", bytes.NewBufferString(body))
+	SignRequest(req, key, []byte(body), now)
+	return req
+}
+
+func TestServiceSignature(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name    string
+		keys    []ServiceKey
+		request func() *http.Request
+		wantErr error
+	}{
+		{
+			name:    "valid",
+			keys:    []ServiceKey{testKeyOld},
+			request: func() *http.Request { return signedRequest(testKeyOld, `{"id":1}`, now) },
+		},
+		{
+			name:    "within skew in the past",
+			keys:    []ServiceKey{testKeyOld},
+			request: func() *http.Request { return signedRequest(testKeyOld, `{}`, now.Add(-50*time.Second)) },
+		},
+		{
+			name:    "within skew in the future",
+			keys:    []ServiceKey{testKeyOld},
+			request: func() *http.Request { return signedRequest(testKeyOld, `{}`, now.Add(50*time.Second)) },
+		},
+		{
+			name:    "too old",
+			keys:    []ServiceKey{testKeyOld},
+			request: func() *http.Request { return signedRequest(testKeyOld, `{}`, now.Add(-2*time.Minute)) },
+			wantErr: ErrServiceReplay,
+		},
+		{
+			name:    "too far in the future",
+			keys:    []ServiceKey{testKeyOld},
+			request: func() *http.Request { return signedRequest(testKeyOld, `{}`, now.Add(2*time.Minute)) },
+			wantErr: ErrServiceReplay,
+		},
+		{
+			name: "timestamp not a number",
+			keys: []ServiceKey{testKeyOld},
+			request: func() *http.Request {
+				req := signedRequest(testKeyOld, `{}`, now)
+				req.Header.Set(HeaderServiceTimestamp, "yesterday")
+				return req
+			},
+			wantErr: ErrServiceReplay,
+		},
+		{
+			name: "timestamp changed after signing",
+			keys: []ServiceKey{testKeyOld},
+			request: func() *http.Request {
+				req := signedRequest(testKeyOld, `{}`, now)
+				req.Header.Set(HeaderServiceTimestamp, strconv.FormatInt(now.Unix()+1, 10))
+				return req
+			},
+			wantErr: ErrServiceSignature,
+		},
+		{
+			name: "missing nonce",
+			keys: []ServiceKey{testKeyOld},
+			request: func() *http.Request {
+				req := signedRequest(testKeyOld, `{}`, now)
+				req.Header.Del(HeaderServiceNonce)
+				return req
+			},
+			wantErr: ErrServiceReplay,
+		},
+		{
+			name: "body changed after signing",
+			keys: []ServiceKey{testKeyOld},
+			request: func() *http.Request {
+				req := signedRequest(testKeyOld, `{"amount":"10"}`, now)
+				req.Body = io.NopCloser(bytes.NewBufferString(`{"amount":"99"}`))
+				return req
+			},
+			wantErr: ErrServiceSignature,
+		},
+		{
+			name: "query changed after signing",
+			keys: []ServiceKey{testKeyOld},
+			request: func() *http.Request {
+				req := signedRequest(testKeyOld, `{}`, now)
+				req.URL.RawQuery = "tenant=other"
+				return req
+			},
+			wantErr: ErrServiceSignature,
+		},
+		{
+			name: "signed with another key's secret",
+			keys: []ServiceKey{testKeyOld},
+			request: func() *http.Request {
+				return signedRequest(ServiceKey{ID: testKeyOld.ID, Secret: testKeyNew.Secret}, `{}`, now)
+			},
+			wantErr: ErrServiceSignature,
+		},
+		{
+			name: "signature not base64",
+			keys: []ServiceKey{testKeyOld},
+			request: func() *http.Request {
+				req := signedRequest(testKeyOld, `{}`, now)
+				req.Header.Set(HeaderServiceSignature, "not base64!")
+				return req
+			},
+			wantErr: ErrServiceSignature,
+		},
+		{
+			name:    "rotation accepts the new key",
+			keys:    []ServiceKey{testKeyOld, testKeyNew},
+			request: func() *http.Request { return signedRequest(testKeyNew, `{}`, now) },
+		},
+		{
+			name:    "rotation still accepts the old key",
+			keys:    []ServiceKey{testKeyOld, testKeyNew},
+			request: func() *http.Request { return signedRequest(testKeyOld, `{}`, now) },
+		},
+		{
+			name:    "retired key is refused",
+			keys:    []ServiceKey{testKeyNew},
+			request: func() *http.Request { return signedRequest(testKeyOld, `{}`, now) },
+			wantErr: ErrServiceSignature,
+		},
+		{
+			name: "no credentials",
+			keys: []ServiceKey{testKeyOld},
+			request: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/internal/jobs", nil)
+			},
+			wantErr: ErrServiceUnauthenticated,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServiceAuthenticator(t, tt.keys...)
+			principal, err := s.Authenticate(tt.request())
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Authenticate error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Authenticate error = %v", err)
+			}
+			if principal.Subject != "billing" || principal.Claims["auth"] != "hmac" {
+				t.Errorf("principal = %+v, want the billing service authenticated by hmac", principal)
+			}
+		})
+	}
+}
+
+func TestServiceSignatureRestoresBody(t *testing.T) {
+	s := newTestServiceAuthenticator(t, testKeyOld)
+	req := signedRequest(testKeyOld, `{"id":1}`, time.Now())
+	if _, err := s.Authenticate(req); err != nil {
+		t.Fatalf("Authenticate error = %v", err)
+	}
+	body, _ := io.ReadAll(req.Body)
+	if string(body) != `{"id":1}` {
+		t.Errorf("body after Authenticate = %q, want the signed body", body)
+	}
+}
+
+func TestServiceSignatureReplay(t *testing.T) {
+	s := newTestServiceAuthenticator(t, testKeyOld, testKeyNew)
+	req := signedRequest(testKeyOld, `{"id":1}`, time.Now())
+	replay := req.Clone(req.Context())
+	replay.Body = io.NopCloser(bytes.NewBufferString(`{"id":1}`))
+
+	if _, err := s.Authenticate(req); err != nil {
+		t.Fatalf("first request error = %v", err)
+	}
+	if _, err := s.Authenticate(replay); !errors.Is(err, ErrServiceReplay) {
+		t.Fatalf("replayed request error = %v, want ErrServiceReplay", err)
+	}
+
+	// A forged request reusing a fresh nonce must not burn it for the real caller.
+	genuine := signedRequest(testKeyNew, `{}`, time.Now())
+	forged := genuine.Clone(genuine.Context())
+	forged.Body = io.NopCloser(bytes.NewBufferString(`{"forged":true}`))
+	if _, err := s.Authenticate(forged); !errors.Is(err, ErrServiceSignature) {
+		t.Fatalf("forged request error = %v, want ErrServiceSignature", err)
+	}
+	if _, err := s.Authenticate(genuine); err != nil {
+		t.Fatalf("genuine request after a forgery error = %v", err)
+	}
+}
+
+func TestParseServiceKeys(t *testing.T) {
+	secret := "0123456789abcdef0123456789abcdef"
+	tests := []struct {
+		name    string
+		entries []string
+		want    int
+		wantErr bool
+	}{
+		{name: "empty", entries: nil, want: 0},
+		{name: "one key", entries: []string{"billing:k1:" + secret}, want: 1},
+		{name: "rotation", entries: []string{"billing:k1:" + secret, " billing:k2:" + secret + " ", ""}, want: 2},
+		{name: "secret with colons", entries: []string{"billing:k1:" + secret + ":x"}, want: 1},
+		{name: "short secret", entries: []string{"billing:k1:short"}, wantErr: true},
+		{name: "missing key ID", entries: []string{"billing::" + secret}, wantErr: true},
+		{name: "duplicate key ID", entries: []string{"billing:k1:" + secret, "crm:k1:" + secret}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := ParseServiceKeys(tt.entries)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseServiceKeys(%q) succeeded, want an error", tt.entries)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseServiceKeys(%q) error = %v", tt.entries, err)
+			}
+			if len(keys) != tt.want {
+				t.Errorf("ParseServiceKeys(%q) returned %d keys, want %d", tt.entries, len(keys), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package authz
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"frego-operations/internal/common"
+	"frego-operations/internal/logging"
+)
+
+// GrantLoader returns the current tenant's role grants: permissions keyed by role name.
+type GrantLoader func(ctx context.Context) (map[string][]string, error)
+
+type tenantGrants struct {
+	grants   map[string][]Permission
+	loadedAt time.Time
+}
+
+// Authorizer checks principals against the policy and the tenant's role grants, which it caches
+// per tenant for ttl.
+type Authorizer struct {
+	policy Policy
+	load   GrantLoader
+	ttl    time.Duration
+
+	mu     sync.Mutex
+	grants map[string]tenantGrants
+}
+
+// New creates an authorizer. A nil load uses the policy alone.
+func New(policy Policy, load GrantLoader, ttl time.Duration) *Authorizer {
+	return &Authorizer{
+		policy: policy,
+		load:   load,
+		ttl:    ttl,
+		grants: make(map[string]tenantGrants),
+	}
+}
+
+// Authorize returns a *DeniedError when the context's principal lacks perm.
+func (a *Authorizer) Authorize(ctx context.Context, perm Permission) error {
+	principal, _ := common.PrincipalFromContext(ctx)
+	return a.policy.Check(principal, perm, a.tenantGrants(ctx))
+}
+
+// AuthorizeScopes returns a *DeniedError when the context's principal lacks a permission that one
+// of scopes grants, so nobody hands a client more than they hold themselves.
+func (a *Authorizer) AuthorizeScopes(ctx context.Context, scopes []string) error {
+	principal, _ := common.PrincipalFromContext(ctx)
+	grants := a.tenantGrants(ctx)
+	for _, scope := range scopes {
+		for _, perm := range a.policy.ScopePermissions(scope) {
+			if err := a.policy.Check(principal, perm, grants); err != nil {
+				return fmt.Errorf("scope %q: %w", scope, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Invalidate drops the cached grants of the context's tenant, after they change.
+func (a *Authorizer) Invalidate(ctx context.Context) {
+	tenantID, _, ok := common.TenantFromContext(ctx)
+	if !ok {
+		return
+	}
+	a.mu.Lock()
+	delete(a.grants, tenantID)
+	a.mu.Unlock()
+}
+
+// tenantGrants returns the tenant's grants, loading them when missing or stale. A failed load
+// falls back to the policy alone rather than failing the request.
+func (a *Authorizer) tenantGrants(ctx context.Context) map[string][]Permission {
+	if a.load == nil {
+		return nil
+	}
+	tenantID, _, ok := common.TenantFromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	a.mu.Lock()
+	cached, cachedOK := a.grants[tenantID]
+	a.mu.Unlock()
+	if cachedOK && time.Since(cached.loadedAt) < a.ttl {
+		return cached.grants
+	}
+
+	raw, err := a.load(ctx)
+	if err != nil {
+		logging.FromContext(ctx).Warn("authz: failed to load role grants", slog.Any("error", err))
+		if cachedOK {
+			return cached.grants
+		}
+		return nil
+	}
+	grants := make(map[string][]Permission, len(raw))
+	for role, perms := range raw {
+		for _, p := range perms {
+			grants[role] = append(grants[role], Permission(p))
+		}
+	}
+
+	a.mu.Lock()
+	a.grants[tenantID] = tenantGrants{grants: grants, loadedAt: time.Now()}
+	a.mu.Unlock()
+	return grants
+}
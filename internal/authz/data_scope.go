@@ -0,0 +1,109 @@
+package authz
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"frego-operations/internal/common"
+)
+
+// Data scope roles. AllBranchesRole, like AdminRole, bypasses data scoping; FreelanceSalesRole
+// limits a principal to the jobs where they are the sales executive.
+const (
+	AllBranchesRole    = "ops-all-branches"
+	FreelanceSalesRole = "ops-freelance-sales"
+)
+
+// Claims of API key principals, which carry the branches stored on the key rather than token
+// claims.
+const (
+	APIKeyBranchesClaim    = "api_key_branch_ids"
+	APIKeyAllBranchesClaim = "api_key_all_branches"
+)
+
+// ScopeClaims names the token claims that carry a principal's branches and employee ID.
+type ScopeClaims struct {
+	Branches string
+	Employee string
+}
+
+// DataScopeFor derives the jobs a principal may see from its token. A branch claim limits it to
+// those branches and FreelanceSalesRole to the jobs where its employee ID is the sales
+// executive; an employee claim without a branch claim limits it to those jobs too. Only AdminRole
+// and AllBranchesRole see the whole tenant: a principal with no branch or employee claim sees
+// nothing. API keys are scoped by their stored branches instead of claims. Claim values that are
+// not UUIDs match nothing, so a malformed claim never widens access.
+func DataScopeFor(principal common.Principal, claims ScopeClaims) common.DataScope {
+	if principal.HasRole(AdminRole) || principal.HasRole(AllBranchesRole) {
+		return common.DataScope{}
+	}
+	if principal.Claims["auth"] == "api_key" {
+		if all, _ := principal.Claims[APIKeyAllBranchesClaim].(bool); all {
+			return common.DataScope{}
+		}
+		claims = ScopeClaims{Branches: APIKeyBranchesClaim}
+	}
+
+	var scope common.DataScope
+	raw, hasBranches := principal.Claims[claims.Branches]
+	if hasBranches && claims.Branches != "" {
+		scope.BranchIDs = []uuid.UUID{}
+		for _, value := range claimStrings(raw) {
+			if id, err := uuid.Parse(value); err == nil {
+				scope.BranchIDs = append(scope.BranchIDs, id)
+			}
+		}
+	}
+	var employee []string
+	if claims.Employee != "" {
+		employee = claimStrings(principal.Claims[claims.Employee])
+	}
+	if principal.HasRole(FreelanceSalesRole) || (scope.BranchIDs == nil && len(employee) > 0) {
+		employeeID := uuid.Nil
+		if len(employee) > 0 {
+			if id, err := uuid.Parse(employee[0]); err == nil {
+				employeeID = id
+			}
+		}
+		scope.SalesExecutiveID = &employeeID
+	}
+	if scope.Unrestricted() {
+		scope.BranchIDs = []uuid.UUID{}
+	}
+	return scope
+}
+
+// ScopeMiddleware places the authenticated principal's data scope on the request context.
+func ScopeMiddleware(claims ScopeClaims) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, _ := common.PrincipalFromContext(r.Context())
+			ctx := common.WithDataScope(r.Context(), DataScopeFor(principal, claims))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// claimStrings reads a claim holding a string, a list of strings, or a comma-separated string.
+func claimStrings(raw any) []string {
+	var values []string
+	switch v := raw.(type) {
+	case string:
+		for _, part := range strings.Split(v, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				values = append(values, part)
+			}
+		}
+	case []string:
+		values = append(values, v...)
+	case []any:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+	return values
+}
@@ -0,0 +1,194 @@
+package authz
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"frego-operations/internal/common"
+)
+
+// Permission is an operation a principal may be allowed to perform.
+type Permission string
+
+// Operations permissions. Routes declare the one they need; the policy grants them to roles and
+// scopes.
+const (
+	ViewJobs        Permission = "jobs.view"
+	EditJobs        Permission = "jobs.edit"
+	ArchiveJobs     Permission = "jobs.archive"
+	ViewFinancials  Permission = "financials.view"
+	EditFinancials  Permission = "financials.edit"
+	DecideApprovals Permission = "approvals.decide"
+	ManageLookups   Permission = "lookups.manage"
+	ManageAPIKeys   Permission = "apikeys.manage"
+)
+
+// AdminRole holds every permission.
+const AdminRole = "ops-admin"
+
+// ErrForbidden indicates the principal lacks the permission an operation needs.
+var ErrForbidden = errors.New("authz: forbidden")
+
+// DeniedError explains which roles or scopes would have allowed a refused operation.
+type DeniedError struct {
+	Permission Permission
+	Roles      []string
+	Scopes     []string
+}
+
+func (e *DeniedError) Error() string {
+	reason := fmt.Sprintf("permission %q requires one of the roles %s", e.Permission, strings.Join(quoted(e.Roles), ", "))
+	if len(e.Scopes) > 0 {
+		reason += fmt.Sprintf(" or one of the scopes %s", strings.Join(quoted(e.Scopes), ", "))
+	}
+	return reason
+}
+
+func (e *DeniedError) Unwrap() error {
+	return ErrForbidden
+}
+
+// Rule lists the Keycloak roles and token scopes that each grant a permission.
+type Rule struct {
+	Roles  []string
+	Scopes []string
+}
+
+// Policy maps each permission to the roles and scopes that grant it.
+type Policy map[Permission]Rule
+
+// DefaultPolicy is the built-in mapping of permissions to Keycloak realm and client roles.
+// Tenants extend it by granting permissions to their own roles in role_details_lu.
+func DefaultPolicy() Policy {
+	return Policy{
+		ViewJobs: {
+			Roles:  []string{"ops-viewer", "ops-user", "ops-finance", "ops-approver", "ops-supervisor"},
+			Scopes: []string{"operations:read", "operations:write"},
+		},
+		EditJobs: {
+			Roles:  []string{"ops-user", "ops-supervisor"},
+			Scopes: []string{"operations:write"},
+		},
+		ArchiveJobs: {
+			Roles: []string{"ops-supervisor"},
+		},
+		ViewFinancials: {
+			Roles:  []string{"ops-finance", "ops-approver", "ops-supervisor"},
+			Scopes: []string{"finance:read", "finance:write"},
+		},
+		EditFinancials: {
+			Roles:  []string{"ops-finance"},
+			Scopes: []string{"finance:write"},
+		},
+		DecideApprovals: {
+			Roles: []string{"ops-approver", "ops-supervisor"},
+		},
+		ManageLookups: {},
+		ManageAPIKeys: {},
+	}
+}
+
+// Permissions returns every known permission, sorted.
+func Permissions() []Permission {
+	perms := make([]Permission, 0, len(DefaultPolicy()))
+	for p := range DefaultPolicy() {
+		perms = append(perms, p)
+	}
+	sort.Slice(perms, func(i, j int) bool { return perms[i] < perms[j] })
+	return perms
+}
+
+// Known reports whether p is a defined permission.
+func Known(p Permission) bool {
+	_, ok := DefaultPolicy()[p]
+	return ok
+}
+
+// Scopes returns every token scope the policy recognises, sorted.
+func Scopes() []string {
+	seen := make(map[string]bool)
+	var scopes []string
+	for _, rule := range DefaultPolicy() {
+		for _, s := range rule.Scopes {
+			if !seen[s] {
+				seen[s] = true
+				scopes = append(scopes, s)
+			}
+		}
+	}
+	sort.Strings(scopes)
+	return scopes
+}
+
+// KnownScope reports whether scope grants any permission.
+func KnownScope(scope string) bool {
+	for _, s := range Scopes() {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// ScopePermissions returns the permissions scope grants under the policy, sorted.
+func (p Policy) ScopePermissions(scope string) []Permission {
+	var perms []Permission
+	for perm, rule := range p {
+		for _, s := range rule.Scopes {
+			if s == scope {
+				perms = append(perms, perm)
+				break
+			}
+		}
+	}
+	sort.Slice(perms, func(i, j int) bool { return perms[i] < perms[j] })
+	return perms
+}
+
+// Check reports whether principal holds perm through the policy, AdminRole, or a tenant grant to
+// one of its roles (grants maps role names to the permissions they were given).
+func (p Policy) Check(principal common.Principal, perm Permission, grants map[string][]Permission) error {
+	if principal.HasRole(AdminRole) {
+		return nil
+	}
+	rule := p[perm]
+	for _, role := range rule.Roles {
+		if principal.HasRole(role) {
+			return nil
+		}
+	}
+	for _, scope := range rule.Scopes {
+		for _, s := range principal.Scopes {
+			if s == scope {
+				return nil
+			}
+		}
+	}
+	var granting []string
+	for role, perms := range grants {
+		for _, granted := range perms {
+			if granted != perm {
+				continue
+			}
+			if principal.HasRole(role) {
+				return nil
+			}
+			granting = append(granting, role)
+		}
+	}
+
+	roles := append([]string{AdminRole}, rule.Roles...)
+	sort.Strings(granting)
+	roles = append(roles, granting...)
+	return &DeniedError{Permission: perm, Roles: roles, Scopes: rule.Scopes}
+}
+
+func quoted(values []string) []string {
+	out := make([]string, len(values))
+	for i, v := range values {
+		out[i] = fmt.Sprintf("%q", v)
+	}
+	return out
+}
@@ -0,0 +1,33 @@
+package common
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+type dataScopeKey struct{}
+
+// DataScope limits the jobs a principal can see. A nil BranchIDs or SalesExecutiveID places no
+// limit on that dimension, so the zero value sees every job in the tenant; an empty, non-nil
+// BranchIDs sees none.
+type DataScope struct {
+	BranchIDs        []uuid.UUID
+	SalesExecutiveID *uuid.UUID
+}
+
+// Unrestricted reports whether the scope allows every job.
+func (s DataScope) Unrestricted() bool {
+	return s.BranchIDs == nil && s.SalesExecutiveID == nil
+}
+
+// WithDataScope stores the principal's data scope on the supplied context.
+func WithDataScope(ctx context.Context, scope DataScope) context.Context {
+	return context.WithValue(ctx, dataScopeKey{}, scope)
+}
+
+// DataScopeFromContext returns the data scope on the context, unrestricted when none is set.
+func DataScopeFromContext(ctx context.Context) DataScope {
+	scope, _ := ctx.Value(dataScopeKey{}).(DataScope)
+	return scope
+}
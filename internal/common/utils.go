@@ -1,12 +1,13 @@
 package common
 
 import (
-	"strconv"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
+
+	"frego-operations/internal/decimal"
 )
 
 // ResolveName determines the best display name from display, legal, and code fields.
@@ -171,28 +172,22 @@ func Int32ToPgtype(i *int32) pgtype.Int4 {
 	return pgtype.Int4{Int32: *i, Valid: true}
 }
 
-func NumericPtr(n pgtype.Numeric) *float64 {
-	if !n.Valid {
-		return nil
-	}
-	val, err := n.Float64Value()
-	if err != nil || !val.Valid {
+// NumericPtr converts pgtype.Numeric to an exact decimal, returning nil for NULL, NaN and infinity.
+func NumericPtr(n pgtype.Numeric) *decimal.Decimal {
+	if !n.Valid || n.NaN || n.InfinityModifier != pgtype.Finite || n.Int == nil {
 		return nil
 	}
-	result := val.Float64
-	return &result
+	value := decimal.FromExponent(n.Int, n.Exp)
+	return &value
 }
 
-func Float64ToNumeric(val *float64) pgtype.Numeric {
+// DecimalToNumeric converts a decimal to pgtype.Numeric without loss of precision.
+func DecimalToNumeric(val *decimal.Decimal) pgtype.Numeric {
 	if val == nil {
 		return pgtype.Numeric{Valid: false}
 	}
-	var num pgtype.Numeric
-	decimal := strconv.FormatFloat(*val, 'f', -1, 64)
-	if err := num.Scan(decimal); err != nil {
-		return pgtype.Numeric{Valid: false}
-	}
-	return num
+	coef, exp := val.Exponent()
+	return pgtype.Numeric{Int: coef, Exp: exp, Valid: true}
 }
 
 // GenerateHumanID produces a short unique identifier for human-friendly references.
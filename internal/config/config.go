@@ -3,6 +3,8 @@ package config
 import (
 	"context"
 	"fmt"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/caarlos0/env/v11"
@@ -19,10 +21,14 @@ type Config struct {
 
 	// Service-specific Database
 	Database DatabaseConfig
-
-	Security       SecurityConfig
-	Storage        StorageConfig
-	InternalSecret string `env:"FREGO_INTERNAL_SECRET"`
+	Shards   ShardConfig
+
+	Security    SecurityConfig
+	Storage     StorageConfig
+	EDI         EDIConfig
+	Screening   ScreeningConfig
+	ServiceAuth ServiceAuthConfig
+	RateLimit   RateLimitConfig
 }
 
 type DatabaseConfig struct {
@@ -34,12 +40,27 @@ type DatabaseConfig struct {
 	User              string        `env:"USER" envDefault:"erp_user"`
 }
 
+// ShardConfig lists the operations databases other than DB_URL as "key=url" entries. The tenant
+// registry assigns tenants to shards by key; each shard's pool is opened on first use.
+type ShardConfig struct {
+	URLs            []string      `env:"DB_SHARDS" envSeparator:","`
+	MaxConns        int           `env:"DB_SHARD_MAX_CONNS" envDefault:"10"`
+	ConnMaxLifetime time.Duration `env:"DB_SHARD_CONN_MAX_LIFETIME" envDefault:"5m"`
+	ConnMaxIdleTime time.Duration `env:"DB_SHARD_CONN_MAX_IDLE_TIME" envDefault:"10m"`
+}
+
 type SecurityConfig struct {
-	KeycloakIssuer      string    `env:"KEYCLOAK_ISSUER,required"`
-	KeycloakAudience    []string  `env:"KEYCLOAK_AUDIENCE" envSeparator:","`
-	KeycloakTenantClaim string    `env:"KEYCLOAK_TENANT_CLAIM" envDefault:"tenant_id"`
-	DefaultTenant       uuid.UUID `env:"DEFAULT_TENANT"`
-	AllowedOrigins      []string  `env:"ALLOWED_ORIGINS" envSeparator:","`
+	KeycloakIssuer        string    `env:"KEYCLOAK_ISSUER,required"`
+	KeycloakAudience      []string  `env:"KEYCLOAK_AUDIENCE" envSeparator:","`
+	KeycloakTenantClaim   string    `env:"KEYCLOAK_TENANT_CLAIM" envDefault:"tenant_id"`
+	KeycloakBranchClaim   string    `env:"KEYCLOAK_BRANCH_CLAIM" envDefault:"branch_ids"`
+	KeycloakEmployeeClaim string    `env:"KEYCLOAK_EMPLOYEE_CLAIM" envDefault:"employee_id"`
+	KeycloakJWKSFile      string    `env:"KEYCLOAK_JWKS_FILE"`
+	KeycloakPublicKeys    []string  `env:"KEYCLOAK_PUBLIC_KEY_FILES" envSeparator:","`
+	DefaultTenant         uuid.UUID `env:"DEFAULT_TENANT"`
+	AllowDefaultTenant    bool      `env:"ALLOW_DEFAULT_TENANT" envDefault:"false"`
+	CrossTenantRole       string    `env:"CROSS_TENANT_ROLE" envDefault:"platform-super-admin"`
+	AllowedOrigins        []string  `env:"ALLOWED_ORIGINS" envSeparator:","`
 }
 
 type StorageConfig struct {
@@ -53,26 +74,39 @@ type StorageConfig struct {
 	MaxUploadSize   int64  `env:"MAX_UPLOAD_SIZE" envDefault:"10485760"` // 10MB
 }
 
-func Load(ctx context.Context) (*Config, error) {
-	cfg := &Config{}
+type EDIConfig struct {
+	SenderID string `env:"EDI_SENDER_ID" envDefault:"FREGO"`
+}
 
-	// Load environment
-	cfg.Environment = getEnvOrDefault("ENVIRONMENT", "development")
-	cfg.HTTPAddress = getEnvOrDefault("HTTP_ADDRESS", ":8080")
+// ScreeningConfig locates the denied-party lists; screening is disabled when ListDir is empty.
+type ScreeningConfig struct {
+	ListDir        string  `env:"SCREENING_LIST_DIR"`
+	MatchThreshold float64 `env:"SCREENING_MATCH_THRESHOLD" envDefault:"0.88"`
+}
 
-	// Load tenant database config
-	if err := env.ParseWithOptions(&cfg.TenantDatabase, env.Options{
-		Prefix: "TENANT_DB_",
-	}); err != nil {
-		return nil, fmt.Errorf("parse tenant database config: %w", err)
-	}
+// ServiceAuthConfig authenticates calls to internal routes from other Frego services. Keys are
+// "service:keyID:secret" entries; list a new key next to the old one to rotate. Audience, when
+// set, also accepts client-credentials tokens issued for it.
+type ServiceAuthConfig struct {
+	Keys     []string      `env:"INTERNAL_SERVICE_KEYS" envSeparator:","`
+	MaxSkew  time.Duration `env:"INTERNAL_SERVICE_MAX_SKEW" envDefault:"5m"`
+	Audience string        `env:"INTERNAL_SERVICE_AUDIENCE"`
+	Clients  []string      `env:"INTERNAL_SERVICE_CLIENTS" envSeparator:","`
+}
 
-	// Load service database config
-	if err := env.ParseWithOptions(&cfg.Database, env.Options{
-		Prefix: "DB_",
-	}); err != nil {
-		return nil, fmt.Errorf("parse database config: %w", err)
+// RateLimitConfig switches request rate limiting; the limits themselves live in the tenant
+// registry and are reloaded every RefreshInterval.
+type RateLimitConfig struct {
+	Enabled         bool          `env:"RATE_LIMIT_ENABLED" envDefault:"true"`
+	RefreshInterval time.Duration `env:"RATE_LIMIT_REFRESH_INTERVAL" envDefault:"1m"`
+}
+
+func Load(ctx context.Context) (*Config, error) {
+	cfg, err := LoadDatabases()
+	if err != nil {
+		return nil, err
 	}
+	cfg.HTTPAddress = getEnvOrDefault("HTTP_ADDRESS", ":8080")
 
 	// Load security config
 	if err := env.Parse(&cfg.Security); err != nil {
@@ -84,6 +118,26 @@ func Load(ctx context.Context) (*Config, error) {
 		return nil, fmt.Errorf("parse storage config: %w", err)
 	}
 
+	// Load EDI config
+	if err := env.Parse(&cfg.EDI); err != nil {
+		return nil, fmt.Errorf("parse edi config: %w", err)
+	}
+
+	// Load screening config
+	if err := env.Parse(&cfg.Screening); err != nil {
+		return nil, fmt.Errorf("parse screening config: %w", err)
+	}
+
+	// Load service-to-service auth config
+	if err := env.Parse(&cfg.ServiceAuth); err != nil {
+		return nil, fmt.Errorf("parse service auth config: %w", err)
+	}
+
+	// Load rate limit config
+	if err := env.Parse(&cfg.RateLimit); err != nil {
+		return nil, fmt.Errorf("parse rate limit config: %w", err)
+	}
+
 	// Parse graceful delay
 	if delayStr := getEnvOrDefault("GRACEFUL_DELAY", "5s"); delayStr != "" {
 		if d, err := time.ParseDuration(delayStr); err == nil {
@@ -94,6 +148,37 @@ func Load(ctx context.Context) (*Config, error) {
 	return cfg, nil
 }
 
+// LoadDatabases loads only the environment and database settings, for commands that work on the
+// databases without serving requests.
+func LoadDatabases() (*Config, error) {
+	cfg := &Config{}
+
+	// Load environment; an unset ENVIRONMENT is treated as production so development-only
+	// behaviour stays off unless asked for
+	cfg.Environment = getEnvOrDefault("ENVIRONMENT", "production")
+
+	// Load tenant database config
+	if err := env.ParseWithOptions(&cfg.TenantDatabase, env.Options{
+		Prefix: "TENANT_DB_",
+	}); err != nil {
+		return nil, fmt.Errorf("parse tenant database config: %w", err)
+	}
+
+	// Load service database config
+	if err := env.ParseWithOptions(&cfg.Database, env.Options{
+		Prefix: "DB_",
+	}); err != nil {
+		return nil, fmt.Errorf("parse database config: %w", err)
+	}
+
+	// Load shard config
+	if err := env.Parse(&cfg.Shards); err != nil {
+		return nil, fmt.Errorf("parse shard config: %w", err)
+	}
+
+	return cfg, nil
+}
+
 func getEnvOrDefault(key, defaultValue string) string {
 	if v := getEnv(key); v != "" {
 		return v
@@ -102,6 +187,5 @@ func getEnvOrDefault(key, defaultValue string) string {
 }
 
 func getEnv(key string) string {
-	// Implementation depends on your env package
-	return ""
+	return strings.TrimSpace(os.Getenv(key))
 }
@@ -0,0 +1,173 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PrimaryShard is the shard key of the service database configured with DB_URL. Tenants without
+// a shard in the registry live there.
+const PrimaryShard = "primary"
+
+var (
+	// ErrUnknownShard indicates a shard key with no configured connection URL.
+	ErrUnknownShard = errors.New("unknown database shard")
+	// ErrTenantMoving indicates the tenant's schema is being moved to another shard.
+	ErrTenantMoving = errors.New("tenant is moving between database shards")
+)
+
+// ShardLimits bound each pool opened for a non-primary shard. Zero values keep pgxpool's defaults.
+type ShardLimits struct {
+	MaxConns          int32
+	ConnMaxLifetime   time.Duration
+	ConnMaxIdleTime   time.Duration
+	PreferSimpleProto bool
+}
+
+// ShardStats reports one shard pool's connections.
+type ShardStats struct {
+	TotalConns    int32 `json:"total_conns"`
+	IdleConns     int32 `json:"idle_conns"`
+	AcquiredConns int32 `json:"acquired_conns"`
+	MaxConns      int32 `json:"max_conns"`
+}
+
+// ShardPools holds a connection pool per service database. The primary pool is opened by the
+// caller; the others are opened on first use, so a shard no tenant of this instance lives on
+// costs no connections.
+type ShardPools struct {
+	primary *pgxpool.Pool
+	urls    map[string]string
+	limits  ShardLimits
+
+	mu    sync.Mutex
+	pools map[string]*pgxpool.Pool
+}
+
+// NewShardPools creates shard pools around the primary pool. urls maps the other shard keys to
+// their connection URLs.
+func NewShardPools(primary *pgxpool.Pool, urls map[string]string, limits ShardLimits) *ShardPools {
+	return &ShardPools{
+		primary: primary,
+		urls:    urls,
+		limits:  limits,
+		pools:   make(map[string]*pgxpool.Pool),
+	}
+}
+
+// ParseShardURLs parses "key=url" entries. The primary key is reserved for DB_URL.
+func ParseShardURLs(entries []string) (map[string]string, error) {
+	urls := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		key, url, ok := strings.Cut(entry, "=")
+		key, url = strings.TrimSpace(key), strings.TrimSpace(url)
+		if !ok || key == "" || url == "" {
+			return nil, fmt.Errorf("shard entry %q must be key=url", entry)
+		}
+		if key == PrimaryShard {
+			return nil, fmt.Errorf("shard key %q is reserved for DB_URL", PrimaryShard)
+		}
+		if _, dup := urls[key]; dup {
+			return nil, fmt.Errorf("shard %q is configured twice", key)
+		}
+		urls[key] = url
+	}
+	return urls, nil
+}
+
+// Pool returns the pool of the shard, opening it on first use. An empty key is the primary.
+func (s *ShardPools) Pool(ctx context.Context, key string) (*pgxpool.Pool, error) {
+	if key == "" || key == PrimaryShard {
+		return s.primary, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if pool, ok := s.pools[key]; ok {
+		return pool, nil
+	}
+	url, ok := s.urls[key]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownShard, key)
+	}
+
+	config, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		return nil, fmt.Errorf("parse url of shard %q: %w", key, err)
+	}
+	if s.limits.MaxConns > 0 {
+		config.MaxConns = s.limits.MaxConns
+	}
+	if s.limits.ConnMaxLifetime > 0 {
+		config.MaxConnLifetime = s.limits.ConnMaxLifetime
+	}
+	if s.limits.ConnMaxIdleTime > 0 {
+		config.MaxConnIdleTime = s.limits.ConnMaxIdleTime
+	}
+	config.ConnConfig.DefaultQueryExecMode = pgx.QueryExecModeExec
+	if s.limits.PreferSimpleProto {
+		config.ConnConfig.DefaultQueryExecMode = pgx.QueryExecModeSimpleProtocol
+	}
+
+	// The pool dials lazily, so opening it here does not hold the lock over a connection.
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		return nil, fmt.Errorf("open shard %q: %w", key, err)
+	}
+	s.pools[key] = pool
+	return pool, nil
+}
+
+// Stats returns the connection counts of the primary and of every opened shard pool.
+func (s *ShardPools) Stats() map[string]ShardStats {
+	s.mu.Lock()
+	pools := make(map[string]*pgxpool.Pool, len(s.pools)+1)
+	for key, pool := range s.pools {
+		pools[key] = pool
+	}
+	s.mu.Unlock()
+	pools[PrimaryShard] = s.primary
+
+	stats := make(map[string]ShardStats, len(pools))
+	for key, pool := range pools {
+		st := pool.Stat()
+		stats[key] = ShardStats{
+			TotalConns:    st.TotalConns(),
+			IdleConns:     st.IdleConns(),
+			AcquiredConns: st.AcquiredConns(),
+			MaxConns:      st.MaxConns(),
+		}
+	}
+	return stats
+}
+
+// Keys returns the configured shard keys, the primary first.
+func (s *ShardPools) Keys() []string {
+	keys := make([]string, 0, len(s.urls))
+	for key := range s.urls {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return append([]string{PrimaryShard}, keys...)
+}
+
+// Close closes the shard pools opened on demand. The primary pool belongs to the caller.
+func (s *ShardPools) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, pool := range s.pools {
+		pool.Close()
+		delete(s.pools, key)
+	}
+}
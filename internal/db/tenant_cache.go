@@ -0,0 +1,222 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// DefaultTenantCacheTTL bounds how long a registry lookup is reused when no change notification
+// arrives.
+const DefaultTenantCacheTTL = 5 * time.Minute
+
+// TenantRegistryChannel is the Postgres channel the registry's triggers notify with the ID of a
+// changed tenant.
+const TenantRegistryChannel = "tenant_registry_changed"
+
+// ErrTenantNotFound indicates the tenant is unknown, inactive, or not subscribed to the service.
+var ErrTenantNotFound = errors.New("tenant not found")
+
+// tenantRecord is what the registry says about a tenant, for this service's module. Shard is
+// empty for tenants on the primary database.
+type tenantRecord struct {
+	Schema     string
+	Name       string
+	Active     bool
+	Subscribed bool
+	Shard      string
+	MovingTo   string
+}
+
+// tenantEntry caches a lookup. A nil record remembers that the tenant does not exist.
+type tenantEntry struct {
+	record   *tenantRecord
+	loadedAt time.Time
+}
+
+type tenantCacheCounters struct {
+	hits          atomic.Int64
+	misses        atomic.Int64
+	invalidations atomic.Int64
+}
+
+// TenantCacheStats reports the tenant cache's effectiveness.
+type TenantCacheStats struct {
+	Hits          int64 `json:"hits"`
+	Misses        int64 `json:"misses"`
+	Invalidations int64 `json:"invalidations"`
+	Entries       int   `json:"entries"`
+}
+
+// SetCacheTTL changes how long registry lookups are reused.
+func (m *TenantSessionManager) SetCacheTTL(ttl time.Duration) {
+	m.mu.Lock()
+	m.cacheTTL = ttl
+	m.mu.Unlock()
+}
+
+// CacheStats returns the tenant cache counters.
+func (m *TenantSessionManager) CacheStats() TenantCacheStats {
+	m.mu.RLock()
+	entries := len(m.tenants)
+	m.mu.RUnlock()
+	return TenantCacheStats{
+		Hits:          m.stats.hits.Load(),
+		Misses:        m.stats.misses.Load(),
+		Invalidations: m.stats.invalidations.Load(),
+		Entries:       entries,
+	}
+}
+
+// InvalidateTenant drops the cached lookup of one tenant.
+func (m *TenantSessionManager) InvalidateTenant(tenantID uuid.UUID) {
+	m.mu.Lock()
+	delete(m.tenants, tenantID)
+	m.generation++
+	m.mu.Unlock()
+	m.stats.invalidations.Add(1)
+}
+
+// InvalidateTenants drops every cached lookup.
+func (m *TenantSessionManager) InvalidateTenants() {
+	m.mu.Lock()
+	m.tenants = make(map[uuid.UUID]tenantEntry)
+	m.generation++
+	m.mu.Unlock()
+	m.stats.invalidations.Add(1)
+}
+
+// lookupTenant returns the registry's record of the tenant, from the cache when fresh.
+func (m *TenantSessionManager) lookupTenant(ctx context.Context, tenantID uuid.UUID) (tenantRecord, error) {
+	m.mu.RLock()
+	entry, ok := m.tenants[tenantID]
+	ttl, generation := m.cacheTTL, m.generation
+	m.mu.RUnlock()
+	if ok && time.Since(entry.loadedAt) < ttl {
+		m.stats.hits.Add(1)
+		if entry.record == nil {
+			return tenantRecord{}, fmt.Errorf("%w: %s", ErrTenantNotFound, tenantID)
+		}
+		return *entry.record, nil
+	}
+	m.stats.misses.Add(1)
+
+	record, err := m.queryTenant(ctx, tenantID)
+	if err != nil && !errors.Is(err, ErrTenantNotFound) {
+		return tenantRecord{}, err
+	}
+	entry = tenantEntry{loadedAt: time.Now()}
+	if err == nil {
+		entry.record = &record
+	}
+	// A lookup that raced an invalidation may have read the old row, so it is not cached.
+	m.mu.Lock()
+	if m.generation == generation {
+		m.tenants[tenantID] = entry
+	}
+	m.mu.Unlock()
+	return record, err
+}
+
+// queryTenant reads the tenant's row from the shared tenant registry.
+func (m *TenantSessionManager) queryTenant(ctx context.Context, tenantID uuid.UUID) (tenantRecord, error) {
+	var schemaColumn string
+
+	// Determine which schema column to query based on service name
+	switch m.serviceName {
+	case "finance":
+		schemaColumn = "finance_schema"
+	case "operations":
+		schemaColumn = "operations_schema"
+	case "inventory":
+		schemaColumn = "inventory_schema"
+	case "hrms":
+		schemaColumn = "hrms_schema"
+	default:
+		return tenantRecord{}, fmt.Errorf("unknown service name: %s", m.serviceName)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT COALESCE(r.%s, ''), r.tenant_name, COALESCE(r.is_active, false),
+		       COALESCE($2 = ANY(r.modules_subscribed), false),
+		       COALESCE(s.shard_key, ''), COALESCE(s.moving_to, '')
+		FROM registry.tenant_registry r
+		LEFT JOIN registry.tenant_module_shard s ON s.tenant_id = r.tenant_id AND s.module_name = $2
+		WHERE r.tenant_id = $1
+	`, schemaColumn)
+
+	var record tenantRecord
+	err := m.tenantPool.QueryRow(ctx, query, tenantID, m.serviceName).Scan(
+		&record.Schema, &record.Name, &record.Active, &record.Subscribed, &record.Shard, &record.MovingTo,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return tenantRecord{}, fmt.Errorf("%w: %s", ErrTenantNotFound, tenantID)
+	}
+	if err != nil {
+		return tenantRecord{}, fmt.Errorf("query tenant registry: %w", err)
+	}
+	return record, nil
+}
+
+// ListenForTenantChanges invalidates cached lookups as the registry notifies changes on
+// TenantRegistryChannel, until ctx ends. A lost connection drops the whole cache, since changes
+// may have been missed, and is retried with backoff; the TTL bounds staleness meanwhile.
+func (m *TenantSessionManager) ListenForTenantChanges(ctx context.Context, logger *slog.Logger) {
+	backoff := time.Second
+	for ctx.Err() == nil {
+		started := time.Now()
+		err := m.listen(ctx, logger)
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(started) > time.Minute {
+			backoff = time.Second
+		}
+		m.InvalidateTenants()
+		logger.Warn("tenant registry listener disconnected",
+			slog.Any("error", err),
+			slog.Duration("retry_in", backoff),
+		)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, time.Minute)
+	}
+}
+
+func (m *TenantSessionManager) listen(ctx context.Context, logger *slog.Logger) error {
+	pooled, err := m.tenantPool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("acquire listener connection: %w", err)
+	}
+	// The connection carries a LISTEN, so it is taken out of the pool rather than returned to it.
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{TenantRegistryChannel}.Sanitize()); err != nil {
+		return fmt.Errorf("listen: %w", err)
+	}
+	// Changes made before LISTEN took effect were not notified.
+	m.InvalidateTenants()
+	logger.Info("listening for tenant registry changes", slog.String("channel", TenantRegistryChannel))
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		if tenantID, err := uuid.Parse(notification.Payload); err == nil {
+			m.InvalidateTenant(tenantID)
+		} else {
+			m.InvalidateTenants()
+		}
+	}
+}
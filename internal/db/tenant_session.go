@@ -4,46 +4,69 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+
+	"frego-operations/internal/common"
 )
 
 // TenantSessionManager manages tenant-specific database sessions
 // with shared tenant registry
 type TenantSessionManager struct {
 	tenantPool  *pgxpool.Pool // Connection to shared frego_tenant_db
-	servicePool *pgxpool.Pool // Connection to service-specific DB (frego_finance_db)
+	shards      *ShardPools   // Connections to the service-specific DBs (frego_operations_db, ...)
 	serviceName string        // "finance", "operations", etc.
-	mu          sync.RWMutex
+
+	// mu guards tenants, the cached registry lookups, and generation, which every invalidation
+	// bumps.
+	mu         sync.RWMutex
+	tenants    map[uuid.UUID]tenantEntry
+	generation uint64
+	cacheTTL   time.Duration
+	stats      tenantCacheCounters
 }
 
-// NewTenantSessionManager creates a new tenant session manager with shared registry
+// NewTenantSessionManager creates a new tenant session manager with shared registry, for a
+// service whose tenants all live in one database
 func NewTenantSessionManager(tenantPool, servicePool *pgxpool.Pool, serviceName string) *TenantSessionManager {
+	return NewShardedTenantSessionManager(tenantPool, NewShardPools(servicePool, nil, ShardLimits{}), serviceName)
+}
+
+// NewShardedTenantSessionManager creates a tenant session manager that routes each tenant to the
+// shard the registry assigns it
+func NewShardedTenantSessionManager(tenantPool *pgxpool.Pool, shards *ShardPools, serviceName string) *TenantSessionManager {
 	return &TenantSessionManager{
 		tenantPool:  tenantPool,
-		servicePool: servicePool,
+		shards:      shards,
 		serviceName: serviceName,
+		tenants:     make(map[uuid.UUID]tenantEntry),
+		cacheTTL:    DefaultTenantCacheTTL,
 	}
 }
 
 // GetSession returns a database connection with the tenant schema set
 func (m *TenantSessionManager) GetSession(ctx context.Context, tenantID uuid.UUID) (*pgxpool.Conn, error) {
-	// 1. Query shared tenant registry for schema name
-	schemaName, err := m.getTenantSchema(ctx, tenantID)
+	// 1. Resolve the tenant's schema and shard from the cached registry lookup
+	tenant, err := m.getTenantRoute(ctx, tenantID)
 	if err != nil {
 		return nil, fmt.Errorf("get tenant schema: %w", err)
 	}
 
-	// 2. Get connection to service-specific DB
-	conn, err := m.servicePool.Acquire(ctx)
+	// 2. Get connection to the tenant's service-specific DB
+	pool, err := m.shards.Pool(ctx, tenant.Shard)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := pool.Acquire(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("acquire connection: %w", err)
 	}
 
 	// 3. Set search path to tenant schema
-	_, err = conn.Exec(ctx, fmt.Sprintf("SET search_path TO %s, public", pgx.Identifier{schemaName}.Sanitize()))
+	_, err = conn.Exec(ctx, fmt.Sprintf("SET search_path TO %s, public", pgx.Identifier{tenant.Schema}.Sanitize()))
 	if err != nil {
 		conn.Release()
 		return nil, fmt.Errorf("set search path: %w", err)
@@ -52,47 +75,40 @@ func (m *TenantSessionManager) GetSession(ctx context.Context, tenantID uuid.UUI
 	return conn, nil
 }
 
-// getTenantSchema fetches the schema name from shared tenant registry
-func (m *TenantSessionManager) getTenantSchema(ctx context.Context, tenantID uuid.UUID) (string, error) {
-	var schemaName string
-	var schemaColumn string
-
-	// Determine which schema column to query based on service name
-	switch m.serviceName {
-	case "finance":
-		schemaColumn = "finance_schema"
-	case "operations":
-		schemaColumn = "operations_schema"
-	case "inventory":
-		schemaColumn = "inventory_schema"
-	case "hrms":
-		schemaColumn = "hrms_schema"
-	default:
-		return "", fmt.Errorf("unknown service name: %s", m.serviceName)
-	}
-
-	// Query shared tenant registry
-	query := fmt.Sprintf(`
-		SELECT %s 
-		FROM registry.tenant_registry 
-		WHERE tenant_id = $1 
-		  AND is_active = true
-		  AND $2 = ANY(modules_subscribed)
-	`, schemaColumn)
-
-	err := m.tenantPool.QueryRow(ctx, query, tenantID, m.serviceName).Scan(&schemaName)
+// getTenantRoute returns the tenant's schema and shard for this service, which it must be
+// subscribed to
+func (m *TenantSessionManager) getTenantRoute(ctx context.Context, tenantID uuid.UUID) (tenantRecord, error) {
+	tenant, err := m.lookupTenant(ctx, tenantID)
 	if err != nil {
-		if err == pgx.ErrNoRows {
-			return "", fmt.Errorf("tenant %s not found or not subscribed to %s module", tenantID, m.serviceName)
-		}
-		return "", fmt.Errorf("query tenant registry: %w", err)
+		return tenantRecord{}, err
 	}
-
-	if schemaName == "" {
-		return "", fmt.Errorf("tenant %s has no schema for %s module", tenantID, m.serviceName)
+	if !tenant.Active || !tenant.Subscribed {
+		return tenantRecord{}, fmt.Errorf("%w: tenant %s not found or not subscribed to %s module", ErrTenantNotFound, tenantID, m.serviceName)
+	}
+	if tenant.Schema == "" {
+		return tenantRecord{}, fmt.Errorf("tenant %s has no schema for %s module", tenantID, m.serviceName)
+	}
+	if tenant.MovingTo != "" {
+		return tenantRecord{}, fmt.Errorf("%w: tenant %s is moving to shard %q", ErrTenantMoving, tenantID, tenant.MovingTo)
 	}
+	return tenant, nil
+}
 
-	return schemaName, nil
+// ResolveTenant returns an active tenant's schema for this service, empty when it has none, and
+// its display name. It fails with ErrTenantNotFound for unknown and inactive tenants, and with
+// ErrTenantMoving while the tenant's schema is being moved between shards.
+func (m *TenantSessionManager) ResolveTenant(ctx context.Context, tenantID uuid.UUID) (string, string, error) {
+	tenant, err := m.lookupTenant(ctx, tenantID)
+	if err != nil {
+		return "", "", err
+	}
+	if !tenant.Active {
+		return "", "", fmt.Errorf("%w: tenant %s is inactive", ErrTenantNotFound, tenantID)
+	}
+	if tenant.MovingTo != "" {
+		return "", "", fmt.Errorf("%w: tenant %s is moving to shard %q", ErrTenantMoving, tenantID, tenant.MovingTo)
+	}
+	return tenant.Schema, tenant.Name, nil
 }
 
 // VerifyTenantAccess verifies tenant has access to this service
@@ -164,10 +180,24 @@ type TenantInfo struct {
 
 // WithTenantTx executes a function within a tenant-scoped transaction
 func (m *TenantSessionManager) WithTenantTx(ctx context.Context, fn func(context.Context, pgx.Tx) error) error {
-	tenantID, ok := ctx.Value("tenant_id").(uuid.UUID)
+	return m.withTenantTx(ctx, pgx.TxOptions{}, fn)
+}
+
+// WithTenantReadTx executes a function within a read-only tenant-scoped transaction, so every
+// query it makes reads the same snapshot on one connection.
+func (m *TenantSessionManager) WithTenantReadTx(ctx context.Context, fn func(context.Context, pgx.Tx) error) error {
+	return m.withTenantTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly}, fn)
+}
+
+func (m *TenantSessionManager) withTenantTx(ctx context.Context, opts pgx.TxOptions, fn func(context.Context, pgx.Tx) error) error {
+	id, _, ok := common.TenantFromContext(ctx)
 	if !ok {
 		return fmt.Errorf("tenant ID not found in context")
 	}
+	tenantID, err := uuid.Parse(id)
+	if err != nil {
+		return fmt.Errorf("tenant ID in context is invalid: %w", err)
+	}
 
 	conn, err := m.GetSession(ctx, tenantID)
 	if err != nil {
@@ -175,7 +205,7 @@ func (m *TenantSessionManager) WithTenantTx(ctx context.Context, fn func(context
 	}
 	defer m.ReleaseSession(conn)
 
-	tx, err := conn.Begin(ctx)
+	tx, err := conn.BeginTx(ctx, opts)
 	if err != nil {
 		return fmt.Errorf("begin transaction: %w", err)
 	}
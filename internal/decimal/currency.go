@@ -0,0 +1,31 @@
+package decimal
+
+import "strings"
+
+// defaultMinorUnits is used for the large majority of ISO 4217 currencies and for unknown codes.
+const defaultMinorUnits = 2
+
+// minorUnits lists the ISO 4217 currencies whose minor unit is not 2.
+var minorUnits = map[string]int32{
+	// no minor unit
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0,
+	"PYG": 0, "RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	// three decimals
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	// four decimals
+	"CLF": 4, "UYW": 4,
+}
+
+// MinorUnits returns the number of decimal places of a currency per ISO 4217.
+func MinorUnits(currency string) int32 {
+	if units, ok := minorUnits[strings.ToUpper(strings.TrimSpace(currency))]; ok {
+		return units
+	}
+	return defaultMinorUnits
+}
+
+// RoundCurrency rounds d half away from zero to the minor unit of the currency,
+// e.g. 2 places for EUR, 0 for JPY and 3 for KWD.
+func (d Decimal) RoundCurrency(currency string) Decimal {
+	return d.Round(MinorUnits(currency))
+}
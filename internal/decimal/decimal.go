@@ -0,0 +1,300 @@
+// Package decimal provides an exact base-10 number type for money, rates and quantities.
+//
+// Values are stored as an arbitrary-precision coefficient and a non-negative scale
+// (digits after the decimal point), the same shape as PostgreSQL numeric, so amounts
+// round-trip through the database and JSON without passing through float64.
+package decimal
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// ErrSyntax indicates a string is not a valid decimal number.
+var ErrSyntax = errors.New("decimal: invalid syntax")
+
+// maxExponent bounds the exponent and scale Parse accepts. PostgreSQL numeric columns hold at
+// most 1000 digits of precision; anything further out only makes the arithmetic expensive.
+const maxExponent = 1000
+
+var (
+	bigOne = big.NewInt(1)
+	bigTen = big.NewInt(10)
+)
+
+// Decimal is the exact value coef × 10^-scale. The zero value is 0.
+// Decimals are immutable; every operation returns a new value.
+type Decimal struct {
+	coef  *big.Int
+	scale int32
+}
+
+// Zero is the decimal 0.
+var Zero = Decimal{}
+
+// New returns unscaled × 10^-scale, e.g. New(1250, 2) is 12.50.
+func New(unscaled int64, scale int32) Decimal {
+	return FromExponent(big.NewInt(unscaled), -scale)
+}
+
+// NewFromInt returns the integer v.
+func NewFromInt(v int64) Decimal {
+	return New(v, 0)
+}
+
+// FromExponent returns coef × 10^exp. It accepts the representation used by
+// pgtype.Numeric, where exp may be positive.
+func FromExponent(coef *big.Int, exp int32) Decimal {
+	if coef == nil {
+		return Zero
+	}
+	c := new(big.Int).Set(coef)
+	if exp > 0 {
+		c.Mul(c, pow10(exp))
+		exp = 0
+	}
+	return Decimal{coef: c, scale: -exp}
+}
+
+// Parse reads a decimal in plain or exponent notation, e.g. "-1234.50" or "1.5e3". The exponent
+// and the number of decimal places may not exceed 1000.
+func Parse(s string) (Decimal, error) {
+	raw := strings.TrimSpace(s)
+	mantissa, exponent, hasExponent := raw, "", false
+	if i := strings.IndexAny(raw, "eE"); i >= 0 {
+		mantissa, exponent, hasExponent = raw[:i], raw[i+1:], true
+	}
+
+	sign := ""
+	if mantissa != "" && (mantissa[0] == '-' || mantissa[0] == '+') {
+		sign, mantissa = mantissa[:1], mantissa[1:]
+	}
+	intPart, fracPart, _ := strings.Cut(mantissa, ".")
+	if intPart+fracPart == "" || !isDigits(intPart) || !isDigits(fracPart) {
+		return Zero, fmt.Errorf("%w: %q", ErrSyntax, s)
+	}
+
+	coef, ok := new(big.Int).SetString(sign+intPart+fracPart, 10)
+	if !ok {
+		return Zero, fmt.Errorf("%w: %q", ErrSyntax, s)
+	}
+	exp := -int64(len(fracPart))
+	if hasExponent {
+		e, err := strconv.ParseInt(exponent, 10, 32)
+		if err != nil {
+			return Zero, fmt.Errorf("%w: %q", ErrSyntax, s)
+		}
+		exp += e
+	}
+	if exp < -maxExponent || exp > maxExponent {
+		return Zero, fmt.Errorf("%w: exponent out of range in %q", ErrSyntax, s)
+	}
+	return FromExponent(coef, int32(exp)), nil
+}
+
+// MustParse is like Parse but panics on invalid input. Intended for constants.
+func MustParse(s string) Decimal {
+	d, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// Exponent returns a copy of the coefficient and the exponent such that d = coef × 10^exp.
+func (d Decimal) Exponent() (*big.Int, int32) {
+	return new(big.Int).Set(d.value()), -d.scale
+}
+
+// Scale returns the number of digits after the decimal point.
+func (d Decimal) Scale() int32 {
+	return d.scale
+}
+
+// Sign returns -1, 0 or +1.
+func (d Decimal) Sign() int {
+	return d.value().Sign()
+}
+
+// IsZero reports whether d is 0.
+func (d Decimal) IsZero() bool {
+	return d.Sign() == 0
+}
+
+// Cmp compares d and e and returns -1, 0 or +1. Scale is ignored: 1.5 equals 1.50.
+func (d Decimal) Cmp(e Decimal) int {
+	a, b := align(d, e)
+	return a.Cmp(b)
+}
+
+// Equal reports whether d and e have the same value.
+func (d Decimal) Equal(e Decimal) bool {
+	return d.Cmp(e) == 0
+}
+
+// Add returns d + e.
+func (d Decimal) Add(e Decimal) Decimal {
+	a, b := align(d, e)
+	return Decimal{coef: a.Add(a, b), scale: max(d.scale, e.scale)}
+}
+
+// Sub returns d - e.
+func (d Decimal) Sub(e Decimal) Decimal {
+	a, b := align(d, e)
+	return Decimal{coef: a.Sub(a, b), scale: max(d.scale, e.scale)}
+}
+
+// Mul returns the exact product d × e.
+func (d Decimal) Mul(e Decimal) Decimal {
+	return Decimal{coef: new(big.Int).Mul(d.value(), e.value()), scale: d.scale + e.scale}
+}
+
+// Neg returns -d.
+func (d Decimal) Neg() Decimal {
+	return Decimal{coef: new(big.Int).Neg(d.value()), scale: d.scale}
+}
+
+// Abs returns |d|.
+func (d Decimal) Abs() Decimal {
+	return Decimal{coef: new(big.Int).Abs(d.value()), scale: d.scale}
+}
+
+// Div returns d / e rounded half away from zero to the given number of decimal places.
+// Like math/big, it panics if e is zero.
+func (d Decimal) Div(e Decimal, places int32) Decimal {
+	if e.IsZero() {
+		panic("decimal: division by zero")
+	}
+	places = max(places, 0)
+	// d/e = (cd × 10^(se+places)) / (ce × 10^sd) × 10^-places
+	num := new(big.Int).Mul(d.value(), pow10(e.scale+places))
+	den := new(big.Int).Mul(e.value(), pow10(d.scale))
+	return Decimal{coef: quoRound(num, den), scale: places}
+}
+
+// Round returns d rounded half away from zero to the given number of decimal places,
+// matching PostgreSQL round(numeric, int). The result always has exactly that scale.
+func (d Decimal) Round(places int32) Decimal {
+	places = max(places, 0)
+	switch {
+	case places == d.scale:
+		return d
+	case places > d.scale:
+		return Decimal{coef: new(big.Int).Mul(d.value(), pow10(places-d.scale)), scale: places}
+	default:
+		return Decimal{coef: quoRound(d.value(), pow10(d.scale-places)), scale: places}
+	}
+}
+
+// Float64 returns the nearest float64. Use only for display or float-based formats.
+func (d Decimal) Float64() float64 {
+	f, _ := new(big.Rat).SetFrac(d.value(), pow10(d.scale)).Float64()
+	return f
+}
+
+// String formats d in plain notation keeping its scale, e.g. "-12.50".
+func (d Decimal) String() string {
+	digits := new(big.Int).Abs(d.value()).String()
+	sign := ""
+	if d.Sign() < 0 {
+		sign = "-"
+	}
+	if d.scale == 0 {
+		return sign + digits
+	}
+	if pad := int(d.scale) + 1 - len(digits); pad > 0 {
+		digits = strings.Repeat("0", pad) + digits
+	}
+	point := len(digits) - int(d.scale)
+	return sign + digits[:point] + "." + digits[point:]
+}
+
+// StringFixed formats d rounded to the given number of decimal places.
+func (d Decimal) StringFixed(places int32) string {
+	return d.Round(places).String()
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (d Decimal) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (d *Decimal) UnmarshalText(text []byte) error {
+	v, err := Parse(string(text))
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+// MarshalJSON encodes d as a JSON string so clients never see a binary float.
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(d.String())), nil
+}
+
+// UnmarshalJSON accepts a JSON string or a bare JSON number; null leaves d unchanged.
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	raw := string(data)
+	if raw == "null" {
+		return nil
+	}
+	if unquoted, err := strconv.Unquote(raw); err == nil {
+		raw = unquoted
+	}
+	return d.UnmarshalText([]byte(raw))
+}
+
+func (d Decimal) value() *big.Int {
+	if d.coef == nil {
+		return new(big.Int)
+	}
+	return d.coef
+}
+
+// align returns fresh coefficients of d and e brought to their common scale.
+func align(d, e Decimal) (*big.Int, *big.Int) {
+	a, b := new(big.Int).Set(d.value()), new(big.Int).Set(e.value())
+	switch {
+	case d.scale < e.scale:
+		a.Mul(a, pow10(e.scale-d.scale))
+	case e.scale < d.scale:
+		b.Mul(b, pow10(d.scale-e.scale))
+	}
+	return a, b
+}
+
+// quoRound returns num/den rounded half away from zero.
+func quoRound(num, den *big.Int) *big.Int {
+	q, r := new(big.Int).QuoRem(num, den, new(big.Int))
+	if r.Sign() == 0 {
+		return q
+	}
+	twice := new(big.Int).Abs(r)
+	twice.Lsh(twice, 1)
+	if twice.Cmp(new(big.Int).Abs(den)) >= 0 {
+		if num.Sign()*den.Sign() < 0 {
+			q.Sub(q, bigOne)
+		} else {
+			q.Add(q, bigOne)
+		}
+	}
+	return q
+}
+
+func pow10(n int32) *big.Int {
+	return new(big.Int).Exp(bigTen, big.NewInt(int64(n)), nil)
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
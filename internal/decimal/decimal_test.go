@@ -0,0 +1,178 @@
+package decimal
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "0", want: "0"},
+		{in: "-1234.50", want: "-1234.50"},
+		{in: "+7", want: "7"},
+		{in: " 12.5 ", want: "12.5"},
+		{in: ".5", want: "0.5"},
+		{in: "5.", want: "5"},
+		{in: "1.5e3", want: "1500"},
+		{in: "1.5E-3", want: "0.0015"},
+		{in: "-2e0", want: "-2"},
+		{in: "1e1000", want: "1" + strings.Repeat("0", 1000)},
+		{in: "1e-1000", want: "0." + strings.Repeat("0", 999) + "1"},
+		{in: "", wantErr: true},
+		{in: "-", wantErr: true},
+		{in: ".", wantErr: true},
+		{in: "1.2.3", wantErr: true},
+		{in: "12a", wantErr: true},
+		{in: "1e", wantErr: true},
+		{in: "1e+", wantErr: true},
+		{in: "NaN", wantErr: true},
+		{in: "1e1001", wantErr: true},
+		{in: "1e-1001", wantErr: true},
+		{in: "1e1048576", wantErr: true},
+		{in: "0." + strings.Repeat("1", 1001), wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if tt.wantErr {
+			if !errors.Is(err, ErrSyntax) {
+				t.Errorf("Parse(%q) error = %v, want ErrSyntax", tt.in, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Parse(%q) error = %v", tt.in, err)
+			continue
+		}
+		if got.String() != tt.want {
+			t.Errorf("Parse(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRound(t *testing.T) {
+	tests := []struct {
+		in     string
+		places int32
+		want   string
+	}{
+		{"2.5", 0, "3"},
+		{"-2.5", 0, "-3"},
+		{"2.4999", 0, "2"},
+		{"1.005", 2, "1.01"},
+		{"-1.005", 2, "-1.01"},
+		{"1.004", 2, "1.00"},
+		{"0.05", 1, "0.1"},
+		{"-0.05", 1, "-0.1"},
+		{"1.5", 3, "1.500"},
+		{"123", -1, "123"},
+		{"0", 2, "0.00"},
+	}
+	for _, tt := range tests {
+		got := MustParse(tt.in).Round(tt.places)
+		if got.String() != tt.want {
+			t.Errorf("Round(%s, %d) = %s, want %s", tt.in, tt.places, got, tt.want)
+		}
+	}
+}
+
+func TestDiv(t *testing.T) {
+	tests := []struct {
+		a, b   string
+		places int32
+		want   string
+	}{
+		{"1", "3", 4, "0.3333"},
+		{"2", "3", 4, "0.6667"},
+		{"-2", "3", 4, "-0.6667"},
+		{"10", "4", 0, "3"},
+		{"-10", "4", 0, "-3"},
+		{"1.5", "0.5", 2, "3.00"},
+		{"0.001", "1000", 6, "0.000001"},
+		{"0", "7", 2, "0.00"},
+	}
+	for _, tt := range tests {
+		got := MustParse(tt.a).Div(MustParse(tt.b), tt.places)
+		if got.String() != tt.want {
+			t.Errorf("%s / %s (%d places) = %s, want %s", tt.a, tt.b, tt.places, got, tt.want)
+		}
+	}
+}
+
+func TestDivByZeroPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Div by zero did not panic")
+		}
+	}()
+	NewFromInt(1).Div(Zero, 2)
+}
+
+func TestRoundCurrency(t *testing.T) {
+	tests := []struct {
+		in       string
+		currency string
+		want     string
+	}{
+		{"1234.5", "JPY", "1235"},
+		{"-1234.5", "JPY", "-1235"},
+		{"1234.49", "jpy", "1234"},
+		{"1.2345", "KWD", "1.235"},
+		{"-1.2345", "KWD", "-1.235"},
+		{"1.2", "KWD", "1.200"},
+		{"1.005", "EUR", "1.01"},
+		{"1.005", "", "1.01"},
+		{"1.00005", "CLF", "1.0001"},
+	}
+	for _, tt := range tests {
+		got := MustParse(tt.in).RoundCurrency(tt.currency)
+		if got.String() != tt.want {
+			t.Errorf("RoundCurrency(%s, %q) = %s, want %s", tt.in, tt.currency, got, tt.want)
+		}
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	type payload struct {
+		Amount Decimal  `json:"amount"`
+		Rate   *Decimal `json:"rate,omitempty"`
+	}
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{`{"amount":"12.50"}`, `{"amount":"12.50"}`},
+		{`{"amount":"-0.001","rate":"83.1234"}`, `{"amount":"-0.001","rate":"83.1234"}`},
+		{`{"amount":12.5}`, `{"amount":"12.5"}`},
+		{`{"amount":1e3}`, `{"amount":"1000"}`},
+		{`{"amount":null}`, `{"amount":"0"}`},
+		{`{"amount":"0.30000000000000004"}`, `{"amount":"0.30000000000000004"}`},
+	}
+	for _, tt := range tests {
+		var p payload
+		if err := json.Unmarshal([]byte(tt.in), &p); err != nil {
+			t.Errorf("Unmarshal(%s) error = %v", tt.in, err)
+			continue
+		}
+		out, err := json.Marshal(p)
+		if err != nil {
+			t.Errorf("Marshal(%s) error = %v", tt.in, err)
+			continue
+		}
+		if string(out) != tt.want {
+			t.Errorf("round trip of %s = %s, want %s", tt.in, out, tt.want)
+		}
+	}
+
+	for _, in := range []string{`{"amount":"abc"}`, `{"amount":"1e5000"}`, `{"amount":true}`} {
+		var p payload
+		if err := json.Unmarshal([]byte(in), &p); err == nil {
+			t.Errorf("Unmarshal(%s) succeeded, want an error", in)
+		}
+	}
+}
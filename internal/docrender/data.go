@@ -0,0 +1,143 @@
+package docrender
+
+import (
+	"time"
+
+	"frego-operations/internal/decimal"
+)
+
+// Party is a participant printed on a document.
+type Party struct {
+	Code string
+	Name string
+}
+
+// Job is the job header as seen by templates.
+type Job struct {
+	Code          string
+	Type          string
+	TransportMode string
+	ServiceType   string
+	Incoterm      string
+	Commodity     string
+	Origin        string
+	Destination   string
+	CustomerName  string
+	BranchName    string
+}
+
+// Carrier is the main-carriage leg as seen by templates.
+type Carrier struct {
+	Name                       string
+	VesselName                 string
+	VoyageNumber               string
+	FlightID                   string
+	FlightDate                 *time.Time
+	PortOfLoading              string
+	PortOfDischarge            string
+	TransportDocumentReference string
+	AccountingInfo             string
+	HandlingInfo               string
+}
+
+// Package is one cargo line as seen by templates.
+type Package struct {
+	ContainerNo   string
+	ContainerType string
+	ContainerSize string
+	SealNo        string
+	PackageType   string
+	Count         decimal.Decimal
+	GrossWeightKg decimal.Decimal
+	VolumeM3      decimal.Decimal
+	Description   string
+	HSCode        string
+}
+
+// InvoiceLine is one billed charge as seen by templates. Amounts are preformatted
+// to the invoice currency's minor units.
+type InvoiceLine struct {
+	Description string
+	Activity    string
+	Quantity    string
+	UnitPrice   string
+	TaxCode     string
+	Amount      string
+	TaxAmount   string
+	Total       string
+}
+
+// TaxSummary totals the lines of an invoice that share a tax code.
+type TaxSummary struct {
+	TaxCode string
+	Rate    string
+	Taxable string
+	Tax     string
+}
+
+// Invoice is a customer invoice as seen by templates.
+type Invoice struct {
+	Number   string
+	Status   string
+	Currency string
+	BillTo   Party
+	Lines    []InvoiceLine
+	Taxes    []TaxSummary
+	Subtotal string
+	TaxTotal string
+	Total    string
+}
+
+// DocumentData is the view model every document template is executed against.
+type DocumentData struct {
+	DocNumber        string
+	DocTypeCode      string
+	DocTypeLabel     string
+	IssuedAt         time.Time
+	TenantName       string
+	Job              Job
+	Shipper          Party
+	Consignee        Party
+	NotifyParty      Party
+	OriginAgent      Party
+	DestinationAgent Party
+	Carrier          Carrier
+	ETD              *time.Time
+	ETA              *time.Time
+	Packages         []Package
+	TotalPackages    decimal.Decimal
+	TotalWeightKg    decimal.Decimal
+	TotalVolumeM3    decimal.Decimal
+	Invoice          Invoice
+}
+
+// sampleData is used to trial-execute templates when they are saved, so that references
+// to unknown fields are rejected before anyone tries to print with them.
+func sampleData() DocumentData {
+	now := time.Now().UTC()
+	return DocumentData{
+		DocNumber:    "HBL-000001",
+		DocTypeCode:  "HBL",
+		DocTypeLabel: "House Bill of Lading",
+		IssuedAt:     now,
+		TenantName:   "Sample Forwarding Ltd",
+		Job:          Job{Code: "FRG-000000-0001", Type: "Sea"},
+		Shipper:      Party{Name: "Sample Shipper"},
+		Consignee:    Party{Name: "Sample Consignee"},
+		Carrier:      Carrier{FlightDate: &now},
+		ETD:          &now,
+		ETA:          &now,
+		Packages:     []Package{{Count: decimal.NewFromInt(1), GrossWeightKg: decimal.NewFromInt(1), VolumeM3: decimal.NewFromInt(1)}},
+		Invoice: Invoice{
+			Number:   "INV-2026-000001",
+			Status:   "Draft",
+			Currency: "USD",
+			BillTo:   Party{Name: "Sample Customer"},
+			Lines:    []InvoiceLine{{Description: "Ocean freight", Quantity: "1", UnitPrice: "100.00", TaxCode: "VAT", Amount: "100.00", TaxAmount: "5.00", Total: "105.00"}},
+			Taxes:    []TaxSummary{{TaxCode: "VAT", Rate: "5", Taxable: "100.00", Tax: "5.00"}},
+			Subtotal: "100.00",
+			TaxTotal: "5.00",
+			Total:    "105.00",
+		},
+	}
+}
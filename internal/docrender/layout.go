@@ -0,0 +1,480 @@
+package docrender
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Typography used by the layout engine. Templates control structure, not fonts.
+const (
+	bodySize     = 9.5
+	cellSize     = 8.5
+	lineSpacing  = 1.3
+	cellPadding  = 3.0
+	borderWidth  = 0.5
+	paragraphGap = 4.0
+)
+
+type blockStyle struct {
+	size  float64
+	font  font
+	align string
+	above float64
+	below float64
+}
+
+var blockStyles = map[atom.Atom]blockStyle{
+	atom.H1:      {size: 16, font: fontBold, above: 4, below: 8},
+	atom.H2:      {size: 13, font: fontBold, above: 6, below: 6},
+	atom.H3:      {size: 11, font: fontBold, above: 4, below: 4},
+	atom.P:       {size: bodySize, below: paragraphGap},
+	atom.Li:      {size: bodySize, below: 2},
+	atom.Address: {size: bodySize, below: paragraphGap},
+}
+
+// run is a span of text in a single font; a run of "\n" forces a line break.
+type run struct {
+	text string
+	font font
+}
+
+// segment is a measured piece of a wrapped line.
+type segment struct {
+	text  string
+	font  font
+	width float64
+}
+
+type line struct {
+	segments []segment
+	width    float64
+}
+
+// RenderPDF lays out an HTML document onto A4 pages using the standard Helvetica fonts.
+// It supports headings, paragraphs, line breaks, bold text, horizontal rules and bordered
+// tables with colspan and percentage column widths, which is what printable shipping
+// documents need. Images, CSS beyond text-align/width and floats are ignored.
+func RenderPDF(document []byte) ([]byte, error) {
+	root, err := html.Parse(bytes.NewReader(document))
+	if err != nil {
+		return nil, fmt.Errorf("docrender: parse html: %w", err)
+	}
+
+	r := &renderer{}
+	r.newPage()
+	r.blockChildren(root)
+	return writePDF(r.pages, documentTitle(root)), nil
+}
+
+type renderer struct {
+	pages []*pdfPage
+	page  *pdfPage
+	y     float64
+}
+
+func (r *renderer) newPage() {
+	r.page = &pdfPage{}
+	r.pages = append(r.pages, r.page)
+	r.y = pageMargin
+}
+
+// ensure starts a new page when height points would overflow the bottom margin.
+func (r *renderer) ensure(height float64) {
+	if r.y+height > pageHeight-pageMargin && r.y > pageMargin {
+		r.newPage()
+	}
+}
+
+func (r *renderer) blockChildren(n *html.Node) {
+	var pending []run
+	flush := func() {
+		if hasText(pending) {
+			r.paragraph(pending, blockStyle{size: bodySize, below: paragraphGap}, pageMargin, contentWidth)
+		}
+		pending = nil
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		switch c.Type {
+		case html.TextNode:
+			pending = append(pending, run{text: c.Data})
+			continue
+		case html.ElementNode:
+		default:
+			continue
+		}
+
+		switch c.DataAtom {
+		case atom.Head, atom.Style, atom.Script, atom.Title, atom.Img:
+		case atom.Table:
+			flush()
+			r.table(c)
+		case atom.Hr:
+			flush()
+			r.ensure(8)
+			r.page.line(pageMargin, r.y+4, pageMargin+contentWidth, r.y+4, borderWidth)
+			r.y += 8
+		case atom.Br:
+			pending = append(pending, run{text: "\n"})
+		case atom.H1, atom.H2, atom.H3, atom.P, atom.Li, atom.Address:
+			flush()
+			style := blockStyles[c.DataAtom]
+			style.align = textAlign(c, "")
+			r.paragraph(collectRuns(c, style.font), style, pageMargin, contentWidth)
+		case atom.Html, atom.Body, atom.Div, atom.Section, atom.Header, atom.Footer,
+			atom.Article, atom.Main, atom.Ul, atom.Ol:
+			flush()
+			r.blockChildren(c)
+		default:
+			pending = append(pending, collectRuns(c, fontRegular)...)
+		}
+	}
+	flush()
+}
+
+func (r *renderer) paragraph(runs []run, style blockStyle, x, width float64) {
+	lines := wrap(runs, style.size, width)
+	lineHeight := style.size * lineSpacing
+	r.y += style.above
+	for _, l := range lines {
+		r.ensure(lineHeight)
+		r.drawLine(l, x, width, r.y+style.size, style)
+		r.y += lineHeight
+	}
+	r.y += style.below
+}
+
+func (r *renderer) drawLine(l line, x, width, baseline float64, style blockStyle) {
+	switch style.align {
+	case "right":
+		x += width - l.width
+	case "center":
+		x += (width - l.width) / 2
+	}
+	for _, seg := range l.segments {
+		r.page.text(x, baseline, seg.font, style.size, seg.text)
+		x += seg.width
+	}
+}
+
+type tableCell struct {
+	runs  []run
+	span  int
+	align string
+}
+
+func (r *renderer) table(n *html.Node) {
+	var rows [][]tableCell
+	var widths []float64
+	columns := 0
+
+	var visit func(*html.Node)
+	visit = func(node *html.Node) {
+		for c := node.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			switch c.DataAtom {
+			case atom.Thead, atom.Tbody, atom.Tfoot:
+				visit(c)
+			case atom.Tr:
+				var row []tableCell
+				span := 0
+				for cell := c.FirstChild; cell != nil; cell = cell.NextSibling {
+					if cell.Type != html.ElementNode || (cell.DataAtom != atom.Td && cell.DataAtom != atom.Th) {
+						continue
+					}
+					f := fontRegular
+					if cell.DataAtom == atom.Th {
+						f = fontBold
+					}
+					cs, _ := strconv.Atoi(attr(cell, "colspan"))
+					if cs < 1 {
+						cs = 1
+					}
+					if len(rows) == 0 {
+						w := percentWidth(cell)
+						for i := 0; i < cs; i++ {
+							widths = append(widths, w/float64(cs))
+						}
+					}
+					row = append(row, tableCell{runs: collectRuns(cell, f), span: cs, align: textAlign(cell, textAlign(c, ""))})
+					span += cs
+				}
+				if span > columns {
+					columns = span
+				}
+				rows = append(rows, row)
+			}
+		}
+	}
+	visit(n)
+	if columns == 0 {
+		return
+	}
+
+	colWidths := columnWidths(widths, columns)
+	bordered := attr(n, "border") != "0"
+	lineHeight := cellSize * lineSpacing
+
+	r.y += 2
+	for _, row := range rows {
+		wrapped := make([][]line, len(row))
+		height := lineHeight + 2*cellPadding
+		col := 0
+		for i, cell := range row {
+			w := spanWidth(colWidths, col, cell.span)
+			wrapped[i] = wrap(cell.runs, cellSize, w-2*cellPadding)
+			if h := float64(len(wrapped[i]))*lineHeight + 2*cellPadding; h > height {
+				height = h
+			}
+			col += cell.span
+		}
+
+		r.ensure(height)
+		x := pageMargin
+		col = 0
+		for i, cell := range row {
+			w := spanWidth(colWidths, col, cell.span)
+			if bordered {
+				r.page.rect(x, r.y, w, height, borderWidth)
+			}
+			style := blockStyle{size: cellSize, align: cell.align}
+			for li, l := range wrapped[i] {
+				r.drawLine(l, x+cellPadding, w-2*cellPadding, r.y+cellPadding+float64(li)*lineHeight+cellSize, style)
+			}
+			x += w
+			col += cell.span
+		}
+		r.y += height
+	}
+	r.y += paragraphGap + 2
+}
+
+// columnWidths distributes the content width using first-row percentages where given
+// and sharing the remainder equally between the other columns.
+func columnWidths(percentages []float64, columns int) []float64 {
+	widths := make([]float64, columns)
+	var assigned float64
+	unassigned := 0
+	for i := 0; i < columns; i++ {
+		if i < len(percentages) && percentages[i] > 0 {
+			widths[i] = contentWidth * percentages[i] / 100
+			assigned += widths[i]
+		} else {
+			unassigned++
+		}
+	}
+	if unassigned > 0 {
+		remaining := contentWidth - assigned
+		if remaining < 0 {
+			remaining = 0
+		}
+		for i := range widths {
+			if widths[i] == 0 {
+				widths[i] = remaining / float64(unassigned)
+			}
+		}
+	}
+	return widths
+}
+
+func spanWidth(widths []float64, start, span int) float64 {
+	var w float64
+	for i := start; i < start+span && i < len(widths); i++ {
+		w += widths[i]
+	}
+	return w
+}
+
+// collectRuns flattens inline content, marking bold spans and turning <br> and nested
+// block elements into line breaks.
+func collectRuns(n *html.Node, f font) []run {
+	var runs []run
+	var visit func(*html.Node, font)
+	visit = func(node *html.Node, f font) {
+		for c := node.FirstChild; c != nil; c = c.NextSibling {
+			switch c.Type {
+			case html.TextNode:
+				runs = append(runs, run{text: c.Data, font: f})
+			case html.ElementNode:
+				switch c.DataAtom {
+				case atom.Br:
+					runs = append(runs, run{text: "\n", font: f})
+				case atom.Strong, atom.B, atom.Th:
+					visit(c, fontBold)
+				case atom.Style, atom.Script, atom.Img:
+				case atom.P, atom.Div, atom.Li, atom.H1, atom.H2, atom.H3, atom.Tr:
+					if hasText(runs) {
+						runs = append(runs, run{text: "\n", font: f})
+					}
+					visit(c, f)
+				default:
+					visit(c, f)
+				}
+			}
+		}
+	}
+	visit(n, f)
+	return runs
+}
+
+// wrap collapses whitespace and breaks runs into lines no wider than width.
+func wrap(runs []run, size, width float64) []line {
+	var lines []line
+	var current line
+	pendingSpace := false
+
+	push := func() {
+		lines = append(lines, current)
+		current = line{}
+	}
+	appendWord := func(word string, f font, space bool) {
+		w := textWidth(word, f, size)
+		spaceWidth := 0.0
+		if space && len(current.segments) > 0 {
+			spaceWidth = textWidth(" ", f, size)
+		}
+		if len(current.segments) > 0 && current.width+spaceWidth+w > width {
+			push()
+			spaceWidth = 0
+		}
+		for w > width && len(word) > 1 {
+			// Break words that cannot fit on a line of their own.
+			cut := fitPrefix(word, f, size, width-current.width)
+			if cut == 0 {
+				if len(current.segments) > 0 {
+					push()
+					continue
+				}
+				_, cut = utf8.DecodeRuneInString(word)
+			}
+			current.segments = append(current.segments, segment{text: word[:cut], font: f, width: textWidth(word[:cut], f, size)})
+			push()
+			word = word[cut:]
+			w = textWidth(word, f, size)
+		}
+		text := word
+		if spaceWidth > 0 {
+			text = " " + word
+		}
+		if n := len(current.segments); n > 0 && current.segments[n-1].font == f {
+			current.segments[n-1].text += text
+			current.segments[n-1].width += spaceWidth + w
+		} else {
+			current.segments = append(current.segments, segment{text: text, font: f, width: spaceWidth + w})
+		}
+		current.width += spaceWidth + w
+	}
+
+	for _, rn := range runs {
+		if rn.text == "\n" {
+			push()
+			pendingSpace = false
+			continue
+		}
+		if rn.text != "" && unicode.IsSpace(rune(rn.text[0])) {
+			pendingSpace = true
+		}
+		words := strings.Fields(rn.text)
+		for i, word := range words {
+			appendWord(word, rn.font, pendingSpace || i > 0)
+			pendingSpace = false
+		}
+		if rn.text != "" && unicode.IsSpace(rune(rn.text[len(rn.text)-1])) {
+			pendingSpace = true
+		}
+	}
+	if len(current.segments) > 0 {
+		push()
+	}
+	return lines
+}
+
+// fitPrefix returns the longest byte prefix of word that fits in width, on rune boundaries.
+func fitPrefix(word string, f font, size, width float64) int {
+	cut := 0
+	for i := range word {
+		if i > 0 && textWidth(word[:i], f, size) > width {
+			break
+		}
+		cut = i
+	}
+	if cut == 0 && textWidth(word, f, size) <= width {
+		return len(word)
+	}
+	return cut
+}
+
+func hasText(runs []run) bool {
+	for _, rn := range runs {
+		if strings.TrimSpace(rn.text) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return strings.TrimSpace(a.Val)
+		}
+	}
+	return ""
+}
+
+// styleProperty reads a single declaration from an inline style attribute.
+func styleProperty(n *html.Node, property string) string {
+	for _, decl := range strings.Split(attr(n, "style"), ";") {
+		key, value, ok := strings.Cut(decl, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(key), property) {
+			return strings.ToLower(strings.TrimSpace(value))
+		}
+	}
+	return ""
+}
+
+func textAlign(n *html.Node, fallback string) string {
+	if v := styleProperty(n, "text-align"); v != "" {
+		return v
+	}
+	if v := strings.ToLower(attr(n, "align")); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func percentWidth(n *html.Node) float64 {
+	value := styleProperty(n, "width")
+	if value == "" {
+		value = attr(n, "width")
+	}
+	if !strings.HasSuffix(value, "%") {
+		return 0
+	}
+	pct, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+	if err != nil || pct <= 0 {
+		return 0
+	}
+	return pct
+}
+
+func documentTitle(n *html.Node) string {
+	if n.Type == html.ElementNode && n.DataAtom == atom.Title && n.FirstChild != nil {
+		return strings.TrimSpace(n.FirstChild.Data)
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if t := documentTitle(c); t != "" {
+			return t
+		}
+	}
+	return ""
+}
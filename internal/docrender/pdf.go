@@ -0,0 +1,200 @@
+package docrender
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// A4 page geometry in PDF points.
+const (
+	pageWidth    = 595.28
+	pageHeight   = 841.89
+	pageMargin   = 40.0
+	contentWidth = pageWidth - 2*pageMargin
+)
+
+// font identifies one of the standard Type 1 fonts every PDF reader ships with,
+// so documents need no embedded font programs.
+type font int
+
+const (
+	fontRegular font = iota
+	fontBold
+)
+
+var fontNames = [...]string{fontRegular: "Helvetica", fontBold: "Helvetica-Bold"}
+
+// Glyph widths (1/1000 em) for WinAnsi codes 32-126 from the Adobe core font metrics.
+var fontWidths = [...][95]int{
+	fontRegular: {
+		278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+		1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+		333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+		556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+	},
+	fontBold: {
+		278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+		975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+		333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+		611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+	},
+}
+
+// defaultGlyphWidth is used for WinAnsi codes outside the printable ASCII table.
+const defaultGlyphWidth = 556
+
+// winAnsiSpecials maps the non-Latin-1 characters WinAnsiEncoding places in 0x80-0x9F.
+var winAnsiSpecials = map[rune]byte{
+	'€': 0x80, '‚': 0x82, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87, '‰': 0x89,
+	'‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '™': 0x99,
+}
+
+// encodeWinAnsi converts text to WinAnsi bytes, replacing unsupported characters with '?'.
+func encodeWinAnsi(text string) []byte {
+	out := make([]byte, 0, len(text))
+	for _, r := range text {
+		switch {
+		case r >= 32 && r <= 126, r >= 0xA0 && r <= 0xFF:
+			out = append(out, byte(r))
+		case r == '\t' || r == '\n' || r == '\r':
+			out = append(out, ' ')
+		default:
+			if b, ok := winAnsiSpecials[r]; ok {
+				out = append(out, b)
+			} else {
+				out = append(out, '?')
+			}
+		}
+	}
+	return out
+}
+
+// textWidth measures text in points at the given size.
+func textWidth(text string, f font, size float64) float64 {
+	var units int
+	for _, b := range encodeWinAnsi(text) {
+		if b >= 32 && b <= 126 {
+			units += fontWidths[f][b-32]
+		} else {
+			units += defaultGlyphWidth
+		}
+	}
+	return float64(units) * size / 1000
+}
+
+// pdfPage accumulates the content stream for one page.
+type pdfPage struct {
+	content bytes.Buffer
+}
+
+// text draws a single line of text with its baseline at y measured from the top edge.
+func (p *pdfPage) text(x, y float64, f font, size float64, value string) {
+	fmt.Fprintf(&p.content, "BT /F%d %s Tf %s %s Td (", int(f)+1, num(size), num(x), num(pageHeight-y))
+	for _, b := range encodeWinAnsi(value) {
+		switch b {
+		case '(', ')', '\\':
+			p.content.WriteByte('\\')
+		}
+		p.content.WriteByte(b)
+	}
+	p.content.WriteString(") Tj ET\n")
+}
+
+// line strokes a straight line between two points measured from the top edge.
+func (p *pdfPage) line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(&p.content, "%s w %s %s m %s %s l S\n",
+		num(width), num(x1), num(pageHeight-y1), num(x2), num(pageHeight-y2))
+}
+
+// rect strokes a rectangle whose top-left corner is at (x, y) measured from the top edge.
+func (p *pdfPage) rect(x, y, w, h, width float64) {
+	fmt.Fprintf(&p.content, "%s w %s %s %s %s re S\n",
+		num(width), num(x), num(pageHeight-y-h), num(w), num(h))
+}
+
+// writePDF serialises pages into a PDF 1.4 file using the two standard fonts.
+func writePDF(pages []*pdfPage, title string) []byte {
+	var buf bytes.Buffer
+	var offsets []int
+	startObject := func() int {
+		offsets = append(offsets, buf.Len())
+		id := len(offsets)
+		fmt.Fprintf(&buf, "%d 0 obj\n", id)
+		return id
+	}
+	endObject := func() {
+		buf.WriteString("endobj\n")
+	}
+
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// Object layout: 1 catalog, 2 page tree, 3-4 fonts, 5 info, then a page and content pair per page.
+	const firstPageObject = 6
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPageObject+2*i)
+	}
+
+	startObject()
+	buf.WriteString("<< /Type /Catalog /Pages 2 0 R >>\n")
+	endObject()
+
+	startObject()
+	fmt.Fprintf(&buf, "<< /Type /Pages /Count %d /Kids [%s] >>\n", len(pages), strings.Join(kids, " "))
+	endObject()
+
+	for _, name := range fontNames {
+		startObject()
+		fmt.Fprintf(&buf, "<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>\n", name)
+		endObject()
+	}
+
+	startObject()
+	fmt.Fprintf(&buf, "<< /Title %s /Producer (frego-operations) >>\n", pdfString(title))
+	endObject()
+
+	for _, page := range pages {
+		pageID := startObject()
+		fmt.Fprintf(&buf, "<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>\n",
+			num(pageWidth), num(pageHeight), pageID+1)
+		endObject()
+
+		startObject()
+		fmt.Fprintf(&buf, "<< /Length %d >>\nstream\n", page.content.Len())
+		buf.Write(page.content.Bytes())
+		buf.WriteString("endstream\n")
+		endObject()
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return buf.Bytes()
+}
+
+func pdfString(value string) string {
+	var b strings.Builder
+	b.WriteByte('(')
+	for _, c := range encodeWinAnsi(value) {
+		switch c {
+		case '(', ')', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteByte(c)
+	}
+	b.WriteByte(')')
+	return b.String()
+}
+
+func num(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
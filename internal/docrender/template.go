@@ -0,0 +1,156 @@
+package docrender
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+
+	"frego-operations/internal/decimal"
+)
+
+// Document types that ship with a built-in template. Each must exist in document_type_lu.
+const (
+	DocHouseBL        = "HBL"
+	DocHouseAWB       = "HAWB"
+	DocDeliveryOrder  = "DO"
+	DocArrivalNotice  = "AN"
+	DocInvoice        = "INV"
+	partialsTemplate  = "templates/partials.html"
+	builtinVersion    = 0
+	dateLayout        = "02 Jan 2006"
+	maxTemplateLength = 256 * 1024
+)
+
+var (
+	// ErrInvalidTemplate indicates a template failed to parse or execute against sample data.
+	ErrInvalidTemplate = errors.New("docrender: invalid template")
+	// ErrNoTemplate indicates neither the tenant nor the built-in set has a template for a document type.
+	ErrNoTemplate = errors.New("docrender: no template for document type")
+)
+
+//go:embed templates/*.html
+var builtinTemplates embed.FS
+
+var builtinFiles = map[string]string{
+	DocHouseBL:       "templates/hbl.html",
+	DocHouseAWB:      "templates/hawb.html",
+	DocDeliveryOrder: "templates/do.html",
+	DocArrivalNotice: "templates/an.html",
+	DocInvoice:       "templates/inv.html",
+}
+
+// Template is one version of a document template. Version 0 is the built-in default.
+type Template struct {
+	DocType string
+	Version int32
+	Body    string
+}
+
+// Rendered holds the intermediate HTML alongside the final PDF.
+type Rendered struct {
+	HTML []byte
+	PDF  []byte
+}
+
+var funcs = template.FuncMap{
+	"date":  formatDate,
+	"num":   formatNumber,
+	"upper": strings.ToUpper,
+	"default": func(fallback, value string) string {
+		if strings.TrimSpace(value) == "" {
+			return fallback
+		}
+		return value
+	},
+}
+
+// Builtin returns the default template shipped for a document type.
+func Builtin(docType string) (Template, error) {
+	file, ok := builtinFiles[strings.ToUpper(docType)]
+	if !ok {
+		return Template{}, fmt.Errorf("%w %q", ErrNoTemplate, docType)
+	}
+	body, err := builtinTemplates.ReadFile(file)
+	if err != nil {
+		return Template{}, fmt.Errorf("docrender: read built-in template: %w", err)
+	}
+	return Template{DocType: strings.ToUpper(docType), Version: builtinVersion, Body: string(body)}, nil
+}
+
+// Validate parses a template body and executes it against sample data.
+func Validate(body string) error {
+	if len(body) > maxTemplateLength {
+		return fmt.Errorf("%w: template exceeds %d bytes", ErrInvalidTemplate, maxTemplateLength)
+	}
+	tpl, err := parse(body)
+	if err != nil {
+		return err
+	}
+	if err := tpl.Execute(io.Discard, sampleData()); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+	}
+	return nil
+}
+
+// Render fills a template with document data and lays the resulting HTML out as a PDF.
+func Render(t Template, data DocumentData) (Rendered, error) {
+	tpl, err := parse(t.Body)
+	if err != nil {
+		return Rendered{}, err
+	}
+
+	var out bytes.Buffer
+	if err := tpl.Execute(&out, data); err != nil {
+		return Rendered{}, fmt.Errorf("docrender: execute %s v%d: %w", t.DocType, t.Version, err)
+	}
+
+	pdf, err := RenderPDF(out.Bytes())
+	if err != nil {
+		return Rendered{}, err
+	}
+	return Rendered{HTML: out.Bytes(), PDF: pdf}, nil
+}
+
+// parse compiles a body together with the shared partials so tenant templates can
+// reuse the standard header and party blocks.
+func parse(body string) (*template.Template, error) {
+	partials, err := builtinTemplates.ReadFile(partialsTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("docrender: read partials: %w", err)
+	}
+	tpl, err := template.New("partials").Funcs(funcs).Parse(string(partials))
+	if err != nil {
+		return nil, fmt.Errorf("docrender: parse partials: %w", err)
+	}
+	tpl, err = tpl.New("document").Parse(body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+	}
+	return tpl, nil
+}
+
+func formatDate(value any) string {
+	switch v := value.(type) {
+	case time.Time:
+		if v.IsZero() {
+			return ""
+		}
+		return v.Format(dateLayout)
+	case *time.Time:
+		if v == nil || v.IsZero() {
+			return ""
+		}
+		return v.Format(dateLayout)
+	default:
+		return ""
+	}
+}
+
+func formatNumber(decimals int, value decimal.Decimal) string {
+	return value.StringFixed(int32(decimals))
+}
@@ -4,6 +4,8 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+
+	"frego-operations/internal/decimal"
 )
 
 // ============================================================
@@ -60,6 +62,14 @@ type RoleDetails struct {
 	RoleDesc *string
 }
 
+// RolePermissions lists the operations permissions a tenant grants to a role_details_lu role
+type RolePermissions struct {
+	RoleID      int16
+	RoleName    string
+	RoleDesc    *string
+	Permissions []string
+}
+
 // SalesExecutiveLookup represents a sales executive lookup entry grouped by branch.
 type SalesExecutiveLookup struct {
 	SalesExecID   uuid.UUID
@@ -84,17 +94,25 @@ type BranchLookup struct {
 }
 
 // IncotermLookup represents an available incoterm option.
+// Responsibilities maps each cost segment to the party bearing it: "shipper" or "consignee".
 type IncotermLookup struct {
-	ID      uuid.UUID
-	Code    string
-	Name    string
-	Version int32
+	ID               uuid.UUID
+	Code             string
+	Name             string
+	Version          int32
+	Responsibilities map[string]string
 }
 
 // ActivityLookup represents an activity type/code pair.
+// CostSegment places the activity in the incoterm responsibility matrix; IATAChargeCode,
+// IATAChargeEntitlement and IATARateClass are what an air waybill prints for it.
 type ActivityLookup struct {
-	ActivityType string
-	ActivityCode string
+	ActivityType          string
+	ActivityCode          string
+	CostSegment           *string
+	IATAChargeCode        *string
+	IATAChargeEntitlement *string
+	IATARateClass         *string
 }
 
 // OperationsLookups aggregates all operations-related lookup data
@@ -198,6 +216,9 @@ type JobDetail struct {
 	Billing             []Billing
 	Provisions          []Provision
 	Tracking            *Tracking
+	// ChargeProposal is set by CreateJob to the charge template lines it could not save because
+	// they lack a quantity, rate or party.
+	ChargeProposal *ChargeProposal
 }
 
 // Package represents a job package
@@ -206,15 +227,15 @@ type Package struct {
 	ContainerNo               *string
 	ContainerType             *string
 	ContainerSize             *string
-	GrossWeightKg             *float64
-	NetWeightKg               *float64
-	Volume                    *float64
+	GrossWeightKg             *decimal.Decimal
+	NetWeightKg               *decimal.Decimal
+	Volume                    *decimal.Decimal
 	CarrierSealNo             *string
 	CommodityCargoDescription *string
 	PackageType               *string
 	CargoType                 *string
-	NoOfPackages              *float64
-	ChargeableWeight          *float64
+	NoOfPackages              *decimal.Decimal
+	ChargeableWeight          *decimal.Decimal
 	HSCode                    *string
 	TemperatureControl        bool
 }
@@ -241,6 +262,7 @@ type Carrier struct {
 	DestinationCountry     *string
 	AccountingInfo         *string
 	HandlingInfo           *string
+	ChargesCode            *string
 	TransportDocumentRef   *string
 	SupportingDocURLs      []string
 	FileRegion             *string
@@ -269,18 +291,19 @@ type Billing struct {
 	PONumber              *string
 	PODate                *time.Time
 	CurrencyCode          *string
-	Quantity              *float64
-	UnitPrice             *float64
-	AmountWithoutTax      *float64
+	Quantity              *decimal.Decimal
+	UnitPrice             *decimal.Decimal
+	AmountWithoutTax      *decimal.Decimal
 	TaxCode               *string
-	TaxAmount             *float64
-	ExchangeRate          *float64
-	TotalAmount           *float64
+	TaxAmount             *decimal.Decimal
+	ExchangeRate          *decimal.Decimal
+	TotalAmount           *decimal.Decimal
 	Description           *string
 	Notes                 *string
 	SupportingDocURLs     []string
 	FileRegion            *string
-	AmountPrimaryCurrency *float64
+	AmountPrimaryCurrency *decimal.Decimal
+	InvoiceID             *uuid.UUID
 }
 
 // Provision represents job provision/cost information
@@ -293,21 +316,22 @@ type Provision struct {
 	InvoiceNumber         *string
 	InvoiceDate           *time.Time
 	CurrencyCode          *string
-	Quantity              *float64
-	UnitPrice             *float64
-	AmountWithoutTax      *float64
+	Quantity              *decimal.Decimal
+	UnitPrice             *decimal.Decimal
+	AmountWithoutTax      *decimal.Decimal
 	TaxCode               *string
-	TaxAmount             *float64
-	TotalAmount           *float64
+	TaxAmount             *decimal.Decimal
+	TotalAmount           *decimal.Decimal
 	PONumber              *string
 	PODate                *time.Time
-	ExchangeRate          *float64
+	ExchangeRate          *decimal.Decimal
 	PaymentPriority       *string
 	Notes                 *string
 	SupportingDocURLs     []string
 	FileRegion            *string
-	AmountPrimaryCurrency *float64
-	Profit                *float64
+	AmountPrimaryCurrency *decimal.Decimal
+	Profit                *decimal.Decimal
+	ApprovalStatus        string
 }
 
 // Tracking represents job tracking information
@@ -416,15 +440,15 @@ type PackageInput struct {
 	ContainerNo               *string
 	ContainerType             *string
 	ContainerSize             *string
-	GrossWeightKg             *float64
-	NetWeightKg               *float64
-	Volume                    *float64
+	GrossWeightKg             *decimal.Decimal
+	NetWeightKg               *decimal.Decimal
+	Volume                    *decimal.Decimal
 	CarrierSealNo             *string
 	CommodityCargoDescription *string
 	PackageType               *string
 	CargoType                 *string
-	NoOfPackages              *float64
-	ChargeableWeight          *float64
+	NoOfPackages              *decimal.Decimal
+	ChargeableWeight          *decimal.Decimal
 	HSCode                    *string
 	TemperatureControl        bool
 }
@@ -450,6 +474,7 @@ type CarrierInput struct {
 	DestinationCountry     *string
 	AccountingInfo         *string
 	HandlingInfo           *string
+	ChargesCode            *string
 	TransportDocumentRef   *string
 	SupportingDocURLs      []string
 	FileRegion             *string
@@ -475,18 +500,18 @@ type BillingInput struct {
 	PONumber              *string
 	PODate                *time.Time
 	CurrencyCode          *string
-	Quantity              *float64
-	UnitPrice             *float64
-	AmountWithoutTax      *float64
+	Quantity              *decimal.Decimal
+	UnitPrice             *decimal.Decimal
+	AmountWithoutTax      *decimal.Decimal
 	TaxCode               *string
-	TaxAmount             *float64
-	ExchangeRate          *float64
-	TotalAmount           *float64
+	TaxAmount             *decimal.Decimal
+	ExchangeRate          *decimal.Decimal
+	TotalAmount           *decimal.Decimal
 	Description           *string
 	Notes                 *string
 	SupportingDocURLs     []string
 	FileRegion            *string
-	AmountPrimaryCurrency *float64
+	AmountPrimaryCurrency *decimal.Decimal
 }
 
 // ProvisionInput represents input for creating provision information
@@ -497,21 +522,20 @@ type ProvisionInput struct {
 	InvoiceNumber         *string
 	InvoiceDate           *time.Time
 	CurrencyCode          *string
-	Quantity              *float64
-	UnitPrice             *float64
-	AmountWithoutTax      *float64
+	Quantity              *decimal.Decimal
+	UnitPrice             *decimal.Decimal
+	AmountWithoutTax      *decimal.Decimal
 	TaxCode               *string
-	TaxAmount             *float64
-	TotalAmount           *float64
+	TaxAmount             *decimal.Decimal
+	TotalAmount           *decimal.Decimal
 	PONumber              *string
 	PODate                *time.Time
-	ExchangeRate          *float64
+	ExchangeRate          *decimal.Decimal
 	PaymentPriority       *string
 	Notes                 *string
 	SupportingDocURLs     []string
 	FileRegion            *string
-	AmountPrimaryCurrency *float64
-	Profit                *float64
+	AmountPrimaryCurrency *decimal.Decimal
 }
 
 // TrackingInput represents input for creating/updating tracking information
@@ -525,3 +549,523 @@ type TrackingInput struct {
 	DocumentStatus *string
 	Notes          *string
 }
+
+// ============================================================
+// EXPORT DTOs
+// ============================================================
+
+// ExportFile represents a generated file ready for download.
+type ExportFile struct {
+	FileName    string
+	ContentType string
+	Data        []byte
+}
+
+// EDIExportOptions identifies the interchange partners and wire format for an EDI export.
+type EDIExportOptions struct {
+	SenderID      string
+	RecipientID   string
+	TestIndicator bool
+	Format        string
+}
+
+// ============================================================
+// DOCUMENT TEMPLATE DTOs
+// ============================================================
+
+// DocumentTemplate represents one version of a printable document template
+type DocumentTemplate struct {
+	ID          uuid.UUID
+	DocTypeCode string
+	Version     int32
+	Name        *string
+	BodyHTML    string
+	CreatedAt   *time.Time
+	CreatedBy   *string
+	IsActive    bool
+}
+
+// DocumentTemplateInput represents input for saving a new template version
+type DocumentTemplateInput struct {
+	DocTypeCode string
+	Name        *string
+	BodyHTML    string
+	CreatedBy   string
+}
+
+// GeneratedDocument represents a rendered document attached to a job
+type GeneratedDocument struct {
+	Document        Document
+	TemplateVersion int32
+}
+
+// ============================================================
+// EXCHANGE RATE DTOs
+// ============================================================
+
+// ExchangeRate represents one rate sheet entry: 1 FromCurrency = Rate ToCurrency
+type ExchangeRate struct {
+	ID            uuid.UUID
+	FromCurrency  string
+	ToCurrency    string
+	EffectiveDate time.Time
+	Rate          decimal.Decimal
+	Source        *string
+	CreatedAt     *time.Time
+	CreatedBy     *string
+}
+
+// ExchangeRateQuote is the rate in force for a currency pair on a date
+type ExchangeRateQuote struct {
+	FromCurrency  string
+	ToCurrency    string
+	OnDate        time.Time
+	EffectiveDate time.Time
+	Rate          decimal.Decimal
+	Inverted      bool
+}
+
+// ExchangeRateImport summarises an imported rate sheet
+type ExchangeRateImport struct {
+	Source   string
+	Imported int
+}
+
+// JobRateUpdate reports how many billing and provision lines a rate operation touched
+type JobRateUpdate struct {
+	JobID uuid.UUID
+	Lines int64
+}
+
+// ============================================================
+// CHARGE TEMPLATE DTOs
+// ============================================================
+
+// ChargeTemplate lists the charges that recur for a service combination and incoterm.
+// Nil keys match any value.
+type ChargeTemplate struct {
+	ID                 uuid.UUID
+	Name               string
+	TransportMode      string
+	ServiceType        *string
+	ServiceSubcategory *string
+	IncotermCode       *string
+	Lines              []ChargeTemplateLine
+	CreatedAt          *time.Time
+	CreatedBy          *string
+}
+
+// ChargeTemplateLine is one default billing or provision charge of a template
+type ChargeTemplateLine struct {
+	ID            uuid.UUID
+	LineKind      string
+	ActivityType  string
+	ActivityCode  string
+	QuantityBasis string
+	PartyRole     *string
+	CurrencyCode  *string
+	UnitPrice     *decimal.Decimal
+	TaxCode       *string
+	Description   *string
+	SortOrder     int32
+}
+
+// ChargeTemplateInput represents input for creating a charge template
+type ChargeTemplateInput struct {
+	Name               string
+	TransportMode      string
+	ServiceType        *string
+	ServiceSubcategory *string
+	IncotermCode       *string
+	Lines              []ChargeTemplateLineInput
+	CreatedBy          string
+}
+
+// ChargeTemplateLineInput represents input for one charge template line
+type ChargeTemplateLineInput struct {
+	LineKind      string
+	ActivityType  string
+	ActivityCode  string
+	QuantityBasis string
+	PartyRole     *string
+	CurrencyCode  *string
+	UnitPrice     *decimal.Decimal
+	TaxCode       *string
+	Description   *string
+}
+
+// ChargeProposal holds the billing and provision lines a charge template proposes for a job.
+// Gaps describes proposed lines that still need a quantity, rate or party before they can be saved.
+type ChargeProposal struct {
+	TemplateID   uuid.UUID
+	TemplateName string
+	Billing      []BillingInput
+	Provisions   []ProvisionInput
+	Gaps         []string
+}
+
+// ============================================================
+// INCOTERM DTOs
+// ============================================================
+
+// IncotermBillingCheck compares a job's billing parties with the incoterm responsibility matrix
+type IncotermBillingCheck struct {
+	JobID       uuid.UUID
+	Incoterm    string
+	ShipperID   *uuid.UUID
+	ConsigneeID *uuid.UUID
+	Lines       []IncotermBillingLine
+}
+
+// IncotermBillingLine is the verdict for one billing line. Status is one of ok, mismatch,
+// suggested (no billing party yet), missing_party (the job lacks the bearing party) or
+// unassigned (the activity has no cost segment).
+type IncotermBillingLine struct {
+	BillingID       uuid.UUID
+	ActivityType    *string
+	ActivityCode    *string
+	CostSegment     *string
+	Bearer          *string
+	BillingPartyID  *uuid.UUID
+	ExpectedPartyID *uuid.UUID
+	Status          string
+}
+
+// ============================================================
+// PROFITABILITY DTOs
+// ============================================================
+
+// ProfitabilityFilter narrows a profitability report. Nil fields match every job;
+// the period is half-open on the job's creation time.
+type ProfitabilityFilter struct {
+	JobID            *uuid.UUID
+	BranchID         *uuid.UUID
+	SalesExecutiveID *uuid.UUID
+	CustomerID       *uuid.UUID
+	TransportMode    *string
+	PeriodFrom       *time.Time
+	PeriodTo         *time.Time
+	Limit            int32
+}
+
+// ProfitabilityFigures holds revenue and cost in the primary currency and what follows from them.
+// MarginPercent is nil when there is no revenue to measure against.
+type ProfitabilityFigures struct {
+	Revenue       decimal.Decimal
+	Cost          decimal.Decimal
+	GrossProfit   decimal.Decimal
+	MarginPercent *decimal.Decimal
+	Loss          bool
+}
+
+// ActivityProfitability is the profitability of one activity across the filtered jobs
+type ActivityProfitability struct {
+	ActivityType *string
+	ActivityCode *string
+	JobCount     int32
+	ProfitabilityFigures
+}
+
+// JobProfitability is the profitability of a single job
+type JobProfitability struct {
+	JobID              uuid.UUID
+	JobCode            string
+	JobType            *string
+	TransportMode      *string
+	Status             *string
+	CustomerID         *uuid.UUID
+	CustomerName       *string
+	BranchID           *uuid.UUID
+	BranchName         *string
+	SalesExecutiveID   *uuid.UUID
+	SalesExecutiveName *string
+	CreatedAt          *time.Time
+	Currency           string
+	ProfitabilityFigures
+	Activities []ActivityProfitability
+}
+
+// ProfitabilityReport summarises profitability across the jobs matching a filter
+type ProfitabilityReport struct {
+	Currency   string
+	Totals     ProfitabilityFigures
+	Jobs       []JobProfitability
+	Activities []ActivityProfitability
+}
+
+// ============================================================
+// INVOICE DTOs
+// ============================================================
+
+// Invoice is a customer invoice drafted from a job's billing lines for one party and currency
+type Invoice struct {
+	ID               uuid.UUID
+	InvoiceNumber    string
+	JobID            uuid.UUID
+	JobCode          string
+	BillingPartyID   *uuid.UUID
+	BillingPartyName *string
+	CurrencyCode     string
+	Status           string
+	Subtotal         decimal.Decimal
+	TaxTotal         decimal.Decimal
+	Total            decimal.Decimal
+	Lines            []InvoiceLine
+	Taxes            []InvoiceTaxSummary
+	CreatedAt        *time.Time
+	CreatedBy        *string
+}
+
+// InvoiceLine is a billing line as it appears on an invoice
+type InvoiceLine struct {
+	BillingID        uuid.UUID
+	ActivityType     *string
+	ActivityCode     *string
+	Description      *string
+	Quantity         *decimal.Decimal
+	UnitPrice        *decimal.Decimal
+	TaxCode          *string
+	AmountWithoutTax decimal.Decimal
+	TaxAmount        decimal.Decimal
+	TotalAmount      decimal.Decimal
+}
+
+// InvoiceTaxSummary totals an invoice's lines per tax code
+type InvoiceTaxSummary struct {
+	TaxCode     string
+	RatePercent *decimal.Decimal
+	Taxable     decimal.Decimal
+	Tax         decimal.Decimal
+}
+
+// DraftInvoices is the outcome of drafting a job's invoices. Skipped lists billing lines
+// left uninvoiced because they have no billing party or currency.
+type DraftInvoices struct {
+	Invoices []Invoice
+	Skipped  []uuid.UUID
+}
+
+// ============================================================
+// ACCRUAL RECONCILIATION DTOs
+// ============================================================
+
+// AccrualTolerance is the tenant's rule for accepting supplier invoice variances without approval.
+// Amount is in Currency, the tenant's primary currency. A nil tolerance is not applied.
+type AccrualTolerance struct {
+	Currency string
+	Percent  *decimal.Decimal
+	Amount   *decimal.Decimal
+}
+
+// AccrualToleranceInput replaces the tenant's accrual tolerance
+type AccrualToleranceInput struct {
+	Percent   *decimal.Decimal
+	Amount    *decimal.Decimal
+	UpdatedBy string
+}
+
+// SupplierInvoiceInput records a supplier invoice against a provision. CurrencyCode defaults
+// to the provision's currency.
+type SupplierInvoiceInput struct {
+	InvoiceNumber    string
+	InvoiceDate      *time.Time
+	CurrencyCode     *string
+	AmountWithoutTax decimal.Decimal
+	TaxAmount        *decimal.Decimal
+	TotalAmount      *decimal.Decimal
+	Notes            *string
+	RecordedBy       string
+}
+
+// ProvisionReconciliation compares a supplier invoice with the provision it settles.
+// Variances are in the provision's currency unless stated otherwise.
+type ProvisionReconciliation struct {
+	ID                      uuid.UUID
+	ProvisionID             uuid.UUID
+	JobID                   uuid.UUID
+	JobCode                 *string
+	ActivityType            *string
+	ActivityCode            *string
+	CostPartyID             *uuid.UUID
+	CostPartyName           *string
+	SupplierInvoiceNumber   string
+	SupplierInvoiceDate     *time.Time
+	CurrencyCode            string
+	AmountWithoutTax        decimal.Decimal
+	TaxAmount               *decimal.Decimal
+	TotalAmount             *decimal.Decimal
+	ExchangeRate            *decimal.Decimal
+	ProvisionCurrency       *string
+	ExpectedAmount          decimal.Decimal
+	Variance                decimal.Decimal
+	VariancePercent         *decimal.Decimal
+	VariancePrimaryCurrency *decimal.Decimal
+	Status                  string
+	Notes                   *string
+	CreatedAt               *time.Time
+	CreatedBy               *string
+}
+
+// OpenAccrualFilter narrows the open accruals report
+type OpenAccrualFilter struct {
+	CostPartyID *uuid.UUID
+	MinAgeDays  *int32
+	Limit       int32
+}
+
+// OpenAccrual is a provision with no supplier invoice recorded against it
+type OpenAccrual struct {
+	ProvisionID           uuid.UUID
+	JobID                 *uuid.UUID
+	JobCode               string
+	ActivityType          *string
+	ActivityCode          *string
+	CostPartyID           *uuid.UUID
+	CostPartyName         *string
+	CurrencyCode          *string
+	AmountWithoutTax      *decimal.Decimal
+	AmountPrimaryCurrency *decimal.Decimal
+	PONumber              *string
+	CreatedAt             *time.Time
+	AgeDays               int32
+}
+
+// VendorAccrualAging totals one vendor's open accruals in the primary currency by age bucket.
+// Unpriced counts accruals without a primary-currency amount, which are left out of the totals.
+type VendorAccrualAging struct {
+	CostPartyID   *uuid.UUID
+	CostPartyName *string
+	Count         int
+	Unpriced      int
+	Days0To30     decimal.Decimal
+	Days31To60    decimal.Decimal
+	Days61To90    decimal.Decimal
+	Over90Days    decimal.Decimal
+	Total         decimal.Decimal
+}
+
+// OpenAccrualReport lists open accruals oldest first and ages them per vendor
+type OpenAccrualReport struct {
+	Currency string
+	Accruals []OpenAccrual
+	Vendors  []VendorAccrualAging
+}
+
+// ProvisionApprovalRule sends matching provisions to an approver role. Every condition that is
+// set must hold for the rule to match.
+type ProvisionApprovalRule struct {
+	ID              uuid.UUID
+	Name            string
+	CurrencyCode    *string
+	MinAmount       *decimal.Decimal
+	PaymentPriority *string
+	MinVendorRisk   *string
+	ApproverRole    string
+	CreatedAt       *time.Time
+	CreatedBy       *string
+}
+
+// ProvisionApprovalRuleInput defines a new approval rule
+type ProvisionApprovalRuleInput struct {
+	Name            string
+	CurrencyCode    *string
+	MinAmount       *decimal.Decimal
+	PaymentPriority *string
+	MinVendorRisk   *string
+	ApproverRole    *string
+	CreatedBy       string
+}
+
+// Approval is an approval request on a provision or a supplier invoice reconciliation
+type Approval struct {
+	ID              uuid.UUID
+	SubjectType     string
+	SubjectID       uuid.UUID
+	JobID           uuid.UUID
+	JobCode         *string
+	RuleID          *uuid.UUID
+	RequiredRole    string
+	Reason          string
+	Status          string
+	ProvisionID     *uuid.UUID
+	ActivityType    *string
+	ActivityCode    *string
+	CostPartyID     *uuid.UUID
+	CostPartyName   *string
+	CurrencyCode    *string
+	TotalAmount     *decimal.Decimal
+	PaymentPriority *string
+	Variance        *decimal.Decimal
+	VariancePercent *decimal.Decimal
+	RequestedAt     *time.Time
+	RequestedBy     *string
+	DecidedAt       *time.Time
+	DecidedBy       *string
+	Comment         *string
+}
+
+// JobStatusChange is one entry of a job's status history
+type JobStatusChange struct {
+	ID         uuid.UUID
+	JobID      uuid.UUID
+	FromStatus *string
+	ToStatus   *string
+	Reason     *string
+	ChangedAt  *time.Time
+	ChangedBy  *string
+}
+
+// CustomerCredit is a customer's credit exposure against their limit, in the primary currency.
+// Exposure is open billed plus unbilled amounts on the customer's active jobs.
+type CustomerCredit struct {
+	PartyID     uuid.UUID
+	Currency    string
+	CreditLimit *decimal.Decimal
+	OpenBilled  decimal.Decimal
+	Unbilled    decimal.Decimal
+	Exposure    decimal.Decimal
+	Available   *decimal.Decimal
+}
+
+// CreditOverride audits a job created or activated past the customer's credit limit
+type CreditOverride struct {
+	ID           uuid.UUID
+	JobID        uuid.UUID
+	JobCode      string
+	PartyID      uuid.UUID
+	CreditLimit  decimal.Decimal
+	Exposure     decimal.Decimal
+	Currency     string
+	OverriddenAt *time.Time
+	OverriddenBy *string
+}
+
+// PartyScreening is the result of screening one job party against one denied-party list: Clear,
+// or a potential match awaiting review, with the list version it was made against
+type PartyScreening struct {
+	ID             uuid.UUID
+	JobID          uuid.UUID
+	PartyID        uuid.UUID
+	PartyRole      string
+	PartyName      string
+	ListName       string
+	ListVersion    string
+	EntryID        *string
+	EntryName      *string
+	MatchedName    *string
+	Program        *string
+	Score          *decimal.Decimal
+	Status         string
+	ScreenedAt     *time.Time
+	ScreenedBy     *string
+	ResolvedAt     *time.Time
+	ResolvedBy     *string
+	ResolutionNote *string
+}
+
+// ScreeningList describes a loaded denied-party list
+type ScreeningList struct {
+	Name    string
+	Version string
+	Entries int
+}
@@ -33,3 +33,36 @@ type CreateTenantResponse struct {
 	SchemaName string    `json:"schema_name"`
 	Message    string    `json:"message"`
 }
+
+// APIKey is a tenant API key as shown to admins; the secret is never returned after issue.
+type APIKey struct {
+	ID          uuid.UUID
+	Name        string
+	Prefix      string
+	Scopes      []string
+	BranchIDs   []uuid.UUID
+	AllBranches bool
+	ExpiresAt   *time.Time
+	LastUsedAt  *time.Time
+	ReplacedBy  *uuid.UUID
+	CreatedAt   time.Time
+	CreatedBy   *string
+	RevokedAt   *time.Time
+	RevokedBy   *string
+}
+
+// APIKeyInput issues a new API key. A nil ExpiresAt issues a key that does not expire. The key
+// sees the jobs of BranchIDs, or of every branch when AllBranches is set; one of them is required.
+type APIKeyInput struct {
+	Name        string
+	Scopes      []string
+	BranchIDs   []uuid.UUID
+	AllBranches bool
+	ExpiresAt   *time.Time
+}
+
+// IssuedAPIKey is a newly issued key together with its secret, which is shown only once.
+type IssuedAPIKey struct {
+	APIKey
+	Key string
+}
@@ -0,0 +1,407 @@
+package edifact
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"frego-operations/internal/decimal"
+)
+
+// Party roles used in NAD segments of a booking request.
+const (
+	PartyShipper     = "CZ"
+	PartyConsignee   = "CN"
+	PartyNotify      = "N1"
+	PartyCarrier     = "CA"
+	PartyForwarder   = "FW"
+	maxNameComponent = 35
+)
+
+// Decimal places of the quantities printed in CNT, GID and MEA. Weights and volumes go down to
+// grams and litres; package counts are whole.
+const (
+	weightPlaces = 3
+	volumePlaces = 3
+	countPlaces  = 0
+)
+
+// ErrMissingBookingData indicates the job lacks data required to build a booking.
+var ErrMissingBookingData = errors.New("edifact: booking data incomplete")
+
+// Envelope identifies the interchange partners for the UNB/UNZ service segments.
+type Envelope struct {
+	SenderID       string
+	RecipientID    string
+	ControlRef     string
+	PreparedAt     time.Time
+	TestIndicator  bool
+	SyntaxVersion  string
+	SenderQual     string
+	RecipientQual  string
+	ApplicationRef string
+}
+
+// BookingParty is a participant printed in a NAD segment.
+type BookingParty struct {
+	Role string
+	Code string
+	Name string
+}
+
+// BookingLeg is the main-carriage leg of the booking.
+type BookingLeg struct {
+	CarrierCode     string
+	CarrierName     string
+	VesselName      string
+	VoyageNumber    string
+	PortOfLoading   string
+	PortOfDischarge string
+	DepartureDate   *time.Time
+	ArrivalDate     *time.Time
+}
+
+// BookingPackage is one goods/equipment line taken from ops_package.
+type BookingPackage struct {
+	ContainerNo   string
+	ContainerType string
+	ContainerSize string
+	SealNo        string
+	PackageType   string
+	PackageCount  *decimal.Decimal
+	GrossWeightKg *decimal.Decimal
+	VolumeM3      *decimal.Decimal
+	Description   string
+	HSCode        string
+}
+
+// Booking carries the job data used to build an IFTMIN booking request.
+type Booking struct {
+	Reference   string
+	JobCode     string
+	IssuedAt    time.Time
+	Commodity   string
+	Incoterm    string
+	Leg         BookingLeg
+	Parties     []BookingParty
+	Packages    []BookingPackage
+	Description string
+}
+
+// Interchange is a rendered and validated EDIFACT interchange.
+type Interchange struct {
+	Segments []Segment
+	Payload  []byte
+}
+
+// BuildIFTMIN renders a D.99B IFTMIN booking request and validates it against the segment rules.
+func BuildIFTMIN(b Booking, env Envelope) (Interchange, error) {
+	if err := checkBooking(b); err != nil {
+		return Interchange{}, err
+	}
+
+	messageRef := messageReference(b.Reference)
+	body := buildIFTMINBody(b, messageRef)
+
+	if problems := validateSegments(body, iftminRules); len(problems) > 0 {
+		return Interchange{}, &ValidationError{Problems: problems}
+	}
+
+	unb, unz := envelopeSegments(env, 1)
+
+	var out strings.Builder
+	out.WriteString(serviceStringAdvice)
+	out.WriteString(unb.String())
+	for _, seg := range body {
+		out.WriteString(seg.String())
+	}
+	out.WriteString(unz.String())
+
+	segments := make([]Segment, 0, len(body)+2)
+	segments = append(segments, unb)
+	segments = append(segments, body...)
+	segments = append(segments, unz)
+
+	return Interchange{
+		Segments: segments,
+		Payload:  []byte(out.String()),
+	}, nil
+}
+
+func checkBooking(b Booking) error {
+	var missing []string
+	if strings.TrimSpace(b.Reference) == "" {
+		missing = append(missing, "booking reference")
+	}
+	if !hasParty(b.Parties, PartyShipper) {
+		missing = append(missing, "shipper")
+	}
+	if !hasParty(b.Parties, PartyConsignee) {
+		missing = append(missing, "consignee")
+	}
+	if strings.TrimSpace(b.Leg.PortOfLoading) == "" {
+		missing = append(missing, "port of loading")
+	}
+	if strings.TrimSpace(b.Leg.PortOfDischarge) == "" {
+		missing = append(missing, "port of discharge")
+	}
+	if len(b.Packages) == 0 {
+		missing = append(missing, "packages")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: missing %s", ErrMissingBookingData, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func hasParty(parties []BookingParty, role string) bool {
+	for _, p := range parties {
+		if p.Role == role && strings.TrimSpace(p.Name) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func buildIFTMINBody(b Booking, messageRef string) []Segment {
+	issued := b.IssuedAt
+	if issued.IsZero() {
+		issued = time.Now().UTC()
+	}
+
+	segs := []Segment{
+		Seg("UNH", messageRef, []string{"IFTMIN", "D", "99B", "UN"}),
+		Seg("BGM", "335", truncate(b.Reference, 35), "9"),
+		Seg("DTM", []string{"137", issued.Format("20060102"), "102"}),
+	}
+
+	if desc := strings.TrimSpace(firstNonEmpty(b.Description, b.Commodity)); desc != "" {
+		segs = append(segs, Seg("FTX", "AAA", nil, nil, splitText(desc, 512, 5)))
+	}
+	if incoterm := strings.TrimSpace(b.Incoterm); incoterm != "" {
+		segs = append(segs, Seg("FTX", "AAI", nil, nil, []string{"INCOTERM " + incoterm}))
+	}
+
+	var totalWeight decimal.Decimal
+	var hasWeight bool
+	for _, pkg := range b.Packages {
+		if pkg.GrossWeightKg != nil {
+			totalWeight = totalWeight.Add(*pkg.GrossWeightKg)
+			hasWeight = true
+		}
+	}
+	if hasWeight {
+		segs = append(segs, Seg("CNT", []string{"7", formatDecimal(totalWeight, weightPlaces), "KGM"}))
+	}
+	containers := countContainers(b.Packages)
+	if containers > 0 {
+		segs = append(segs, Seg("CNT", []string{"16", strconv.Itoa(containers)}))
+	}
+
+	if b.JobCode != "" {
+		segs = append(segs, Seg("RFF", []string{"FF", truncate(b.JobCode, 35)}))
+	}
+
+	// Main carriage (SG5) with ports (SG6) and dates.
+	carrier := []string{truncate(b.Leg.CarrierCode, 17), "", "", truncate(b.Leg.CarrierName, 35)}
+	transportID := []string{"", "", "", truncate(b.Leg.VesselName, 35)}
+	segs = append(segs, Seg("TDT", "20", truncate(b.Leg.VoyageNumber, 17), "1", nil, carrier, nil, nil, transportID))
+	segs = append(segs, Seg("LOC", "9", []string{truncate(b.Leg.PortOfLoading, 25)}))
+	if b.Leg.DepartureDate != nil {
+		segs = append(segs, Seg("DTM", []string{"133", b.Leg.DepartureDate.UTC().Format("20060102"), "102"}))
+	}
+	segs = append(segs, Seg("LOC", "11", []string{truncate(b.Leg.PortOfDischarge, 25)}))
+	if b.Leg.ArrivalDate != nil {
+		segs = append(segs, Seg("DTM", []string{"132", b.Leg.ArrivalDate.UTC().Format("20060102"), "102"}))
+	}
+
+	// Parties (SG11).
+	for _, role := range []string{PartyShipper, PartyConsignee, PartyNotify, PartyCarrier, PartyForwarder} {
+		for _, p := range b.Parties {
+			if p.Role != role || strings.TrimSpace(p.Name) == "" {
+				continue
+			}
+			var id []string
+			if p.Code != "" {
+				id = []string{truncate(p.Code, 35), "", "ZZZ"}
+			}
+			segs = append(segs, Seg("NAD", p.Role, id, nil, splitText(p.Name, maxNameComponent, 5)))
+		}
+	}
+
+	// Goods items (SG18) with equipment split (SGP).
+	for idx, pkg := range b.Packages {
+		var packages []string
+		if pkg.PackageCount != nil {
+			packages = []string{formatDecimal(*pkg.PackageCount, countPlaces), truncate(pkg.PackageType, 17)}
+		}
+		segs = append(segs, Seg("GID", strconv.Itoa(idx+1), packages))
+		if pkg.HSCode != "" {
+			segs = append(segs, Seg("PIA", "5", []string{truncate(pkg.HSCode, 35), "HS"}))
+		}
+		if desc := strings.TrimSpace(pkg.Description); desc != "" {
+			segs = append(segs, Seg("FTX", "AAA", nil, nil, splitText(desc, 512, 5)))
+		}
+		if pkg.GrossWeightKg != nil {
+			segs = append(segs, Seg("MEA", "AAE", "G", []string{"KGM", formatDecimal(*pkg.GrossWeightKg, weightPlaces)}))
+		}
+		if pkg.VolumeM3 != nil {
+			segs = append(segs, Seg("MEA", "AAE", "AAW", []string{"MTQ", formatDecimal(*pkg.VolumeM3, volumePlaces)}))
+		}
+		if pkg.ContainerNo != "" {
+			segs = append(segs, Seg("SGP", truncate(pkg.ContainerNo, 17)))
+		}
+	}
+
+	// Equipment details (SG37) with seals.
+	for _, pkg := range b.Packages {
+		if pkg.ContainerNo == "" {
+			continue
+		}
+		segs = append(segs, Seg("EQD", "CN", truncate(pkg.ContainerNo, 17), []string{ISOSizeType(pkg.ContainerSize, pkg.ContainerType), "6346", "5"}, nil, nil, "5"))
+		if pkg.SealNo != "" {
+			segs = append(segs, Seg("SEL", truncate(pkg.SealNo, 10), "CA"))
+		}
+	}
+
+	// UNT counts every segment from UNH to UNT inclusive.
+	segs = append(segs, Seg("UNT", strconv.Itoa(len(segs)+1), messageRef))
+	return segs
+}
+
+func envelopeSegments(env Envelope, messageCount int) (Segment, Segment) {
+	prepared := env.PreparedAt
+	if prepared.IsZero() {
+		prepared = time.Now().UTC()
+	}
+	syntax := firstNonEmpty(env.SyntaxVersion, "3")
+	controlRef := firstNonEmpty(env.ControlRef, prepared.Format("060102150405"))
+	controlRef = truncate(controlRef, 14)
+
+	sender := []string{truncate(env.SenderID, 35)}
+	if env.SenderQual != "" {
+		sender = append(sender, env.SenderQual)
+	}
+	recipient := []string{truncate(env.RecipientID, 35)}
+	if env.RecipientQual != "" {
+		recipient = append(recipient, env.RecipientQual)
+	}
+
+	elements := []any{
+		[]string{"UNOC", syntax},
+		sender,
+		recipient,
+		[]string{prepared.Format("060102"), prepared.Format("1504")},
+		controlRef,
+	}
+	if env.ApplicationRef != "" || env.TestIndicator {
+		elements = append(elements, nil, env.ApplicationRef, nil, nil, nil)
+		if env.TestIndicator {
+			elements = append(elements, "1")
+		}
+	}
+
+	unb := Seg("UNB", elements...)
+	unz := Seg("UNZ", strconv.Itoa(messageCount), controlRef)
+	return unb, unz
+}
+
+// ISOSizeType maps the job's container size and type to an ISO 6346 size-type code.
+func ISOSizeType(size, kind string) string {
+	size = strings.ToUpper(strings.TrimSpace(size))
+	kind = strings.ToUpper(strings.TrimSpace(kind))
+	size = strings.TrimSuffix(strings.TrimSuffix(size, "FT"), "'")
+
+	switch {
+	case size == "20" && (kind == "" || kind == "GP" || kind == "DRY" || kind == "DV"):
+		return "22G1"
+	case size == "40" && (kind == "" || kind == "GP" || kind == "DRY" || kind == "DV"):
+		return "42G1"
+	case size == "40" && (kind == "HC" || kind == "HQ" || kind == "HIGH CUBE"):
+		return "45G1"
+	case size == "45" && (kind == "HC" || kind == "HQ" || kind == "HIGH CUBE"):
+		return "L5G1"
+	case size == "20" && (kind == "RF" || kind == "REEFER"):
+		return "22R1"
+	case size == "40" && (kind == "RF" || kind == "REEFER" || kind == "RH"):
+		return "45R1"
+	case size == "20" && (kind == "OT" || kind == "OPEN TOP"):
+		return "22U1"
+	case size == "40" && (kind == "OT" || kind == "OPEN TOP"):
+		return "42U1"
+	case size == "20" && (kind == "FR" || kind == "FLAT RACK"):
+		return "22P1"
+	case size == "40" && (kind == "FR" || kind == "FLAT RACK"):
+		return "42P1"
+	case size == "20" && kind == "TK":
+		return "22T1"
+	}
+	return truncate(size+kind, 10)
+}
+
+func countContainers(pkgs []BookingPackage) int {
+	seen := make(map[string]struct{})
+	for _, p := range pkgs {
+		if p.ContainerNo == "" {
+			continue
+		}
+		seen[p.ContainerNo] = struct{}{}
+	}
+	return len(seen)
+}
+
+func messageReference(ref string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(ref) {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		}
+	}
+	out := b.String()
+	if len(out) > 14 {
+		out = out[len(out)-14:]
+	}
+	if out == "" {
+		out = "1"
+	}
+	return out
+}
+
+// formatDecimal prints v with a fixed number of decimal places, rounded half away from zero.
+func formatDecimal(v decimal.Decimal, places int32) string {
+	return v.StringFixed(places)
+}
+
+func splitText(text string, width, maxParts int) []string {
+	runes := []rune(strings.TrimSpace(text))
+	var parts []string
+	for len(runes) > 0 && len(parts) < maxParts {
+		end := width
+		if end > len(runes) {
+			end = len(runes)
+		}
+		parts = append(parts, string(runes[:end]))
+		runes = runes[end:]
+	}
+	return parts
+}
+
+func truncate(value string, max int) string {
+	value = strings.TrimSpace(value)
+	runes := []rune(value)
+	if len(runes) <= max {
+		return value
+	}
+	return string(runes[:max])
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
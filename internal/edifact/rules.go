@@ -0,0 +1,213 @@
+package edifact
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrInvalidMessage indicates a message failed segment rule validation.
+var ErrInvalidMessage = errors.New("edifact: message failed validation")
+
+// ValidationError collects every rule violation found in a message.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrInvalidMessage, strings.Join(e.Problems, "; "))
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ErrInvalidMessage
+}
+
+// componentRule describes a single component of a data element.
+type componentRule struct {
+	Required bool
+	MaxLen   int
+	Numeric  bool
+	Codes    []string
+}
+
+// elementRule describes a simple or composite data element.
+type elementRule struct {
+	Name       string
+	Required   bool
+	Components []componentRule
+}
+
+// segmentRule describes the layout of one segment tag.
+type segmentRule struct {
+	Elements []elementRule
+}
+
+func an(maxLen int) componentRule    { return componentRule{MaxLen: maxLen} }
+func anReq(maxLen int) componentRule { return componentRule{Required: true, MaxLen: maxLen} }
+func n(maxLen int) componentRule     { return componentRule{MaxLen: maxLen, Numeric: true} }
+func nReq(maxLen int) componentRule {
+	return componentRule{Required: true, MaxLen: maxLen, Numeric: true}
+}
+func code(maxLen int, codes ...string) componentRule {
+	return componentRule{Required: true, MaxLen: maxLen, Codes: codes}
+}
+
+// iftminRules is the subset of the D.99B IFTMIN directory used by the booking export.
+var iftminRules = map[string]segmentRule{
+	"UNH": {Elements: []elementRule{
+		{Name: "message reference", Required: true, Components: []componentRule{anReq(14)}},
+		{Name: "message identifier", Required: true, Components: []componentRule{
+			code(6, "IFTMIN"), code(3, "D"), code(3, "99B"), code(2, "UN"),
+		}},
+	}},
+	"BGM": {Elements: []elementRule{
+		{Name: "document name", Required: true, Components: []componentRule{code(3, "335", "610")}},
+		{Name: "document identification", Required: true, Components: []componentRule{anReq(35)}},
+		{Name: "message function", Required: true, Components: []componentRule{code(3, "1", "5", "9")}},
+	}},
+	"DTM": {Elements: []elementRule{
+		{Name: "date/time/period", Required: true, Components: []componentRule{anReq(3), anReq(35), code(3, "102", "203")}},
+	}},
+	"FTX": {Elements: []elementRule{
+		{Name: "text subject qualifier", Required: true, Components: []componentRule{anReq(3)}},
+		{Name: "free text function", Components: []componentRule{an(3)}},
+		{Name: "text reference", Components: []componentRule{an(17)}},
+		{Name: "text literal", Required: true, Components: []componentRule{anReq(512), an(512), an(512), an(512), an(512)}},
+	}},
+	"CNT": {Elements: []elementRule{
+		{Name: "control", Required: true, Components: []componentRule{anReq(3), nReq(18), an(3)}},
+	}},
+	"RFF": {Elements: []elementRule{
+		{Name: "reference", Required: true, Components: []componentRule{anReq(3), anReq(35)}},
+	}},
+	"TDT": {Elements: []elementRule{
+		{Name: "transport stage qualifier", Required: true, Components: []componentRule{code(3, "20")}},
+		{Name: "conveyance reference", Components: []componentRule{an(17)}},
+		{Name: "mode of transport", Components: []componentRule{code(3, "1")}},
+		{Name: "transport means", Components: []componentRule{an(8), an(17)}},
+		{Name: "carrier", Components: []componentRule{an(17), an(3), an(3), an(35)}},
+		{Name: "transit direction", Components: []componentRule{an(3)}},
+		{Name: "excess transportation", Components: []componentRule{an(3)}},
+		{Name: "transport identification", Components: []componentRule{an(9), an(3), an(3), an(35), an(3)}},
+	}},
+	"LOC": {Elements: []elementRule{
+		{Name: "place/location qualifier", Required: true, Components: []componentRule{code(3, "9", "11", "88", "7")}},
+		{Name: "location identification", Required: true, Components: []componentRule{an(25), an(3), an(3), an(256)}},
+	}},
+	"NAD": {Elements: []elementRule{
+		{Name: "party qualifier", Required: true, Components: []componentRule{code(3, "CZ", "CN", "N1", "CA", "FW")}},
+		{Name: "party identification", Components: []componentRule{an(35), an(3), an(3)}},
+		{Name: "name and address", Components: []componentRule{an(35)}},
+		{Name: "party name", Components: []componentRule{anReq(35), an(35), an(35), an(35), an(35)}},
+	}},
+	"GID": {Elements: []elementRule{
+		{Name: "goods item number", Required: true, Components: []componentRule{nReq(5)}},
+		{Name: "number and type of packages", Components: []componentRule{n(8), an(17)}},
+	}},
+	"PIA": {Elements: []elementRule{
+		{Name: "product id function", Required: true, Components: []componentRule{code(3, "5")}},
+		{Name: "item number identification", Required: true, Components: []componentRule{anReq(35), code(3, "HS")}},
+	}},
+	"MEA": {Elements: []elementRule{
+		{Name: "measurement purpose", Required: true, Components: []componentRule{code(3, "AAE", "WT", "VOL")}},
+		{Name: "measurement details", Components: []componentRule{code(3, "G", "AAW", "AAL")}},
+		{Name: "value/range", Required: true, Components: []componentRule{code(3, "KGM", "MTQ"), nReq(18)}},
+	}},
+	"SGP": {Elements: []elementRule{
+		{Name: "equipment identification", Required: true, Components: []componentRule{anReq(17)}},
+		{Name: "number of packages", Components: []componentRule{n(8)}},
+	}},
+	"EQD": {Elements: []elementRule{
+		{Name: "equipment qualifier", Required: true, Components: []componentRule{code(3, "CN")}},
+		{Name: "equipment identification", Components: []componentRule{an(17)}},
+		{Name: "equipment size and type", Components: []componentRule{an(10), an(17), an(3), an(35)}},
+		{Name: "equipment supplier", Components: []componentRule{an(3)}},
+		{Name: "equipment status", Components: []componentRule{an(3)}},
+		{Name: "full/empty indicator", Components: []componentRule{an(3)}},
+	}},
+	"SEL": {Elements: []elementRule{
+		{Name: "seal number", Required: true, Components: []componentRule{anReq(10)}},
+		{Name: "sealing party", Components: []componentRule{code(3, "CA", "SH", "CU")}},
+	}},
+	"UNT": {Elements: []elementRule{
+		{Name: "number of segments", Required: true, Components: []componentRule{nReq(6)}},
+		{Name: "message reference", Required: true, Components: []componentRule{anReq(14)}},
+	}},
+}
+
+// validateSegments checks every segment against rules and returns a ValidationError if any fail.
+func validateSegments(segments []Segment, rules map[string]segmentRule) []string {
+	var problems []string
+	for idx, seg := range segments {
+		rule, ok := rules[seg.Tag]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("segment %d: %s is not permitted in this message", idx+1, seg.Tag))
+			continue
+		}
+		if len(seg.Elements) > len(rule.Elements) {
+			problems = append(problems, fmt.Sprintf("segment %d (%s): %d elements exceeds maximum of %d", idx+1, seg.Tag, len(seg.Elements), len(rule.Elements)))
+		}
+		for elIdx, elRule := range rule.Elements {
+			components := seg.Element(elIdx)
+			if isEmptyElement(components) {
+				if elRule.Required {
+					problems = append(problems, fmt.Sprintf("segment %d (%s): %s is mandatory", idx+1, seg.Tag, elRule.Name))
+				}
+				continue
+			}
+			if len(components) > len(elRule.Components) {
+				problems = append(problems, fmt.Sprintf("segment %d (%s): %s has too many components", idx+1, seg.Tag, elRule.Name))
+			}
+			for cIdx, cRule := range elRule.Components {
+				value := ""
+				if cIdx < len(components) {
+					value = components[cIdx]
+				}
+				if msg := checkComponent(value, cRule); msg != "" {
+					problems = append(problems, fmt.Sprintf("segment %d (%s): %s component %d %s", idx+1, seg.Tag, elRule.Name, cIdx+1, msg))
+				}
+			}
+		}
+	}
+	return problems
+}
+
+func checkComponent(value string, rule componentRule) string {
+	if value == "" {
+		if rule.Required {
+			return "is mandatory"
+		}
+		return ""
+	}
+	if rule.MaxLen > 0 && utf8.RuneCountInString(value) > rule.MaxLen {
+		return fmt.Sprintf("exceeds %d characters", rule.MaxLen)
+	}
+	if rule.Numeric && !isNumeric(value) {
+		return fmt.Sprintf("must be numeric, got %q", value)
+	}
+	if len(rule.Codes) > 0 {
+		for _, c := range rule.Codes {
+			if value == c {
+				return ""
+			}
+		}
+		return fmt.Sprintf("has unsupported code %q", value)
+	}
+	return ""
+}
+
+func isNumeric(value string) bool {
+	seenDigit := false
+	for i, r := range value {
+		switch {
+		case r >= '0' && r <= '9':
+			seenDigit = true
+		case r == decimalMark:
+		case r == '-' && i == 0:
+		default:
+			return false
+		}
+	}
+	return seenDigit
+}
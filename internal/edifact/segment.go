@@ -0,0 +1,116 @@
+package edifact
+
+import (
+	"strings"
+)
+
+// Default UNOC service characters as advertised in the UNA service string advice.
+const (
+	componentSeparator = ':'
+	elementSeparator   = '+'
+	decimalMark        = '.'
+	releaseCharacter   = '?'
+	segmentTerminator  = '\''
+)
+
+// serviceStringAdvice is emitted ahead of UNB so receivers do not have to assume defaults.
+const serviceStringAdvice = "UNA:+.? '"
+
+// Segment is a single EDIFACT segment. Each element is a list of components;
+// a simple data element is an element with exactly one component.
+type Segment struct {
+	Tag      string
+	Elements [][]string
+}
+
+// Seg builds a segment from simple or composite elements. Each argument may be a
+// string (simple element), a []string (composite element) or nil (empty element).
+func Seg(tag string, elements ...any) Segment {
+	seg := Segment{Tag: tag}
+	for _, el := range elements {
+		switch v := el.(type) {
+		case nil:
+			seg.Elements = append(seg.Elements, nil)
+		case string:
+			seg.Elements = append(seg.Elements, []string{v})
+		case []string:
+			seg.Elements = append(seg.Elements, v)
+		default:
+			seg.Elements = append(seg.Elements, nil)
+		}
+	}
+	return seg
+}
+
+// Element returns the component values for the element at position idx (0-based).
+func (s Segment) Element(idx int) []string {
+	if idx < 0 || idx >= len(s.Elements) {
+		return nil
+	}
+	return s.Elements[idx]
+}
+
+// String renders the segment with release-escaping and trailing empty values trimmed.
+func (s Segment) String() string {
+	var b strings.Builder
+	b.WriteString(s.Tag)
+
+	elements := trimEmptyElements(s.Elements)
+	for _, el := range elements {
+		b.WriteByte(elementSeparator)
+		components := trimEmptyComponents(el)
+		for i, comp := range components {
+			if i > 0 {
+				b.WriteByte(componentSeparator)
+			}
+			b.WriteString(Escape(comp))
+		}
+	}
+	b.WriteByte(segmentTerminator)
+	return b.String()
+}
+
+// Escape prefixes EDIFACT service characters with the release character.
+func Escape(value string) string {
+	if value == "" {
+		return ""
+	}
+	var b strings.Builder
+	b.Grow(len(value))
+	for _, r := range value {
+		switch r {
+		case componentSeparator, elementSeparator, releaseCharacter, segmentTerminator:
+			b.WriteRune(releaseCharacter)
+		case '\r', '\n':
+			b.WriteRune(' ')
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func trimEmptyElements(elements [][]string) [][]string {
+	end := len(elements)
+	for end > 0 && isEmptyElement(elements[end-1]) {
+		end--
+	}
+	return elements[:end]
+}
+
+func trimEmptyComponents(components []string) []string {
+	end := len(components)
+	for end > 0 && components[end-1] == "" {
+		end--
+	}
+	return components[:end]
+}
+
+func isEmptyElement(components []string) bool {
+	for _, c := range components {
+		if c != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,55 @@
+package iata
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidAWB indicates an air waybill number failed format or check digit validation.
+var ErrInvalidAWB = errors.New("iata: invalid air waybill number")
+
+// AWBNumber is an 11-digit master air waybill number: a 3-digit airline prefix and an
+// 8-digit serial whose last digit is the modulus-7 check digit of the first seven.
+type AWBNumber struct {
+	Prefix string
+	Serial string
+}
+
+// ParseAWB normalises and validates an AWB number such as "176-12345675" or "17612345675".
+func ParseAWB(raw string) (AWBNumber, error) {
+	var digits strings.Builder
+	for _, r := range raw {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r == '-' || r == ' ':
+		default:
+			return AWBNumber{}, fmt.Errorf("%w: %q contains invalid character %q", ErrInvalidAWB, raw, r)
+		}
+	}
+	d := digits.String()
+	if len(d) != 11 {
+		return AWBNumber{}, fmt.Errorf("%w: %q must have 11 digits", ErrInvalidAWB, raw)
+	}
+
+	awb := AWBNumber{Prefix: d[:3], Serial: d[3:]}
+	if expected := CheckDigit(awb.Serial[:7]); awb.Serial[7] != expected {
+		return AWBNumber{}, fmt.Errorf("%w: %q check digit should be %c", ErrInvalidAWB, raw, expected)
+	}
+	return awb, nil
+}
+
+// CheckDigit returns the modulus-7 check digit for a 7-digit serial.
+func CheckDigit(serial7 string) byte {
+	var rem int
+	for _, r := range serial7 {
+		rem = (rem*10 + int(r-'0')) % 7
+	}
+	return byte('0' + rem)
+}
+
+// String renders the AWB in the hyphenated prefix-serial form used on documents and messages.
+func (a AWBNumber) String() string {
+	return a.Prefix + "-" + a.Serial
+}
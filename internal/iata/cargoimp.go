@@ -0,0 +1,193 @@
+package iata
+
+import (
+	"fmt"
+	"strings"
+
+	"frego-operations/internal/decimal"
+)
+
+// Cargo-IMP message versions produced by this package.
+const (
+	fwbVersion = "FWB/16"
+	fhlVersion = "FHL/4"
+)
+
+// Field limits from the Cargo-IMP FWB/FHL message specifications.
+const (
+	impLineMax        = 65
+	impNameMax        = 35
+	impGoodsMax       = 20
+	impManifestMax    = 15
+	impPlaceMax       = 17
+	impHouseNumberMax = 12
+	impLineBreak      = "\r\n"
+)
+
+// fwbCargoIMP renders a waybill as a Cargo-IMP FWB/16 message.
+func fwbCargoIMP(w Waybill) string {
+	var lines []string
+	add := func(format string, args ...any) {
+		lines = append(lines, fmt.Sprintf(format, args...))
+	}
+
+	add("%s", fwbVersion)
+	consignment := fmt.Sprintf("%s%s%s/T%dK%s", w.AWB, w.Origin, w.Destination, w.Pieces, impWeight(w.GrossWeightKg))
+	if w.VolumeM3.Sign() > 0 {
+		consignment += "MC" + impVolume(w.VolumeM3)
+	}
+	add("%s", consignment)
+
+	if flight := impCode(w.Flight.Number); flight != "" {
+		if w.Flight.Date != nil {
+			add("FLT/%s/%02d", flight, w.Flight.Date.Day())
+		} else {
+			add("FLT/%s", flight)
+		}
+		if len(flight) >= 2 {
+			add("RTG/%s%s", w.Destination, flight[:2])
+		}
+	}
+
+	lines = append(lines, impParty("SHP", w.Shipper)...)
+	lines = append(lines, impParty("CNE", w.Consignee)...)
+
+	if handling := impText(w.HandlingInfo, impLineMax-4); handling != "" {
+		add("SSR/%s", handling)
+	}
+	if accounting := impText(w.AccountingInfo, impLineMax-8); accounting != "" {
+		add("ACC/GEN/%s", accounting)
+	}
+
+	chargeCode, payment := "PP", "P"
+	if !w.Prepaid {
+		chargeCode, payment = "CC", "C"
+	}
+	add("CVD/%s//%s/NVD/NCV/XXX", w.Currency, chargeCode)
+
+	for i, r := range w.Rates {
+		add("RTD/%d/P%d/K%s/C%s/W%s/R%s/T%s", i+1, r.Pieces, impWeight(r.GrossWeightKg), r.RateClass,
+			impWeight(r.ChargeableWeightKg), impAmount(r.Rate), impAmount(r.Total))
+		description := r.Description
+		if description == "" {
+			description = w.GoodsDescription
+		}
+		if goods := impText(description, impGoodsMax); goods != "" {
+			add("/2/NG/%s", goods)
+		}
+	}
+
+	for i, c := range w.OtherCharges {
+		prefix := "/"
+		if i == 0 {
+			prefix = "OTH/"
+		}
+		add("%s%s/%s%c%s", prefix, payment, strings.ToUpper(c.Code), c.Entitlement, impAmount(c.Amount))
+	}
+
+	summary := "PPD"
+	if !w.Prepaid {
+		summary = "COL"
+	}
+	weightCharge := w.WeightChargeTotal()
+	dueAgent := w.OtherChargeTotal(EntitlementAgent)
+	dueCarrier := w.OtherChargeTotal(EntitlementCarrier)
+	totals := fmt.Sprintf("%s/WT%s", summary, impAmount(weightCharge))
+	if !dueAgent.IsZero() {
+		totals += "/OA" + impAmount(dueAgent)
+	}
+	if !dueCarrier.IsZero() {
+		totals += "/OC" + impAmount(dueCarrier)
+	}
+	add("%s", totals)
+	add("/CT%s", impAmount(weightCharge.Add(dueAgent).Add(dueCarrier)))
+
+	issued := "ISU/" + strings.ToUpper(w.IssuedAt.Format("02Jan06"))
+	if place := impText(w.IssuedPlace, impPlaceMax); place != "" {
+		issued += "/" + place
+	}
+	add("%s", issued)
+
+	return strings.Join(lines, impLineBreak) + impLineBreak
+}
+
+// fhlCargoIMP renders a consolidation list as a Cargo-IMP FHL/4 message.
+func fhlCargoIMP(m HouseManifest) string {
+	lines := []string{
+		fhlVersion,
+		fmt.Sprintf("MBI/%s%s%s/T%dK%s", m.Master, m.Origin, m.Destination, m.Pieces, impWeight(m.GrossWeightKg)),
+	}
+
+	for _, h := range m.Houses {
+		lines = append(lines, fmt.Sprintf("HBS/%s/%s%s/%d/K%s//%s",
+			impCode(h.Number), firstNonEmpty(h.Origin, m.Origin), firstNonEmpty(h.Destination, m.Destination),
+			h.Pieces, impWeight(h.GrossWeightKg), impText(h.Description, impManifestMax)))
+		if full := impText(h.Description, impLineMax-4); len(full) > impManifestMax {
+			lines = append(lines, "TXT/"+full)
+		}
+		if h.Shipper.Name != "" {
+			lines = append(lines, impParty("SHP", h.Shipper)...)
+		}
+		if h.Consignee.Name != "" {
+			lines = append(lines, impParty("CNE", h.Consignee)...)
+		}
+	}
+
+	return strings.Join(lines, impLineBreak) + impLineBreak
+}
+
+func impParty(tag string, p Party) []string {
+	header := tag
+	if code := impCode(p.Code); code != "" {
+		header += "/" + code
+	}
+	return []string{header, "NAM/" + impText(p.Name, impNameMax)}
+}
+
+// impText upper-cases free text and replaces characters outside the Cargo-IMP set with spaces.
+func impText(value string, maxLen int) string {
+	var b strings.Builder
+	lastSpace := true
+	for _, r := range strings.ToUpper(value) {
+		switch {
+		case (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '.' || r == '-' || r == ',':
+			b.WriteRune(r)
+			lastSpace = false
+		case !lastSpace:
+			b.WriteByte(' ')
+			lastSpace = true
+		}
+	}
+	text := strings.TrimSpace(b.String())
+	if len(text) > maxLen {
+		text = strings.TrimSpace(text[:maxLen])
+	}
+	return text
+}
+
+// impCode strips everything but letters and digits, as required for identifiers.
+func impCode(value string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(value) {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		}
+	}
+	code := b.String()
+	if len(code) > impHouseNumberMax {
+		code = code[:impHouseNumberMax]
+	}
+	return code
+}
+
+func impWeight(kg decimal.Decimal) string {
+	return kg.StringFixed(1)
+}
+
+func impVolume(m3 decimal.Decimal) string {
+	return m3.StringFixed(2)
+}
+
+func impAmount(amount decimal.Decimal) string {
+	return amount.StringFixed(2)
+}
@@ -0,0 +1,353 @@
+package iata
+
+import (
+	"encoding/xml"
+	"fmt"
+	"time"
+
+	"frego-operations/internal/decimal"
+)
+
+// Cargo-XML namespaces and document codes for XFWB and XFHL version 3.
+const (
+	xmlNamespaceWaybill       = "iata:waybill:1"
+	xmlNamespaceHouseManifest = "iata:housemanifest:1"
+	xmlNamespaceDataModel     = "iata:datamodel:3"
+	xmlVersion                = "3.00"
+	xmlTypeWaybill            = "740"
+	xmlTypeHouseManifest      = "785"
+	xmlDateTimeLayout         = "2006-01-02T15:04:05"
+)
+
+type xmlText struct {
+	Value string `xml:",chardata"`
+}
+
+type xmlMeasure struct {
+	UnitCode string `xml:"unitCode,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type xmlAmount struct {
+	CurrencyID string `xml:"currencyID,attr"`
+	Value      string `xml:",chardata"`
+}
+
+type xmlID struct {
+	ID string `xml:"ram:ID"`
+}
+
+type xmlParty struct {
+	PrimaryID string `xml:"ram:PrimaryID,omitempty"`
+	Name      string `xml:"ram:Name"`
+}
+
+type xmlMessageHeader struct {
+	ID            string `xml:"ram:ID"`
+	Name          string `xml:"ram:Name"`
+	TypeCode      string `xml:"ram:TypeCode"`
+	IssueDateTime string `xml:"ram:IssueDateTime"`
+	PurposeCode   string `xml:"ram:PurposeCode"`
+	VersionID     string `xml:"ram:VersionID"`
+	SenderParty   *struct {
+		PrimaryID string `xml:"ram:PrimaryID"`
+	} `xml:"ram:SenderParty,omitempty"`
+	RecipientParty *struct {
+		PrimaryID string `xml:"ram:PrimaryID"`
+	} `xml:"ram:RecipientParty,omitempty"`
+}
+
+type xmlWaybill struct {
+	XMLName        xml.Name         `xml:"rsm:Waybill"`
+	NSRsm          string           `xml:"xmlns:rsm,attr"`
+	NSRam          string           `xml:"xmlns:ram,attr"`
+	MessageHeader  xmlMessageHeader `xml:"rsm:MessageHeaderDocument"`
+	BusinessHeader struct {
+		ID                             string `xml:"ram:ID"`
+		SignatoryCarrierAuthentication struct {
+			ActualDateTime string  `xml:"ram:ActualDateTime"`
+			IssueLocation  xmlText `xml:"ram:IssueAuthenticationLocation>ram:Name"`
+		} `xml:"ram:SignatoryCarrierAuthentication"`
+	} `xml:"rsm:BusinessHeaderDocument"`
+	MasterConsignment xmlMasterConsignment `xml:"rsm:MasterConsignment"`
+}
+
+type xmlMasterConsignment struct {
+	GrossWeight        xmlMeasure            `xml:"ram:IncludedTareGrossWeightMeasure"`
+	GrossVolume        *xmlMeasure           `xml:"ram:GrossVolumeMeasure,omitempty"`
+	TotalPieceQuantity int                   `xml:"ram:TotalPieceQuantity"`
+	Consignor          xmlParty              `xml:"ram:ConsignorParty"`
+	Consignee          xmlParty              `xml:"ram:ConsigneeParty"`
+	Origin             xmlID                 `xml:"ram:OriginLocation"`
+	Destination        xmlID                 `xml:"ram:FinalDestinationLocation"`
+	Movement           *xmlTransportMovement `xml:"ram:SpecifiedLogisticsTransportMovement,omitempty"`
+	HandlingSSR        *struct {
+		Description string `xml:"ram:Description"`
+	} `xml:"ram:HandlingSSRInstructions,omitempty"`
+	AccountingNote *struct {
+		ContentCode string `xml:"ram:ContentCode"`
+		Content     string `xml:"ram:Content"`
+	} `xml:"ram:IncludedAccountingNote,omitempty"`
+	OriginCurrency struct {
+		SourceCurrencyCode string `xml:"ram:SourceCurrencyCode"`
+	} `xml:"ram:ApplicableOriginCurrencyExchange"`
+	ServiceCharge struct {
+		TransportPaymentMethodCode string `xml:"ram:TransportPaymentMethodCode"`
+	} `xml:"ram:ApplicableLogisticsServiceCharge"`
+	AllowanceCharges []xmlAllowanceCharge `xml:"ram:ApplicableLogisticsAllowanceCharge"`
+	Rating           xmlRating            `xml:"ram:ApplicableRating"`
+	TotalRating      xmlTotalRating       `xml:"ram:ApplicableTotalRating"`
+}
+
+type xmlTransportMovement struct {
+	StageCode string `xml:"ram:StageCode"`
+	ModeCode  string `xml:"ram:ModeCode"`
+	ID        string `xml:"ram:ID"`
+	Departure *struct {
+		ScheduledOccurrenceDateTime string `xml:"ram:ScheduledOccurrenceDateTime"`
+	} `xml:"ram:DepartureEvent,omitempty"`
+}
+
+type xmlAllowanceCharge struct {
+	ID               string    `xml:"ram:ID"`
+	PrepaidIndicator bool      `xml:"ram:PrepaidIndicator"`
+	PartyTypeCode    string    `xml:"ram:PartyTypeCode"`
+	ActualAmount     xmlAmount `xml:"ram:ActualAmount"`
+}
+
+type xmlRating struct {
+	TypeCode          string          `xml:"ram:TypeCode"`
+	TotalChargeAmount xmlAmount       `xml:"ram:TotalChargeAmount"`
+	Items             []xmlRatingItem `xml:"ram:IncludedMasterConsignmentItem"`
+}
+
+type xmlRatingItem struct {
+	SequenceNumeric int        `xml:"ram:SequenceNumeric"`
+	GrossWeight     xmlMeasure `xml:"ram:GrossWeightMeasure"`
+	PieceQuantity   int        `xml:"ram:PieceQuantity"`
+	Nature          *struct {
+		Identification string `xml:"ram:Identification"`
+	} `xml:"ram:NatureIdentificationTransportCargo,omitempty"`
+	FreightRate struct {
+		CategoryCode     string     `xml:"ram:CategoryCode"`
+		ChargeableWeight xmlMeasure `xml:"ram:ChargeableWeightMeasure"`
+		AppliedRate      string     `xml:"ram:AppliedRate"`
+		AppliedAmount    xmlAmount  `xml:"ram:AppliedAmount"`
+	} `xml:"ram:ApplicableFreightRateServiceCharge"`
+}
+
+type xmlTotalRating struct {
+	TypeCode  string `xml:"ram:TypeCode"`
+	Summation struct {
+		PrepaidIndicator  bool      `xml:"ram:PrepaidIndicator"`
+		WeightChargeTotal xmlAmount `xml:"ram:WeightChargeTotalAmount"`
+		AgentTotalDue     xmlAmount `xml:"ram:AgentTotalDuePayableAmount"`
+		CarrierTotalDue   xmlAmount `xml:"ram:CarrierTotalDuePayableAmount"`
+		GrandTotal        xmlAmount `xml:"ram:GrandTotalAmount"`
+	} `xml:"ram:ApplicablePrepaidCollectMonetarySummation"`
+}
+
+type xmlHouseManifest struct {
+	XMLName        xml.Name         `xml:"rsm:HouseManifest"`
+	NSRsm          string           `xml:"xmlns:rsm,attr"`
+	NSRam          string           `xml:"xmlns:ram,attr"`
+	MessageHeader  xmlMessageHeader `xml:"rsm:MessageHeaderDocument"`
+	BusinessHeader xmlID            `xml:"rsm:BusinessHeaderDocument"`
+	Master         struct {
+		GrossWeight        xmlMeasure            `xml:"ram:IncludedTareGrossWeightMeasure"`
+		TotalPieceQuantity int                   `xml:"ram:TotalPieceQuantity"`
+		TransportContract  xmlID                 `xml:"ram:TransportContractDocument"`
+		Origin             xmlID                 `xml:"ram:OriginLocation"`
+		Destination        xmlID                 `xml:"ram:FinalDestinationLocation"`
+		Houses             []xmlHouseConsignment `xml:"ram:IncludedHouseConsignment"`
+	} `xml:"rsm:MasterConsignment"`
+}
+
+type xmlHouseConsignment struct {
+	SequenceNumeric    int        `xml:"ram:SequenceNumeric"`
+	GrossWeight        xmlMeasure `xml:"ram:IncludedTareGrossWeightMeasure"`
+	TotalPieceQuantity int        `xml:"ram:TotalPieceQuantity"`
+	SummaryDescription string     `xml:"ram:SummaryDescription,omitempty"`
+	TransportContract  xmlID      `xml:"ram:TransportContractDocument"`
+	Consignor          *xmlParty  `xml:"ram:ConsignorParty,omitempty"`
+	Consignee          *xmlParty  `xml:"ram:ConsigneeParty,omitempty"`
+	Origin             xmlID      `xml:"ram:OriginLocation"`
+	Destination        xmlID      `xml:"ram:FinalDestinationLocation"`
+}
+
+// xfwb renders a waybill as a Cargo-XML XFWB document.
+func xfwb(w Waybill, env Envelope) ([]byte, error) {
+	doc := xmlWaybill{
+		NSRsm:         xmlNamespaceWaybill,
+		NSRam:         xmlNamespaceDataModel,
+		MessageHeader: messageHeader(w.AWB.String(), "Master Air Waybill", xmlTypeWaybill, w.IssuedAt, env),
+	}
+	doc.BusinessHeader.ID = w.AWB.String()
+	doc.BusinessHeader.SignatoryCarrierAuthentication.ActualDateTime = w.IssuedAt.UTC().Format(xmlDateTimeLayout)
+	doc.BusinessHeader.SignatoryCarrierAuthentication.IssueLocation.Value = w.IssuedPlace
+
+	mc := &doc.MasterConsignment
+	mc.GrossWeight = kilograms(w.GrossWeightKg)
+	if w.VolumeM3.Sign() > 0 {
+		mc.GrossVolume = &xmlMeasure{UnitCode: "MTQ", Value: impVolume(w.VolumeM3)}
+	}
+	mc.TotalPieceQuantity = w.Pieces
+	mc.Consignor = xmlParty{PrimaryID: w.Shipper.Code, Name: w.Shipper.Name}
+	mc.Consignee = xmlParty{PrimaryID: w.Consignee.Code, Name: w.Consignee.Name}
+	mc.Origin.ID = w.Origin
+	mc.Destination.ID = w.Destination
+
+	if w.Flight.Number != "" {
+		mc.Movement = &xmlTransportMovement{StageCode: "Main-Carriage", ModeCode: "4", ID: w.Flight.Number}
+		if w.Flight.Date != nil {
+			mc.Movement.Departure = &struct {
+				ScheduledOccurrenceDateTime string `xml:"ram:ScheduledOccurrenceDateTime"`
+			}{w.Flight.Date.UTC().Format(xmlDateTimeLayout)}
+		}
+	}
+	if w.HandlingInfo != "" {
+		mc.HandlingSSR = &struct {
+			Description string `xml:"ram:Description"`
+		}{w.HandlingInfo}
+	}
+	if w.AccountingInfo != "" {
+		mc.AccountingNote = &struct {
+			ContentCode string `xml:"ram:ContentCode"`
+			Content     string `xml:"ram:Content"`
+		}{"GEN", w.AccountingInfo}
+	}
+
+	mc.OriginCurrency.SourceCurrencyCode = w.Currency
+	mc.ServiceCharge.TransportPaymentMethodCode = "PP"
+	if !w.Prepaid {
+		mc.ServiceCharge.TransportPaymentMethodCode = "CC"
+	}
+
+	for _, c := range w.OtherCharges {
+		mc.AllowanceCharges = append(mc.AllowanceCharges, xmlAllowanceCharge{
+			ID:               c.Code,
+			PrepaidIndicator: w.Prepaid,
+			PartyTypeCode:    string(c.Entitlement),
+			ActualAmount:     amount(c.Amount, w.Currency),
+		})
+	}
+
+	weightCharge := w.WeightChargeTotal()
+	mc.Rating.TypeCode = "F"
+	mc.Rating.TotalChargeAmount = amount(weightCharge, w.Currency)
+	for i, r := range w.Rates {
+		item := xmlRatingItem{
+			SequenceNumeric: i + 1,
+			GrossWeight:     kilograms(r.GrossWeightKg),
+			PieceQuantity:   r.Pieces,
+		}
+		description := r.Description
+		if description == "" {
+			description = w.GoodsDescription
+		}
+		if description != "" {
+			item.Nature = &struct {
+				Identification string `xml:"ram:Identification"`
+			}{description}
+		}
+		item.FreightRate.CategoryCode = r.RateClass
+		item.FreightRate.ChargeableWeight = kilograms(r.ChargeableWeightKg)
+		item.FreightRate.AppliedRate = impAmount(r.Rate)
+		item.FreightRate.AppliedAmount = amount(r.Total, w.Currency)
+		mc.Rating.Items = append(mc.Rating.Items, item)
+	}
+
+	dueAgent := w.OtherChargeTotal(EntitlementAgent)
+	dueCarrier := w.OtherChargeTotal(EntitlementCarrier)
+	mc.TotalRating.TypeCode = "F"
+	mc.TotalRating.Summation.PrepaidIndicator = w.Prepaid
+	mc.TotalRating.Summation.WeightChargeTotal = amount(weightCharge, w.Currency)
+	mc.TotalRating.Summation.AgentTotalDue = amount(dueAgent, w.Currency)
+	mc.TotalRating.Summation.CarrierTotalDue = amount(dueCarrier, w.Currency)
+	mc.TotalRating.Summation.GrandTotal = amount(weightCharge.Add(dueAgent).Add(dueCarrier), w.Currency)
+
+	return marshalDocument(doc)
+}
+
+// xfhl renders a consolidation list as a Cargo-XML XFHL document.
+func xfhl(m HouseManifest, env Envelope) ([]byte, error) {
+	doc := xmlHouseManifest{
+		NSRsm:         xmlNamespaceHouseManifest,
+		NSRam:         xmlNamespaceDataModel,
+		MessageHeader: messageHeader(m.Master.String(), "House Manifest", xmlTypeHouseManifest, m.IssuedAt, env),
+	}
+	doc.BusinessHeader.ID = m.Master.String()
+	doc.Master.GrossWeight = kilograms(m.GrossWeightKg)
+	doc.Master.TotalPieceQuantity = m.Pieces
+	doc.Master.TransportContract.ID = m.Master.String()
+	doc.Master.Origin.ID = m.Origin
+	doc.Master.Destination.ID = m.Destination
+
+	for i, h := range m.Houses {
+		house := xmlHouseConsignment{
+			SequenceNumeric:    i + 1,
+			GrossWeight:        kilograms(h.GrossWeightKg),
+			TotalPieceQuantity: h.Pieces,
+			SummaryDescription: h.Description,
+			TransportContract:  xmlID{ID: h.Number},
+			Origin:             xmlID{ID: firstNonEmpty(h.Origin, m.Origin)},
+			Destination:        xmlID{ID: firstNonEmpty(h.Destination, m.Destination)},
+		}
+		if h.Shipper.Name != "" {
+			house.Consignor = &xmlParty{PrimaryID: h.Shipper.Code, Name: h.Shipper.Name}
+		}
+		if h.Consignee.Name != "" {
+			house.Consignee = &xmlParty{PrimaryID: h.Consignee.Code, Name: h.Consignee.Name}
+		}
+		doc.Master.Houses = append(doc.Master.Houses, house)
+	}
+
+	return marshalDocument(doc)
+}
+
+func messageHeader(id, name, typeCode string, issuedAt time.Time, env Envelope) xmlMessageHeader {
+	header := xmlMessageHeader{
+		ID:            id,
+		Name:          name,
+		TypeCode:      typeCode,
+		IssueDateTime: issuedAt.UTC().Format(xmlDateTimeLayout),
+		PurposeCode:   "Creation",
+		VersionID:     xmlVersion,
+	}
+	if env.SenderID != "" {
+		header.SenderParty = &struct {
+			PrimaryID string `xml:"ram:PrimaryID"`
+		}{env.SenderID}
+	}
+	if env.RecipientID != "" {
+		header.RecipientParty = &struct {
+			PrimaryID string `xml:"ram:PrimaryID"`
+		}{env.RecipientID}
+	}
+	return header
+}
+
+func marshalDocument(doc any) ([]byte, error) {
+	body, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("iata: marshal cargo-xml: %w", err)
+	}
+	return append([]byte(xml.Header), body...), nil
+}
+
+func kilograms(kg decimal.Decimal) xmlMeasure {
+	return xmlMeasure{UnitCode: "KGM", Value: impWeight(kg)}
+}
+
+func amount(value decimal.Decimal, currency string) xmlAmount {
+	return xmlAmount{CurrencyID: currency, Value: impAmount(value)}
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
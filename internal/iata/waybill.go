@@ -0,0 +1,266 @@
+package iata
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"frego-operations/internal/decimal"
+)
+
+// Format selects the wire representation of an air waybill message.
+type Format string
+
+// Supported message formats.
+const (
+	FormatCargoIMP Format = "cargo-imp"
+	FormatCargoXML Format = "cargo-xml"
+)
+
+// Other charge entitlements as printed in Cargo-IMP OTH lines.
+const (
+	EntitlementAgent   = 'A'
+	EntitlementCarrier = 'C'
+)
+
+// ErrInvalidWaybill indicates the waybill data failed the checks required by FWB/FHL.
+var ErrInvalidWaybill = errors.New("iata: waybill data invalid")
+
+// ValidationError collects every problem found while preparing a waybill message.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrInvalidWaybill, strings.Join(e.Problems, "; "))
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ErrInvalidWaybill
+}
+
+// Envelope identifies the message partners carried in the Cargo-XML header.
+type Envelope struct {
+	SenderID    string
+	RecipientID string
+}
+
+// Party is a shipper, consignee or agent printed on the waybill.
+type Party struct {
+	Code string
+	Name string
+}
+
+// Flight is the booked main-carriage flight.
+type Flight struct {
+	Number string
+	Date   *time.Time
+}
+
+// RateLine is one weight-charge line of the rate description.
+type RateLine struct {
+	Pieces             int
+	GrossWeightKg      decimal.Decimal
+	ChargeableWeightKg decimal.Decimal
+	RateClass          string // IATA rate class, e.g. N, Q or M
+	Rate               decimal.Decimal
+	Total              decimal.Decimal
+	Description        string
+}
+
+// OtherCharge is a non-weight charge due to the agent or the carrier.
+type OtherCharge struct {
+	Code        string
+	Entitlement rune
+	Amount      decimal.Decimal
+}
+
+// Waybill carries the data for an FWB master air waybill.
+type Waybill struct {
+	AWB              AWBNumber
+	Origin           string
+	Destination      string
+	Flight           Flight
+	Shipper          Party
+	Consignee        Party
+	Pieces           int
+	GrossWeightKg    decimal.Decimal
+	VolumeM3         decimal.Decimal
+	Currency         string
+	Prepaid          bool
+	Rates            []RateLine
+	OtherCharges     []OtherCharge
+	GoodsDescription string
+	AccountingInfo   string
+	HandlingInfo     string
+	IssuedAt         time.Time
+	IssuedPlace      string
+}
+
+// HouseWaybill is one house consignment consolidated under a master AWB.
+type HouseWaybill struct {
+	Number        string
+	Origin        string
+	Destination   string
+	Pieces        int
+	GrossWeightKg decimal.Decimal
+	Description   string
+	Shipper       Party
+	Consignee     Party
+}
+
+// HouseManifest carries the data for an FHL consolidation list.
+type HouseManifest struct {
+	Master        AWBNumber
+	Origin        string
+	Destination   string
+	Pieces        int
+	GrossWeightKg decimal.Decimal
+	Houses        []HouseWaybill
+	IssuedAt      time.Time
+}
+
+// BuildFWB validates a master air waybill and renders it as an FWB message in the given format.
+func BuildFWB(w Waybill, format Format, env Envelope) ([]byte, error) {
+	if err := checkWaybill(w); err != nil {
+		return nil, err
+	}
+	switch format {
+	case FormatCargoIMP:
+		return []byte(fwbCargoIMP(w)), nil
+	case FormatCargoXML:
+		return xfwb(w, env)
+	default:
+		return nil, fmt.Errorf("%w: unsupported format %q", ErrInvalidWaybill, format)
+	}
+}
+
+// BuildFHL validates a consolidation and renders its house list as an FHL message in the given format.
+func BuildFHL(m HouseManifest, format Format, env Envelope) ([]byte, error) {
+	if err := checkManifest(m); err != nil {
+		return nil, err
+	}
+	switch format {
+	case FormatCargoIMP:
+		return []byte(fhlCargoIMP(m)), nil
+	case FormatCargoXML:
+		return xfhl(m, env)
+	default:
+		return nil, fmt.Errorf("%w: unsupported format %q", ErrInvalidWaybill, format)
+	}
+}
+
+// WeightChargeTotal sums the totals of all rate lines.
+func (w Waybill) WeightChargeTotal() decimal.Decimal {
+	total := decimal.Zero
+	for _, r := range w.Rates {
+		total = total.Add(r.Total)
+	}
+	return total
+}
+
+// OtherChargeTotal sums other charges with the given entitlement.
+func (w Waybill) OtherChargeTotal(entitlement rune) decimal.Decimal {
+	total := decimal.Zero
+	for _, c := range w.OtherCharges {
+		if c.Entitlement == entitlement {
+			total = total.Add(c.Amount)
+		}
+	}
+	return total
+}
+
+func checkWaybill(w Waybill) error {
+	var problems []string
+	if w.AWB.Prefix == "" {
+		problems = append(problems, "master AWB number is missing")
+	}
+	problems = append(problems, checkStations(w.Origin, w.Destination)...)
+	if strings.TrimSpace(w.Shipper.Name) == "" {
+		problems = append(problems, "shipper is missing")
+	}
+	if strings.TrimSpace(w.Consignee.Name) == "" {
+		problems = append(problems, "consignee is missing")
+	}
+	if w.Pieces <= 0 {
+		problems = append(problems, "number of pieces must be positive")
+	}
+	if w.GrossWeightKg.Sign() <= 0 {
+		problems = append(problems, "gross weight must be positive")
+	}
+	if !isAlpha(w.Currency, 3) {
+		problems = append(problems, fmt.Sprintf("currency %q is not an ISO 4217 code", w.Currency))
+	}
+	for i, r := range w.Rates {
+		if !isAlpha(r.RateClass, 1) {
+			problems = append(problems, fmt.Sprintf("rate line %d: rate class %q must be one letter", i+1, r.RateClass))
+		}
+	}
+	for i, c := range w.OtherCharges {
+		if !isAlpha(c.Code, 2) {
+			problems = append(problems, fmt.Sprintf("other charge %d: code %q must be two letters", i+1, c.Code))
+		}
+		if c.Entitlement != EntitlementAgent && c.Entitlement != EntitlementCarrier {
+			problems = append(problems, fmt.Sprintf("other charge %d: entitlement must be A or C", i+1))
+		}
+	}
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+func checkManifest(m HouseManifest) error {
+	var problems []string
+	if m.Master.Prefix == "" {
+		problems = append(problems, "master AWB number is missing")
+	}
+	problems = append(problems, checkStations(m.Origin, m.Destination)...)
+	if len(m.Houses) == 0 {
+		problems = append(problems, "no house waybills are consolidated under this job")
+	}
+	seen := make(map[string]bool, len(m.Houses))
+	for i, h := range m.Houses {
+		switch {
+		case strings.TrimSpace(h.Number) == "":
+			problems = append(problems, fmt.Sprintf("house %d: number is missing", i+1))
+		case seen[h.Number]:
+			problems = append(problems, fmt.Sprintf("house %d: number %s is duplicated", i+1, h.Number))
+		}
+		seen[h.Number] = true
+		if h.Pieces <= 0 {
+			problems = append(problems, fmt.Sprintf("house %s: number of pieces must be positive", h.Number))
+		}
+		if h.GrossWeightKg.Sign() <= 0 {
+			problems = append(problems, fmt.Sprintf("house %s: gross weight must be positive", h.Number))
+		}
+	}
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+func checkStations(origin, destination string) []string {
+	var problems []string
+	if !isAlpha(origin, 3) {
+		problems = append(problems, fmt.Sprintf("origin %q is not a 3-letter airport code", origin))
+	}
+	if !isAlpha(destination, 3) {
+		problems = append(problems, fmt.Sprintf("destination %q is not a 3-letter airport code", destination))
+	}
+	return problems
+}
+
+func isAlpha(value string, length int) bool {
+	if len(value) != length {
+		return false
+	}
+	for _, r := range value {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
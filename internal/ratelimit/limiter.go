@@ -0,0 +1,269 @@
+package ratelimit
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"frego-operations/internal/logging"
+)
+
+// Class groups routes that share a limit.
+type Class string
+
+// Route classes.
+const (
+	Read   Class = "read"
+	Write  Class = "write"
+	Export Class = "export"
+)
+
+// Level is what a limit is counted against.
+type Level string
+
+// Limit levels. A request draws from its tenant's bucket and from its principal's; an API key
+// principal uses an APIKey rule for that key when one exists.
+const (
+	LevelTenant    Level = "tenant"
+	LevelPrincipal Level = "principal"
+	LevelAPIKey    Level = "api_key"
+)
+
+// Limit is a token bucket that refills PerMinute tokens a minute and holds up to Burst.
+type Limit struct {
+	PerMinute int
+	Burst     int
+}
+
+// Rule is a limit stored in the tenant registry. A nil TenantID makes it the default for every
+// tenant; APIKeyID is set only on LevelAPIKey rules.
+type Rule struct {
+	TenantID *uuid.UUID
+	APIKeyID *uuid.UUID
+	Class    Class
+	Level    Level
+	Limit    Limit
+}
+
+// RuleLoader returns the rules that apply to a tenant: its own and the defaults.
+type RuleLoader func(ctx context.Context, tenantID uuid.UUID) ([]Rule, error)
+
+// DefaultLimits apply when the registry has no rule for a class and level.
+func DefaultLimits() map[Class]map[Level]Limit {
+	return map[Class]map[Level]Limit{
+		Read: {
+			LevelTenant:    {PerMinute: 1200, Burst: 200},
+			LevelPrincipal: {PerMinute: 300, Burst: 60},
+		},
+		Write: {
+			LevelTenant:    {PerMinute: 300, Burst: 60},
+			LevelPrincipal: {PerMinute: 120, Burst: 30},
+		},
+		Export: {
+			LevelTenant:    {PerMinute: 60, Burst: 10},
+			LevelPrincipal: {PerMinute: 20, Burst: 5},
+		},
+	}
+}
+
+// Decision is the outcome of drawing a token. Limit, Remaining and Reset describe the most
+// constrained bucket; RetryAfter is set when the request is refused.
+type Decision struct {
+	Allowed    bool
+	Level      Level
+	Limit      int
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+type tenantRules struct {
+	rules    []Rule
+	loadedAt time.Time
+}
+
+type bucket struct {
+	limit  Limit
+	tokens float64
+	last   time.Time
+}
+
+// idleBucket is how long an untouched bucket is kept; a full bucket carries no state worth keeping.
+const idleBucket = 10 * time.Minute
+
+// Limiter enforces the limits with in-memory token buckets. Buckets are per instance, so with N
+// replicas behind a balancer a client sees up to N times the configured rate.
+type Limiter struct {
+	load RuleLoader
+	ttl  time.Duration
+
+	mu        sync.Mutex
+	rules     map[uuid.UUID]tenantRules
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// New creates a limiter that reloads each tenant's rules after ttl. A nil load uses the defaults.
+func New(load RuleLoader, ttl time.Duration) *Limiter {
+	return &Limiter{
+		load:    load,
+		ttl:     ttl,
+		rules:   make(map[uuid.UUID]tenantRules),
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Allow draws a token for one request of class from the tenant's bucket and the principal's.
+// Nothing is drawn unless both have one. apiKeyID is set when the principal is an API key.
+func (l *Limiter) Allow(ctx context.Context, tenantID uuid.UUID, principal string, apiKeyID *uuid.UUID, class Class) Decision {
+	rules := l.tenantRules(ctx, tenantID)
+	tenantLimit := resolve(rules, tenantID, nil, class, LevelTenant)
+	principalLevel, principalLimit := LevelPrincipal, resolve(rules, tenantID, nil, class, LevelPrincipal)
+	if apiKeyID != nil {
+		if limit, ok := lookup(rules, tenantID, apiKeyID, class, LevelAPIKey); ok {
+			principalLevel, principalLimit = LevelAPIKey, limit
+		}
+	}
+
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+
+	tb := l.bucket(string(class)+"|"+tenantID.String(), tenantLimit, now)
+	pb := l.bucket(string(class)+"|"+tenantID.String()+"|"+principal, principalLimit, now)
+
+	allowed := tb.tokens >= 1 && pb.tokens >= 1
+	if allowed {
+		tb.tokens--
+		pb.tokens--
+	}
+
+	// Report the bucket that refused the request, or else the one closest to running out.
+	level, b := LevelTenant, tb
+	switch {
+	case !allowed && tb.tokens >= 1:
+		level, b = principalLevel, pb
+	case !allowed && pb.tokens < 1 && pb.untilTokens(1) > tb.untilTokens(1):
+		level, b = principalLevel, pb
+	case allowed && pb.tokens < tb.tokens:
+		level, b = principalLevel, pb
+	}
+	decision := Decision{
+		Allowed:   allowed,
+		Level:     level,
+		Limit:     b.limit.Burst,
+		Remaining: int(math.Floor(b.tokens)),
+		Reset:     b.untilTokens(float64(b.limit.Burst)),
+	}
+	if !allowed {
+		decision.RetryAfter = b.untilTokens(1)
+	}
+	return decision
+}
+
+// Invalidate drops the cached rules of a tenant, after they change.
+func (l *Limiter) Invalidate(tenantID uuid.UUID) {
+	l.mu.Lock()
+	delete(l.rules, tenantID)
+	l.mu.Unlock()
+}
+
+// tenantRules returns the tenant's rules, loading them when missing or stale. A failed load keeps
+// the previous rules, or the defaults, rather than failing the request.
+func (l *Limiter) tenantRules(ctx context.Context, tenantID uuid.UUID) []Rule {
+	if l.load == nil {
+		return nil
+	}
+	l.mu.Lock()
+	cached, ok := l.rules[tenantID]
+	l.mu.Unlock()
+	if ok && time.Since(cached.loadedAt) < l.ttl {
+		return cached.rules
+	}
+
+	rules, err := l.load(ctx, tenantID)
+	if err != nil {
+		logging.FromContext(ctx).Warn("ratelimit: failed to load limits", slog.Any("error", err))
+		if ok {
+			return cached.rules
+		}
+		return nil
+	}
+	l.mu.Lock()
+	l.rules[tenantID] = tenantRules{rules: rules, loadedAt: time.Now()}
+	l.mu.Unlock()
+	return rules
+}
+
+// bucket returns the bucket for key refilled to now, creating it full. A bucket whose limit
+// changed keeps its tokens, capped at the new burst.
+func (l *Limiter) bucket(key string, limit Limit, now time.Time) *bucket {
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{limit: limit, tokens: float64(limit.Burst), last: now}
+		l.buckets[key] = b
+		return b
+	}
+	b.limit = limit
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+now.Sub(b.last).Minutes()*float64(limit.PerMinute))
+	b.last = now
+	return b
+}
+
+// sweep drops idle buckets once a minute.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	for key, b := range l.buckets {
+		if now.Sub(b.last) > idleBucket {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
+
+// untilTokens is how long the bucket needs to refill to n tokens.
+func (b *bucket) untilTokens(n float64) time.Duration {
+	if b.tokens >= n || b.limit.PerMinute <= 0 {
+		return 0
+	}
+	return time.Duration((n - b.tokens) / float64(b.limit.PerMinute) * float64(time.Minute))
+}
+
+// resolve returns the tenant's rule for class and level, else the registry default, else the
+// built-in default.
+func resolve(rules []Rule, tenantID uuid.UUID, apiKeyID *uuid.UUID, class Class, level Level) Limit {
+	if limit, ok := lookup(rules, tenantID, apiKeyID, class, level); ok {
+		return limit
+	}
+	return DefaultLimits()[class][level]
+}
+
+func lookup(rules []Rule, tenantID uuid.UUID, apiKeyID *uuid.UUID, class Class, level Level) (Limit, bool) {
+	var fallback *Limit
+	for i := range rules {
+		r := rules[i]
+		if r.Class != class || r.Level != level {
+			continue
+		}
+		if level == LevelAPIKey && (r.APIKeyID == nil || apiKeyID == nil || *r.APIKeyID != *apiKeyID) {
+			continue
+		}
+		if r.TenantID != nil && *r.TenantID == tenantID {
+			return r.Limit, true
+		}
+		if r.TenantID == nil {
+			fallback = &rules[i].Limit
+		}
+	}
+	if fallback != nil {
+		return *fallback, true
+	}
+	return Limit{}, false
+}
@@ -0,0 +1,168 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type call struct {
+	principal   string
+	apiKeyID    *uuid.UUID
+	class       Class
+	wantAllowed bool
+	wantLevel   Level
+	wantLimit   int
+}
+
+// repeat returns n copies of c.
+func repeat(n int, c call) []call {
+	calls := make([]call, n)
+	for i := range calls {
+		calls[i] = c
+	}
+	return calls
+}
+
+func TestLimiterAllow(t *testing.T) {
+	tenant := uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	otherTenant := uuid.MustParse("22222222-2222-2222-2222-222222222222")
+	key := uuid.MustParse("33333333-3333-3333-3333-333333333333")
+	otherKey := uuid.MustParse("44444444-4444-4444-4444-444444444444")
+
+	tests := []struct {
+		name    string
+		rules   []Rule
+		loadErr error
+		calls   []call
+	}{
+		{
+			name: "built-in principal limit",
+			calls: append(
+				repeat(5, call{principal: "alice", class: Export, wantAllowed: true, wantLevel: LevelPrincipal, wantLimit: 5}),
+				call{principal: "alice", class: Export, wantAllowed: false, wantLevel: LevelPrincipal, wantLimit: 5},
+				// other classes are counted apart
+				call{principal: "alice", class: Read, wantAllowed: true, wantLevel: LevelPrincipal, wantLimit: 60},
+			),
+		},
+		{
+			name: "tenant limit shared by principals",
+			rules: []Rule{
+				{TenantID: &tenant, Class: Write, Level: LevelTenant, Limit: Limit{PerMinute: 1, Burst: 2}},
+			},
+			calls: []call{
+				{principal: "alice", class: Write, wantAllowed: true, wantLevel: LevelTenant, wantLimit: 2},
+				{principal: "bob", class: Write, wantAllowed: true, wantLevel: LevelTenant, wantLimit: 2},
+				{principal: "carol", class: Write, wantAllowed: false, wantLevel: LevelTenant, wantLimit: 2},
+			},
+		},
+		{
+			name: "refused request draws nothing",
+			rules: []Rule{
+				{TenantID: &tenant, Class: Write, Level: LevelTenant, Limit: Limit{PerMinute: 1, Burst: 3}},
+				{TenantID: &tenant, Class: Write, Level: LevelPrincipal, Limit: Limit{PerMinute: 1, Burst: 1}},
+			},
+			calls: []call{
+				{principal: "alice", class: Write, wantAllowed: true, wantLevel: LevelPrincipal, wantLimit: 1},
+				{principal: "alice", class: Write, wantAllowed: false, wantLevel: LevelPrincipal, wantLimit: 1},
+				{principal: "alice", class: Write, wantAllowed: false, wantLevel: LevelPrincipal, wantLimit: 1},
+				// alice's refusals took nothing from the tenant, which still has two tokens
+				{principal: "bob", class: Write, wantAllowed: true, wantLevel: LevelPrincipal, wantLimit: 1},
+			},
+		},
+		{
+			name: "tenant rule beats the registry default",
+			rules: []Rule{
+				{Class: Read, Level: LevelPrincipal, Limit: Limit{PerMinute: 1, Burst: 1}},
+				{TenantID: &tenant, Class: Read, Level: LevelPrincipal, Limit: Limit{PerMinute: 1, Burst: 2}},
+				{TenantID: &otherTenant, Class: Read, Level: LevelPrincipal, Limit: Limit{PerMinute: 1, Burst: 9}},
+			},
+			calls: []call{
+				{principal: "alice", class: Read, wantAllowed: true, wantLevel: LevelPrincipal, wantLimit: 2},
+				{principal: "alice", class: Read, wantAllowed: true, wantLevel: LevelPrincipal, wantLimit: 2},
+				{principal: "alice", class: Read, wantAllowed: false, wantLevel: LevelPrincipal, wantLimit: 2},
+			},
+		},
+		{
+			name: "registry default beats the built-in default",
+			rules: []Rule{
+				{TenantID: &otherTenant, Class: Read, Level: LevelPrincipal, Limit: Limit{PerMinute: 1, Burst: 9}},
+				{Class: Read, Level: LevelPrincipal, Limit: Limit{PerMinute: 1, Burst: 1}},
+			},
+			calls: []call{
+				{principal: "alice", class: Read, wantAllowed: true, wantLevel: LevelPrincipal, wantLimit: 1},
+				{principal: "alice", class: Read, wantAllowed: false, wantLevel: LevelPrincipal, wantLimit: 1},
+			},
+		},
+		{
+			name: "api key rule replaces the principal limit",
+			rules: []Rule{
+				{TenantID: &tenant, APIKeyID: &key, Class: Read, Level: LevelAPIKey, Limit: Limit{PerMinute: 1, Burst: 1}},
+			},
+			calls: []call{
+				{principal: "key", apiKeyID: &key, class: Read, wantAllowed: true, wantLevel: LevelAPIKey, wantLimit: 1},
+				{principal: "key", apiKeyID: &key, class: Read, wantAllowed: false, wantLevel: LevelAPIKey, wantLimit: 1},
+				// a key without a rule of its own keeps the principal limit
+				{principal: "other-key", apiKeyID: &otherKey, class: Read, wantAllowed: true, wantLevel: LevelPrincipal, wantLimit: 60},
+			},
+		},
+		{
+			name:    "failed load falls back to the defaults",
+			loadErr: errors.New("registry unavailable"),
+			calls: append(
+				repeat(5, call{principal: "alice", class: Export, wantAllowed: true, wantLevel: LevelPrincipal, wantLimit: 5}),
+				call{principal: "alice", class: Export, wantAllowed: false, wantLevel: LevelPrincipal, wantLimit: 5},
+			),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			load := func(_ context.Context, tenantID uuid.UUID) ([]Rule, error) {
+				if tenantID != tenant {
+					t.Errorf("rules loaded for tenant %s, want %s", tenantID, tenant)
+				}
+				return tt.rules, tt.loadErr
+			}
+			l := New(load, time.Minute)
+			for i, c := range tt.calls {
+				d := l.Allow(context.Background(), tenant, c.principal, c.apiKeyID, c.class)
+				if d.Allowed != c.wantAllowed || d.Level != c.wantLevel || d.Limit != c.wantLimit {
+					t.Fatalf("call %d (%s, %s): allowed %t at %s limit %d, want allowed %t at %s limit %d",
+						i, c.principal, c.class, d.Allowed, d.Level, d.Limit, c.wantAllowed, c.wantLevel, c.wantLimit)
+				}
+				if d.Allowed && d.RetryAfter != 0 {
+					t.Errorf("call %d: allowed with RetryAfter %s", i, d.RetryAfter)
+				}
+				if !d.Allowed && (d.RetryAfter <= 0 || d.Remaining != 0) {
+					t.Errorf("call %d: refused with RetryAfter %s and %d remaining", i, d.RetryAfter, d.Remaining)
+				}
+			}
+		})
+	}
+}
+
+func TestLimiterInvalidate(t *testing.T) {
+	tenant := uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	burst := 1
+	load := func(context.Context, uuid.UUID) ([]Rule, error) {
+		return []Rule{{TenantID: &tenant, Class: Write, Level: LevelPrincipal, Limit: Limit{PerMinute: 1, Burst: burst}}}, nil
+	}
+	l := New(load, time.Hour)
+	if d := l.Allow(context.Background(), tenant, "alice", nil, Write); !d.Allowed || d.Limit != 1 {
+		t.Fatalf("first call = %+v, want allowed with limit 1", d)
+	}
+
+	burst = 3
+	if d := l.Allow(context.Background(), tenant, "alice", nil, Write); d.Allowed || d.Limit != 1 {
+		t.Fatalf("call with cached rules = %+v, want refused with limit 1", d)
+	}
+	l.Invalidate(tenant)
+	// The bucket keeps its tokens under the new limit, so the raised burst does not refill it.
+	if d := l.Allow(context.Background(), tenant, "alice", nil, Write); d.Allowed || d.Limit != 3 {
+		t.Fatalf("call after Invalidate = %+v, want refused with limit 3", d)
+	}
+}
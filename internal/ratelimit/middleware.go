@@ -0,0 +1,71 @@
+package ratelimit
+
+import (
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"frego-operations/internal/common"
+	"frego-operations/internal/logging"
+)
+
+// Classifier returns the route class of a request.
+type Classifier func(r *http.Request) Class
+
+// Middleware refuses requests over their tenant's or principal's limit with 429, and sets the
+// RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers on every limited response.
+// Requests without a tenant pass through.
+func Middleware(limiter *Limiter, classify Classifier) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id, _, ok := common.TenantFromContext(r.Context())
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			tenantID, err := uuid.Parse(id)
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			principal, _ := common.PrincipalFromContext(r.Context())
+			var apiKeyID *uuid.UUID
+			if raw, ok := principal.Claims["api_key_id"].(string); ok {
+				if id, err := uuid.Parse(raw); err == nil {
+					apiKeyID = &id
+				}
+			}
+			subject := principal.Subject
+			if subject == "" {
+				subject = principal.Username
+			}
+
+			class := classify(r)
+			decision := limiter.Allow(r.Context(), tenantID, subject, apiKeyID, class)
+
+			h := w.Header()
+			h.Set("RateLimit-Limit", strconv.Itoa(decision.Limit))
+			h.Set("RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+			h.Set("RateLimit-Reset", strconv.Itoa(seconds(decision.Reset)))
+			if !decision.Allowed {
+				logging.FromContext(r.Context()).Info("request rate limited",
+					slog.String("class", string(class)),
+					slog.String("level", string(decision.Level)),
+					slog.String("subject", subject),
+				)
+				h.Set("Retry-After", strconv.Itoa(max(1, seconds(decision.RetryAfter))))
+				http.Error(w, "rate limit exceeded for "+string(class)+" requests", http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,151 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	sqlc "frego-operations/internal/db/sqlc"
+)
+
+// JobDetail is a job with everything attached to it, read from one snapshot.
+type JobDetail struct {
+	Job        sqlc.GetJobRow
+	Packages   []sqlc.OpsPackage
+	Carriers   []sqlc.OpsCarrier
+	Documents  []sqlc.OpsJobDocument
+	Billing    []sqlc.ListJobBillingRow
+	Provisions []sqlc.ListJobProvisionsRow
+	Tracking   *sqlc.OpsTracking
+}
+
+// The child queries of GetJobDetail are sent as one pgx batch, which sqlc cannot generate across
+// queries. They are ListJobPackages, GetJobCarriers, ListJobDocuments, ListJobBilling,
+// ListJobProvisions and GetJobTracking with the columns written out in the order of the sqlc row
+// types, so rows scan into those types by position whatever the column order of the live table;
+// keep them in step with db/schema.sql when columns are added.
+const (
+	batchJobPackages = `
+		SELECT
+			id, job_id, container_no, container_type, container_size, gross_weight_kg, net_weight_kg,
+			volume, carrier_seal_no, commodity_cargo_description, package_type, cargo_type,
+			no_of_packages, chargeable_weight, hs_code, temperature_control, created_at, created_by,
+			modified_at, modified_by, is_active
+		FROM ops_package
+		WHERE job_id = $1 AND is_active
+		ORDER BY created_at`
+	batchJobCarriers = `
+		SELECT
+			id, job_id, carrier_party_id, carrier_name, carrier_contact, vessel_name, voyage_number,
+			flight_id, flight_date, airport_report_date, vehicle_number, vehicle_type, route_details,
+			driver_name, driver_contact, origin_port_station, destination_port_station,
+			origin_country, destination_country, accounting_info, handling_info, charges_code,
+			transport_document_reference, supporting_doc_url, file_region, description, created_at,
+			created_by, modified_at, modified_by, is_active
+		FROM ops_carrier
+		WHERE job_id = $1 AND is_active
+		ORDER BY created_at`
+	batchJobDocuments = `
+		SELECT
+			id, job_id, doc_type_code, doc_number, issued_at, issued_date, description, file_key,
+			file_region, supporting_doc_urls, bl_awb_uploads, house_doc_number, house_issued_at,
+			house_issued_date, house_description, partial_bl_number, switch_bl_awb_number,
+			switch_bl_awb_issued_at, switch_bl_awb_issued_date, switch_bl_awb_description, created_at,
+			created_by, modified_at, modified_by, is_active
+		FROM ops_job_document
+		WHERE job_id = $1 AND is_active
+		ORDER BY created_at`
+	batchJobBilling = `
+		SELECT
+			b.id, b.job_id, b.activity_type, b.activity_code, b.billing_party_id, b.po_number,
+			b.po_date, b.currency_code, b.quantity, b.unit_price, b.amount_without_tax, b.tax_code,
+			b.tax_amount, b.exchange_rate, b.total_amount, b.description, b.notes,
+			b.supporting_doc_url, b.file_region, b.amount_primary_currency, b.rate_locked_at,
+			b.rate_locked_by, b.invoice_id, b.invoiced_at, b.created_at, b.created_by, b.modified_at,
+			b.modified_by, b.is_active,
+			p.name AS billing_party_name
+		FROM ops_billing b
+		LEFT JOIN party_master p ON p.id = b.billing_party_id
+		WHERE b.job_id = $1 AND b.is_active
+		ORDER BY b.created_at`
+	batchJobProvisions = `
+		SELECT
+			p.id, p.job_id, p.activity_type, p.activity_code, p.cost_party_id, p.invoice_number,
+			p.invoice_date, p.currency_code, p.quantity, p.unit_price, p.amount_without_tax,
+			p.tax_code, p.tax_amount, p.total_amount, p.po_number, p.po_date, p.exchange_rate,
+			p.payment_priority, p.notes, p.supporting_doc_url, p.file_region,
+			p.amount_primary_currency, p.profit, p.rate_locked_at, p.rate_locked_by,
+			p.approval_status, p.created_at, p.created_by, p.modified_at, p.modified_by, p.is_active,
+			pm.name AS cost_party_name
+		FROM ops_provision p
+		LEFT JOIN party_master pm ON pm.id = p.cost_party_id
+		WHERE p.job_id = $1 AND p.is_active
+		ORDER BY p.created_at`
+	batchJobTracking = `
+		SELECT
+			id, job_id, etd_date, eta_date, atd_date, ata_date, job_status, pod_doc_urls, file_region,
+			document_status, notes, created_at, created_by, modified_at, modified_by, is_active
+		FROM ops_tracking
+		WHERE job_id = $1 AND is_active
+		LIMIT 1`
+)
+
+// GetJobDetail returns the job and its packages, carriers, documents, billing, provisions and
+// tracking in one read-only transaction: the scoped job read, then one batch for the rest. It
+// returns pgx.ErrNoRows when the job is outside the caller's data scope; a job without tracking
+// has a nil Tracking.
+func (r *Repository) GetJobDetail(ctx context.Context, id uuid.UUID) (JobDetail, error) {
+	var detail JobDetail
+	branches, salesExecutive := dataScope(ctx)
+	read := func(ctx context.Context, tx pgx.Tx) error {
+		var err error
+		detail.Job, err = sqlc.New(tx).GetJob(ctx, sqlc.GetJobParams{
+			ID:                    id,
+			ScopeBranches:         branches,
+			ScopeSalesExecutiveID: salesExecutive,
+		})
+		if err != nil {
+			return err
+		}
+
+		batch := &pgx.Batch{}
+		queueRows(batch, batchJobPackages, id, "packages", &detail.Packages)
+		queueRows(batch, batchJobCarriers, id, "carriers", &detail.Carriers)
+		queueRows(batch, batchJobDocuments, id, "documents", &detail.Documents)
+		queueRows(batch, batchJobBilling, id, "billing", &detail.Billing)
+		queueRows(batch, batchJobProvisions, id, "provisions", &detail.Provisions)
+		batch.Queue(batchJobTracking, id).Query(func(rows pgx.Rows) error {
+			tracking, err := pgx.CollectOneRow(rows, pgx.RowToStructByPos[sqlc.OpsTracking])
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("tracking: %w", err)
+			}
+			detail.Tracking = &tracking
+			return nil
+		})
+		return tx.SendBatch(ctx, batch).Close()
+	}
+	// Inside InTx the read sees the transaction's own writes
+	if tx, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return detail, read(ctx, tx)
+	}
+	err := r.tenantSessions.WithTenantReadTx(ctx, read)
+	return detail, err
+}
+
+// queueRows queues query and collects its rows into dest when the batch runs.
+func queueRows[T any](batch *pgx.Batch, query string, jobID uuid.UUID, name string, dest *[]T) {
+	batch.Queue(query, jobID).Query(func(rows pgx.Rows) error {
+		collected, err := pgx.CollectRows(rows, pgx.RowToStructByPos[T])
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		*dest = collected
+		return nil
+	})
+}
@@ -2,12 +2,15 @@ package operations
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 
+	"frego-operations/internal/common"
 	"frego-operations/internal/db"
 	sqlc "frego-operations/internal/db/sqlc"
 )
@@ -27,7 +30,32 @@ func NewWithSessions(sessions *db.TenantSessionManager) *Repository {
 	}
 }
 
+// dataScope returns the query arguments that limit job reads to the caller's data scope. Every
+// query that lists or fetches jobs for a caller takes them.
+func dataScope(ctx context.Context) ([]uuid.UUID, pgtype.UUID) {
+	scope := common.DataScopeFromContext(ctx)
+	return scope.BranchIDs, NullUUIDFromUUID(scope.SalesExecutiveID)
+}
+
+// txKey carries the transaction opened by InTx.
+type txKey struct{}
+
+// InTx runs fn in one tenant transaction. Repository calls made with the context fn receives run
+// in that transaction, so its writes commit or roll back together; a nested InTx joins the outer
+// transaction.
+func (r *Repository) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return fn(ctx)
+	}
+	return r.tenantSessions.WithTenantTx(ctx, func(ctx context.Context, tx pgx.Tx) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+}
+
 func (r *Repository) withQueries(ctx context.Context, fn func(*sqlc.Queries) error) error {
+	if tx, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return fn(sqlc.New(tx))
+	}
 	return r.tenantSessions.WithTenantTx(ctx, func(ctx context.Context, tx pgx.Tx) error {
 		q := sqlc.New(tx)
 		return fn(q)
@@ -98,6 +126,66 @@ func (r *Repository) ListActivityLookups(ctx context.Context) ([]sqlc.ListActivi
 	return rows, err
 }
 
+func (r *Repository) ListIncotermLookups(ctx context.Context) ([]sqlc.ListIncotermLookupsRow, error) {
+	var rows []sqlc.ListIncotermLookupsRow
+	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
+		var err error
+		rows, err = q.ListIncotermLookups(ctx)
+		return err
+	})
+	return rows, err
+}
+
+func (r *Repository) GetIncoterm(ctx context.Context, code string) (sqlc.GetIncotermRow, error) {
+	var row sqlc.GetIncotermRow
+	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
+		var err error
+		row, err = q.GetIncoterm(ctx, code)
+		return err
+	})
+	return row, err
+}
+
+func (r *Repository) GetTaxRate(ctx context.Context, taxCode string) (pgtype.Numeric, error) {
+	var rate pgtype.Numeric
+	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
+		var err error
+		rate, err = q.GetTaxRate(ctx, taxCode)
+		return err
+	})
+	return rate, err
+}
+
+func (r *Repository) GetPrimaryCurrency(ctx context.Context) (string, error) {
+	var currency string
+	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
+		var err error
+		currency, err = q.GetPrimaryCurrency(ctx)
+		return err
+	})
+	return currency, err
+}
+
+func (r *Repository) GetAccrualTolerance(ctx context.Context) (sqlc.GetAccrualToleranceRow, error) {
+	var row sqlc.GetAccrualToleranceRow
+	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
+		var err error
+		row, err = q.GetAccrualTolerance(ctx)
+		return err
+	})
+	return row, err
+}
+
+func (r *Repository) UpdateAccrualTolerance(ctx context.Context, params sqlc.UpdateAccrualToleranceParams) (sqlc.UpdateAccrualToleranceRow, error) {
+	var row sqlc.UpdateAccrualToleranceRow
+	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
+		var err error
+		row, err = q.UpdateAccrualTolerance(ctx, params)
+		return err
+	})
+	return row, err
+}
+
 // ============================================================
 // JOB CRUD METHODS
 // ============================================================
@@ -114,24 +202,33 @@ func (r *Repository) GetNextJobSequence(ctx context.Context, prefix string) (int
 
 func (r *Repository) ListJobs(ctx context.Context, status, jobType *string, customerID *uuid.UUID, limit int32) ([]sqlc.ListJobsRow, error) {
 	var rows []sqlc.ListJobsRow
+	branches, salesExecutive := dataScope(ctx)
 	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
 		var err error
 		rows, err = q.ListJobs(ctx, sqlc.ListJobsParams{
-			Status:     NullTextFromString(status),
-			CustomerID: NullUUIDFromUUID(customerID),
-			JobType:    NullTextFromString(jobType),
-			RowLimit:   limit,
+			Status:                NullTextFromString(status),
+			CustomerID:            NullUUIDFromUUID(customerID),
+			JobType:               NullTextFromString(jobType),
+			ScopeBranches:         branches,
+			ScopeSalesExecutiveID: salesExecutive,
+			RowLimit:              limit,
 		})
 		return err
 	})
 	return rows, err
 }
 
+// GetJob returns the job, or pgx.ErrNoRows when it is outside the caller's data scope.
 func (r *Repository) GetJob(ctx context.Context, id uuid.UUID) (sqlc.GetJobRow, error) {
 	var row sqlc.GetJobRow
+	branches, salesExecutive := dataScope(ctx)
 	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
 		var err error
-		row, err = q.GetJob(ctx, id)
+		row, err = q.GetJob(ctx, sqlc.GetJobParams{
+			ID:                    id,
+			ScopeBranches:         branches,
+			ScopeSalesExecutiveID: salesExecutive,
+		})
 		return err
 	})
 	return row, err
@@ -147,23 +244,125 @@ func (r *Repository) CreateJob(ctx context.Context, params sqlc.CreateJobParams)
 	return job, err
 }
 
+// ErrJobClosed is returned when a write targets a closed job.
+var ErrJobClosed = errors.New("repository: job is closed")
+
+// LockJob takes a row lock on the job for the rest of the transaction and returns its status, so
+// a close cannot slip in between the caller's check and its writes. Call it inside InTx.
+func (r *Repository) LockJob(ctx context.Context, id uuid.UUID) (string, error) {
+	var status string
+	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
+		row, err := q.LockJob(ctx, id)
+		if err != nil {
+			return err
+		}
+		status = row.Status.String
+		return nil
+	})
+	return status, err
+}
+
+// UpdateJob updates an open job. It returns ErrJobClosed when the job is closed.
 func (r *Repository) UpdateJob(ctx context.Context, params sqlc.UpdateJobParams) (sqlc.OpsJob, error) {
 	var job sqlc.OpsJob
 	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
 		var err error
 		job, err = q.UpdateJob(ctx, params)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrJobClosed
+		}
 		return err
 	})
 	return job, err
 }
 
+// ArchiveJob archives an open job. It returns ErrJobClosed when the job is closed.
 func (r *Repository) ArchiveJob(ctx context.Context, id uuid.UUID, actor string) error {
 	return r.withQueries(ctx, func(q *sqlc.Queries) error {
-		return q.ArchiveJob(ctx, sqlc.ArchiveJobParams{
+		n, err := q.ArchiveJob(ctx, sqlc.ArchiveJobParams{
 			ID:    id,
 			Actor: pgtype.Text{String: actor, Valid: true},
 		})
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return ErrJobClosed
+		}
+		return nil
+	})
+}
+
+// ErrJobNotClosed is returned when reopening a job that is not (or no longer) closed.
+var ErrJobNotClosed = errors.New("repository: job is not closed")
+
+// ReopenJob moves a closed job back to Active, records the transition and its reason, and queues
+// the event built from the history row, in one transaction.
+func (r *Repository) ReopenJob(ctx context.Context, id uuid.UUID, reason, actor string, event func(sqlc.OpsJobStatusHistory) (sqlc.CreateOutboxEventParams, error)) (sqlc.OpsJobStatusHistory, error) {
+	var history sqlc.OpsJobStatusHistory
+	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
+		n, err := q.ReopenJob(ctx, sqlc.ReopenJobParams{
+			Actor: pgtype.Text{String: actor, Valid: true},
+			ID:    id,
+		})
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return ErrJobNotClosed
+		}
+		history, err = q.CreateJobStatusHistory(ctx, sqlc.CreateJobStatusHistoryParams{
+			JobID:      id,
+			FromStatus: pgtype.Text{String: "Closed", Valid: true},
+			ToStatus:   pgtype.Text{String: "Active", Valid: true},
+			Reason:     pgtype.Text{String: reason, Valid: true},
+			Actor:      pgtype.Text{String: actor, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+		params, err := event(history)
+		if err != nil {
+			return err
+		}
+		return q.CreateOutboxEvent(ctx, params)
+	})
+	return history, err
+}
+
+func (r *Repository) CreateJobStatusHistory(ctx context.Context, params sqlc.CreateJobStatusHistoryParams) (sqlc.OpsJobStatusHistory, error) {
+	var row sqlc.OpsJobStatusHistory
+	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
+		var err error
+		row, err = q.CreateJobStatusHistory(ctx, params)
+		return err
+	})
+	return row, err
+}
+
+func (r *Repository) ListJobStatusHistory(ctx context.Context, jobID uuid.UUID) ([]sqlc.OpsJobStatusHistory, error) {
+	var rows []sqlc.OpsJobStatusHistory
+	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
+		var err error
+		rows, err = q.ListJobStatusHistory(ctx, jobID)
+		return err
+	})
+	return rows, err
+}
+
+func (r *Repository) ListHouseJobs(ctx context.Context, parentJobID uuid.UUID) ([]sqlc.ListHouseJobsRow, error) {
+	var rows []sqlc.ListHouseJobsRow
+	branches, salesExecutive := dataScope(ctx)
+	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
+		var err error
+		rows, err = q.ListHouseJobs(ctx, sqlc.ListHouseJobsParams{
+			ParentJobID:           pgtype.UUID{Bytes: parentJobID, Valid: true},
+			ScopeBranches:         branches,
+			ScopeSalesExecutiveID: salesExecutive,
+		})
+		return err
 	})
+	return rows, err
 }
 
 // ============================================================
@@ -250,6 +449,167 @@ func (r *Repository) CreateJobDocument(ctx context.Context, params sqlc.CreateJo
 	return doc, err
 }
 
+func (r *Repository) GetNextDocumentSequence(ctx context.Context, prefix string) (int32, error) {
+	var seq int32
+	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
+		var err error
+		seq, err = q.GetNextDocumentSequence(ctx, pgtype.Text{String: prefix, Valid: true})
+		return err
+	})
+	return seq, err
+}
+
+// ============================================================
+// DOCUMENT TEMPLATE METHODS
+// ============================================================
+
+func (r *Repository) GetDocumentType(ctx context.Context, code string) (sqlc.GetDocumentTypeRow, error) {
+	var row sqlc.GetDocumentTypeRow
+	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
+		var err error
+		row, err = q.GetDocumentType(ctx, code)
+		return err
+	})
+	return row, err
+}
+
+func (r *Repository) GetActiveDocTemplate(ctx context.Context, docTypeCode string) (sqlc.DocTemplate, error) {
+	var tpl sqlc.DocTemplate
+	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
+		var err error
+		tpl, err = q.GetActiveDocTemplate(ctx, docTypeCode)
+		return err
+	})
+	return tpl, err
+}
+
+func (r *Repository) ListDocTemplates(ctx context.Context, docTypeCode *string) ([]sqlc.DocTemplate, error) {
+	var rows []sqlc.DocTemplate
+	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
+		var err error
+		rows, err = q.ListDocTemplates(ctx, NullTextFromString(docTypeCode))
+		return err
+	})
+	return rows, err
+}
+
+func (r *Repository) CreateDocTemplate(ctx context.Context, params sqlc.CreateDocTemplateParams) (sqlc.DocTemplate, error) {
+	var tpl sqlc.DocTemplate
+	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
+		var err error
+		tpl, err = q.CreateDocTemplate(ctx, params)
+		return err
+	})
+	return tpl, err
+}
+
+// ============================================================
+// CHARGE TEMPLATE METHODS
+// ============================================================
+
+// ListChargeTemplates returns the active charge templates and all of their lines.
+func (r *Repository) ListChargeTemplates(ctx context.Context, transportMode *string) ([]sqlc.ChargeTemplate, []sqlc.ChargeTemplateLine, error) {
+	var (
+		templates []sqlc.ChargeTemplate
+		lines     []sqlc.ChargeTemplateLine
+	)
+	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
+		var err error
+		templates, err = q.ListChargeTemplates(ctx, NullTextFromString(transportMode))
+		if err != nil || len(templates) == 0 {
+			return err
+		}
+		ids := make([]uuid.UUID, 0, len(templates))
+		for _, t := range templates {
+			ids = append(ids, t.ID)
+		}
+		lines, err = q.ListChargeTemplateLines(ctx, ids)
+		return err
+	})
+	return templates, lines, err
+}
+
+// GetMatchingChargeTemplate returns the most specific active template for a service combination.
+func (r *Repository) GetMatchingChargeTemplate(ctx context.Context, params sqlc.GetMatchingChargeTemplateParams) (sqlc.ChargeTemplate, []sqlc.ChargeTemplateLine, error) {
+	var (
+		template sqlc.ChargeTemplate
+		lines    []sqlc.ChargeTemplateLine
+	)
+	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
+		var err error
+		template, err = q.GetMatchingChargeTemplate(ctx, params)
+		if err != nil {
+			return err
+		}
+		lines, err = q.ListChargeTemplateLines(ctx, []uuid.UUID{template.ID})
+		return err
+	})
+	return template, lines, err
+}
+
+// CreateChargeTemplate stores a template and its lines in one transaction.
+func (r *Repository) CreateChargeTemplate(ctx context.Context, params sqlc.CreateChargeTemplateParams, lineParams []sqlc.CreateChargeTemplateLineParams) (sqlc.ChargeTemplate, []sqlc.ChargeTemplateLine, error) {
+	var (
+		template sqlc.ChargeTemplate
+		lines    []sqlc.ChargeTemplateLine
+	)
+	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
+		var err error
+		template, err = q.CreateChargeTemplate(ctx, params)
+		if err != nil {
+			return err
+		}
+		lines = make([]sqlc.ChargeTemplateLine, 0, len(lineParams))
+		for _, lp := range lineParams {
+			lp.TemplateID = template.ID
+			line, err := q.CreateChargeTemplateLine(ctx, lp)
+			if err != nil {
+				return err
+			}
+			lines = append(lines, line)
+		}
+		return nil
+	})
+	return template, lines, err
+}
+
+func (r *Repository) DeactivateChargeTemplate(ctx context.Context, id uuid.UUID, actor string) (int64, error) {
+	var n int64
+	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
+		var err error
+		n, err = q.DeactivateChargeTemplate(ctx, sqlc.DeactivateChargeTemplateParams{
+			Actor: pgtype.Text{String: actor, Valid: true},
+			ID:    id,
+		})
+		return err
+	})
+	return n, err
+}
+
+// ============================================================
+// JOB PARTY METHODS
+// ============================================================
+
+func (r *Repository) GetJobParty(ctx context.Context, jobID uuid.UUID) (sqlc.OpsParty, error) {
+	var party sqlc.OpsParty
+	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
+		var err error
+		party, err = q.GetJobParty(ctx, jobID)
+		return err
+	})
+	return party, err
+}
+
+func (r *Repository) ListPartiesByIDs(ctx context.Context, ids []uuid.UUID) ([]sqlc.ListPartiesByIDsRow, error) {
+	var rows []sqlc.ListPartiesByIDsRow
+	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
+		var err error
+		rows, err = q.ListPartiesByIDs(ctx, ids)
+		return err
+	})
+	return rows, err
+}
+
 // ============================================================
 // JOB BILLING METHODS
 // ============================================================
@@ -302,6 +662,12 @@ func (r *Repository) CreateJobProvision(ctx context.Context, params sqlc.CreateJ
 	return provision, err
 }
 
+func (r *Repository) UpdateJobProvisionProfit(ctx context.Context, params sqlc.UpdateJobProvisionProfitParams) error {
+	return r.withQueries(ctx, func(q *sqlc.Queries) error {
+		return q.UpdateJobProvisionProfit(ctx, params)
+	})
+}
+
 // ============================================================
 // JOB TRACKING METHODS
 // ============================================================
@@ -328,6 +694,681 @@ func (r *Repository) UpsertJobTracking(ctx context.Context, params sqlc.UpsertJo
 	return tracking, err
 }
 
+// ============================================================
+// EXCHANGE RATE METHODS
+// ============================================================
+
+func (r *Repository) GetExchangeRateInForce(ctx context.Context, from, to string, on time.Time) (sqlc.GetExchangeRateInForceRow, error) {
+	var row sqlc.GetExchangeRateInForceRow
+	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
+		var err error
+		row, err = q.GetExchangeRateInForce(ctx, sqlc.GetExchangeRateInForceParams{
+			FromCurrency: from,
+			ToCurrency:   to,
+			OnDate:       pgtype.Date{Time: on, Valid: true},
+		})
+		return err
+	})
+	return row, err
+}
+
+func (r *Repository) ListExchangeRates(ctx context.Context, from, to *string, limit int32) ([]sqlc.ExchangeRate, error) {
+	var rows []sqlc.ExchangeRate
+	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
+		var err error
+		rows, err = q.ListExchangeRates(ctx, sqlc.ListExchangeRatesParams{
+			FromCurrency: NullTextFromString(from),
+			ToCurrency:   NullTextFromString(to),
+			RowLimit:     limit,
+		})
+		return err
+	})
+	return rows, err
+}
+
+// ImportExchangeRates upserts a whole rate sheet in one transaction.
+func (r *Repository) ImportExchangeRates(ctx context.Context, rates []sqlc.UpsertExchangeRateParams) error {
+	return r.withQueries(ctx, func(q *sqlc.Queries) error {
+		for _, rate := range rates {
+			if _, err := q.UpsertExchangeRate(ctx, rate); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ApplyJobRates rewrites the exchange rate of unlocked billing and provision lines in one transaction.
+func (r *Repository) ApplyJobRates(ctx context.Context, billing []sqlc.UpdateJobBillingRateParams, provisions []sqlc.UpdateJobProvisionRateParams) (int64, error) {
+	var updated int64
+	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
+		for _, params := range billing {
+			n, err := q.UpdateJobBillingRate(ctx, params)
+			if err != nil {
+				return err
+			}
+			updated += n
+		}
+		for _, params := range provisions {
+			n, err := q.UpdateJobProvisionRate(ctx, params)
+			if err != nil {
+				return err
+			}
+			updated += n
+		}
+		return nil
+	})
+	return updated, err
+}
+
+// LockJobRates stamps every unlocked billing and provision line of a job so its rate no longer moves.
+func (r *Repository) LockJobRates(ctx context.Context, jobID uuid.UUID, actor string) (int64, error) {
+	var locked int64
+	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
+		n, err := q.LockJobBillingRates(ctx, sqlc.LockJobBillingRatesParams{
+			JobID: pgtype.UUID{Bytes: jobID, Valid: true},
+			Actor: pgtype.Text{String: actor, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+		locked += n
+		n, err = q.LockJobProvisionRates(ctx, sqlc.LockJobProvisionRatesParams{
+			JobID: pgtype.UUID{Bytes: jobID, Valid: true},
+			Actor: pgtype.Text{String: actor, Valid: true},
+		})
+		locked += n
+		return err
+	})
+	return locked, err
+}
+
+// ============================================================
+// PROFITABILITY METHODS
+// ============================================================
+
+func (r *Repository) ListJobProfitability(ctx context.Context, params sqlc.ListJobProfitabilityParams) ([]sqlc.ListJobProfitabilityRow, error) {
+	var rows []sqlc.ListJobProfitabilityRow
+	params.ScopeBranches, params.ScopeSalesExecutiveID = dataScope(ctx)
+	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
+		var err error
+		rows, err = q.ListJobProfitability(ctx, params)
+		return err
+	})
+	return rows, err
+}
+
+func (r *Repository) ListActivityProfitability(ctx context.Context, params sqlc.ListActivityProfitabilityParams) ([]sqlc.ListActivityProfitabilityRow, error) {
+	var rows []sqlc.ListActivityProfitabilityRow
+	params.ScopeBranches, params.ScopeSalesExecutiveID = dataScope(ctx)
+	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
+		var err error
+		rows, err = q.ListActivityProfitability(ctx, params)
+		return err
+	})
+	return rows, err
+}
+
+// ============================================================
+// ACCRUAL RECONCILIATION METHODS
+// ============================================================
+
+func (r *Repository) GetProvision(ctx context.Context, id uuid.UUID) (sqlc.GetProvisionRow, error) {
+	var row sqlc.GetProvisionRow
+	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
+		var err error
+		row, err = q.GetProvision(ctx, id)
+		return err
+	})
+	return row, err
+}
+
+// RecordProvisionReconciliation replaces the provision's active reconciliation and copies the
+// supplier invoice reference onto the provision, in one transaction. Pending approvals of the
+// replaced reconciliation are cancelled; approval, when set, is requested for the new one.
+func (r *Repository) RecordProvisionReconciliation(ctx context.Context, params sqlc.CreateProvisionReconciliationParams, approval *sqlc.CreateApprovalParams) (sqlc.OpsProvisionReconciliation, error) {
+	var row sqlc.OpsProvisionReconciliation
+	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
+		replaced, err := q.DeactivateProvisionReconciliation(ctx, sqlc.DeactivateProvisionReconciliationParams{
+			Actor:       params.Actor,
+			ProvisionID: params.ProvisionID,
+		})
+		if err != nil {
+			return err
+		}
+		if len(replaced) > 0 {
+			err = q.CancelPendingApprovals(ctx, sqlc.CancelPendingApprovalsParams{
+				Actor:       params.Actor,
+				SubjectType: ApprovalSubjectReconciliation,
+				SubjectIds:  replaced,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		row, err = q.CreateProvisionReconciliation(ctx, params)
+		if err != nil {
+			return err
+		}
+		if approval != nil {
+			approval.SubjectID = row.ID
+			if _, err := q.CreateApproval(ctx, *approval); err != nil {
+				return err
+			}
+		}
+		return q.UpdateProvisionSupplierInvoice(ctx, sqlc.UpdateProvisionSupplierInvoiceParams{
+			InvoiceNumber: pgtype.Text{String: params.SupplierInvoiceNumber, Valid: true},
+			InvoiceDate:   params.SupplierInvoiceDate,
+			Actor:         params.Actor,
+			ID:            params.ProvisionID,
+		})
+	})
+	return row, err
+}
+
+func (r *Repository) GetProvisionReconciliation(ctx context.Context, id uuid.UUID) (sqlc.GetProvisionReconciliationRow, error) {
+	var row sqlc.GetProvisionReconciliationRow
+	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
+		var err error
+		row, err = q.GetProvisionReconciliation(ctx, id)
+		return err
+	})
+	return row, err
+}
+
+func (r *Repository) ListJobReconciliations(ctx context.Context, jobID uuid.UUID) ([]sqlc.ListJobReconciliationsRow, error) {
+	var rows []sqlc.ListJobReconciliationsRow
+	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
+		var err error
+		rows, err = q.ListJobReconciliations(ctx, jobID)
+		return err
+	})
+	return rows, err
+}
+
+func (r *Repository) ListReconciliationsByStatus(ctx context.Context, params sqlc.ListReconciliationsByStatusParams) ([]sqlc.ListReconciliationsByStatusRow, error) {
+	var rows []sqlc.ListReconciliationsByStatusRow
+	params.ScopeBranches, params.ScopeSalesExecutiveID = dataScope(ctx)
+	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
+		var err error
+		rows, err = q.ListReconciliationsByStatus(ctx, params)
+		return err
+	})
+	return rows, err
+}
+
+func (r *Repository) ListOpenAccruals(ctx context.Context, params sqlc.ListOpenAccrualsParams) ([]sqlc.ListOpenAccrualsRow, error) {
+	var rows []sqlc.ListOpenAccrualsRow
+	params.ScopeBranches, params.ScopeSalesExecutiveID = dataScope(ctx)
+	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
+		var err error
+		rows, err = q.ListOpenAccruals(ctx, params)
+		return err
+	})
+	return rows, err
+}
+
+// ============================================================
+// APPROVAL METHODS
+// ============================================================
+
+// Subjects of an approval request, as constrained in ops_approval.subject_type.
+const (
+	ApprovalSubjectProvision      = "provision"
+	ApprovalSubjectReconciliation = "reconciliation"
+)
+
+// ErrApprovalDecided is returned when an approval request was decided by a concurrent request.
+var ErrApprovalDecided = errors.New("repository: approval already decided")
+
+func (r *Repository) ListProvisionApprovalRules(ctx context.Context) ([]sqlc.ProvisionApprovalRule, error) {
+	var rows []sqlc.ProvisionApprovalRule
+	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
+		var err error
+		rows, err = q.ListProvisionApprovalRules(ctx)
+		return err
+	})
+	return rows, err
+}
+
+func (r *Repository) CreateProvisionApprovalRule(ctx context.Context, params sqlc.CreateProvisionApprovalRuleParams) (sqlc.ProvisionApprovalRule, error) {
+	var row sqlc.ProvisionApprovalRule
+	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
+		var err error
+		row, err = q.CreateProvisionApprovalRule(ctx, params)
+		return err
+	})
+	return row, err
+}
+
+func (r *Repository) DeactivateProvisionApprovalRule(ctx context.Context, id uuid.UUID, actor string) (int64, error) {
+	var n int64
+	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
+		var err error
+		n, err = q.DeactivateProvisionApprovalRule(ctx, sqlc.DeactivateProvisionApprovalRuleParams{
+			Actor: pgtype.Text{String: actor, Valid: true},
+			ID:    id,
+		})
+		return err
+	})
+	return n, err
+}
+
+func (r *Repository) GetPartyRisk(ctx context.Context, id uuid.UUID) (sqlc.GetPartyRiskRow, error) {
+	var row sqlc.GetPartyRiskRow
+	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
+		var err error
+		row, err = q.GetPartyRisk(ctx, id)
+		return err
+	})
+	return row, err
+}
+
+// RequestProvisionApproval opens an approval request for a provision and marks it pending approval.
+func (r *Repository) RequestProvisionApproval(ctx context.Context, params sqlc.CreateApprovalParams) (sqlc.OpsApproval, error) {
+	var approval sqlc.OpsApproval
+	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
+		var err error
+		approval, err = q.CreateApproval(ctx, params)
+		if err != nil {
+			return err
+		}
+		return q.SetProvisionApprovalStatus(ctx, sqlc.SetProvisionApprovalStatusParams{
+			ApprovalStatus: "PendingApproval",
+			Actor:          params.Actor,
+			ID:             params.SubjectID,
+		})
+	})
+	return approval, err
+}
+
+func (r *Repository) GetApproval(ctx context.Context, id uuid.UUID) (sqlc.OpsApproval, error) {
+	var approval sqlc.OpsApproval
+	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
+		var err error
+		approval, err = q.GetApproval(ctx, id)
+		return err
+	})
+	return approval, err
+}
+
+// DecideApproval records the decision on a pending request and carries its status (Approved or
+// Rejected) over to the provision or reconciliation, in one transaction.
+func (r *Repository) DecideApproval(ctx context.Context, approval sqlc.OpsApproval, status string, actor string, comment pgtype.Text) error {
+	return r.withQueries(ctx, func(q *sqlc.Queries) error {
+		n, err := q.DecideApproval(ctx, sqlc.DecideApprovalParams{
+			Status:  status,
+			Actor:   pgtype.Text{String: actor, Valid: true},
+			Comment: comment,
+			ID:      approval.ID,
+		})
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return ErrApprovalDecided
+		}
+		switch approval.SubjectType {
+		case ApprovalSubjectProvision:
+			return q.SetProvisionApprovalStatus(ctx, sqlc.SetProvisionApprovalStatusParams{
+				ApprovalStatus: status,
+				Actor:          pgtype.Text{String: actor, Valid: true},
+				ID:             approval.SubjectID,
+			})
+		case ApprovalSubjectReconciliation:
+			return q.SetReconciliationStatus(ctx, sqlc.SetReconciliationStatusParams{
+				Status: status,
+				Actor:  pgtype.Text{String: actor, Valid: true},
+				ID:     approval.SubjectID,
+			})
+		}
+		return nil
+	})
+}
+
+func (r *Repository) ListPendingApprovals(ctx context.Context, roles []string, limit int32) ([]sqlc.ListPendingApprovalsRow, error) {
+	var rows []sqlc.ListPendingApprovalsRow
+	branches, salesExecutive := dataScope(ctx)
+	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
+		var err error
+		rows, err = q.ListPendingApprovals(ctx, sqlc.ListPendingApprovalsParams{
+			Roles:                 roles,
+			ScopeBranches:         branches,
+			ScopeSalesExecutiveID: salesExecutive,
+			RowLimit:              limit,
+		})
+		return err
+	})
+	return rows, err
+}
+
+// ============================================================
+// PARTY ELIGIBILITY AND CREDIT METHODS
+// ============================================================
+
+func (r *Repository) ListPartyStatuses(ctx context.Context, ids []uuid.UUID) ([]sqlc.ListPartyStatusesRow, error) {
+	var rows []sqlc.ListPartyStatusesRow
+	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
+		var err error
+		rows, err = q.ListPartyStatuses(ctx, ids)
+		return err
+	})
+	return rows, err
+}
+
+func (r *Repository) ListJobPartyStatuses(ctx context.Context, jobID uuid.UUID) ([]sqlc.ListJobPartyStatusesRow, error) {
+	var rows []sqlc.ListJobPartyStatusesRow
+	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
+		var err error
+		rows, err = q.ListJobPartyStatuses(ctx, jobID)
+		return err
+	})
+	return rows, err
+}
+
+func (r *Repository) GetPartyCreditLimit(ctx context.Context, partyID uuid.UUID) (sqlc.PartyCreditLimit, error) {
+	var row sqlc.PartyCreditLimit
+	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
+		var err error
+		row, err = q.GetPartyCreditLimit(ctx, partyID)
+		return err
+	})
+	return row, err
+}
+
+func (r *Repository) UpsertPartyCreditLimit(ctx context.Context, params sqlc.UpsertPartyCreditLimitParams) (sqlc.PartyCreditLimit, error) {
+	var row sqlc.PartyCreditLimit
+	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
+		var err error
+		row, err = q.UpsertPartyCreditLimit(ctx, params)
+		return err
+	})
+	return row, err
+}
+
+func (r *Repository) DeletePartyCreditLimit(ctx context.Context, partyID uuid.UUID) (int64, error) {
+	var n int64
+	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
+		var err error
+		n, err = q.DeletePartyCreditLimit(ctx, partyID)
+		return err
+	})
+	return n, err
+}
+
+// GetCustomerCreditExposure sums the customer's open billed and unbilled amounts on active jobs,
+// counting jobID whatever its status.
+func (r *Repository) GetCustomerCreditExposure(ctx context.Context, customerID uuid.UUID, jobID *uuid.UUID) (sqlc.GetCustomerCreditExposureRow, error) {
+	var row sqlc.GetCustomerCreditExposureRow
+	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
+		var err error
+		row, err = q.GetCustomerCreditExposure(ctx, sqlc.GetCustomerCreditExposureParams{
+			CustomerID: NullUUIDFromUUID(&customerID),
+			JobID:      NullUUIDFromUUID(jobID),
+		})
+		return err
+	})
+	return row, err
+}
+
+func (r *Repository) CreateCreditOverride(ctx context.Context, params sqlc.CreateCreditOverrideParams) error {
+	return r.withQueries(ctx, func(q *sqlc.Queries) error {
+		return q.CreateCreditOverride(ctx, params)
+	})
+}
+
+func (r *Repository) ListPartyCreditOverrides(ctx context.Context, partyID uuid.UUID) ([]sqlc.ListPartyCreditOverridesRow, error) {
+	var rows []sqlc.ListPartyCreditOverridesRow
+	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
+		var err error
+		rows, err = q.ListPartyCreditOverrides(ctx, partyID)
+		return err
+	})
+	return rows, err
+}
+
+// ============================================================
+// RESTRICTED-PARTY SCREENING METHODS
+// ============================================================
+
+// ErrScreeningResolved is returned when a screening match was resolved by a concurrent request.
+var ErrScreeningResolved = errors.New("repository: screening match already resolved")
+
+func (r *Repository) ListJobScreeningParties(ctx context.Context, jobID uuid.UUID) ([]sqlc.ListJobScreeningPartiesRow, error) {
+	var rows []sqlc.ListJobScreeningPartiesRow
+	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
+		var err error
+		rows, err = q.ListJobScreeningParties(ctx, jobID)
+		return err
+	})
+	return rows, err
+}
+
+func (r *Repository) ListResolvedScreeningMatches(ctx context.Context, jobID uuid.UUID) ([]sqlc.ListResolvedScreeningMatchesRow, error) {
+	var rows []sqlc.ListResolvedScreeningMatchesRow
+	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
+		var err error
+		rows, err = q.ListResolvedScreeningMatches(ctx, jobID)
+		return err
+	})
+	return rows, err
+}
+
+// RecordJobScreening supersedes the job's open screening results and stores the new ones, in one
+// transaction.
+func (r *Repository) RecordJobScreening(ctx context.Context, jobID uuid.UUID, actor string, results []sqlc.CreatePartyScreeningParams) ([]sqlc.OpsPartyScreening, error) {
+	var rows []sqlc.OpsPartyScreening
+	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
+		err := q.SupersedeJobScreenings(ctx, sqlc.SupersedeJobScreeningsParams{
+			Actor: pgtype.Text{String: actor, Valid: true},
+			JobID: jobID,
+		})
+		if err != nil {
+			return err
+		}
+		rows = make([]sqlc.OpsPartyScreening, 0, len(results))
+		for _, params := range results {
+			row, err := q.CreatePartyScreening(ctx, params)
+			if err != nil {
+				return err
+			}
+			rows = append(rows, row)
+		}
+		return nil
+	})
+	return rows, err
+}
+
+func (r *Repository) ListJobScreenings(ctx context.Context, jobID uuid.UUID) ([]sqlc.OpsPartyScreening, error) {
+	var rows []sqlc.OpsPartyScreening
+	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
+		var err error
+		rows, err = q.ListJobScreenings(ctx, jobID)
+		return err
+	})
+	return rows, err
+}
+
+func (r *Repository) GetPartyScreening(ctx context.Context, id uuid.UUID) (sqlc.OpsPartyScreening, error) {
+	var row sqlc.OpsPartyScreening
+	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
+		var err error
+		row, err = q.GetPartyScreening(ctx, id)
+		return err
+	})
+	return row, err
+}
+
+func (r *Repository) ResolvePartyScreening(ctx context.Context, params sqlc.ResolvePartyScreeningParams) error {
+	return r.withQueries(ctx, func(q *sqlc.Queries) error {
+		n, err := q.ResolvePartyScreening(ctx, params)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return ErrScreeningResolved
+		}
+		return nil
+	})
+}
+
+func (r *Repository) CountBlockingScreenings(ctx context.Context, jobID uuid.UUID) (int64, error) {
+	var n int64
+	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
+		var err error
+		n, err = q.CountBlockingScreenings(ctx, jobID)
+		return err
+	})
+	return n, err
+}
+
+// ============================================================
+// ROLE PERMISSION METHODS
+// ============================================================
+
+func (r *Repository) ListRolePermissions(ctx context.Context) ([]sqlc.ListRolePermissionsRow, error) {
+	var rows []sqlc.ListRolePermissionsRow
+	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
+		var err error
+		rows, err = q.ListRolePermissions(ctx)
+		return err
+	})
+	return rows, err
+}
+
+// ReplaceRolePermissions sets the permissions granted to a role_details_lu role, in one
+// transaction. It returns pgx.ErrNoRows when the role does not exist.
+func (r *Repository) ReplaceRolePermissions(ctx context.Context, roleID int16, permissions []string, actor string) (sqlc.GetRoleDetailsRow, error) {
+	var role sqlc.GetRoleDetailsRow
+	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
+		var err error
+		role, err = q.GetRoleDetails(ctx, roleID)
+		if err != nil {
+			return err
+		}
+		if err := q.DeleteRolePermissions(ctx, roleID); err != nil {
+			return err
+		}
+		for _, permission := range permissions {
+			err := q.CreateRolePermission(ctx, sqlc.CreateRolePermissionParams{
+				RoleID:     roleID,
+				Permission: permission,
+				Actor:      pgtype.Text{String: actor, Valid: actor != ""},
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return role, err
+}
+
+// ============================================================
+// INVOICE METHODS
+// ============================================================
+
+// ErrBillingAlreadyInvoiced is returned when a billing line was invoiced by a concurrent request.
+var ErrBillingAlreadyInvoiced = errors.New("repository: billing line already invoiced")
+
+// InvoiceDraft is one invoice to create together with the billing lines it covers.
+// Event builds the outbox event for the stored invoice, once its number is known.
+type InvoiceDraft struct {
+	Invoice    sqlc.CreateInvoiceParams
+	BillingIDs []uuid.UUID
+	Event      func(sqlc.OpsInvoice) (sqlc.CreateOutboxEventParams, error)
+}
+
+func (r *Repository) ListUninvoicedJobBilling(ctx context.Context, jobID uuid.UUID) ([]sqlc.OpsBilling, error) {
+	var rows []sqlc.OpsBilling
+	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
+		var err error
+		rows, err = q.ListUninvoicedJobBilling(ctx, NullUUIDFromUUID(&jobID))
+		return err
+	})
+	return rows, err
+}
+
+// CreateDraftInvoices numbers and stores the drafts, marks their billing lines invoiced and
+// queues their events in one transaction, so either every draft is created or none is.
+func (r *Repository) CreateDraftInvoices(ctx context.Context, numberPrefix string, drafts []InvoiceDraft) ([]sqlc.OpsInvoice, error) {
+	var invoices []sqlc.OpsInvoice
+	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
+		invoices = make([]sqlc.OpsInvoice, 0, len(drafts))
+		for _, draft := range drafts {
+			number, err := q.NextInvoiceNumber(ctx, numberPrefix)
+			if err != nil {
+				return err
+			}
+			params := draft.Invoice
+			params.InvoiceNumber = number
+			invoice, err := q.CreateInvoice(ctx, params)
+			if err != nil {
+				return err
+			}
+			n, err := q.MarkBillingInvoiced(ctx, sqlc.MarkBillingInvoicedParams{
+				InvoiceID:  NullUUIDFromUUID(&invoice.ID),
+				Actor:      params.Actor,
+				BillingIds: draft.BillingIDs,
+			})
+			if err != nil {
+				return err
+			}
+			if n != int64(len(draft.BillingIDs)) {
+				return ErrBillingAlreadyInvoiced
+			}
+			event, err := draft.Event(invoice)
+			if err != nil {
+				return err
+			}
+			if err := q.CreateOutboxEvent(ctx, event); err != nil {
+				return err
+			}
+			invoices = append(invoices, invoice)
+		}
+		return nil
+	})
+	return invoices, err
+}
+
+func (r *Repository) GetInvoice(ctx context.Context, id uuid.UUID) (sqlc.GetInvoiceRow, []sqlc.OpsBilling, error) {
+	var (
+		invoice sqlc.GetInvoiceRow
+		lines   []sqlc.OpsBilling
+	)
+	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
+		var err error
+		invoice, err = q.GetInvoice(ctx, id)
+		if err != nil {
+			return err
+		}
+		lines, err = q.ListInvoiceBilling(ctx, []uuid.UUID{id})
+		return err
+	})
+	return invoice, lines, err
+}
+
+func (r *Repository) ListJobInvoices(ctx context.Context, jobID uuid.UUID) ([]sqlc.ListJobInvoicesRow, []sqlc.OpsBilling, error) {
+	var (
+		invoices []sqlc.ListJobInvoicesRow
+		lines    []sqlc.OpsBilling
+	)
+	err := r.withQueries(ctx, func(q *sqlc.Queries) error {
+		var err error
+		invoices, err = q.ListJobInvoices(ctx, jobID)
+		if err != nil || len(invoices) == 0 {
+			return err
+		}
+		ids := make([]uuid.UUID, 0, len(invoices))
+		for _, invoice := range invoices {
+			ids = append(ids, invoice.ID)
+		}
+		lines, err = q.ListInvoiceBilling(ctx, ids)
+		return err
+	})
+	return invoices, lines, err
+}
+
 func NullTextFromString(s *string) pgtype.Text {
 	if s == nil {
 		return pgtype.Text{Valid: false}
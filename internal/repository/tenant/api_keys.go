@@ -0,0 +1,198 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrAPIKeyInactive indicates the API key was already revoked or rotated.
+var ErrAPIKeyInactive = errors.New("repository: api key is revoked or already rotated")
+
+// APIKey is a stored tenant API key. KeyHash is the SHA-256 of the key's secret.
+type APIKey struct {
+	ID          uuid.UUID
+	TenantID    uuid.UUID
+	Name        string
+	KeyPrefix   string
+	KeyHash     []byte
+	Scopes      []string
+	BranchIDs   []uuid.UUID
+	AllBranches bool
+	ExpiresAt   *time.Time
+	LastUsedAt  *time.Time
+	ReplacedBy  *uuid.UUID
+	CreatedAt   time.Time
+	CreatedBy   *string
+	RevokedAt   *time.Time
+	RevokedBy   *string
+}
+
+const apiKeyColumns = `id, tenant_id, name, key_prefix, key_hash, scopes, branch_ids, all_branches,
+	expires_at, last_used_at, replaced_by, created_at, created_by, revoked_at, revoked_by`
+
+func scanAPIKey(row pgx.Row) (APIKey, error) {
+	var k APIKey
+	err := row.Scan(&k.ID, &k.TenantID, &k.Name, &k.KeyPrefix, &k.KeyHash, &k.Scopes, &k.BranchIDs,
+		&k.AllBranches, &k.ExpiresAt, &k.LastUsedAt, &k.ReplacedBy, &k.CreatedAt, &k.CreatedBy,
+		&k.RevokedAt, &k.RevokedBy)
+	return k, err
+}
+
+// CreateAPIKey stores a new API key.
+func (r *Repository) CreateAPIKey(ctx context.Context, key APIKey) (APIKey, error) {
+	created, err := scanAPIKey(r.tenantPool.QueryRow(ctx, `
+		INSERT INTO registry.tenant_api_key (
+			tenant_id, name, key_prefix, key_hash, scopes, branch_ids, all_branches, expires_at, created_by
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING `+apiKeyColumns,
+		key.TenantID, key.Name, key.KeyPrefix, key.KeyHash, key.Scopes, key.BranchIDs, key.AllBranches,
+		key.ExpiresAt, key.CreatedBy))
+	if err != nil {
+		return APIKey{}, fmt.Errorf("create api key: %w", err)
+	}
+	return created, nil
+}
+
+// GetAPIKeyByPrefix returns the key with the given public prefix, for authentication. The
+// tenant must be active.
+func (r *Repository) GetAPIKeyByPrefix(ctx context.Context, prefix string) (APIKey, error) {
+	key, err := scanAPIKey(r.tenantPool.QueryRow(ctx, `
+		SELECT `+apiKeyColumns+`
+		FROM registry.tenant_api_key k
+		WHERE key_prefix = $1
+		  AND EXISTS (
+		    SELECT 1 FROM registry.tenant_registry t
+		    WHERE t.tenant_id = k.tenant_id AND t.is_active = true
+		  )
+	`, prefix))
+	if err != nil {
+		return APIKey{}, fmt.Errorf("get api key: %w", err)
+	}
+	return key, nil
+}
+
+// ListAPIKeys returns the tenant's API keys, newest first.
+func (r *Repository) ListAPIKeys(ctx context.Context, tenantID uuid.UUID) ([]APIKey, error) {
+	rows, err := r.tenantPool.Query(ctx, `
+		SELECT `+apiKeyColumns+`
+		FROM registry.tenant_api_key
+		WHERE tenant_id = $1
+		ORDER BY created_at DESC
+	`, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("list api keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan api key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list api keys: %w", err)
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey revokes one of the tenant's keys. It returns pgx.ErrNoRows when the tenant has no
+// such key and ErrAPIKeyInactive when it is already revoked.
+func (r *Repository) RevokeAPIKey(ctx context.Context, tenantID, keyID uuid.UUID, actor string) (APIKey, error) {
+	key, err := scanAPIKey(r.tenantPool.QueryRow(ctx, `
+		UPDATE registry.tenant_api_key
+		SET revoked_at = now(), revoked_by = $3
+		WHERE tenant_id = $1 AND id = $2 AND revoked_at IS NULL
+		RETURNING `+apiKeyColumns,
+		tenantID, keyID, actor))
+	if errors.Is(err, pgx.ErrNoRows) {
+		if _, getErr := r.GetAPIKey(ctx, tenantID, keyID); getErr != nil {
+			return APIKey{}, getErr
+		}
+		return APIKey{}, ErrAPIKeyInactive
+	}
+	if err != nil {
+		return APIKey{}, fmt.Errorf("revoke api key: %w", err)
+	}
+	return key, nil
+}
+
+// RotateAPIKey stores replacement for one of the tenant's active keys and cuts the old key's
+// expiry to oldExpiresAt, in one transaction.
+func (r *Repository) RotateAPIKey(ctx context.Context, tenantID, keyID uuid.UUID, replacement APIKey, oldExpiresAt time.Time) (APIKey, error) {
+	tx, err := r.tenantPool.Begin(ctx)
+	if err != nil {
+		return APIKey{}, fmt.Errorf("begin rotate api key: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	old, err := scanAPIKey(tx.QueryRow(ctx, `
+		SELECT `+apiKeyColumns+`
+		FROM registry.tenant_api_key
+		WHERE tenant_id = $1 AND id = $2
+		FOR UPDATE
+	`, tenantID, keyID))
+	if err != nil {
+		return APIKey{}, fmt.Errorf("get api key: %w", err)
+	}
+	if old.RevokedAt != nil || old.ReplacedBy != nil {
+		return APIKey{}, ErrAPIKeyInactive
+	}
+
+	created, err := scanAPIKey(tx.QueryRow(ctx, `
+		INSERT INTO registry.tenant_api_key (
+			tenant_id, name, key_prefix, key_hash, scopes, branch_ids, all_branches, expires_at, created_by
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING `+apiKeyColumns,
+		tenantID, replacement.Name, replacement.KeyPrefix, replacement.KeyHash, replacement.Scopes,
+		replacement.BranchIDs, replacement.AllBranches, replacement.ExpiresAt, replacement.CreatedBy))
+	if err != nil {
+		return APIKey{}, fmt.Errorf("create api key: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE registry.tenant_api_key
+		SET replaced_by = $2, expires_at = LEAST(COALESCE(expires_at, $3), $3)
+		WHERE id = $1
+	`, keyID, created.ID, oldExpiresAt); err != nil {
+		return APIKey{}, fmt.Errorf("retire api key: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return APIKey{}, fmt.Errorf("commit rotate api key: %w", err)
+	}
+	return created, nil
+}
+
+// TouchAPIKey records that the key was just used. Writes are throttled to one a minute per key.
+func (r *Repository) TouchAPIKey(ctx context.Context, keyID uuid.UUID) error {
+	_, err := r.tenantPool.Exec(ctx, `
+		UPDATE registry.tenant_api_key
+		SET last_used_at = now()
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < now() - interval '1 minute')
+	`, keyID)
+	if err != nil {
+		return fmt.Errorf("touch api key: %w", err)
+	}
+	return nil
+}
+
+// GetAPIKey returns one of the tenant's keys.
+func (r *Repository) GetAPIKey(ctx context.Context, tenantID, keyID uuid.UUID) (APIKey, error) {
+	key, err := scanAPIKey(r.tenantPool.QueryRow(ctx, `
+		SELECT `+apiKeyColumns+`
+		FROM registry.tenant_api_key
+		WHERE tenant_id = $1 AND id = $2
+	`, tenantID, keyID))
+	if err != nil {
+		return APIKey{}, fmt.Errorf("get api key: %w", err)
+	}
+	return key, nil
+}
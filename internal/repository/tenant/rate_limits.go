@@ -0,0 +1,44 @@
+package tenant
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// RateLimit is a stored rate limit rule. A nil TenantID is a default for every tenant.
+type RateLimit struct {
+	TenantID          *uuid.UUID
+	APIKeyID          *uuid.UUID
+	RouteClass        string
+	LimitLevel        string
+	RequestsPerMinute int32
+	Burst             int32
+}
+
+// ListRateLimits returns the tenant's rate limit rules and the defaults for every tenant.
+func (r *Repository) ListRateLimits(ctx context.Context, tenantID uuid.UUID) ([]RateLimit, error) {
+	rows, err := r.tenantPool.Query(ctx, `
+		SELECT tenant_id, api_key_id, route_class, limit_level, requests_per_minute, burst
+		FROM registry.tenant_rate_limit
+		WHERE tenant_id = $1 OR tenant_id IS NULL
+	`, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("list rate limits: %w", err)
+	}
+	defer rows.Close()
+
+	var limits []RateLimit
+	for rows.Next() {
+		var l RateLimit
+		if err := rows.Scan(&l.TenantID, &l.APIKeyID, &l.RouteClass, &l.LimitLevel, &l.RequestsPerMinute, &l.Burst); err != nil {
+			return nil, fmt.Errorf("scan rate limit: %w", err)
+		}
+		limits = append(limits, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list rate limits: %w", err)
+	}
+	return limits, nil
+}
@@ -0,0 +1,230 @@
+package screening
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ErrInvalidList indicates a denied-party list file could not be parsed.
+var ErrInvalidList = errors.New("screening: invalid denied-party list")
+
+// Entry is one denied party, with the names it is listed under.
+type Entry struct {
+	ID      string
+	Name    string
+	Aliases []string
+	Program string
+	Country string
+}
+
+// List is a consolidated denied-party list loaded from one file. Version identifies the file's
+// content so every screening result can be traced to the list it was made against.
+type List struct {
+	Name    string
+	Version string
+	Entries []Entry
+}
+
+// CSV header names accepted for each entry field, compared case-insensitively. They cover the
+// consolidated screening list export as well as simple hand-maintained lists.
+var csvColumns = map[string][]string{
+	"id":      {"id", "uid", "entity_number", "ent_num"},
+	"name":    {"name", "sdn_name", "entity_name"},
+	"aliases": {"aliases", "alias", "alt_names", "aka"},
+	"program": {"program", "programs", "source", "list"},
+	"country": {"country", "countries"},
+}
+
+// LoadDir loads every .csv and .xml list in dir, in file name order.
+func LoadDir(dir string) ([]List, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("screening: read list directory: %w", err)
+	}
+	var names []string
+	for _, f := range files {
+		ext := strings.ToLower(filepath.Ext(f.Name()))
+		if !f.IsDir() && (ext == ".csv" || ext == ".xml") {
+			names = append(names, f.Name())
+		}
+	}
+	sort.Strings(names)
+
+	lists := make([]List, 0, len(names))
+	for _, name := range names {
+		list, err := LoadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, list)
+	}
+	return lists, nil
+}
+
+// LoadFile loads a CSV or XML list. The list is named after the file; its version is the file's
+// own version attribute when it has one, followed by a digest of the content.
+func LoadFile(path string) (List, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return List{}, fmt.Errorf("screening: read list: %w", err)
+	}
+	base := filepath.Base(path)
+	ext := strings.ToLower(filepath.Ext(base))
+
+	list := List{Name: strings.TrimSuffix(base, filepath.Ext(base))}
+	var declared string
+	switch ext {
+	case ".csv":
+		list.Entries, err = parseCSV(bytes.NewReader(data))
+	case ".xml":
+		list.Entries, declared, err = parseXML(bytes.NewReader(data))
+	default:
+		err = fmt.Errorf("%w: unsupported file type %q", ErrInvalidList, ext)
+	}
+	if err != nil {
+		return List{}, fmt.Errorf("%s: %w", base, err)
+	}
+
+	sum := sha256.Sum256(data)
+	list.Version = hex.EncodeToString(sum[:6])
+	if declared = strings.TrimSpace(declared); declared != "" {
+		list.Version = declared + "+" + list.Version
+	}
+	return list, nil
+}
+
+func parseCSV(r io.Reader) ([]Entry, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: missing header: %v", ErrInvalidList, err)
+	}
+	columns := make(map[string]int)
+	for i, h := range header {
+		h = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))
+		for field, names := range csvColumns {
+			for _, name := range names {
+				if _, taken := columns[field]; h == name && !taken {
+					columns[field] = i
+				}
+			}
+		}
+	}
+	if _, ok := columns["name"]; !ok {
+		return nil, fmt.Errorf("%w: no name column", ErrInvalidList)
+	}
+
+	var entries []Entry
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidList, line, err)
+		}
+		field := func(name string) string {
+			i, ok := columns[name]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+		entry := Entry{
+			ID:      field("id"),
+			Name:    field("name"),
+			Aliases: splitAliases(field("aliases")),
+			Program: field("program"),
+			Country: field("country"),
+		}
+		if entry.Name == "" {
+			continue
+		}
+		if entry.ID == "" {
+			entry.ID = fmt.Sprintf("line-%d", line)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// xmlList is the XML list format: entries anywhere under the root, each with a name and any
+// number of aliases.
+type xmlList struct {
+	Version string     `xml:"version,attr"`
+	Entries []xmlEntry `xml:",any"`
+}
+
+type xmlEntry struct {
+	XMLName xml.Name
+	ID      string     `xml:"id,attr"`
+	UID     string     `xml:"uid"`
+	Name    string     `xml:"name"`
+	Aliases []string   `xml:"alias"`
+	AKAs    []string   `xml:"aka"`
+	Program string     `xml:"program"`
+	Country string     `xml:"country"`
+	Entries []xmlEntry `xml:",any"`
+}
+
+func parseXML(r io.Reader) ([]Entry, string, error) {
+	var doc xmlList
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrInvalidList, err)
+	}
+	var entries []Entry
+	var walk func([]xmlEntry)
+	walk = func(nodes []xmlEntry) {
+		for _, n := range nodes {
+			if name := strings.TrimSpace(n.Name); name != "" {
+				id := strings.TrimSpace(n.ID)
+				if id == "" {
+					id = strings.TrimSpace(n.UID)
+				}
+				if id == "" {
+					id = fmt.Sprintf("entry-%d", len(entries)+1)
+				}
+				var aliases []string
+				for _, a := range append(n.Aliases, n.AKAs...) {
+					if a = strings.TrimSpace(a); a != "" {
+						aliases = append(aliases, a)
+					}
+				}
+				entries = append(entries, Entry{
+					ID:      id,
+					Name:    name,
+					Aliases: aliases,
+					Program: strings.TrimSpace(n.Program),
+					Country: strings.TrimSpace(n.Country),
+				})
+				continue
+			}
+			walk(n.Entries)
+		}
+	}
+	walk(doc.Entries)
+	return entries, doc.Version, nil
+}
+
+func splitAliases(raw string) []string {
+	var aliases []string
+	for _, a := range strings.FieldsFunc(raw, func(r rune) bool { return r == ';' || r == '|' }) {
+		if a = strings.TrimSpace(a); a != "" {
+			aliases = append(aliases, a)
+		}
+	}
+	return aliases
+}
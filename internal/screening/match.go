@@ -0,0 +1,231 @@
+package screening
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// DefaultThreshold is the similarity at or above which a name is a potential match.
+const DefaultThreshold = 0.88
+
+// legalSuffixes are dropped from names before comparison, so "Acme Trading LLC" and
+// "ACME Trading Co." compare equal.
+var legalSuffixes = map[string]bool{
+	"co": true, "company": true, "corp": true, "corporation": true, "inc": true, "incorporated": true,
+	"llc": true, "llp": true, "ltd": true, "limited": true, "plc": true, "pte": true, "pty": true,
+	"gmbh": true, "ag": true, "sa": true, "sarl": true, "srl": true, "spa": true, "bv": true, "nv": true,
+	"fze": true, "fzc": true, "fzco": true, "fzllc": true, "est": true, "establishment": true,
+	"the": true, "and": true, "of": true,
+}
+
+// latinFolds strips the diacritics most common in party names.
+var latinFolds = map[rune]string{
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'æ': "ae",
+	'ç': "c", 'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ì': "i", 'í': "i", 'î': "i", 'ï': "i",
+	'ñ': "n", 'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o", 'œ': "oe",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ý': "y", 'ÿ': "y", 'ß': "ss",
+	'ğ': "g", 'ı': "i", 'ş': "s", 'ł': "l", 'ś': "s", 'ź': "z", 'ż': "z", 'č': "c", 'ř': "r", 'š': "s", 'ž': "z",
+}
+
+// Normalize lower-cases a name, folds diacritics, replaces punctuation with spaces and drops
+// legal-form words, leaving its significant tokens separated by single spaces.
+func Normalize(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case latinFolds[r] != "":
+			b.WriteString(latinFolds[r])
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+		case r == '.' || r == '\'':
+			// "L.L.C." and "O'Brien" keep their letters together
+		default:
+			b.WriteRune(' ')
+		}
+	}
+	tokens := strings.Fields(b.String())
+	kept := tokens[:0]
+	for _, t := range tokens {
+		if !legalSuffixes[t] {
+			kept = append(kept, t)
+		}
+	}
+	if len(kept) == 0 {
+		return strings.Join(tokens, " ")
+	}
+	return strings.Join(kept, " ")
+}
+
+// Similarity scores two names from 0 to 1 after normalisation: the better of the Jaro-Winkler
+// similarity of the names as written and with their words sorted, so word order does not matter.
+func Similarity(a, b string) float64 {
+	na, nb := Normalize(a), Normalize(b)
+	if na == "" || nb == "" {
+		return 0
+	}
+	if na == nb {
+		return 1
+	}
+	score := jaroWinkler(na, nb)
+	if sorted := jaroWinkler(sortTokens(na), sortTokens(nb)); sorted > score {
+		score = sorted
+	}
+	return score
+}
+
+func sortTokens(s string) string {
+	tokens := strings.Fields(s)
+	sort.Strings(tokens)
+	return strings.Join(tokens, " ")
+}
+
+func jaroWinkler(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	jaro := jaroSimilarity(ra, rb)
+	prefix := 0
+	for prefix < len(ra) && prefix < len(rb) && prefix < 4 && ra[prefix] == rb[prefix] {
+		prefix++
+	}
+	return jaro + float64(prefix)*0.1*(1-jaro)
+}
+
+func jaroSimilarity(a, b []rune) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	window := max(len(a), len(b))/2 - 1
+	if window < 0 {
+		window = 0
+	}
+	matchedA := make([]bool, len(a))
+	matchedB := make([]bool, len(b))
+	matches := 0
+	for i := range a {
+		lo, hi := max(0, i-window), min(len(b), i+window+1)
+		for j := lo; j < hi; j++ {
+			if !matchedB[j] && a[i] == b[j] {
+				matchedA[i], matchedB[j] = true, true
+				matches++
+				break
+			}
+		}
+	}
+	if matches == 0 {
+		return 0
+	}
+	transpositions, j := 0, 0
+	for i := range a {
+		if !matchedA[i] {
+			continue
+		}
+		for !matchedB[j] {
+			j++
+		}
+		if a[i] != b[j] {
+			transpositions++
+		}
+		j++
+	}
+	m := float64(matches)
+	return (m/float64(len(a)) + m/float64(len(b)) + (m-float64(transpositions)/2)/m) / 3
+}
+
+// Match is a listed name similar enough to a screened name to need review.
+type Match struct {
+	List        string
+	Version     string
+	EntryID     string
+	EntryName   string
+	MatchedName string
+	Program     string
+	Score       float64
+}
+
+// Screener matches names against the loaded denied-party lists. It is safe for concurrent use
+// and its lists can be reloaded while it serves.
+type Screener struct {
+	dir       string
+	threshold float64
+
+	mu    sync.RWMutex
+	lists []List
+}
+
+// NewScreener creates a screener over lists; a threshold outside (0, 1] uses DefaultThreshold.
+func NewScreener(lists []List, threshold float64) *Screener {
+	if threshold <= 0 || threshold > 1 {
+		threshold = DefaultThreshold
+	}
+	return &Screener{threshold: threshold, lists: lists}
+}
+
+// Open creates a screener over the lists in dir, which Reload reads again.
+func Open(dir string, threshold float64) (*Screener, error) {
+	lists, err := LoadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	s := NewScreener(lists, threshold)
+	s.dir = dir
+	return s, nil
+}
+
+// Reload re-reads the list directory and swaps in its lists. On error the current lists stay.
+func (s *Screener) Reload() ([]List, error) {
+	if s.dir == "" {
+		return s.Lists(), nil
+	}
+	lists, err := LoadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.lists = lists
+	s.mu.Unlock()
+	return lists, nil
+}
+
+// Lists returns the loaded lists.
+func (s *Screener) Lists() []List {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.lists
+}
+
+// Screen matches name against the loaded lists and returns the lists it used, so callers can
+// record their versions, with the matches.
+func (s *Screener) Screen(name string) ([]List, []Match) {
+	lists := s.Lists()
+	return lists, MatchName(lists, name, s.threshold)
+}
+
+// MatchName returns the best-scoring name of every list entry that matches name at or above
+// threshold, highest score first.
+func MatchName(lists []List, name string, threshold float64) []Match {
+	var matches []Match
+	for _, list := range lists {
+		for _, entry := range list.Entries {
+			best, bestName := 0.0, ""
+			for _, listed := range append([]string{entry.Name}, entry.Aliases...) {
+				if score := Similarity(name, listed); score > best {
+					best, bestName = score, listed
+				}
+			}
+			if best >= threshold {
+				matches = append(matches, Match{
+					List:        list.Name,
+					Version:     list.Version,
+					EntryID:     entry.ID,
+					EntryName:   entry.Name,
+					MatchedName: bestName,
+					Program:     entry.Program,
+					Score:       best,
+				})
+			}
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+	return matches
+}
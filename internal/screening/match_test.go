@@ -0,0 +1,65 @@
+package screening
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Acme Trading LLC", "acme trading"},
+		{"ACME Trading Co.", "acme trading"},
+		{"Müller & Söhne GmbH", "muller sohne"},
+		{"O'Brien Logistics", "obrien logistics"},
+		{"Al-Rashid Est.", "al rashid"},
+		{"Gulf Star F.Z.E.", "gulf star"},
+		{"The Limited Co", "the limited co"},
+		{"  ", ""},
+	}
+	for _, tt := range tests {
+		if got := Normalize(tt.in); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b  string
+		want  float64
+		match bool
+	}{
+		// the Jaro-Winkler reference pairs
+		{"Martha", "Marhta", 0.9611, true},
+		{"Dwayne", "Duane", 0.8400, false},
+		// legal forms, case, punctuation and diacritics do not count
+		{"Acme Trading LLC", "ACME Trading Co.", 1, true},
+		{"Müller GmbH", "Muller", 1, true},
+		{"O'Brien Logistics", "OBrien Logistics", 1, true},
+		{"Al-Rashid Est.", "Al Rashid", 1, true},
+		// nor does word order
+		{"Trading Acme", "Acme Trading", 1, true},
+		// a name made only of legal-form words is still compared
+		{"LLC", "LLC", 1, true},
+		{"Rosneft", "Rosneft Trading", 0.8933, true},
+		{"Acme", "Acne", 0.8667, false},
+		{"Global Shipping", "Ocean Freight", 0.5372, false},
+		{"abc", "xyz", 0, false},
+		{"", "Acme", 0, false},
+		{"Acme", "", 0, false},
+	}
+	for _, tt := range tests {
+		got := Similarity(tt.a, tt.b)
+		if math.Abs(got-tt.want) > 0.0001 {
+			t.Errorf("Similarity(%q, %q) = %.4f, want %.4f", tt.a, tt.b, got, tt.want)
+		}
+		if reverse := Similarity(tt.b, tt.a); math.Abs(got-reverse) > 1e-9 {
+			t.Errorf("Similarity(%q, %q) = %.4f but reversed = %.4f", tt.a, tt.b, got, reverse)
+		}
+		if match := got >= DefaultThreshold; match != tt.match {
+			t.Errorf("Similarity(%q, %q) = %.4f, match at the default threshold = %t, want %t", tt.a, tt.b, got, match, tt.match)
+		}
+	}
+}
@@ -71,6 +71,7 @@ func BuildRouter(
 	apiHandler http.Handler,
 	tenantHandler http.Handler,
 	corsMiddleware func(http.Handler) http.Handler,
+	serviceMiddleware func(http.Handler) http.Handler,
 	authMiddleware func(http.Handler) http.Handler,
 	tenantMiddleware func(http.Handler) http.Handler,
 ) http.Handler {
@@ -88,9 +89,12 @@ func BuildRouter(
 		w.Write([]byte("OK"))
 	})
 
-	// Internal tenant provisioning routes (no auth required - for backend-to-finance calls)
+	// Internal tenant provisioning routes, for calls from other Frego services
 	if tenantHandler != nil {
-		r.Mount("/", tenantHandler)
+		r.Group(func(r chi.Router) {
+			r.Use(serviceMiddleware)
+			r.Mount("/", tenantHandler)
+		})
 	}
 
 	// API routes with auth and tenant middleware
@@ -2,71 +2,152 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"strings"
 
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+
+	"frego-operations/internal/common"
+	"frego-operations/internal/db"
 )
 
 const tenantIDHeader = "X-Tenant-ID"
 
-// TenantMiddleware extracts tenant ID from request and sets it in context
-func TenantMiddleware(logger *slog.Logger, pool *pgxpool.Pool, defaultTenant uuid.UUID) func(http.Handler) http.Handler {
+// TenantOptions controls how TenantMiddleware resolves the request tenant.
+type TenantOptions struct {
+	// DefaultTenant is used when neither the header nor the token names a tenant, and only when
+	// AllowDefault is set, as it is in development.
+	DefaultTenant uuid.UUID
+	AllowDefault  bool
+	// CrossTenantRole lets a principal address a tenant other than its token's through the
+	// header. Every such request is recorded in registry.tenant_access_audit.
+	CrossTenantRole string
+	// Module is the service's module name, recorded in the audit.
+	Module string
+}
+
+// TenantResolver looks up an active tenant's schema and display name in the registry, failing
+// with db.ErrTenantNotFound for unknown and inactive tenants and db.ErrTenantMoving for tenants
+// being moved between shards.
+type TenantResolver interface {
+	ResolveTenant(ctx context.Context, tenantID uuid.UUID) (schema string, name string, err error)
+}
+
+// TenantMiddleware resolves the request tenant from the token's tenant claim. An X-Tenant-ID
+// header must name the same tenant unless the principal holds CrossTenantRole, in which case the
+// access is audited in the registry through pool. The tenant must be active; a tenant being
+// moved between shards gets 503 until the move completes.
+func TenantMiddleware(logger *slog.Logger, pool *pgxpool.Pool, tenants TenantResolver, opts TenantOptions) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			tenantIDStr := r.Header.Get(tenantIDHeader)
+			principal, _ := common.PrincipalFromContext(r.Context())
 
-			var tenantID uuid.UUID
-			var err error
+			var tokenTenant uuid.UUID
+			if principal.TenantID != "" {
+				id, err := uuid.Parse(principal.TenantID)
+				if err != nil {
+					logger.Warn("tenant claim is not a UUID", slog.String("tenant_id", principal.TenantID))
+					http.Error(w, "tenant context in token is invalid", http.StatusForbidden)
+					return
+				}
+				tokenTenant = id
+			}
 
-			if tenantIDStr != "" {
-				tenantID, err = uuid.Parse(tenantIDStr)
+			tenantID := tokenTenant
+			crossTenant := false
+			if header := strings.TrimSpace(r.Header.Get(tenantIDHeader)); header != "" {
+				headerTenant, err := uuid.Parse(header)
 				if err != nil {
-					logger.Warn("invalid tenant ID in header, using default",
-						slog.String("tenant_id", tenantIDStr),
-						slog.Any("error", err),
-					)
-					tenantID = defaultTenant
+					http.Error(w, "X-Tenant-ID must be a UUID", http.StatusBadRequest)
+					return
+				}
+				if headerTenant != tokenTenant {
+					if opts.CrossTenantRole == "" || !principal.HasRole(opts.CrossTenantRole) {
+						logger.Warn("tenant header does not match token",
+							slog.String("tenant_id", headerTenant.String()),
+							slog.String("token_tenant_id", principal.TenantID),
+							slog.String("subject", principal.Subject),
+						)
+						http.Error(w, "X-Tenant-ID does not match the token's tenant", http.StatusForbidden)
+						return
+					}
+					crossTenant = true
+				}
+				tenantID = headerTenant
+			}
+			if tenantID == uuid.Nil {
+				if !opts.AllowDefault || opts.DefaultTenant == uuid.Nil {
+					http.Error(w, "tenant context missing", http.StatusForbidden)
+					return
 				}
-			} else {
-				tenantID = defaultTenant
+				logger.Warn("no tenant in request, using default tenant", slog.String("tenant_id", opts.DefaultTenant.String()))
+				tenantID = opts.DefaultTenant
 			}
 
 			// Verify tenant exists and is active
-			var exists bool
-			err = pool.QueryRow(r.Context(), `
-				SELECT EXISTS (
-					SELECT 1 FROM registry.tenant_registry 
-					WHERE tenant_id = $1 AND is_active = true
-				)
-			`, tenantID).Scan(&exists)
-
-			if err != nil && err != pgx.ErrNoRows {
+			schema, name, err := tenants.ResolveTenant(r.Context(), tenantID)
+			if errors.Is(err, db.ErrTenantNotFound) {
+				logger.Warn("tenant not found or inactive", slog.String("tenant_id", tenantID.String()))
+				http.Error(w, "Tenant not found or inactive", http.StatusForbidden)
+				return
+			}
+			if errors.Is(err, db.ErrTenantMoving) {
+				logger.Warn("tenant is moving between shards", slog.String("tenant_id", tenantID.String()))
+				w.Header().Set("Retry-After", "30")
+				http.Error(w, "Tenant is being migrated, retry shortly", http.StatusServiceUnavailable)
+				return
+			}
+			if err != nil {
 				logger.Error("failed to verify tenant", slog.Any("error", err))
 				http.Error(w, "Internal server error", http.StatusInternalServerError)
 				return
 			}
 
-			if !exists {
-				logger.Warn("tenant not found or inactive", slog.String("tenant_id", tenantID.String()))
-				http.Error(w, "Tenant not found or inactive", http.StatusForbidden)
-				return
+			if crossTenant {
+				if err := auditCrossTenantAccess(r, pool, tenantID, principal, opts.Module); err != nil {
+					logger.Error("failed to audit cross-tenant access", slog.Any("error", err))
+					http.Error(w, "Internal server error", http.StatusInternalServerError)
+					return
+				}
+				logger.Warn("cross-tenant access",
+					slog.String("tenant_id", tenantID.String()),
+					slog.String("token_tenant_id", principal.TenantID),
+					slog.String("subject", principal.Subject),
+					slog.String("path", r.URL.Path),
+				)
 			}
 
-			ctx := context.WithValue(r.Context(), "tenant_id", tenantID)
+			ctx := common.WithTenant(r.Context(), tenantID.String(), schema, name)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
+// auditCrossTenantAccess records a request made into a tenant other than the principal's own.
+// The request is refused when the record cannot be written.
+func auditCrossTenantAccess(r *http.Request, pool *pgxpool.Pool, tenantID uuid.UUID, principal common.Principal, module string) error {
+	_, err := pool.Exec(r.Context(), `
+		INSERT INTO registry.tenant_access_audit (
+			tenant_id, home_tenant_id, subject, username, module_name, method, path, request_id
+		) VALUES ($1, NULLIF($2, ''), $3, NULLIF($4, ''), $5, $6, $7, NULLIF($8, ''))
+	`, tenantID, principal.TenantID, principal.Subject, principal.Username, module, r.Method, r.URL.Path, middleware.GetReqID(r.Context()))
+	return err
+}
+
 // GetTenantID extracts tenant ID from context
 func GetTenantID(ctx context.Context) (uuid.UUID, error) {
-	tenantID, ok := ctx.Value("tenant_id").(uuid.UUID)
+	id, _, ok := common.TenantFromContext(ctx)
 	if !ok {
 		return uuid.Nil, fmt.Errorf("tenant ID not found in context")
 	}
+	tenantID, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("tenant ID in context is invalid: %w", err)
+	}
 	return tenantID, nil
 }
@@ -0,0 +1,162 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"frego-operations/internal/common"
+	"frego-operations/internal/db"
+)
+
+type fakeResolver map[uuid.UUID]error
+
+func (f fakeResolver) ResolveTenant(_ context.Context, tenantID uuid.UUID) (string, string, error) {
+	err, ok := f[tenantID]
+	if !ok {
+		return "", "", db.ErrTenantNotFound
+	}
+	if err != nil {
+		return "", "", err
+	}
+	return "tenant_" + tenantID.String()[:8], "Tenant " + tenantID.String()[:8], nil
+}
+
+func TestTenantMiddleware(t *testing.T) {
+	home := uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	other := uuid.MustParse("22222222-2222-2222-2222-222222222222")
+	moving := uuid.MustParse("33333333-3333-3333-3333-333333333333")
+	broken := uuid.MustParse("44444444-4444-4444-4444-444444444444")
+	unknown := uuid.MustParse("55555555-5555-5555-5555-555555555555")
+	resolver := fakeResolver{
+		home:   nil,
+		other:  nil,
+		moving: db.ErrTenantMoving,
+		broken: errors.New("registry unavailable"),
+	}
+
+	tests := []struct {
+		name       string
+		principal  common.Principal
+		header     string
+		opts       TenantOptions
+		wantStatus int
+		wantTenant uuid.UUID
+	}{
+		{
+			name:       "token tenant",
+			principal:  common.Principal{TenantID: home.String()},
+			wantStatus: http.StatusOK,
+			wantTenant: home,
+		},
+		{
+			name:       "header matching the token",
+			principal:  common.Principal{TenantID: home.String()},
+			header:     home.String(),
+			wantStatus: http.StatusOK,
+			wantTenant: home,
+		},
+		{
+			name:       "header naming another tenant",
+			principal:  common.Principal{TenantID: home.String()},
+			header:     other.String(),
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "header naming another tenant without the configured role",
+			principal:  common.Principal{TenantID: home.String(), Roles: []string{"ops-user"}},
+			header:     other.String(),
+			opts:       TenantOptions{CrossTenantRole: "support"},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "header that is not a UUID",
+			principal:  common.Principal{TenantID: home.String()},
+			header:     "acme",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "token tenant that is not a UUID",
+			principal:  common.Principal{TenantID: "acme"},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "header with a token that names no tenant",
+			principal:  common.Principal{Subject: "svc"},
+			header:     home.String(),
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "no tenant",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "no tenant without AllowDefault",
+			opts:       TenantOptions{DefaultTenant: home},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "no tenant with the default allowed",
+			opts:       TenantOptions{DefaultTenant: home, AllowDefault: true},
+			wantStatus: http.StatusOK,
+			wantTenant: home,
+		},
+		{
+			name:       "unknown or inactive tenant",
+			principal:  common.Principal{TenantID: unknown.String()},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "tenant moving between shards",
+			principal:  common.Principal{TenantID: moving.String()},
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:       "registry failure",
+			principal:  common.Principal{TenantID: broken.String()},
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotTenant, gotSchema string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotTenant, gotSchema, _ = common.TenantFromContext(r.Context())
+			})
+			// Cross-tenant access that is allowed writes an audit row, so no case here reaches the pool.
+			handler := TenantMiddleware(logger, nil, resolver, tt.opts)(next)
+
+			req := httptest.NewRequest(http.MethodGet, "/jobs", nil)
+			if tt.header != "" {
+				req.Header.Set(tenantIDHeader, tt.header)
+			}
+			req = req.WithContext(common.WithPrincipal(req.Context(), tt.principal))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (%s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus == http.StatusServiceUnavailable && rec.Header().Get("Retry-After") == "" {
+				t.Error("503 without Retry-After")
+			}
+			if tt.wantStatus != http.StatusOK {
+				if gotTenant != "" {
+					t.Errorf("refused request reached the handler with tenant %s", gotTenant)
+				}
+				return
+			}
+			if gotTenant != tt.wantTenant.String() || gotSchema == "" {
+				t.Errorf("tenant in context = %q (schema %q), want %s", gotTenant, gotSchema, tt.wantTenant)
+			}
+		})
+	}
+}
@@ -0,0 +1,382 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"frego-operations/internal/common"
+	sqlc "frego-operations/internal/db/sqlc"
+	"frego-operations/internal/decimal"
+	operationsdto "frego-operations/internal/dto/operations"
+	"frego-operations/internal/iata"
+	"frego-operations/internal/logging"
+)
+
+// masterAWBDocTypes are the document types whose doc_number holds the master AWB.
+var masterAWBDocTypes = map[string]bool{"MAWB": true, "AWB": true}
+
+// ExportFWB builds an FWB master air waybill message for an air job.
+func (s *Service) ExportFWB(ctx context.Context, jobID uuid.UUID, opts operationsdto.EDIExportOptions) (operationsdto.ExportFile, error) {
+	logger := logging.FromContext(ctx)
+	logger.Info("exporting fwb", slog.String("jobID", jobID.String()), slog.String("format", opts.Format))
+
+	job, carrier, err := s.loadAirJob(ctx, jobID, "FWB")
+	if err != nil {
+		return operationsdto.ExportFile{}, err
+	}
+
+	waybill, err := s.loadWaybill(ctx, job, carrier)
+	if err != nil {
+		return operationsdto.ExportFile{}, err
+	}
+
+	format := iata.Format(opts.Format)
+	data, err := iata.BuildFWB(waybill, format, iata.Envelope{SenderID: opts.SenderID, RecipientID: opts.RecipientID})
+	if err != nil {
+		logger.Warn("fwb rejected", slog.Any("error", err))
+		return operationsdto.ExportFile{}, fmt.Errorf("operations: export fwb: %w", err)
+	}
+
+	logger.Info("exported fwb", slog.String("jobCode", job.JobCode), slog.String("awb", waybill.AWB.String()))
+	return airWaybillFile("FWB", job.JobCode, format, data), nil
+}
+
+// ExportFHL builds an FHL house manifest listing the house jobs consolidated under an air job.
+func (s *Service) ExportFHL(ctx context.Context, jobID uuid.UUID, opts operationsdto.EDIExportOptions) (operationsdto.ExportFile, error) {
+	logger := logging.FromContext(ctx)
+	logger.Info("exporting fhl", slog.String("jobID", jobID.String()), slog.String("format", opts.Format))
+
+	job, carrier, err := s.loadAirJob(ctx, jobID, "FHL")
+	if err != nil {
+		return operationsdto.ExportFile{}, err
+	}
+
+	master, err := s.masterAWB(ctx, job.ID, carrier)
+	if err != nil {
+		return operationsdto.ExportFile{}, err
+	}
+
+	houses, err := s.repo.ListHouseJobs(ctx, job.ID)
+	if err != nil {
+		logger.Error("failed to list house jobs", slog.Any("error", err))
+		return operationsdto.ExportFile{}, fmt.Errorf("operations: export fhl houses: %w", err)
+	}
+
+	manifest := iata.HouseManifest{
+		Master:      master,
+		Origin:      stationCode(carrier.OriginPortStation),
+		Destination: stationCode(carrier.DestinationPortStation),
+		IssuedAt:    time.Now().UTC(),
+	}
+	for _, h := range houses {
+		house := iata.HouseWaybill{
+			Number:        firstText(h.HouseDocNumber, h.TransportDocumentReference),
+			Origin:        stationCode(h.OriginPortStation),
+			Destination:   stationCode(h.DestinationPortStation),
+			Pieces:        int(math.Round(derefFloat(float64FromNumeric(h.NoOfPackages)))),
+			GrossWeightKg: derefDecimal(decimalFromNumeric(h.GrossWeightKg)),
+			Description:   common.PgtypeTextToString(h.Commodity),
+			Shipper: iata.Party{
+				Code: common.PgtypeTextToString(h.ShipperHumanID),
+				Name: common.PgtypeTextToString(h.ShipperName),
+			},
+			Consignee: iata.Party{
+				Code: common.PgtypeTextToString(h.ConsigneeHumanID),
+				Name: common.PgtypeTextToString(h.ConsigneeName),
+			},
+		}
+		if house.Number == "" {
+			house.Number = h.JobCode
+		}
+		manifest.Pieces += house.Pieces
+		manifest.GrossWeightKg = manifest.GrossWeightKg.Add(house.GrossWeightKg)
+		manifest.Houses = append(manifest.Houses, house)
+	}
+
+	format := iata.Format(opts.Format)
+	data, err := iata.BuildFHL(manifest, format, iata.Envelope{SenderID: opts.SenderID, RecipientID: opts.RecipientID})
+	if err != nil {
+		logger.Warn("fhl rejected", slog.Any("error", err))
+		return operationsdto.ExportFile{}, fmt.Errorf("operations: export fhl: %w", err)
+	}
+
+	logger.Info("exported fhl",
+		slog.String("jobCode", job.JobCode),
+		slog.String("awb", master.String()),
+		slog.Int("houses", len(manifest.Houses)),
+	)
+	return airWaybillFile("FHL", job.JobCode, format, data), nil
+}
+
+// loadAirJob fetches an air job and its main-carriage carrier leg.
+func (s *Service) loadAirJob(ctx context.Context, jobID uuid.UUID, message string) (sqlc.GetJobRow, sqlc.OpsCarrier, error) {
+	logger := logging.FromContext(ctx)
+
+	job, err := s.repo.GetJob(ctx, jobID)
+	if err != nil {
+		logger.Error("failed to get job", slog.Any("error", err))
+		return job, sqlc.OpsCarrier{}, fmt.Errorf("operations: export %s: %w", strings.ToLower(message), err)
+	}
+	if jobType := common.PgtypeTextToString(job.JobType); jobType != "Air" {
+		return job, sqlc.OpsCarrier{}, fmt.Errorf("%w: %s requires an Air job, got %q", ErrUnsupportedJobType, message, jobType)
+	}
+
+	carriers, err := s.repo.GetJobCarriers(ctx, job.ID)
+	if err != nil {
+		logger.Error("failed to get job carriers", slog.Any("error", err))
+		return job, sqlc.OpsCarrier{}, fmt.Errorf("operations: export %s carriers: %w", strings.ToLower(message), err)
+	}
+	if len(carriers) == 0 {
+		return job, sqlc.OpsCarrier{}, &iata.ValidationError{Problems: []string{"job has no carrier leg"}}
+	}
+	return job, carriers[0], nil
+}
+
+// masterAWB reads the master AWB number from the carrier leg, falling back to the job's
+// MAWB document, and verifies its check digit.
+func (s *Service) masterAWB(ctx context.Context, jobID uuid.UUID, carrier sqlc.OpsCarrier) (iata.AWBNumber, error) {
+	raw := strings.TrimSpace(common.PgtypeTextToString(carrier.TransportDocumentReference))
+	if raw == "" {
+		docs, err := s.repo.ListJobDocuments(ctx, jobID)
+		if err != nil {
+			logging.FromContext(ctx).Error("failed to list job documents", slog.Any("error", err))
+			return iata.AWBNumber{}, fmt.Errorf("operations: load master awb: %w", err)
+		}
+		for _, doc := range docs {
+			if masterAWBDocTypes[strings.ToUpper(common.PgtypeTextToString(doc.DocTypeCode))] && doc.DocNumber.Valid {
+				raw = strings.TrimSpace(doc.DocNumber.String)
+				break
+			}
+		}
+	}
+	if raw == "" {
+		return iata.AWBNumber{}, &iata.ValidationError{Problems: []string{"master AWB number is missing"}}
+	}
+
+	awb, err := iata.ParseAWB(raw)
+	if err != nil {
+		return iata.AWBNumber{}, fmt.Errorf("operations: load master awb: %w", err)
+	}
+	return awb, nil
+}
+
+// loadWaybill collects parties, packages and billing for an FWB.
+func (s *Service) loadWaybill(ctx context.Context, job sqlc.GetJobRow, carrier sqlc.OpsCarrier) (iata.Waybill, error) {
+	logger := logging.FromContext(ctx)
+
+	awb, err := s.masterAWB(ctx, job.ID, carrier)
+	if err != nil {
+		return iata.Waybill{}, err
+	}
+
+	waybill := iata.Waybill{
+		AWB:         awb,
+		Origin:      stationCode(carrier.OriginPortStation),
+		Destination: stationCode(carrier.DestinationPortStation),
+		Flight: iata.Flight{
+			Number: common.PgtypeTextToString(carrier.FlightID),
+			Date:   timeFromPgtype(carrier.FlightDate),
+		},
+		GoodsDescription: common.PgtypeTextToString(job.Commodity),
+		AccountingInfo:   common.PgtypeTextToString(carrier.AccountingInfo),
+		HandlingInfo:     common.PgtypeTextToString(carrier.HandlingInfo),
+		IssuedAt:         time.Now().UTC(),
+		IssuedPlace:      common.PgtypeTextToString(job.BranchName),
+	}
+	if waybill.IssuedPlace == "" {
+		waybill.IssuedPlace = waybill.Origin
+	}
+	switch stationCode(carrier.ChargesCode) {
+	case "PP":
+		waybill.Prepaid = true
+	case "CC":
+		waybill.Prepaid = false
+	default:
+		return waybill, &iata.ValidationError{Problems: []string{"carrier leg has no charges code (PP or CC)"}}
+	}
+
+	party, err := s.repo.GetJobParty(ctx, job.ID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		logger.Error("failed to get job parties", slog.Any("error", err))
+		return waybill, fmt.Errorf("operations: load waybill parties: %w", err)
+	}
+	var ids []uuid.UUID
+	shipperID, consigneeID := uuidFromPgtype(party.ShipperID), uuidFromPgtype(party.ConsigneeID)
+	for _, id := range []*uuid.UUID{shipperID, consigneeID} {
+		if id != nil {
+			ids = append(ids, *id)
+		}
+	}
+	if len(ids) > 0 {
+		rows, err := s.repo.ListPartiesByIDs(ctx, ids)
+		if err != nil {
+			logger.Error("failed to list parties", slog.Any("error", err))
+			return waybill, fmt.Errorf("operations: load waybill parties: %w", err)
+		}
+		for _, row := range rows {
+			p := iata.Party{Code: common.PgtypeTextToString(row.HumanID), Name: row.Name}
+			if shipperID != nil && row.ID == *shipperID {
+				waybill.Shipper = p
+			}
+			if consigneeID != nil && row.ID == *consigneeID {
+				waybill.Consignee = p
+			}
+		}
+	}
+
+	packages, err := s.repo.ListJobPackages(ctx, job.ID)
+	if err != nil {
+		logger.Error("failed to list job packages", slog.Any("error", err))
+		return waybill, fmt.Errorf("operations: load waybill packages: %w", err)
+	}
+	var pieces float64
+	chargeableWeight := decimal.Zero
+	for _, pkg := range packages {
+		pieces += derefFloat(float64FromNumeric(pkg.NoOfPackages))
+		waybill.GrossWeightKg = waybill.GrossWeightKg.Add(derefDecimal(decimalFromNumeric(pkg.GrossWeightKg)))
+		waybill.VolumeM3 = waybill.VolumeM3.Add(derefDecimal(decimalFromNumeric(pkg.Volume)))
+		chargeableWeight = chargeableWeight.Add(derefDecimal(decimalFromNumeric(pkg.ChargeableWeight)))
+		if waybill.GoodsDescription == "" {
+			waybill.GoodsDescription = common.PgtypeTextToString(pkg.CommodityCargoDescription)
+		}
+	}
+	waybill.Pieces = int(math.Round(pieces))
+	if chargeableWeight.Cmp(waybill.GrossWeightKg) < 0 {
+		chargeableWeight = waybill.GrossWeightKg
+	}
+
+	billing, err := s.repo.ListJobBilling(ctx, job.ID)
+	if err != nil {
+		logger.Error("failed to list job billing", slog.Any("error", err))
+		return waybill, fmt.Errorf("operations: load waybill billing: %w", err)
+	}
+	activityRows, err := s.repo.ListActivityLookups(ctx)
+	if err != nil {
+		logger.Error("failed to list activity lookups", slog.Any("error", err))
+		return waybill, fmt.Errorf("operations: load waybill activities: %w", err)
+	}
+	activities := make(map[[2]string]sqlc.ListActivityLookupsRow, len(activityRows))
+	for _, row := range activityRows {
+		activities[[2]string{row.ActivityType, row.ActivityCode}] = row
+	}
+	if problems := applyBillingRates(&waybill, billing, activities, chargeableWeight); len(problems) > 0 {
+		return waybill, &iata.ValidationError{Problems: problems}
+	}
+
+	return waybill, nil
+}
+
+// applyBillingRates turns billing lines whose activity has an IATA rate class into rate lines and
+// the others into other charges. All lines must share one currency, which becomes the waybill
+// currency. The rate class, other charge code and due agent/carrier entitlement come from the
+// line's activity in activity_lu; a line whose activity has neither a rate class nor a charge
+// code is refused rather than guessed.
+func applyBillingRates(w *iata.Waybill, billing []sqlc.ListJobBillingRow, activities map[[2]string]sqlc.ListActivityLookupsRow, chargeableWeight decimal.Decimal) []string {
+	var problems []string
+	for _, line := range billing {
+		activityType, activityCode := common.PgtypeTextToString(line.ActivityType), common.PgtypeTextToString(line.ActivityCode)
+		currency := strings.ToUpper(strings.TrimSpace(common.PgtypeTextToString(line.CurrencyCode)))
+		switch {
+		case w.Currency == "":
+			w.Currency = currency
+		case currency != w.Currency:
+			problems = append(problems, fmt.Sprintf("billing line %s is in %s but the waybill is rated in %s",
+				activityCode, currency, w.Currency))
+			continue
+		}
+
+		activity := activities[[2]string{activityType, activityCode}]
+		amount := derefDecimal(decimalFromNumeric(line.AmountWithoutTax))
+		if activity.IataRateClass.Valid {
+			rate := iata.RateLine{
+				ChargeableWeightKg: derefDecimal(decimalFromNumeric(line.Quantity)),
+				RateClass:          activity.IataRateClass.String,
+				Rate:               derefDecimal(decimalFromNumeric(line.UnitPrice)),
+				Total:              amount,
+				Description:        common.PgtypeTextToString(line.Description),
+			}
+			if rate.ChargeableWeightKg.IsZero() {
+				rate.ChargeableWeightKg = chargeableWeight
+			}
+			if len(w.Rates) == 0 {
+				rate.Pieces = w.Pieces
+				rate.GrossWeightKg = w.GrossWeightKg
+			}
+			w.Rates = append(w.Rates, rate)
+			continue
+		}
+
+		if !activity.IataChargeCode.Valid || !activity.IataChargeEntitlement.Valid {
+			problems = append(problems, fmt.Sprintf("billing line %s/%s: activity has no IATA rate class or other charge code", activityType, activityCode))
+			continue
+		}
+		w.OtherCharges = append(w.OtherCharges, iata.OtherCharge{
+			Code:        activity.IataChargeCode.String,
+			Entitlement: rune(activity.IataChargeEntitlement.String[0]),
+			Amount:      amount,
+		})
+	}
+	if len(w.Rates) == 0 && len(problems) == 0 {
+		problems = append(problems, "job has no freight billing line to rate the waybill")
+	}
+	return problems
+}
+
+func airWaybillFile(message, jobCode string, format iata.Format, data []byte) operationsdto.ExportFile {
+	if format == iata.FormatCargoXML {
+		return operationsdto.ExportFile{
+			FileName:    fmt.Sprintf("X%s_%s.xml", message, jobCode),
+			ContentType: "application/xml",
+			Data:        data,
+		}
+	}
+	return operationsdto.ExportFile{
+		FileName:    fmt.Sprintf("%s_%s.txt", message, jobCode),
+		ContentType: "text/plain; charset=us-ascii",
+		Data:        data,
+	}
+}
+
+func stationCode(t pgtype.Text) string {
+	return strings.ToUpper(strings.TrimSpace(common.PgtypeTextToString(t)))
+}
+
+// chargesCode normalises a carrier leg's PP/CC charges code for storage.
+func chargesCode(code *string) pgtype.Text {
+	if code == nil || strings.TrimSpace(*code) == "" {
+		return pgtype.Text{}
+	}
+	return pgtype.Text{String: strings.ToUpper(strings.TrimSpace(*code)), Valid: true}
+}
+
+func firstText(values ...pgtype.Text) string {
+	for _, v := range values {
+		if s := strings.TrimSpace(common.PgtypeTextToString(v)); s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+func derefFloat(v *float64) float64 {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
+
+func derefDecimal(v *decimal.Decimal) decimal.Decimal {
+	if v == nil {
+		return decimal.Zero
+	}
+	return *v
+}
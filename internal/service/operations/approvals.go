@@ -0,0 +1,402 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"frego-operations/internal/common"
+	sqlc "frego-operations/internal/db/sqlc"
+	operationsdto "frego-operations/internal/dto/operations"
+	"frego-operations/internal/logging"
+	repository "frego-operations/internal/repository/operations"
+)
+
+// DefaultApproverRole decides supplier invoice variances and rules that name no approver role.
+const DefaultApproverRole = "ops-approver"
+
+// Approval request states, as constrained in ops_approval.status.
+const (
+	ApprovalPending   = "Pending"
+	ApprovalApproved  = "Approved"
+	ApprovalRejected  = "Rejected"
+	ApprovalCancelled = "Cancelled"
+)
+
+const defaultApprovalInboxLimit = 100
+
+// vendorRiskRank orders party_master.risk_level; blacklisted parties rank as high risk.
+var vendorRiskRank = map[string]int{"Low": 1, "Medium": 2, "High": 3}
+
+var (
+	// ErrInvalidApprovalRule indicates an approval rule failed validation.
+	ErrInvalidApprovalRule = errors.New("operations: invalid approval rule")
+	// ErrApprovalForbidden indicates the caller may not decide an approval request.
+	ErrApprovalForbidden = errors.New("operations: approval forbidden")
+	// ErrApprovalDecided indicates an approval request is no longer pending.
+	ErrApprovalDecided = errors.New("operations: approval already decided")
+)
+
+// ApprovalRuleError lists every problem found in an approval rule.
+type ApprovalRuleError struct {
+	Problems []string
+}
+
+func (e *ApprovalRuleError) Error() string {
+	return fmt.Sprintf("operations: invalid approval rule: %s", strings.Join(e.Problems, "; "))
+}
+
+func (e *ApprovalRuleError) Unwrap() error {
+	return ErrInvalidApprovalRule
+}
+
+// ListApprovalRules returns the tenant's active provision approval rules.
+func (s *Service) ListApprovalRules(ctx context.Context) ([]operationsdto.ProvisionApprovalRule, error) {
+	rows, err := s.repo.ListProvisionApprovalRules(ctx)
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to list approval rules", slog.Any("error", err))
+		return nil, fmt.Errorf("operations: list approval rules: %w", err)
+	}
+	result := make([]operationsdto.ProvisionApprovalRule, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, approvalRuleFromSqlc(row))
+	}
+	return result, nil
+}
+
+// CreateApprovalRule adds a provision approval rule. A rule needs at least one condition, and an
+// amount threshold needs the currency it is expressed in.
+func (s *Service) CreateApprovalRule(ctx context.Context, input operationsdto.ProvisionApprovalRuleInput) (operationsdto.ProvisionApprovalRule, error) {
+	var problems []string
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		problems = append(problems, "name is required")
+	}
+	currency := strings.ToUpper(strings.TrimSpace(derefString(input.CurrencyCode)))
+	if currency != "" && !isCurrencyCode(currency) {
+		problems = append(problems, fmt.Sprintf("currency %q is not an ISO 4217 code", currency))
+	}
+	if input.MinAmount != nil {
+		if input.MinAmount.Sign() < 0 {
+			problems = append(problems, "minimum amount must not be negative")
+		}
+		if currency == "" {
+			problems = append(problems, "minimum amount requires a currency")
+		}
+	}
+	priority := strings.TrimSpace(derefString(input.PaymentPriority))
+	risk := strings.TrimSpace(derefString(input.MinVendorRisk))
+	if risk != "" {
+		if _, ok := vendorRiskRank[risk]; !ok {
+			problems = append(problems, "minimum vendor risk must be Low, Medium or High")
+		}
+	}
+	if input.MinAmount == nil && priority == "" && risk == "" {
+		problems = append(problems, "rule needs a minimum amount, payment priority or minimum vendor risk")
+	}
+	role := strings.TrimSpace(derefString(input.ApproverRole))
+	if role == "" {
+		role = DefaultApproverRole
+	}
+	if len(problems) > 0 {
+		return operationsdto.ProvisionApprovalRule{}, &ApprovalRuleError{Problems: problems}
+	}
+
+	row, err := s.repo.CreateProvisionApprovalRule(ctx, sqlc.CreateProvisionApprovalRuleParams{
+		Name:            name,
+		CurrencyCode:    pgtype.Text{String: currency, Valid: currency != ""},
+		MinAmount:       numericFromDecimal(input.MinAmount),
+		PaymentPriority: pgtype.Text{String: priority, Valid: priority != ""},
+		MinVendorRisk:   pgtype.Text{String: risk, Valid: risk != ""},
+		ApproverRole:    role,
+		Actor:           pgtype.Text{String: input.CreatedBy, Valid: true},
+	})
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to create approval rule", slog.Any("error", err))
+		return operationsdto.ProvisionApprovalRule{}, fmt.Errorf("operations: create approval rule: %w", err)
+	}
+	return approvalRuleFromSqlc(row), nil
+}
+
+// DeactivateApprovalRule retires an approval rule. Requests it already raised stay pending.
+func (s *Service) DeactivateApprovalRule(ctx context.Context, id uuid.UUID, actor string) error {
+	n, err := s.repo.DeactivateProvisionApprovalRule(ctx, id, actor)
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to deactivate approval rule", slog.Any("error", err))
+		return fmt.Errorf("operations: deactivate approval rule: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("operations: deactivate approval rule: %w", pgx.ErrNoRows)
+	}
+	return nil
+}
+
+// routeProvisionApprovals puts provisions matching an approval rule into pending approval. The
+// first matching rule, in name order, picks the approver role. Callers run it in the transaction
+// that inserts the provisions and fail with it, so no provision is saved without its approval.
+func (s *Service) routeProvisionApprovals(ctx context.Context, provisions []sqlc.OpsProvision, actor string) error {
+	if len(provisions) == 0 {
+		return nil
+	}
+	rules, err := s.repo.ListProvisionApprovalRules(ctx)
+	if err != nil {
+		return fmt.Errorf("operations: list approval rules: %w", err)
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+
+	risks := make(map[uuid.UUID]int)
+	for _, provision := range provisions {
+		risk := 0
+		if provision.CostPartyID.Valid {
+			partyID := uuid.UUID(provision.CostPartyID.Bytes)
+			cached, ok := risks[partyID]
+			if !ok {
+				cached, err = s.vendorRisk(ctx, partyID)
+				if err != nil {
+					return err
+				}
+				risks[partyID] = cached
+			}
+			risk = cached
+		}
+
+		for _, rule := range rules {
+			reason, matched, err := s.matchApprovalRule(ctx, rule, provision, risk)
+			if err != nil {
+				return err
+			}
+			if !matched {
+				continue
+			}
+			_, err = s.repo.RequestProvisionApproval(ctx, sqlc.CreateApprovalParams{
+				SubjectType:  repository.ApprovalSubjectProvision,
+				SubjectID:    provision.ID,
+				JobID:        uuid.UUID(provision.JobID.Bytes),
+				RuleID:       uuidToPgtype(rule.ID),
+				RequiredRole: rule.ApproverRole,
+				Reason:       reason,
+				Actor:        pgtype.Text{String: actor, Valid: true},
+			})
+			if err != nil {
+				return fmt.Errorf("operations: request provision approval: %w", err)
+			}
+			logging.FromContext(ctx).Info("provision requires approval",
+				slog.String("provisionID", provision.ID.String()),
+				slog.String("rule", rule.Name),
+				slog.String("role", rule.ApproverRole),
+			)
+			break
+		}
+	}
+	return nil
+}
+
+// matchApprovalRule reports whether every condition the rule sets holds for the provision, with
+// a reason naming them. An amount that cannot be converted to the rule's currency matches, so a
+// missing exchange rate never skips an approval.
+func (s *Service) matchApprovalRule(ctx context.Context, rule sqlc.ProvisionApprovalRule, provision sqlc.OpsProvision, risk int) (string, bool, error) {
+	var reasons []string
+	if rule.MinAmount.Valid {
+		threshold := numericOrZero(rule.MinAmount)
+		amount := decimalFromNumeric(provision.TotalAmount)
+		if amount == nil {
+			amount = decimalFromNumeric(provision.AmountWithoutTax)
+		}
+		if amount == nil {
+			return "", false, nil
+		}
+		ruleCurrency := strings.ToUpper(rule.CurrencyCode.String)
+		currency := strings.ToUpper(strings.TrimSpace(provision.CurrencyCode.String))
+		converted := *amount
+		if currency != ruleCurrency {
+			quote, found, err := s.exchangeRate(ctx, currency, ruleCurrency, rateDate(timeFromTimestamptz(provision.InvoiceDate)))
+			if err != nil {
+				return "", false, err
+			}
+			if found {
+				converted = amount.Mul(quote.Rate).RoundCurrency(ruleCurrency)
+			}
+			if found && converted.Cmp(threshold) < 0 {
+				return "", false, nil
+			}
+		} else if converted.Cmp(threshold) < 0 {
+			return "", false, nil
+		}
+		reasons = append(reasons, fmt.Sprintf("amount %s %s reaches %s %s", amount.String(), currency, threshold.String(), ruleCurrency))
+	}
+	if rule.PaymentPriority.Valid {
+		if !strings.EqualFold(strings.TrimSpace(provision.PaymentPriority.String), rule.PaymentPriority.String) {
+			return "", false, nil
+		}
+		reasons = append(reasons, fmt.Sprintf("payment priority %s", rule.PaymentPriority.String))
+	}
+	if rule.MinVendorRisk.Valid {
+		if risk < vendorRiskRank[rule.MinVendorRisk.String] {
+			return "", false, nil
+		}
+		reasons = append(reasons, fmt.Sprintf("vendor risk at least %s", rule.MinVendorRisk.String))
+	}
+	return fmt.Sprintf("%s: %s", rule.Name, strings.Join(reasons, ", ")), true, nil
+}
+
+func (s *Service) vendorRisk(ctx context.Context, partyID uuid.UUID) (int, error) {
+	row, err := s.repo.GetPartyRisk(ctx, partyID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("operations: get party risk: %w", err)
+	}
+	if row.Status.String == "Blacklisted" {
+		return vendorRiskRank["High"], nil
+	}
+	return vendorRiskRank[row.RiskLevel.String], nil
+}
+
+// ApprovalInbox lists the pending approval requests the caller's roles allow them to decide,
+// oldest first.
+func (s *Service) ApprovalInbox(ctx context.Context, limit int32) ([]operationsdto.Approval, error) {
+	principal, _ := common.PrincipalFromContext(ctx)
+	if len(principal.Roles) == 0 {
+		return []operationsdto.Approval{}, nil
+	}
+	if limit <= 0 {
+		limit = defaultApprovalInboxLimit
+	}
+	rows, err := s.repo.ListPendingApprovals(ctx, principal.Roles, limit)
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to list pending approvals", slog.Any("error", err))
+		return nil, fmt.Errorf("operations: approval inbox: %w", err)
+	}
+	result := make([]operationsdto.Approval, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, approvalFromSqlc(row))
+	}
+	return result, nil
+}
+
+// DecideApproval approves or rejects a pending request. The caller must hold the request's
+// required role and may not decide a request they raised; rejections need a comment.
+func (s *Service) DecideApproval(ctx context.Context, id uuid.UUID, approve bool, comment *string, actor string) (operationsdto.Approval, error) {
+	logger := logging.FromContext(ctx)
+	logger.Info("deciding approval", slog.String("approvalID", id.String()), slog.Bool("approve", approve))
+
+	approval, err := s.repo.GetApproval(ctx, id)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			logger.Error("failed to get approval", slog.Any("error", err))
+		}
+		return operationsdto.Approval{}, fmt.Errorf("operations: decide approval: %w", err)
+	}
+	if err := s.ensureJobVisible(ctx, approval.JobID); err != nil {
+		return operationsdto.Approval{}, err
+	}
+	if approval.Status != ApprovalPending {
+		return operationsdto.Approval{}, fmt.Errorf("%w: request is %s", ErrApprovalDecided, strings.ToLower(approval.Status))
+	}
+	principal, _ := common.PrincipalFromContext(ctx)
+	if !principal.HasRole(approval.RequiredRole) {
+		return operationsdto.Approval{}, fmt.Errorf("%w: role %q is required", ErrApprovalForbidden, approval.RequiredRole)
+	}
+	if approval.RequestedBy.Valid && approval.RequestedBy.String == actor {
+		return operationsdto.Approval{}, fmt.Errorf("%w: requests cannot be decided by their requester", ErrApprovalForbidden)
+	}
+
+	status := ApprovalApproved
+	if !approve {
+		status = ApprovalRejected
+		if strings.TrimSpace(derefString(comment)) == "" {
+			return operationsdto.Approval{}, &ApprovalRuleError{Problems: []string{"a comment is required to reject"}}
+		}
+	}
+
+	err = s.repo.DecideApproval(ctx, approval, status, actor, textFromString(comment))
+	if errors.Is(err, repository.ErrApprovalDecided) {
+		return operationsdto.Approval{}, fmt.Errorf("%w: request was decided concurrently", ErrApprovalDecided)
+	}
+	if err != nil {
+		logger.Error("failed to decide approval", slog.Any("error", err))
+		return operationsdto.Approval{}, fmt.Errorf("operations: decide approval: %w", err)
+	}
+
+	decided, err := s.repo.GetApproval(ctx, id)
+	if err != nil {
+		logger.Error("failed to get approval", slog.Any("error", err))
+		return operationsdto.Approval{}, fmt.Errorf("operations: decide approval: %w", err)
+	}
+	logger.Info("decided approval",
+		slog.String("subjectType", decided.SubjectType),
+		slog.String("subjectID", decided.SubjectID.String()),
+		slog.String("status", status),
+	)
+	return approvalFromSqlc(sqlc.ListPendingApprovalsRow{
+		ID:           decided.ID,
+		SubjectType:  decided.SubjectType,
+		SubjectID:    decided.SubjectID,
+		JobID:        decided.JobID,
+		RuleID:       decided.RuleID,
+		RequiredRole: decided.RequiredRole,
+		Reason:       decided.Reason,
+		Status:       decided.Status,
+		RequestedAt:  decided.RequestedAt,
+		RequestedBy:  decided.RequestedBy,
+		DecidedAt:    decided.DecidedAt,
+		DecidedBy:    decided.DecidedBy,
+		Comment:      decided.Comment,
+	}), nil
+}
+
+func approvalRuleFromSqlc(row sqlc.ProvisionApprovalRule) operationsdto.ProvisionApprovalRule {
+	return operationsdto.ProvisionApprovalRule{
+		ID:              row.ID,
+		Name:            row.Name,
+		CurrencyCode:    textToStringPtr(row.CurrencyCode),
+		MinAmount:       decimalFromNumeric(row.MinAmount),
+		PaymentPriority: textToStringPtr(row.PaymentPriority),
+		MinVendorRisk:   textToStringPtr(row.MinVendorRisk),
+		ApproverRole:    row.ApproverRole,
+		CreatedAt:       timeFromTimestamptz(row.CreatedAt),
+		CreatedBy:       textToStringPtr(row.CreatedBy),
+	}
+}
+
+func approvalFromSqlc(row sqlc.ListPendingApprovalsRow) operationsdto.Approval {
+	var jobCode *string
+	if row.JobCode != "" {
+		jobCode = &row.JobCode
+	}
+	return operationsdto.Approval{
+		ID:              row.ID,
+		SubjectType:     row.SubjectType,
+		SubjectID:       row.SubjectID,
+		JobID:           row.JobID,
+		JobCode:         jobCode,
+		RuleID:          uuidFromPgtype(row.RuleID),
+		RequiredRole:    row.RequiredRole,
+		Reason:          row.Reason,
+		Status:          row.Status,
+		ProvisionID:     uuidFromPgtype(row.ProvisionID),
+		ActivityType:    textToStringPtr(row.ActivityType),
+		ActivityCode:    textToStringPtr(row.ActivityCode),
+		CostPartyID:     uuidFromPgtype(row.CostPartyID),
+		CostPartyName:   textToStringPtr(row.CostPartyName),
+		CurrencyCode:    textToStringPtr(row.CurrencyCode),
+		TotalAmount:     decimalFromNumeric(row.TotalAmount),
+		PaymentPriority: textToStringPtr(row.PaymentPriority),
+		Variance:        decimalFromNumeric(row.Variance),
+		VariancePercent: decimalFromNumeric(row.VariancePercent),
+		RequestedAt:     timeFromTimestamptz(row.RequestedAt),
+		RequestedBy:     textToStringPtr(row.RequestedBy),
+		DecidedAt:       timeFromTimestamptz(row.DecidedAt),
+		DecidedBy:       textToStringPtr(row.DecidedBy),
+		Comment:         textToStringPtr(row.Comment),
+	}
+}
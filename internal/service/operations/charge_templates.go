@@ -0,0 +1,503 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	sqlc "frego-operations/internal/db/sqlc"
+	"frego-operations/internal/decimal"
+	operationsdto "frego-operations/internal/dto/operations"
+	"frego-operations/internal/logging"
+	repository "frego-operations/internal/repository/operations"
+)
+
+// Charge template line kinds and quantity bases, as constrained in charge_template_line.
+const (
+	chargeLineBilling   = "billing"
+	chargeLineProvision = "provision"
+
+	basisPerContainer = "per_container"
+	basisPerKg        = "per_kg"
+	basisPerShipment  = "per_shipment"
+)
+
+// chargePartyRoles are the job parties a template line can default its billing or cost party to.
+var chargePartyRoles = map[string]bool{
+	"customer":          true,
+	"agent":             true,
+	"carrier":           true,
+	"shipper":           true,
+	"consignee":         true,
+	"notify_party":      true,
+	"origin_agent":      true,
+	"destination_agent": true,
+}
+
+// ErrInvalidChargeTemplate indicates a charge template failed validation.
+var ErrInvalidChargeTemplate = errors.New("operations: invalid charge template")
+
+// ChargeTemplateError lists every problem found in a charge template. Nothing is stored when present.
+type ChargeTemplateError struct {
+	Problems []string
+}
+
+func (e *ChargeTemplateError) Error() string {
+	return fmt.Sprintf("operations: invalid charge template: %s", strings.Join(e.Problems, "; "))
+}
+
+func (e *ChargeTemplateError) Unwrap() error {
+	return ErrInvalidChargeTemplate
+}
+
+// chargeBasis is what a job offers to turn template lines into concrete charges. When the job's
+// incoterm is known, billing lines without a party role are billed to the party bearing their
+// cost segment.
+type chargeBasis struct {
+	containers int64
+	weightKg   decimal.Decimal
+	parties    map[string]*uuid.UUID
+	segments   map[[2]string]string
+	bearers    map[string]string
+}
+
+// quantity returns the line quantity for a basis, or nil when the job has nothing to measure it by.
+func (b chargeBasis) quantity(basis string) *decimal.Decimal {
+	var q decimal.Decimal
+	switch basis {
+	case basisPerContainer:
+		q = decimal.NewFromInt(b.containers)
+	case basisPerKg:
+		q = b.weightKg.Round(2)
+	case basisPerShipment:
+		q = decimalOne
+	}
+	if q.Sign() <= 0 {
+		return nil
+	}
+	return &q
+}
+
+// proposedCharge is one template line applied to a job, with whatever it still lacks.
+type proposedCharge struct {
+	billing   *operationsdto.BillingInput
+	provision *operationsdto.ProvisionInput
+	gaps      []string
+}
+
+// ListChargeTemplates returns the active charge templates, optionally for one transport mode.
+func (s *Service) ListChargeTemplates(ctx context.Context, transportMode *string) ([]operationsdto.ChargeTemplate, error) {
+	logger := logging.FromContext(ctx)
+
+	templates, lines, err := s.repo.ListChargeTemplates(ctx, transportMode)
+	if err != nil {
+		logger.Error("failed to list charge templates", slog.Any("error", err))
+		return nil, fmt.Errorf("operations: list charge templates: %w", err)
+	}
+
+	byTemplate := make(map[uuid.UUID][]sqlc.ChargeTemplateLine, len(templates))
+	for _, line := range lines {
+		byTemplate[line.TemplateID] = append(byTemplate[line.TemplateID], line)
+	}
+	result := make([]operationsdto.ChargeTemplate, 0, len(templates))
+	for _, t := range templates {
+		result = append(result, chargeTemplateFromSqlc(t, byTemplate[t.ID]))
+	}
+	return result, nil
+}
+
+// CreateChargeTemplate validates a charge template against the activity and tax lookups and stores it.
+func (s *Service) CreateChargeTemplate(ctx context.Context, input operationsdto.ChargeTemplateInput) (operationsdto.ChargeTemplate, error) {
+	logger := logging.FromContext(ctx)
+
+	params, lineParams, err := s.chargeTemplateParams(ctx, input)
+	if err != nil {
+		return operationsdto.ChargeTemplate{}, err
+	}
+
+	template, lines, err := s.repo.CreateChargeTemplate(ctx, params, lineParams)
+	if err != nil {
+		logger.Error("failed to create charge template", slog.Any("error", err))
+		return operationsdto.ChargeTemplate{}, fmt.Errorf("operations: create charge template: %w", err)
+	}
+
+	logger.Info("created charge template", slog.String("templateID", template.ID.String()), slog.Int("lines", len(lines)))
+	return chargeTemplateFromSqlc(template, lines), nil
+}
+
+// DeactivateChargeTemplate retires a charge template so it no longer matches new jobs.
+func (s *Service) DeactivateChargeTemplate(ctx context.Context, id uuid.UUID, actor string) error {
+	n, err := s.repo.DeactivateChargeTemplate(ctx, id, actor)
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to deactivate charge template", slog.Any("error", err))
+		return fmt.Errorf("operations: deactivate charge template: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("operations: deactivate charge template: %w", pgx.ErrNoRows)
+	}
+	return nil
+}
+
+// ProposeJobCharges applies the job's matching charge template to its packages and parties and
+// returns the billing and provision lines it proposes. Nothing is saved.
+func (s *Service) ProposeJobCharges(ctx context.Context, jobID uuid.UUID) (operationsdto.ChargeProposal, error) {
+	logger := logging.FromContext(ctx)
+
+	job, err := s.GetJob(ctx, jobID)
+	if err != nil {
+		return operationsdto.ChargeProposal{}, err
+	}
+
+	basis := chargeBasis{parties: map[string]*uuid.UUID{
+		"customer": job.CustomerID,
+		"agent":    job.AgentID,
+	}}
+	for _, pkg := range job.Packages {
+		basis.addPackage(pkg.ContainerNo, pkg.ContainerType, pkg.ChargeableWeight, pkg.GrossWeightKg)
+	}
+	if job.Carrier != nil {
+		basis.parties["carrier"] = job.Carrier.CarrierPartyID
+	}
+	if job.IncotermCode != nil {
+		basis.bearers, err = s.incotermBearers(ctx, job.IncotermCode)
+		if err != nil && !errors.Is(err, ErrUnknownIncoterm) {
+			return operationsdto.ChargeProposal{}, err
+		}
+		if basis.bearers != nil {
+			if basis.segments, err = s.activitySegments(ctx); err != nil {
+				return operationsdto.ChargeProposal{}, err
+			}
+		}
+	}
+	party, err := s.repo.GetJobParty(ctx, jobID)
+	switch {
+	case err == nil:
+		basis.parties["shipper"] = uuidFromPgtype(party.ShipperID)
+		basis.parties["consignee"] = uuidFromPgtype(party.ConsigneeID)
+		basis.parties["notify_party"] = uuidFromPgtype(party.NotifyPartyID)
+		basis.parties["origin_agent"] = uuidFromPgtype(party.OriginAgentID)
+		basis.parties["destination_agent"] = uuidFromPgtype(party.DestinationAgentID)
+	case !errors.Is(err, pgx.ErrNoRows):
+		logger.Error("failed to get job party", slog.Any("error", err))
+		return operationsdto.ChargeProposal{}, fmt.Errorf("operations: propose job charges: %w", err)
+	}
+
+	template, charges, err := s.matchChargeTemplate(ctx, job.TransportMode, job.ServiceType, job.ServiceSubcategory, job.IncotermCode, basis)
+	if err != nil {
+		return operationsdto.ChargeProposal{}, err
+	}
+
+	proposal := operationsdto.ChargeProposal{
+		TemplateID:   template.ID,
+		TemplateName: template.Name,
+		Billing:      []operationsdto.BillingInput{},
+		Provisions:   []operationsdto.ProvisionInput{},
+	}
+	for _, charge := range charges {
+		if charge.billing != nil {
+			proposal.Billing = append(proposal.Billing, *charge.billing)
+		} else {
+			proposal.Provisions = append(proposal.Provisions, *charge.provision)
+		}
+		proposal.Gaps = append(proposal.Gaps, charge.gaps...)
+	}
+	return proposal, nil
+}
+
+// applyChargeTemplate fills a new job that arrives without charges with the complete lines of its
+// matching charge template. Lines that still lack a quantity, rate or party are returned as a
+// proposal, with their gaps, for the user to complete; it is nil when nothing was left out.
+func (s *Service) applyChargeTemplate(ctx context.Context, input *operationsdto.CreateJobInput) *operationsdto.ChargeProposal {
+	logger := logging.FromContext(ctx)
+
+	basis := chargeBasis{parties: map[string]*uuid.UUID{
+		"customer": input.CustomerID,
+		"agent":    input.AgentID,
+	}}
+	for _, pkg := range input.Packages {
+		basis.addPackage(pkg.ContainerNo, pkg.ContainerType, pkg.ChargeableWeight, pkg.GrossWeightKg)
+	}
+	if input.Carrier != nil {
+		basis.parties["carrier"] = input.Carrier.CarrierPartyID
+	}
+
+	template, charges, err := s.matchChargeTemplate(ctx, input.TransportMode, input.ServiceType, input.ServiceSubcategory, input.IncotermCode, basis)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		logger.Warn("failed to apply charge template", slog.Any("error", err))
+		return nil
+	}
+
+	proposal := &operationsdto.ChargeProposal{
+		TemplateID:   template.ID,
+		TemplateName: template.Name,
+		Billing:      []operationsdto.BillingInput{},
+		Provisions:   []operationsdto.ProvisionInput{},
+	}
+	for _, charge := range charges {
+		switch {
+		case len(charge.gaps) > 0 && charge.billing != nil:
+			proposal.Billing = append(proposal.Billing, *charge.billing)
+			proposal.Gaps = append(proposal.Gaps, charge.gaps...)
+		case len(charge.gaps) > 0:
+			proposal.Provisions = append(proposal.Provisions, *charge.provision)
+			proposal.Gaps = append(proposal.Gaps, charge.gaps...)
+		case charge.billing != nil:
+			input.Billing = append(input.Billing, *charge.billing)
+		default:
+			input.Provisions = append(input.Provisions, *charge.provision)
+		}
+	}
+	logger.Info("applied charge template",
+		slog.String("templateID", template.ID.String()),
+		slog.Int("billing", len(input.Billing)),
+		slog.Int("provisions", len(input.Provisions)),
+		slog.Int("proposed", len(proposal.Billing)+len(proposal.Provisions)))
+	if len(proposal.Gaps) == 0 {
+		return nil
+	}
+	return proposal
+}
+
+// matchChargeTemplate finds the most specific template for a service combination and turns its
+// lines into charges. It returns pgx.ErrNoRows when no template matches.
+func (s *Service) matchChargeTemplate(ctx context.Context, transportMode, serviceType, serviceSubcategory, incoterm *string, basis chargeBasis) (sqlc.ChargeTemplate, []proposedCharge, error) {
+	if transportMode == nil || strings.TrimSpace(*transportMode) == "" {
+		return sqlc.ChargeTemplate{}, nil, fmt.Errorf("operations: match charge template: job has no transport mode: %w", pgx.ErrNoRows)
+	}
+
+	template, lines, err := s.repo.GetMatchingChargeTemplate(ctx, sqlc.GetMatchingChargeTemplateParams{
+		TransportMode:      *transportMode,
+		ServiceType:        repository.NullTextFromString(serviceType),
+		ServiceSubcategory: repository.NullTextFromString(serviceSubcategory),
+		IncoTermCode:       repository.NullTextFromString(incoterm),
+	})
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			logging.FromContext(ctx).Error("failed to match charge template", slog.Any("error", err))
+		}
+		return sqlc.ChargeTemplate{}, nil, fmt.Errorf("operations: match charge template: %w", err)
+	}
+
+	charges := make([]proposedCharge, 0, len(lines))
+	for _, line := range lines {
+		charges = append(charges, basis.propose(line))
+	}
+	return template, charges, nil
+}
+
+func (b *chargeBasis) addPackage(containerNo, containerType *string, chargeableWeight, grossWeight *decimal.Decimal) {
+	if (containerNo != nil && *containerNo != "") || (containerType != nil && *containerType != "") {
+		b.containers++
+	}
+	switch {
+	case chargeableWeight != nil:
+		b.weightKg = b.weightKg.Add(*chargeableWeight)
+	case grossWeight != nil:
+		b.weightKg = b.weightKg.Add(*grossWeight)
+	}
+}
+
+func (b chargeBasis) propose(line sqlc.ChargeTemplateLine) proposedCharge {
+	label := fmt.Sprintf("%s %s/%s", line.LineKind, line.ActivityType, line.ActivityCode)
+	activityType, activityCode := line.ActivityType, line.ActivityCode
+
+	var charge proposedCharge
+	quantity := b.quantity(line.QuantityBasis)
+	if quantity == nil {
+		charge.gaps = append(charge.gaps, fmt.Sprintf("%s: job has nothing to charge %s", label, strings.ReplaceAll(line.QuantityBasis, "_", " ")))
+	}
+	unitPrice := decimalFromNumeric(line.UnitPrice)
+	currency := textToStringPtr(line.CurrencyCode)
+	if unitPrice == nil || currency == nil {
+		charge.gaps = append(charge.gaps, fmt.Sprintf("%s: no default rate", label))
+	}
+	var partyID *uuid.UUID
+	switch {
+	case line.PartyRole.Valid:
+		partyID = b.parties[line.PartyRole.String]
+		if partyID == nil {
+			charge.gaps = append(charge.gaps, fmt.Sprintf("%s: job has no %s", label, strings.ReplaceAll(line.PartyRole.String, "_", " ")))
+		}
+	case line.LineKind == chargeLineBilling:
+		if segment, ok := b.segments[[2]string{activityType, activityCode}]; ok {
+			partyID = b.parties[b.bearers[segment]]
+		}
+	}
+
+	if line.LineKind == chargeLineBilling {
+		charge.billing = &operationsdto.BillingInput{
+			ActivityType:   &activityType,
+			ActivityCode:   &activityCode,
+			BillingPartyID: partyID,
+			CurrencyCode:   currency,
+			Quantity:       quantity,
+			UnitPrice:      unitPrice,
+			TaxCode:        textToStringPtr(line.TaxCode),
+			Description:    textToStringPtr(line.Description),
+		}
+	} else {
+		charge.provision = &operationsdto.ProvisionInput{
+			ActivityType: &activityType,
+			ActivityCode: &activityCode,
+			CostPartyID:  partyID,
+			CurrencyCode: currency,
+			Quantity:     quantity,
+			UnitPrice:    unitPrice,
+			TaxCode:      textToStringPtr(line.TaxCode),
+			Notes:        textToStringPtr(line.Description),
+		}
+	}
+	return charge
+}
+
+// chargeTemplateParams validates a template and builds its insert parameters.
+func (s *Service) chargeTemplateParams(ctx context.Context, input operationsdto.ChargeTemplateInput) (sqlc.CreateChargeTemplateParams, []sqlc.CreateChargeTemplateLineParams, error) {
+	var problems []string
+	name := strings.TrimSpace(input.Name)
+	transportMode := strings.TrimSpace(input.TransportMode)
+	if name == "" {
+		problems = append(problems, "name is required")
+	}
+	if transportMode == "" {
+		problems = append(problems, "transport mode is required")
+	}
+	if len(input.Lines) == 0 {
+		problems = append(problems, "at least one line is required")
+	}
+
+	activityRows, err := s.repo.ListActivityLookups(ctx)
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to list activities", slog.Any("error", err))
+		return sqlc.CreateChargeTemplateParams{}, nil, fmt.Errorf("operations: create charge template: %w", err)
+	}
+	activities := make(map[[2]string]bool, len(activityRows))
+	for _, row := range activityRows {
+		activities[[2]string{row.ActivityType, row.ActivityCode}] = true
+	}
+	taxCodes := map[string]bool{}
+
+	actor := pgtype.Text{String: input.CreatedBy, Valid: true}
+	lineParams := make([]sqlc.CreateChargeTemplateLineParams, 0, len(input.Lines))
+	for i, line := range input.Lines {
+		label := fmt.Sprintf("line %d", i+1)
+		kind := strings.ToLower(strings.TrimSpace(line.LineKind))
+		basis := strings.ToLower(strings.TrimSpace(line.QuantityBasis))
+		activityType := strings.TrimSpace(line.ActivityType)
+		activityCode := strings.TrimSpace(line.ActivityCode)
+
+		if kind != chargeLineBilling && kind != chargeLineProvision {
+			problems = append(problems, fmt.Sprintf("%s: line kind must be billing or provision", label))
+		}
+		if basis != basisPerContainer && basis != basisPerKg && basis != basisPerShipment {
+			problems = append(problems, fmt.Sprintf("%s: quantity basis must be per_container, per_kg or per_shipment", label))
+		}
+		if !activities[[2]string{activityType, activityCode}] {
+			problems = append(problems, fmt.Sprintf("%s: unknown activity %s/%s", label, activityType, activityCode))
+		}
+
+		var role *string
+		if line.PartyRole != nil {
+			r := strings.ToLower(strings.TrimSpace(*line.PartyRole))
+			if !chargePartyRoles[r] {
+				problems = append(problems, fmt.Sprintf("%s: unknown party role %q", label, *line.PartyRole))
+			}
+			role = &r
+		}
+
+		currency := upperPtr(line.CurrencyCode)
+		switch {
+		case currency != nil && !isCurrencyCode(*currency):
+			problems = append(problems, fmt.Sprintf("%s: currency must be an ISO 4217 code", label))
+		case (currency == nil) != (line.UnitPrice == nil):
+			problems = append(problems, fmt.Sprintf("%s: currency and unit price must be given together", label))
+		case line.UnitPrice != nil && line.UnitPrice.Sign() < 0:
+			problems = append(problems, fmt.Sprintf("%s: unit price must not be negative", label))
+		}
+
+		var taxCode *string
+		if line.TaxCode != nil {
+			code := strings.TrimSpace(*line.TaxCode)
+			taxCode = &code
+			known, checked := taxCodes[code]
+			if !checked {
+				_, err := s.repo.GetTaxRate(ctx, code)
+				switch {
+				case err == nil:
+					known = true
+				case !errors.Is(err, pgx.ErrNoRows):
+					logging.FromContext(ctx).Error("failed to get tax rate", slog.Any("error", err))
+					return sqlc.CreateChargeTemplateParams{}, nil, fmt.Errorf("operations: create charge template: %w", err)
+				}
+				taxCodes[code] = known
+			}
+			if !known {
+				problems = append(problems, fmt.Sprintf("%s: unknown tax code %q", label, code))
+			}
+		}
+
+		lineParams = append(lineParams, sqlc.CreateChargeTemplateLineParams{
+			LineKind:      kind,
+			ActivityType:  activityType,
+			ActivityCode:  activityCode,
+			QuantityBasis: basis,
+			PartyRole:     repository.NullTextFromString(role),
+			CurrencyCode:  repository.NullTextFromString(currency),
+			UnitPrice:     numericFromDecimal(line.UnitPrice),
+			TaxCode:       repository.NullTextFromString(taxCode),
+			Description:   repository.NullTextFromString(line.Description),
+			SortOrder:     int32(i),
+			Actor:         actor,
+		})
+	}
+	if len(problems) > 0 {
+		return sqlc.CreateChargeTemplateParams{}, nil, &ChargeTemplateError{Problems: problems}
+	}
+
+	return sqlc.CreateChargeTemplateParams{
+		Name:               name,
+		TransportMode:      transportMode,
+		ServiceType:        repository.NullTextFromString(input.ServiceType),
+		ServiceSubcategory: repository.NullTextFromString(input.ServiceSubcategory),
+		IncoTermCode:       repository.NullTextFromString(input.IncotermCode),
+		Actor:              actor,
+	}, lineParams, nil
+}
+
+func chargeTemplateFromSqlc(t sqlc.ChargeTemplate, lines []sqlc.ChargeTemplateLine) operationsdto.ChargeTemplate {
+	result := operationsdto.ChargeTemplate{
+		ID:                 t.ID,
+		Name:               t.Name,
+		TransportMode:      t.TransportMode,
+		ServiceType:        textToStringPtr(t.ServiceType),
+		ServiceSubcategory: textToStringPtr(t.ServiceSubcategory),
+		IncotermCode:       textToStringPtr(t.IncoTermCode),
+		Lines:              make([]operationsdto.ChargeTemplateLine, 0, len(lines)),
+		CreatedAt:          timeFromTimestamptz(t.CreatedAt),
+		CreatedBy:          textToStringPtr(t.CreatedBy),
+	}
+	for _, line := range lines {
+		result.Lines = append(result.Lines, operationsdto.ChargeTemplateLine{
+			ID:            line.ID,
+			LineKind:      line.LineKind,
+			ActivityType:  line.ActivityType,
+			ActivityCode:  line.ActivityCode,
+			QuantityBasis: line.QuantityBasis,
+			PartyRole:     textToStringPtr(line.PartyRole),
+			CurrencyCode:  textToStringPtr(line.CurrencyCode),
+			UnitPrice:     decimalFromNumeric(line.UnitPrice),
+			TaxCode:       textToStringPtr(line.TaxCode),
+			Description:   textToStringPtr(line.Description),
+			SortOrder:     line.SortOrder,
+		})
+	}
+	return result
+}
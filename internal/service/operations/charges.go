@@ -0,0 +1,332 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"frego-operations/internal/common"
+	sqlc "frego-operations/internal/db/sqlc"
+	"frego-operations/internal/decimal"
+	operationsdto "frego-operations/internal/dto/operations"
+	"frego-operations/internal/logging"
+)
+
+// ErrInvalidCharge indicates billing or provision lines could not be priced or
+// disagree with the amounts the service computes.
+var ErrInvalidCharge = errors.New("operations: invalid charge")
+
+// ChargeError lists every problem found while pricing a job's billing and provision lines.
+type ChargeError struct {
+	Problems []string
+}
+
+func (e *ChargeError) Error() string {
+	return fmt.Sprintf("operations: invalid charge: %s", strings.Join(e.Problems, "; "))
+}
+
+func (e *ChargeError) Unwrap() error {
+	return ErrInvalidCharge
+}
+
+var (
+	decimalOne     = decimal.NewFromInt(1)
+	decimalHundred = decimal.NewFromInt(100)
+)
+
+// chargeAmounts are the server-computed figures stored for one billing or provision line.
+type chargeAmounts struct {
+	Quantity              decimal.Decimal
+	ExchangeRate          decimal.Decimal
+	AmountWithoutTax      decimal.Decimal
+	TaxAmount             decimal.Decimal
+	TotalAmount           decimal.Decimal
+	AmountPrimaryCurrency decimal.Decimal
+}
+
+// chargeInput is the pricing-relevant part shared by BillingInput and ProvisionInput.
+type chargeInput struct {
+	label                 string
+	currency              *string
+	taxCode               *string
+	quantity              *decimal.Decimal
+	unitPrice             *decimal.Decimal
+	exchangeRate          *decimal.Decimal
+	amountWithoutTax      *decimal.Decimal
+	taxAmount             *decimal.Decimal
+	totalAmount           *decimal.Decimal
+	amountPrimaryCurrency *decimal.Decimal
+	rateDate              time.Time
+}
+
+// chargePricer prices lines against the tenant's primary currency and tax codes,
+// caching lookups for the duration of one request.
+type chargePricer struct {
+	s               *Service
+	primaryCurrency string
+	taxRates        map[string]decimal.Decimal
+	problems        []string
+}
+
+// priceCharges computes net, tax, total and primary-currency amounts for every billing and
+// provision line. Client-supplied amounts are accepted only when they match the computed ones.
+func (s *Service) priceCharges(ctx context.Context, billing []operationsdto.BillingInput, provisions []operationsdto.ProvisionInput) ([]chargeAmounts, []chargeAmounts, error) {
+	if len(billing) == 0 && len(provisions) == 0 {
+		return nil, nil, nil
+	}
+
+	primary, err := s.primaryCurrency(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	p := &chargePricer{s: s, primaryCurrency: primary, taxRates: map[string]decimal.Decimal{}}
+
+	billed := make([]chargeAmounts, len(billing))
+	for i, b := range billing {
+		billed[i], err = p.price(ctx, chargeInput{
+			label:                 fmt.Sprintf("billing line %d", i+1),
+			currency:              b.CurrencyCode,
+			taxCode:               b.TaxCode,
+			quantity:              b.Quantity,
+			unitPrice:             b.UnitPrice,
+			exchangeRate:          b.ExchangeRate,
+			amountWithoutTax:      b.AmountWithoutTax,
+			taxAmount:             b.TaxAmount,
+			totalAmount:           b.TotalAmount,
+			amountPrimaryCurrency: b.AmountPrimaryCurrency,
+			rateDate:              rateDate(b.PODate),
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	provisioned := make([]chargeAmounts, len(provisions))
+	for i, pr := range provisions {
+		provisioned[i], err = p.price(ctx, chargeInput{
+			label:                 fmt.Sprintf("provision line %d", i+1),
+			currency:              pr.CurrencyCode,
+			taxCode:               pr.TaxCode,
+			quantity:              pr.Quantity,
+			unitPrice:             pr.UnitPrice,
+			exchangeRate:          pr.ExchangeRate,
+			amountWithoutTax:      pr.AmountWithoutTax,
+			taxAmount:             pr.TaxAmount,
+			totalAmount:           pr.TotalAmount,
+			amountPrimaryCurrency: pr.AmountPrimaryCurrency,
+			rateDate:              rateDate(pr.InvoiceDate, pr.PODate),
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if len(p.problems) > 0 {
+		return nil, nil, &ChargeError{Problems: p.problems}
+	}
+	return billed, provisioned, nil
+}
+
+// price computes one line. Pricing problems are collected on the pricer; only lookup
+// failures are returned as errors.
+func (p *chargePricer) price(ctx context.Context, in chargeInput) (chargeAmounts, error) {
+	var out chargeAmounts
+
+	currency := ""
+	if in.currency != nil {
+		currency = strings.ToUpper(strings.TrimSpace(*in.currency))
+	}
+	if currency == "" {
+		p.problems = append(p.problems, in.label+": currency is required")
+		return out, nil
+	}
+	if in.unitPrice == nil {
+		p.problems = append(p.problems, in.label+": unit price is required")
+		return out, nil
+	}
+
+	out.Quantity = decimalOne
+	if in.quantity != nil {
+		out.Quantity = *in.quantity
+	}
+	out.AmountWithoutTax = out.Quantity.Mul(*in.unitPrice).RoundCurrency(currency)
+
+	out.TaxAmount = decimal.Zero.RoundCurrency(currency)
+	if in.taxCode != nil && strings.TrimSpace(*in.taxCode) != "" {
+		rate, ok, err := p.taxRate(ctx, strings.TrimSpace(*in.taxCode))
+		if err != nil {
+			return out, err
+		}
+		if !ok {
+			p.problems = append(p.problems, fmt.Sprintf("%s: unknown tax code %q", in.label, *in.taxCode))
+			return out, nil
+		}
+		out.TaxAmount = out.AmountWithoutTax.Mul(rate).Div(decimalHundred, decimal.MinorUnits(currency))
+	}
+	out.TotalAmount = out.AmountWithoutTax.Add(out.TaxAmount)
+
+	switch {
+	case currency == p.primaryCurrency:
+		out.ExchangeRate = decimalOne
+		if in.exchangeRate != nil && !in.exchangeRate.Equal(decimalOne) {
+			p.problems = append(p.problems, fmt.Sprintf("%s: exchange rate must be 1 for the primary currency %s", in.label, currency))
+		}
+	default:
+		quote, ok, err := p.s.exchangeRate(ctx, currency, p.primaryCurrency, in.rateDate)
+		if err != nil {
+			return out, err
+		}
+		if !ok {
+			p.problems = append(p.problems, fmt.Sprintf("%s: no exchange rate from %s to %s in force on %s",
+				in.label, currency, p.primaryCurrency, in.rateDate.Format(rateDateLayout)))
+			return out, nil
+		}
+		out.ExchangeRate = quote.Rate
+		if in.exchangeRate != nil && !in.exchangeRate.Equal(quote.Rate) {
+			p.problems = append(p.problems, fmt.Sprintf("%s: exchange rate %s does not match %s in force on %s",
+				in.label, in.exchangeRate, quote.Rate, in.rateDate.Format(rateDateLayout)))
+		}
+	}
+	out.AmountPrimaryCurrency = out.AmountWithoutTax.Mul(out.ExchangeRate).RoundCurrency(p.primaryCurrency)
+
+	p.match(in.label, "amountWithoutTax", in.amountWithoutTax, out.AmountWithoutTax, currency)
+	p.match(in.label, "taxAmount", in.taxAmount, out.TaxAmount, currency)
+	p.match(in.label, "totalAmount", in.totalAmount, out.TotalAmount, currency)
+	p.match(in.label, "amountPrimaryCurrency", in.amountPrimaryCurrency, out.AmountPrimaryCurrency, p.primaryCurrency)
+	return out, nil
+}
+
+// match records a problem when a client-supplied amount differs from the computed one
+// after rounding to the currency's minor unit.
+func (p *chargePricer) match(label, field string, supplied *decimal.Decimal, computed decimal.Decimal, currency string) {
+	if supplied == nil || supplied.RoundCurrency(currency).Equal(computed) {
+		return
+	}
+	p.problems = append(p.problems, fmt.Sprintf("%s: %s %s does not match computed %s", label, field, supplied, computed))
+}
+
+func (p *chargePricer) taxRate(ctx context.Context, code string) (decimal.Decimal, bool, error) {
+	if rate, ok := p.taxRates[code]; ok {
+		return rate, true, nil
+	}
+	row, err := p.s.repo.GetTaxRate(ctx, code)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return decimal.Zero, false, nil
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to get tax rate", slog.Any("error", err))
+		return decimal.Zero, false, fmt.Errorf("operations: get tax rate: %w", err)
+	}
+	rate := decimalFromNumeric(row)
+	if rate == nil {
+		return decimal.Zero, false, nil
+	}
+	p.taxRates[code] = *rate
+	return *rate, true, nil
+}
+
+// primaryCurrency returns the tenant's reporting currency from tenant_setting.
+func (s *Service) primaryCurrency(ctx context.Context) (string, error) {
+	currency, err := s.repo.GetPrimaryCurrency(ctx)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", &ChargeError{Problems: []string{"tenant has no primary currency configured"}}
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to get primary currency", slog.Any("error", err))
+		return "", fmt.Errorf("operations: get primary currency: %w", err)
+	}
+	return strings.ToUpper(strings.TrimSpace(currency)), nil
+}
+
+// recalculateProfit derives ops_provision.profit from the billing lines of the same activity.
+// Revenue in the primary currency is split across that activity's provisions in proportion
+// to their cost, so the profits of one activity always add up to revenue minus cost. It runs in
+// the transaction that changed the charges, so stored profit never lags them.
+func (s *Service) recalculateProfit(ctx context.Context, jobID uuid.UUID, actor string) error {
+	logger := logging.FromContext(ctx)
+
+	provisions, err := s.repo.ListJobProvisions(ctx, jobID)
+	if err != nil {
+		logger.Error("failed to list job provisions", slog.Any("error", err))
+		return fmt.Errorf("operations: recalculate profit: %w", err)
+	}
+	if len(provisions) == 0 {
+		return nil
+	}
+	billing, err := s.repo.ListJobBilling(ctx, jobID)
+	if err != nil {
+		logger.Error("failed to list job billing", slog.Any("error", err))
+		return fmt.Errorf("operations: recalculate profit: %w", err)
+	}
+	primary, err := s.primaryCurrency(ctx)
+	if err != nil {
+		return err
+	}
+
+	revenue := map[string]decimal.Decimal{}
+	for _, b := range billing {
+		key := activityKey(b.ActivityType, b.ActivityCode)
+		revenue[key] = revenue[key].Add(derefDecimal(decimalFromNumeric(b.AmountPrimaryCurrency)))
+	}
+
+	byActivity := map[string][]sqlc.ListJobProvisionsRow{}
+	var order []string
+	for _, p := range provisions {
+		key := activityKey(p.ActivityType, p.ActivityCode)
+		if _, seen := byActivity[key]; !seen {
+			order = append(order, key)
+		}
+		byActivity[key] = append(byActivity[key], p)
+	}
+
+	for _, key := range order {
+		rows := byActivity[key]
+		costs := make([]decimal.Decimal, len(rows))
+		totalCost := decimal.Zero
+		for i, p := range rows {
+			costs[i] = derefDecimal(decimalFromNumeric(p.AmountPrimaryCurrency))
+			totalCost = totalCost.Add(costs[i])
+		}
+
+		remaining := revenue[key]
+		for i, p := range rows {
+			share := remaining
+			if i < len(rows)-1 {
+				share = decimal.Zero
+				if !totalCost.IsZero() {
+					share = revenue[key].Mul(costs[i]).Div(totalCost, decimal.MinorUnits(primary))
+				} else if i == 0 {
+					share = revenue[key]
+				}
+			}
+			remaining = remaining.Sub(share)
+
+			profit := share.Sub(costs[i]).RoundCurrency(primary)
+			if current := decimalFromNumeric(p.Profit); current != nil && current.Equal(profit) {
+				continue
+			}
+			err := s.repo.UpdateJobProvisionProfit(ctx, sqlc.UpdateJobProvisionProfitParams{
+				ID:     p.ID,
+				Profit: numericFromDecimal(&profit),
+				Actor:  pgtype.Text{String: actor, Valid: true},
+			})
+			if err != nil {
+				logger.Error("failed to update provision profit", slog.Any("error", err))
+				return fmt.Errorf("operations: update provision profit: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+func activityKey(activityType, activityCode pgtype.Text) string {
+	return strings.ToLower(strings.TrimSpace(common.PgtypeTextToString(activityType))) + "/" +
+		strings.ToLower(strings.TrimSpace(common.PgtypeTextToString(activityCode)))
+}
@@ -0,0 +1,50 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"frego-operations/internal/common"
+)
+
+// ErrOutOfScope indicates a job change that would place the job outside the caller's data scope.
+var ErrOutOfScope = errors.New("operations: outside data scope")
+
+// ensureJobVisible returns pgx.ErrNoRows, wrapped, when the job is outside the caller's data
+// scope, so such a job looks the same as a missing one. Reads keyed by job ID that do not load
+// the job itself call it first.
+func (s *Service) ensureJobVisible(ctx context.Context, jobID uuid.UUID) error {
+	if common.DataScopeFromContext(ctx).Unrestricted() {
+		return nil
+	}
+	if _, err := s.repo.GetJob(ctx, jobID); err != nil {
+		return fmt.Errorf("operations: get job: %w", err)
+	}
+	return nil
+}
+
+// checkJobScope refuses a branch or sales executive that would take a job out of the caller's
+// data scope. Updates check only the values they change; a new job must name them.
+func checkJobScope(ctx context.Context, branchID, salesExecutiveID *uuid.UUID, creating bool) error {
+	scope := common.DataScopeFromContext(ctx)
+	var problems []string
+	if scope.BranchIDs != nil && (branchID != nil || creating) {
+		if branchID == nil || !slices.Contains(scope.BranchIDs, *branchID) {
+			problems = append(problems, "the job's branch must be one of your branches")
+		}
+	}
+	if scope.SalesExecutiveID != nil && (salesExecutiveID != nil || creating) {
+		if salesExecutiveID == nil || *salesExecutiveID != *scope.SalesExecutiveID {
+			problems = append(problems, "you must be the job's sales executive")
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrOutOfScope, strings.Join(problems, "; "))
+	}
+	return nil
+}
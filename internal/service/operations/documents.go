@@ -0,0 +1,345 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"frego-operations/internal/common"
+	sqlc "frego-operations/internal/db/sqlc"
+	"frego-operations/internal/docrender"
+	operationsdto "frego-operations/internal/dto/operations"
+	"frego-operations/internal/logging"
+	"frego-operations/internal/storage"
+)
+
+// ErrUnknownDocumentType indicates the document type is not in document_type_lu.
+var ErrUnknownDocumentType = errors.New("operations: unknown document type")
+
+// ListDocumentTemplates returns every stored template version, optionally for one document type.
+func (s *Service) ListDocumentTemplates(ctx context.Context, docTypeCode *string) ([]operationsdto.DocumentTemplate, error) {
+	logger := logging.FromContext(ctx)
+
+	rows, err := s.repo.ListDocTemplates(ctx, docTypeCode)
+	if err != nil {
+		logger.Error("failed to list document templates", slog.Any("error", err))
+		return nil, fmt.Errorf("operations: list document templates: %w", err)
+	}
+
+	templates := make([]operationsdto.DocumentTemplate, 0, len(rows))
+	for _, row := range rows {
+		templates = append(templates, documentTemplateFromSqlc(row))
+	}
+	return templates, nil
+}
+
+// CreateDocumentTemplate validates a template body and stores it as the next version for its document type.
+func (s *Service) CreateDocumentTemplate(ctx context.Context, input operationsdto.DocumentTemplateInput) (operationsdto.DocumentTemplate, error) {
+	logger := logging.FromContext(ctx)
+	docType := strings.ToUpper(strings.TrimSpace(input.DocTypeCode))
+
+	if _, err := s.documentType(ctx, docType); err != nil {
+		return operationsdto.DocumentTemplate{}, err
+	}
+	if err := docrender.Validate(input.BodyHTML); err != nil {
+		return operationsdto.DocumentTemplate{}, fmt.Errorf("operations: create document template: %w", err)
+	}
+
+	row, err := s.repo.CreateDocTemplate(ctx, sqlc.CreateDocTemplateParams{
+		DocTypeCode: docType,
+		Name:        textFromString(input.Name),
+		BodyHtml:    input.BodyHTML,
+		Actor:       pgtype.Text{String: input.CreatedBy, Valid: true},
+	})
+	if err != nil {
+		logger.Error("failed to create document template", slog.Any("error", err))
+		return operationsdto.DocumentTemplate{}, fmt.Errorf("operations: create document template: %w", err)
+	}
+
+	logger.Info("created document template", slog.String("docType", docType), slog.Int("version", int(row.Version)))
+	return documentTemplateFromSqlc(row), nil
+}
+
+// GenerateJobDocument renders a printable document for a job from the tenant's newest template
+// (or the built-in default), stores the PDF and attaches it to the job as an ops_job_document.
+// If the attach fails the stored PDF is deleted again; the document number it took is not reused.
+func (s *Service) GenerateJobDocument(ctx context.Context, jobID uuid.UUID, docTypeCode, actor string) (operationsdto.GeneratedDocument, error) {
+	logger := logging.FromContext(ctx)
+	docType := strings.ToUpper(strings.TrimSpace(docTypeCode))
+	logger.Info("generating job document", slog.String("jobID", jobID.String()), slog.String("docType", docType))
+
+	docTypeRow, err := s.documentType(ctx, docType)
+	if err != nil {
+		return operationsdto.GeneratedDocument{}, err
+	}
+
+	tpl, err := s.activeTemplate(ctx, docType)
+	if err != nil {
+		return operationsdto.GeneratedDocument{}, err
+	}
+
+	job, err := s.ensureJobOpen(ctx, jobID)
+	if err != nil {
+		return operationsdto.GeneratedDocument{}, err
+	}
+
+	tenantID, tenantName := tenantFromContext(ctx)
+	data, err := s.loadDocumentData(ctx, job)
+	if err != nil {
+		return operationsdto.GeneratedDocument{}, err
+	}
+	data.DocTypeCode = docType
+	data.DocTypeLabel = docTypeRow.Label
+	data.TenantName = tenantName
+	data.IssuedAt = time.Now().UTC()
+
+	prefix := fmt.Sprintf("%s-%d%02d", docType, data.IssuedAt.Year(), data.IssuedAt.Month())
+	seq, err := s.repo.GetNextDocumentSequence(ctx, prefix)
+	if err != nil {
+		logger.Error("failed to get next document sequence", slog.Any("error", err))
+		return operationsdto.GeneratedDocument{}, fmt.Errorf("operations: generate document number: %w", err)
+	}
+	data.DocNumber = fmt.Sprintf("%s-%04d", prefix, seq)
+
+	rendered, err := docrender.Render(tpl, data)
+	if err != nil {
+		logger.Error("failed to render document", slog.Any("error", err))
+		return operationsdto.GeneratedDocument{}, fmt.Errorf("operations: render document: %w", err)
+	}
+
+	location, err := s.documents.UploadJobDocument(ctx, tenantID, tenantName, job.ID, job.JobCode, docType, storage.DocumentPayload{
+		FileName:    data.DocNumber + ".pdf",
+		ContentType: "application/pdf",
+		Data:        rendered.PDF,
+	})
+	if err != nil {
+		logger.Error("failed to store document", slog.Any("error", err))
+		return operationsdto.GeneratedDocument{}, fmt.Errorf("operations: store document: %w", err)
+	}
+
+	// The job may have been closed while the document rendered; attach it only under the lock
+	description := fmt.Sprintf("%s generated from template v%d", docTypeRow.Label, tpl.Version)
+	var doc sqlc.OpsJobDocument
+	err = s.repo.InTx(ctx, func(ctx context.Context) error {
+		if _, err := s.ensureJobOpen(ctx, jobID); err != nil {
+			return err
+		}
+		var err error
+		doc, err = s.repo.CreateJobDocument(ctx, sqlc.CreateJobDocumentParams{
+			JobID:       job.ID,
+			DocTypeCode: pgtype.Text{String: docType, Valid: true},
+			DocNumber:   pgtype.Text{String: data.DocNumber, Valid: true},
+			IssuedAt:    job.BranchName,
+			IssuedDate:  pgtype.Timestamptz{Time: data.IssuedAt, Valid: true},
+			Description: pgtype.Text{String: description, Valid: true},
+			FileKey:     pgtype.Text{String: location.Key, Valid: true},
+			FileRegion:  pgtype.Text{String: location.Region, Valid: true},
+			Actor:       pgtype.Text{String: actor, Valid: true},
+		})
+		return err
+	})
+	if err != nil {
+		// Nothing refers to the stored PDF yet, so it must not outlive the failed attach.
+		if delErr := s.documents.DeleteDocument(context.WithoutCancel(ctx), location.Region, location.Key); delErr != nil {
+			logger.Error("failed to delete unattached document", slog.String("key", location.Key), slog.Any("error", delErr))
+		}
+		if errors.Is(err, ErrJobClosed) {
+			return operationsdto.GeneratedDocument{}, err
+		}
+		logger.Error("failed to attach document to job", slog.Any("error", err))
+		return operationsdto.GeneratedDocument{}, fmt.Errorf("operations: attach document: %w", err)
+	}
+
+	logger.Info("generated job document",
+		slog.String("jobCode", job.JobCode),
+		slog.String("docNumber", data.DocNumber),
+		slog.Int("templateVersion", int(tpl.Version)),
+		slog.Int("bytes", len(rendered.PDF)),
+	)
+	return operationsdto.GeneratedDocument{
+		Document:        documentFromSqlc(doc),
+		TemplateVersion: tpl.Version,
+	}, nil
+}
+
+func (s *Service) documentType(ctx context.Context, code string) (sqlc.GetDocumentTypeRow, error) {
+	row, err := s.repo.GetDocumentType(ctx, code)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return row, fmt.Errorf("%w %q", ErrUnknownDocumentType, code)
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to get document type", slog.Any("error", err))
+		return row, fmt.Errorf("operations: get document type: %w", err)
+	}
+	return row, nil
+}
+
+// activeTemplate returns the tenant's newest active template, falling back to the built-in one.
+func (s *Service) activeTemplate(ctx context.Context, docType string) (docrender.Template, error) {
+	row, err := s.repo.GetActiveDocTemplate(ctx, docType)
+	switch {
+	case err == nil:
+		return docrender.Template{DocType: row.DocTypeCode, Version: row.Version, Body: row.BodyHtml}, nil
+	case errors.Is(err, pgx.ErrNoRows):
+		return docrender.Builtin(docType)
+	default:
+		logging.FromContext(ctx).Error("failed to get document template", slog.Any("error", err))
+		return docrender.Template{}, fmt.Errorf("operations: get document template: %w", err)
+	}
+}
+
+// loadDocumentData collects the job's parties, carrier leg, tracking and cargo for templates.
+func (s *Service) loadDocumentData(ctx context.Context, job sqlc.GetJobRow) (docrender.DocumentData, error) {
+	logger := logging.FromContext(ctx)
+
+	data := docrender.DocumentData{
+		Job: docrender.Job{
+			Code:          job.JobCode,
+			Type:          common.PgtypeTextToString(job.JobType),
+			TransportMode: common.PgtypeTextToString(job.TransportMode),
+			ServiceType:   common.PgtypeTextToString(job.ServiceType),
+			Incoterm:      common.PgtypeTextToString(job.IncoTermCode),
+			Commodity:     common.PgtypeTextToString(job.Commodity),
+			Origin:        joinNonEmpty(common.PgtypeTextToString(job.SourceCity), common.PgtypeTextToString(job.SourceCountry)),
+			Destination:   joinNonEmpty(common.PgtypeTextToString(job.DestinationCity), common.PgtypeTextToString(job.DestinationCountry)),
+			CustomerName:  common.PgtypeTextToString(job.CustomerName),
+			BranchName:    common.PgtypeTextToString(job.BranchName),
+		},
+	}
+
+	party, err := s.repo.GetJobParty(ctx, job.ID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		logger.Error("failed to get job parties", slog.Any("error", err))
+		return data, fmt.Errorf("operations: load document parties: %w", err)
+	}
+	slots := []struct {
+		id     pgtype.UUID
+		target *docrender.Party
+	}{
+		{party.ShipperID, &data.Shipper},
+		{party.ConsigneeID, &data.Consignee},
+		{party.NotifyPartyID, &data.NotifyParty},
+		{party.OriginAgentID, &data.OriginAgent},
+		{party.DestinationAgentID, &data.DestinationAgent},
+	}
+	var ids []uuid.UUID
+	for _, slot := range slots {
+		if id := uuidFromPgtype(slot.id); id != nil {
+			ids = append(ids, *id)
+		}
+	}
+	if len(ids) > 0 {
+		rows, err := s.repo.ListPartiesByIDs(ctx, ids)
+		if err != nil {
+			logger.Error("failed to list parties", slog.Any("error", err))
+			return data, fmt.Errorf("operations: load document parties: %w", err)
+		}
+		byID := make(map[uuid.UUID]sqlc.ListPartiesByIDsRow, len(rows))
+		for _, row := range rows {
+			byID[row.ID] = row
+		}
+		for _, slot := range slots {
+			id := uuidFromPgtype(slot.id)
+			if id == nil {
+				continue
+			}
+			if row, ok := byID[*id]; ok {
+				*slot.target = docrender.Party{Code: common.PgtypeTextToString(row.HumanID), Name: row.Name}
+			}
+		}
+	}
+
+	carriers, err := s.repo.GetJobCarriers(ctx, job.ID)
+	if err != nil {
+		logger.Error("failed to get job carriers", slog.Any("error", err))
+		return data, fmt.Errorf("operations: load document carriers: %w", err)
+	}
+	if len(carriers) > 0 {
+		c := carriers[0]
+		data.Carrier = docrender.Carrier{
+			Name:                       common.PgtypeTextToString(c.CarrierName),
+			VesselName:                 common.PgtypeTextToString(c.VesselName),
+			VoyageNumber:               common.PgtypeTextToString(c.VoyageNumber),
+			FlightID:                   common.PgtypeTextToString(c.FlightID),
+			FlightDate:                 timeFromPgtype(c.FlightDate),
+			PortOfLoading:              common.PgtypeTextToString(c.OriginPortStation),
+			PortOfDischarge:            common.PgtypeTextToString(c.DestinationPortStation),
+			TransportDocumentReference: common.PgtypeTextToString(c.TransportDocumentReference),
+			AccountingInfo:             common.PgtypeTextToString(c.AccountingInfo),
+			HandlingInfo:               common.PgtypeTextToString(c.HandlingInfo),
+		}
+	}
+
+	tracking, err := s.repo.GetJobTracking(ctx, job.ID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		logger.Error("failed to get job tracking", slog.Any("error", err))
+		return data, fmt.Errorf("operations: load document tracking: %w", err)
+	}
+	data.ETD = timeFromPgtype(tracking.EtdDate)
+	data.ETA = timeFromPgtype(tracking.EtaDate)
+
+	packages, err := s.repo.ListJobPackages(ctx, job.ID)
+	if err != nil {
+		logger.Error("failed to list job packages", slog.Any("error", err))
+		return data, fmt.Errorf("operations: load document packages: %w", err)
+	}
+	for _, pkg := range packages {
+		p := docrender.Package{
+			ContainerNo:   common.PgtypeTextToString(pkg.ContainerNo),
+			ContainerType: common.PgtypeTextToString(pkg.ContainerType),
+			ContainerSize: common.PgtypeTextToString(pkg.ContainerSize),
+			SealNo:        common.PgtypeTextToString(pkg.CarrierSealNo),
+			PackageType:   common.PgtypeTextToString(pkg.PackageType),
+			Count:         derefDecimal(decimalFromNumeric(pkg.NoOfPackages)),
+			GrossWeightKg: derefDecimal(decimalFromNumeric(pkg.GrossWeightKg)),
+			VolumeM3:      derefDecimal(decimalFromNumeric(pkg.Volume)),
+			Description:   common.PgtypeTextToString(pkg.CommodityCargoDescription),
+			HSCode:        common.PgtypeTextToString(pkg.HsCode),
+		}
+		data.TotalPackages = data.TotalPackages.Add(p.Count)
+		data.TotalWeightKg = data.TotalWeightKg.Add(p.GrossWeightKg)
+		data.TotalVolumeM3 = data.TotalVolumeM3.Add(p.VolumeM3)
+		data.Packages = append(data.Packages, p)
+	}
+
+	return data, nil
+}
+
+func documentTemplateFromSqlc(row sqlc.DocTemplate) operationsdto.DocumentTemplate {
+	return operationsdto.DocumentTemplate{
+		ID:          row.ID,
+		DocTypeCode: row.DocTypeCode,
+		Version:     row.Version,
+		Name:        common.PgtypeTextToStringPtr(row.Name),
+		BodyHTML:    row.BodyHtml,
+		CreatedAt:   timeFromTimestamptz(row.CreatedAt),
+		CreatedBy:   common.PgtypeTextToStringPtr(row.CreatedBy),
+		IsActive:    row.IsActive.Valid && row.IsActive.Bool,
+	}
+}
+
+// tenantFromContext returns the tenant identifier and display name for storage keys.
+func tenantFromContext(ctx context.Context) (string, string) {
+	if id, _, ok := common.TenantFromContext(ctx); ok {
+		name, _ := common.TenantDisplayNameFromContext(ctx)
+		return id, name
+	}
+	return "", ""
+}
+
+func joinNonEmpty(values ...string) string {
+	var parts []string
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			parts = append(parts, v)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,209 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"frego-operations/internal/common"
+	sqlc "frego-operations/internal/db/sqlc"
+	operationsdto "frego-operations/internal/dto/operations"
+	"frego-operations/internal/edifact"
+	"frego-operations/internal/logging"
+)
+
+// ErrUnsupportedJobType indicates the export does not apply to the job's transport mode.
+var ErrUnsupportedJobType = errors.New("operations: export not supported for job type")
+
+// ExportIFTMIN builds an IFTMIN D.99B booking request for a sea job.
+func (s *Service) ExportIFTMIN(ctx context.Context, jobID uuid.UUID, opts operationsdto.EDIExportOptions) (operationsdto.ExportFile, error) {
+	logger := logging.FromContext(ctx)
+	logger.Info("exporting iftmin booking", slog.String("jobID", jobID.String()))
+
+	job, err := s.repo.GetJob(ctx, jobID)
+	if err != nil {
+		logger.Error("failed to get job", slog.Any("error", err))
+		return operationsdto.ExportFile{}, fmt.Errorf("operations: export iftmin: %w", err)
+	}
+	if jobType := common.PgtypeTextToString(job.JobType); jobType != "Sea" {
+		return operationsdto.ExportFile{}, fmt.Errorf("%w: IFTMIN requires a Sea job, got %q", ErrUnsupportedJobType, jobType)
+	}
+
+	booking, err := s.loadBooking(ctx, job)
+	if err != nil {
+		return operationsdto.ExportFile{}, err
+	}
+
+	recipient := strings.TrimSpace(opts.RecipientID)
+	if recipient == "" {
+		recipient = booking.Leg.CarrierCode
+	}
+
+	interchange, err := edifact.BuildIFTMIN(booking, edifact.Envelope{
+		SenderID:      opts.SenderID,
+		RecipientID:   recipient,
+		PreparedAt:    time.Now().UTC(),
+		TestIndicator: opts.TestIndicator,
+	})
+	if err != nil {
+		logger.Warn("iftmin booking rejected", slog.Any("error", err))
+		return operationsdto.ExportFile{}, fmt.Errorf("operations: export iftmin: %w", err)
+	}
+
+	logger.Info("exported iftmin booking",
+		slog.String("jobCode", job.JobCode),
+		slog.Int("segments", len(interchange.Segments)),
+	)
+
+	return operationsdto.ExportFile{
+		FileName:    fmt.Sprintf("IFTMIN_%s.edi", job.JobCode),
+		ContentType: "application/edifact",
+		Data:        interchange.Payload,
+	}, nil
+}
+
+// loadBooking collects carrier legs, parties, tracking and packages for a booking request.
+func (s *Service) loadBooking(ctx context.Context, job sqlc.GetJobRow) (edifact.Booking, error) {
+	logger := logging.FromContext(ctx)
+
+	booking := edifact.Booking{
+		Reference: job.JobCode,
+		JobCode:   job.JobCode,
+		IssuedAt:  time.Now().UTC(),
+		Commodity: common.PgtypeTextToString(job.Commodity),
+		Incoterm:  common.PgtypeTextToString(job.IncoTermCode),
+	}
+
+	carriers, err := s.repo.GetJobCarriers(ctx, job.ID)
+	if err != nil {
+		logger.Error("failed to get job carriers", slog.Any("error", err))
+		return booking, fmt.Errorf("operations: load booking carriers: %w", err)
+	}
+
+	party, err := s.repo.GetJobParty(ctx, job.ID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		logger.Error("failed to get job parties", slog.Any("error", err))
+		return booking, fmt.Errorf("operations: load booking parties: %w", err)
+	}
+
+	roles := []struct {
+		role string
+		id   *uuid.UUID
+	}{
+		{edifact.PartyShipper, uuidFromPgtype(party.ShipperID)},
+		{edifact.PartyConsignee, uuidFromPgtype(party.ConsigneeID)},
+		{edifact.PartyNotify, uuidFromPgtype(party.NotifyPartyID)},
+	}
+	var carrierPartyID *uuid.UUID
+	if len(carriers) > 0 {
+		carrierPartyID = uuidFromPgtype(carriers[0].CarrierPartyID)
+		roles = append(roles, struct {
+			role string
+			id   *uuid.UUID
+		}{edifact.PartyCarrier, carrierPartyID})
+	}
+
+	var ids []uuid.UUID
+	for _, r := range roles {
+		if r.id != nil {
+			ids = append(ids, *r.id)
+		}
+	}
+	parties := make(map[uuid.UUID]sqlc.ListPartiesByIDsRow, len(ids))
+	if len(ids) > 0 {
+		rows, err := s.repo.ListPartiesByIDs(ctx, ids)
+		if err != nil {
+			logger.Error("failed to list parties", slog.Any("error", err))
+			return booking, fmt.Errorf("operations: load booking parties: %w", err)
+		}
+		for _, row := range rows {
+			parties[row.ID] = row
+		}
+	}
+
+	for _, r := range roles {
+		if r.id == nil {
+			continue
+		}
+		row, ok := parties[*r.id]
+		if !ok {
+			continue
+		}
+		booking.Parties = append(booking.Parties, edifact.BookingParty{
+			Role: r.role,
+			Code: common.PgtypeTextToString(row.HumanID),
+			Name: row.Name,
+		})
+	}
+
+	if len(carriers) > 0 {
+		c := carriers[0]
+		booking.Leg = edifact.BookingLeg{
+			CarrierName:     common.PgtypeTextToString(c.CarrierName),
+			VesselName:      common.PgtypeTextToString(c.VesselName),
+			VoyageNumber:    common.PgtypeTextToString(c.VoyageNumber),
+			PortOfLoading:   common.PgtypeTextToString(c.OriginPortStation),
+			PortOfDischarge: common.PgtypeTextToString(c.DestinationPortStation),
+		}
+		if carrierPartyID != nil {
+			if row, ok := parties[*carrierPartyID]; ok {
+				booking.Leg.CarrierCode = common.PgtypeTextToString(row.HumanID)
+				if booking.Leg.CarrierName == "" {
+					booking.Leg.CarrierName = row.Name
+				}
+			}
+		}
+		if booking.Leg.CarrierName != "" && !hasBookingParty(booking.Parties, edifact.PartyCarrier) {
+			booking.Parties = append(booking.Parties, edifact.BookingParty{
+				Role: edifact.PartyCarrier,
+				Code: booking.Leg.CarrierCode,
+				Name: booking.Leg.CarrierName,
+			})
+		}
+	}
+
+	tracking, err := s.repo.GetJobTracking(ctx, job.ID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		logger.Error("failed to get job tracking", slog.Any("error", err))
+		return booking, fmt.Errorf("operations: load booking tracking: %w", err)
+	}
+	booking.Leg.DepartureDate = timeFromPgtype(tracking.EtdDate)
+	booking.Leg.ArrivalDate = timeFromPgtype(tracking.EtaDate)
+
+	packages, err := s.repo.ListJobPackages(ctx, job.ID)
+	if err != nil {
+		logger.Error("failed to list job packages", slog.Any("error", err))
+		return booking, fmt.Errorf("operations: load booking packages: %w", err)
+	}
+	for _, pkg := range packages {
+		booking.Packages = append(booking.Packages, edifact.BookingPackage{
+			ContainerNo:   common.PgtypeTextToString(pkg.ContainerNo),
+			ContainerType: common.PgtypeTextToString(pkg.ContainerType),
+			ContainerSize: common.PgtypeTextToString(pkg.ContainerSize),
+			SealNo:        common.PgtypeTextToString(pkg.CarrierSealNo),
+			PackageType:   common.PgtypeTextToString(pkg.PackageType),
+			PackageCount:  decimalFromNumeric(pkg.NoOfPackages),
+			GrossWeightKg: decimalFromNumeric(pkg.GrossWeightKg),
+			VolumeM3:      decimalFromNumeric(pkg.Volume),
+			Description:   common.PgtypeTextToString(pkg.CommodityCargoDescription),
+			HSCode:        common.PgtypeTextToString(pkg.HsCode),
+		})
+	}
+
+	return booking, nil
+}
+
+func hasBookingParty(parties []edifact.BookingParty, role string) bool {
+	for _, p := range parties {
+		if p.Role == role {
+			return true
+		}
+	}
+	return false
+}
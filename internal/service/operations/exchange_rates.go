@@ -0,0 +1,427 @@
+package operations
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"frego-operations/internal/common"
+	sqlc "frego-operations/internal/db/sqlc"
+	"frego-operations/internal/decimal"
+	operationsdto "frego-operations/internal/dto/operations"
+	"frego-operations/internal/logging"
+)
+
+const (
+	// rateScale matches exchange_rate.rate numeric(18,8).
+	rateScale       = 8
+	rateDateLayout  = "2006-01-02"
+	maxRateSheetRow = 10000
+)
+
+var (
+	// ErrInvalidRateSheet indicates an uploaded rate sheet could not be imported.
+	ErrInvalidRateSheet = errors.New("operations: invalid rate sheet")
+	// ErrNoExchangeRate indicates no rate is in force for a currency pair on a date.
+	ErrNoExchangeRate = errors.New("operations: no exchange rate in force")
+)
+
+// RateSheetError lists every row problem found in a rate sheet. Nothing is imported when present.
+type RateSheetError struct {
+	Problems []string
+}
+
+func (e *RateSheetError) Error() string {
+	return fmt.Sprintf("operations: invalid rate sheet: %s", strings.Join(e.Problems, "; "))
+}
+
+func (e *RateSheetError) Unwrap() error {
+	return ErrInvalidRateSheet
+}
+
+// rateSheetColumns maps accepted header names to the canonical column.
+var rateSheetColumns = map[string]string{
+	"from": "from", "from_currency": "from", "base": "from", "base_currency": "from",
+	"to": "to", "to_currency": "to", "quote": "to", "quote_currency": "to",
+	"date": "date", "effective_date": "date",
+	"rate": "rate",
+}
+
+// ListExchangeRates returns rate sheet entries, newest first, optionally for one pair.
+func (s *Service) ListExchangeRates(ctx context.Context, from, to *string, limit int32) ([]operationsdto.ExchangeRate, error) {
+	logger := logging.FromContext(ctx)
+
+	rows, err := s.repo.ListExchangeRates(ctx, upperPtr(from), upperPtr(to), limit)
+	if err != nil {
+		logger.Error("failed to list exchange rates", slog.Any("error", err))
+		return nil, fmt.Errorf("operations: list exchange rates: %w", err)
+	}
+
+	rates := make([]operationsdto.ExchangeRate, 0, len(rows))
+	for _, row := range rows {
+		rates = append(rates, operationsdto.ExchangeRate{
+			ID:            row.ID,
+			FromCurrency:  row.FromCurrency,
+			ToCurrency:    row.ToCurrency,
+			EffectiveDate: row.EffectiveDate.Time,
+			Rate:          derefDecimal(decimalFromNumeric(row.Rate)),
+			Source:        common.PgtypeTextToStringPtr(row.Source),
+			CreatedAt:     timeFromTimestamptz(row.CreatedAt),
+			CreatedBy:     common.PgtypeTextToStringPtr(row.CreatedBy),
+		})
+	}
+	return rates, nil
+}
+
+// LookupExchangeRate returns the rate converting from into to that is in force on the given date.
+func (s *Service) LookupExchangeRate(ctx context.Context, from, to string, on time.Time) (operationsdto.ExchangeRateQuote, error) {
+	from, to = strings.ToUpper(strings.TrimSpace(from)), strings.ToUpper(strings.TrimSpace(to))
+	quote, ok, err := s.exchangeRate(ctx, from, to, on)
+	if err != nil {
+		return operationsdto.ExchangeRateQuote{}, err
+	}
+	if !ok {
+		return operationsdto.ExchangeRateQuote{}, fmt.Errorf("%w: %s to %s on %s", ErrNoExchangeRate, from, to, on.Format(rateDateLayout))
+	}
+	return quote, nil
+}
+
+// ImportExchangeRates reads a CSV rate sheet with from, to, date and rate columns and upserts
+// every row. The sheet is validated as a whole and imported in a single transaction.
+func (s *Service) ImportExchangeRates(ctx context.Context, sheet io.Reader, source, actor string) (operationsdto.ExchangeRateImport, error) {
+	logger := logging.FromContext(ctx)
+
+	params, err := parseRateSheet(sheet, source, actor)
+	if err != nil {
+		return operationsdto.ExchangeRateImport{}, err
+	}
+
+	if err := s.repo.ImportExchangeRates(ctx, params); err != nil {
+		logger.Error("failed to import exchange rates", slog.Any("error", err))
+		return operationsdto.ExchangeRateImport{}, fmt.Errorf("operations: import exchange rates: %w", err)
+	}
+
+	logger.Info("imported exchange rates", slog.String("source", source), slog.Int("rows", len(params)))
+	return operationsdto.ExchangeRateImport{Source: source, Imported: len(params)}, nil
+}
+
+// RefreshJobRates re-reads the rate in force for every unlocked billing and provision line of a
+// job and updates its exchange rate and primary-currency amount. Locked lines are left alone.
+func (s *Service) RefreshJobRates(ctx context.Context, jobID uuid.UUID, actor string) (operationsdto.JobRateUpdate, error) {
+	logger := logging.FromContext(ctx)
+	logger.Info("refreshing job exchange rates", slog.String("jobID", jobID.String()))
+
+	var updated int64
+	err := s.repo.InTx(ctx, func(ctx context.Context) error {
+		var err error
+		updated, err = s.refreshJobRates(ctx, jobID, actor)
+		return err
+	})
+	if err != nil {
+		return operationsdto.JobRateUpdate{}, err
+	}
+
+	logger.Info("refreshed job exchange rates", slog.String("jobID", jobID.String()), slog.Int64("lines", updated))
+	return operationsdto.JobRateUpdate{JobID: jobID, Lines: updated}, nil
+}
+
+// refreshJobRates reprices the job's unlocked lines inside the caller's transaction, under the
+// job's row lock.
+func (s *Service) refreshJobRates(ctx context.Context, jobID uuid.UUID, actor string) (int64, error) {
+	logger := logging.FromContext(ctx)
+
+	if _, err := s.ensureJobOpen(ctx, jobID); err != nil {
+		return 0, err
+	}
+	billing, err := s.repo.ListJobBilling(ctx, jobID)
+	if err != nil {
+		logger.Error("failed to list job billing", slog.Any("error", err))
+		return 0, fmt.Errorf("operations: refresh job rates: %w", err)
+	}
+	provisions, err := s.repo.ListJobProvisions(ctx, jobID)
+	if err != nil {
+		logger.Error("failed to list job provisions", slog.Any("error", err))
+		return 0, fmt.Errorf("operations: refresh job rates: %w", err)
+	}
+	primary, err := s.primaryCurrency(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var problems []string
+	reprice := func(label string, locked pgtype.Timestamptz, currency pgtype.Text, net, current pgtype.Numeric, on time.Time) (decimal.Decimal, decimal.Decimal, bool, error) {
+		if locked.Valid {
+			return decimal.Zero, decimal.Zero, false, nil
+		}
+		code := strings.ToUpper(strings.TrimSpace(common.PgtypeTextToString(currency)))
+		rate := decimalOne
+		if code != primary {
+			quote, ok, err := s.exchangeRate(ctx, code, primary, on)
+			if err != nil {
+				return decimal.Zero, decimal.Zero, false, err
+			}
+			if !ok {
+				problems = append(problems, fmt.Sprintf("%s: no exchange rate from %s to %s in force on %s", label, code, primary, on.Format(rateDateLayout)))
+				return decimal.Zero, decimal.Zero, false, nil
+			}
+			rate = quote.Rate
+		}
+		if existing := decimalFromNumeric(current); existing != nil && existing.Equal(rate) {
+			return decimal.Zero, decimal.Zero, false, nil
+		}
+		amount := derefDecimal(decimalFromNumeric(net)).Mul(rate).RoundCurrency(primary)
+		return rate, amount, true, nil
+	}
+
+	var billingUpdates []sqlc.UpdateJobBillingRateParams
+	for i, b := range billing {
+		rate, amount, changed, err := reprice(fmt.Sprintf("billing line %d", i+1), b.RateLockedAt, b.CurrencyCode,
+			b.AmountWithoutTax, b.ExchangeRate, rateDate(timeFromPgtype(b.PoDate)))
+		if err != nil {
+			return 0, err
+		}
+		if changed {
+			billingUpdates = append(billingUpdates, sqlc.UpdateJobBillingRateParams{
+				ID:                    b.ID,
+				ExchangeRate:          numericFromDecimal(&rate),
+				AmountPrimaryCurrency: numericFromDecimal(&amount),
+				Actor:                 pgtype.Text{String: actor, Valid: true},
+			})
+		}
+	}
+	var provisionUpdates []sqlc.UpdateJobProvisionRateParams
+	for i, p := range provisions {
+		rate, amount, changed, err := reprice(fmt.Sprintf("provision line %d", i+1), p.RateLockedAt, p.CurrencyCode,
+			p.AmountWithoutTax, p.ExchangeRate, rateDate(timeFromPgtype(p.InvoiceDate), timeFromPgtype(p.PoDate)))
+		if err != nil {
+			return 0, err
+		}
+		if changed {
+			provisionUpdates = append(provisionUpdates, sqlc.UpdateJobProvisionRateParams{
+				ID:                    p.ID,
+				ExchangeRate:          numericFromDecimal(&rate),
+				AmountPrimaryCurrency: numericFromDecimal(&amount),
+				Actor:                 pgtype.Text{String: actor, Valid: true},
+			})
+		}
+	}
+	if len(problems) > 0 {
+		return 0, &ChargeError{Problems: problems}
+	}
+
+	updated, err := s.repo.ApplyJobRates(ctx, billingUpdates, provisionUpdates)
+	if err != nil {
+		logger.Error("failed to apply job exchange rates", slog.Any("error", err))
+		return 0, fmt.Errorf("operations: refresh job rates: %w", err)
+	}
+	if updated > 0 {
+		if err := s.recalculateProfit(ctx, jobID, actor); err != nil {
+			return 0, err
+		}
+	}
+	return updated, nil
+}
+
+// LockJobRates freezes the exchange rate on every billing and provision line of a job once its
+// financials are final, so later rate sheet changes do not move it. Closing a job through
+// UpdateJob locks them in the same transaction.
+func (s *Service) LockJobRates(ctx context.Context, jobID uuid.UUID, actor string) (operationsdto.JobRateUpdate, error) {
+	logger := logging.FromContext(ctx)
+
+	if _, err := s.repo.GetJob(ctx, jobID); err != nil {
+		logger.Error("failed to get job", slog.Any("error", err))
+		return operationsdto.JobRateUpdate{}, fmt.Errorf("operations: lock job rates: %w", err)
+	}
+	locked, err := s.repo.LockJobRates(ctx, jobID, actor)
+	if err != nil {
+		logger.Error("failed to lock job exchange rates", slog.Any("error", err))
+		return operationsdto.JobRateUpdate{}, fmt.Errorf("operations: lock job rates: %w", err)
+	}
+
+	logger.Info("locked job exchange rates", slog.String("jobID", jobID.String()), slog.Int64("lines", locked))
+	return operationsdto.JobRateUpdate{JobID: jobID, Lines: locked}, nil
+}
+
+// exchangeRate finds the rate in force for from→to on a date. When only the opposite pair
+// is on file its inverse is used.
+func (s *Service) exchangeRate(ctx context.Context, from, to string, on time.Time) (operationsdto.ExchangeRateQuote, bool, error) {
+	quote := operationsdto.ExchangeRateQuote{FromCurrency: from, ToCurrency: to, OnDate: on}
+	if from == to {
+		quote.Rate, quote.EffectiveDate = decimalOne, on
+		return quote, true, nil
+	}
+
+	row, err := s.repo.GetExchangeRateInForce(ctx, from, to, on)
+	if err == nil {
+		quote.Rate, quote.EffectiveDate = derefDecimal(decimalFromNumeric(row.Rate)), row.EffectiveDate.Time
+		return quote, true, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		logging.FromContext(ctx).Error("failed to get exchange rate", slog.Any("error", err))
+		return quote, false, fmt.Errorf("operations: get exchange rate: %w", err)
+	}
+
+	row, err = s.repo.GetExchangeRateInForce(ctx, to, from, on)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return quote, false, nil
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to get exchange rate", slog.Any("error", err))
+		return quote, false, fmt.Errorf("operations: get exchange rate: %w", err)
+	}
+	inverse := decimalFromNumeric(row.Rate)
+	if inverse == nil || inverse.Sign() <= 0 {
+		return quote, false, nil
+	}
+	quote.Rate, quote.EffectiveDate, quote.Inverted = decimalOne.Div(*inverse, rateScale), row.EffectiveDate.Time, true
+	return quote, true, nil
+}
+
+func parseRateSheet(sheet io.Reader, source, actor string) ([]sqlc.UpsertExchangeRateParams, error) {
+	reader := csv.NewReader(sheet)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, &RateSheetError{Problems: []string{"rate sheet is empty"}}
+	}
+	if err != nil {
+		return nil, rateSheetReadError(err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if canonical, ok := rateSheetColumns[name]; ok {
+			columns[canonical] = i
+		}
+	}
+	var problems []string
+	for _, required := range []string{"from", "to", "date", "rate"} {
+		if _, ok := columns[required]; !ok {
+			problems = append(problems, fmt.Sprintf("header is missing the %s column", required))
+		}
+	}
+	if len(problems) > 0 {
+		return nil, &RateSheetError{Problems: problems}
+	}
+
+	var params []sqlc.UpsertExchangeRateParams
+	seen := map[string]int{}
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, rateSheetReadError(err)
+		}
+		if len(params) >= maxRateSheetRow {
+			problems = append(problems, fmt.Sprintf("rate sheet has more than %d rows", maxRateSheetRow))
+			break
+		}
+		field := func(name string) string {
+			if i := columns[name]; i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		if strings.Join(record, "") == "" {
+			continue
+		}
+
+		from, to := strings.ToUpper(field("from")), strings.ToUpper(field("to"))
+		if !isCurrencyCode(from) || !isCurrencyCode(to) || from == to {
+			problems = append(problems, fmt.Sprintf("line %d: invalid currency pair %q/%q", line, field("from"), field("to")))
+			continue
+		}
+		date, err := time.Parse(rateDateLayout, field("date"))
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("line %d: date %q is not YYYY-MM-DD", line, field("date")))
+			continue
+		}
+		rate, err := decimal.Parse(field("rate"))
+		if err != nil || rate.Sign() <= 0 {
+			problems = append(problems, fmt.Sprintf("line %d: rate %q is not a positive number", line, field("rate")))
+			continue
+		}
+		if rate.Scale() > rateScale {
+			rate = rate.Round(rateScale)
+		}
+		key := from + to + date.Format(rateDateLayout)
+		if first, dup := seen[key]; dup {
+			problems = append(problems, fmt.Sprintf("line %d: duplicates line %d", line, first))
+			continue
+		}
+		seen[key] = line
+
+		params = append(params, sqlc.UpsertExchangeRateParams{
+			FromCurrency:  from,
+			ToCurrency:    to,
+			EffectiveDate: pgtype.Date{Time: date, Valid: true},
+			Rate:          numericFromDecimal(&rate),
+			Source:        textFromString(common.TrimString(source)),
+			Actor:         pgtype.Text{String: actor, Valid: true},
+		})
+	}
+
+	if len(problems) > 0 {
+		return nil, &RateSheetError{Problems: problems}
+	}
+	if len(params) == 0 {
+		return nil, &RateSheetError{Problems: []string{"rate sheet has no rows"}}
+	}
+	return params, nil
+}
+
+// rateSheetReadError reports malformed CSV as a sheet problem and passes I/O failures through.
+func rateSheetReadError(err error) error {
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return &RateSheetError{Problems: []string{parseErr.Error()}}
+	}
+	return fmt.Errorf("operations: read rate sheet: %w", err)
+}
+
+// rateDate picks the first date set on a line (invoice, then PO), defaulting to today.
+func rateDate(dates ...*time.Time) time.Time {
+	for _, d := range dates {
+		if d != nil && !d.IsZero() {
+			return d.UTC()
+		}
+	}
+	return time.Now().UTC()
+}
+
+func isCurrencyCode(code string) bool {
+	return len(code) == 3 && isUpperAlpha(code)
+}
+
+func isUpperAlpha(s string) bool {
+	for _, r := range s {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+func upperPtr(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	v := strings.ToUpper(strings.TrimSpace(*s))
+	if v == "" {
+		return nil
+	}
+	return &v
+}
@@ -6,6 +6,9 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
+
+	"frego-operations/internal/common"
+	"frego-operations/internal/decimal"
 )
 
 // ============================================================
@@ -27,6 +30,13 @@ func textToStringPtr(t pgtype.Text) *string {
 	return &t.String
 }
 
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
 // UUID helpers
 func uuidToPgtype(u uuid.UUID) pgtype.UUID {
 	var bytes [16]byte
@@ -97,29 +107,23 @@ func timeFromTimestamptz(t pgtype.Timestamptz) *time.Time {
 }
 
 // Numeric helpers
-func numericFromFloat64(f *float64) pgtype.Numeric {
-	if f == nil {
-		return pgtype.Numeric{Valid: false}
-	}
-	var num pgtype.Numeric
-	if err := num.Scan(fmt.Sprintf("%f", *f)); err != nil {
-		return pgtype.Numeric{Valid: false}
-	}
-	return num
+func numericFromDecimal(d *decimal.Decimal) pgtype.Numeric {
+	return common.DecimalToNumeric(d)
+}
+
+func decimalFromNumeric(n pgtype.Numeric) *decimal.Decimal {
+	return common.NumericPtr(n)
 }
 
+// float64FromNumeric is for counts that end up as whole numbers; weights, volumes
+// and amounts stay decimal.
 func float64FromNumeric(n pgtype.Numeric) *float64 {
-	if !n.Valid {
+	d := decimalFromNumeric(n)
+	if d == nil {
 		return nil
 	}
-	f, err := n.Float64Value()
-	if err != nil {
-		return nil
-	}
-	if f.Valid {
-		return &f.Float64
-	}
-	return nil
+	f := d.Float64()
+	return &f
 }
 
 // Int helpers
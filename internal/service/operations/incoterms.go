@@ -0,0 +1,167 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	sqlc "frego-operations/internal/db/sqlc"
+	operationsdto "frego-operations/internal/dto/operations"
+	"frego-operations/internal/logging"
+)
+
+// Cost segments of the incoterm responsibility matrix, as constrained in activity_lu.cost_segment.
+const (
+	segmentOriginHandling      = "origin_handling"
+	segmentExportCustoms       = "export_customs"
+	segmentMainCarriage        = "main_carriage"
+	segmentInsurance           = "insurance"
+	segmentImportCustoms       = "import_customs"
+	segmentDestinationDelivery = "destination_delivery"
+)
+
+// Verdicts for a billing line checked against the incoterm matrix.
+const (
+	incotermLineOK           = "ok"
+	incotermLineMismatch     = "mismatch"
+	incotermLineSuggested    = "suggested"
+	incotermLineMissingParty = "missing_party"
+	incotermLineUnassigned   = "unassigned"
+)
+
+// ErrUnknownIncoterm indicates a job has no incoterm or one that is not in incoterm_lu.
+var ErrUnknownIncoterm = errors.New("operations: unknown incoterm")
+
+// ListIncoterms returns the incoterm catalogue with each term's responsibility matrix.
+func (s *Service) ListIncoterms(ctx context.Context) ([]operationsdto.IncotermLookup, error) {
+	rows, err := s.repo.ListIncotermLookups(ctx)
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to list incoterm lookups", slog.Any("error", err))
+		return nil, fmt.Errorf("operations: list incoterms: %w", err)
+	}
+	result := make([]operationsdto.IncotermLookup, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, operationsdto.IncotermLookup{
+			ID:               row.ID,
+			Code:             row.Code,
+			Name:             row.Name,
+			Version:          row.Version,
+			Responsibilities: incotermResponsibilities(sqlc.GetIncotermRow(row)),
+		})
+	}
+	return result, nil
+}
+
+// CheckJobBillingIncoterm compares each billing line's party with the shipper or consignee the
+// job's incoterm makes responsible for the line's cost segment.
+func (s *Service) CheckJobBillingIncoterm(ctx context.Context, jobID uuid.UUID) (operationsdto.IncotermBillingCheck, error) {
+	logger := logging.FromContext(ctx)
+	logger.Info("checking job billing against incoterm", slog.String("jobID", jobID.String()))
+
+	job, err := s.GetJob(ctx, jobID)
+	if err != nil {
+		return operationsdto.IncotermBillingCheck{}, err
+	}
+	bearers, err := s.incotermBearers(ctx, job.IncotermCode)
+	if err != nil {
+		return operationsdto.IncotermBillingCheck{}, err
+	}
+	segments, err := s.activitySegments(ctx)
+	if err != nil {
+		return operationsdto.IncotermBillingCheck{}, err
+	}
+
+	result := operationsdto.IncotermBillingCheck{
+		JobID:    jobID,
+		Incoterm: strings.ToUpper(*job.IncotermCode),
+		Lines:    make([]operationsdto.IncotermBillingLine, 0, len(job.Billing)),
+	}
+	party, err := s.repo.GetJobParty(ctx, jobID)
+	switch {
+	case err == nil:
+		result.ShipperID = uuidFromPgtype(party.ShipperID)
+		result.ConsigneeID = uuidFromPgtype(party.ConsigneeID)
+	case !errors.Is(err, pgx.ErrNoRows):
+		logger.Error("failed to get job party", slog.Any("error", err))
+		return operationsdto.IncotermBillingCheck{}, fmt.Errorf("operations: check billing incoterm: %w", err)
+	}
+	parties := map[string]*uuid.UUID{"shipper": result.ShipperID, "consignee": result.ConsigneeID}
+
+	for _, bill := range job.Billing {
+		line := operationsdto.IncotermBillingLine{
+			BillingID:      bill.ID,
+			ActivityType:   bill.ActivityType,
+			ActivityCode:   bill.ActivityCode,
+			BillingPartyID: bill.BillingPartyID,
+			Status:         incotermLineUnassigned,
+		}
+		if bill.ActivityType != nil && bill.ActivityCode != nil {
+			if segment, ok := segments[[2]string{*bill.ActivityType, *bill.ActivityCode}]; ok {
+				bearer := bearers[segment]
+				line.CostSegment = &segment
+				line.Bearer = &bearer
+				line.ExpectedPartyID = parties[bearer]
+				switch {
+				case line.ExpectedPartyID == nil:
+					line.Status = incotermLineMissingParty
+				case bill.BillingPartyID == nil:
+					line.Status = incotermLineSuggested
+				case *bill.BillingPartyID == *line.ExpectedPartyID:
+					line.Status = incotermLineOK
+				default:
+					line.Status = incotermLineMismatch
+				}
+			}
+		}
+		result.Lines = append(result.Lines, line)
+	}
+	return result, nil
+}
+
+// incotermBearers returns the cost segment to bearer map of an incoterm.
+func (s *Service) incotermBearers(ctx context.Context, code *string) (map[string]string, error) {
+	if code == nil || strings.TrimSpace(*code) == "" {
+		return nil, fmt.Errorf("%w: job has no incoterm", ErrUnknownIncoterm)
+	}
+	row, err := s.repo.GetIncoterm(ctx, strings.TrimSpace(*code))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownIncoterm, *code)
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to get incoterm", slog.Any("error", err))
+		return nil, fmt.Errorf("operations: get incoterm: %w", err)
+	}
+	return incotermResponsibilities(row), nil
+}
+
+// activitySegments maps activity type/code pairs to their cost segment, skipping unassigned ones.
+func (s *Service) activitySegments(ctx context.Context) (map[[2]string]string, error) {
+	rows, err := s.repo.ListActivityLookups(ctx)
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to list activities", slog.Any("error", err))
+		return nil, fmt.Errorf("operations: list activities: %w", err)
+	}
+	segments := make(map[[2]string]string, len(rows))
+	for _, row := range rows {
+		if row.CostSegment.Valid {
+			segments[[2]string{row.ActivityType, row.ActivityCode}] = row.CostSegment.String
+		}
+	}
+	return segments, nil
+}
+
+func incotermResponsibilities(row sqlc.GetIncotermRow) map[string]string {
+	return map[string]string{
+		segmentOriginHandling:      row.OriginHandling,
+		segmentExportCustoms:       row.ExportCustoms,
+		segmentMainCarriage:        row.MainCarriage,
+		segmentInsurance:           row.Insurance,
+		segmentImportCustoms:       row.ImportCustoms,
+		segmentDestinationDelivery: row.DestinationDelivery,
+	}
+}
@@ -0,0 +1,444 @@
+package operations
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	sqlc "frego-operations/internal/db/sqlc"
+	"frego-operations/internal/decimal"
+	"frego-operations/internal/docrender"
+	operationsdto "frego-operations/internal/dto/operations"
+	"frego-operations/internal/logging"
+	repository "frego-operations/internal/repository/operations"
+)
+
+const (
+	invoiceStatusDraft       = "Draft"
+	invoiceAggregateType     = "invoice"
+	eventInvoiceDraftCreated = "invoice.draft_created"
+	invoiceDocTypeLabel      = "Customer Invoice"
+)
+
+var (
+	// ErrNothingToInvoice indicates a job has no billing line that can be invoiced.
+	ErrNothingToInvoice = errors.New("operations: nothing to invoice")
+	// ErrAlreadyInvoiced indicates billing lines were invoiced by a concurrent request.
+	ErrAlreadyInvoiced = errors.New("operations: billing already invoiced")
+)
+
+// invoiceMessage is the JSON form of an invoice handed to the finance module, both as the
+// payload of outbox events and as the JSON export.
+type invoiceMessage struct {
+	ID               uuid.UUID            `json:"id"`
+	InvoiceNumber    string               `json:"invoiceNumber"`
+	Status           string               `json:"status"`
+	JobID            uuid.UUID            `json:"jobId"`
+	JobCode          string               `json:"jobCode"`
+	BillingPartyID   *uuid.UUID           `json:"billingPartyId,omitempty"`
+	BillingPartyName *string              `json:"billingPartyName,omitempty"`
+	Currency         string               `json:"currency"`
+	Subtotal         decimal.Decimal      `json:"subtotal"`
+	TaxTotal         decimal.Decimal      `json:"taxTotal"`
+	Total            decimal.Decimal      `json:"total"`
+	Lines            []invoiceMessageLine `json:"lines"`
+	Taxes            []invoiceMessageTax  `json:"taxes"`
+	CreatedAt        *time.Time           `json:"createdAt,omitempty"`
+	CreatedBy        *string              `json:"createdBy,omitempty"`
+}
+
+type invoiceMessageLine struct {
+	BillingID        uuid.UUID        `json:"billingId"`
+	ActivityType     *string          `json:"activityType,omitempty"`
+	ActivityCode     *string          `json:"activityCode,omitempty"`
+	Description      *string          `json:"description,omitempty"`
+	Quantity         *decimal.Decimal `json:"quantity,omitempty"`
+	UnitPrice        *decimal.Decimal `json:"unitPrice,omitempty"`
+	TaxCode          *string          `json:"taxCode,omitempty"`
+	AmountWithoutTax decimal.Decimal  `json:"amountWithoutTax"`
+	TaxAmount        decimal.Decimal  `json:"taxAmount"`
+	TotalAmount      decimal.Decimal  `json:"totalAmount"`
+}
+
+type invoiceMessageTax struct {
+	TaxCode     string           `json:"taxCode"`
+	RatePercent *decimal.Decimal `json:"ratePercent,omitempty"`
+	Taxable     decimal.Decimal  `json:"taxable"`
+	Tax         decimal.Decimal  `json:"tax"`
+}
+
+type invoiceKey struct {
+	partyID  uuid.UUID
+	currency string
+}
+
+// CreateDraftInvoices groups the job's uninvoiced billing lines by billing party and currency
+// and drafts one invoice per group. The lines are marked invoiced and an invoice.draft_created
+// event is queued for each draft in the same transaction.
+func (s *Service) CreateDraftInvoices(ctx context.Context, jobID uuid.UUID, actor string) (operationsdto.DraftInvoices, error) {
+	logger := logging.FromContext(ctx)
+	logger.Info("drafting job invoices", slog.String("jobID", jobID.String()))
+
+	job, err := s.repo.GetJob(ctx, jobID)
+	if err != nil {
+		logger.Error("failed to get job", slog.Any("error", err))
+		return operationsdto.DraftInvoices{}, fmt.Errorf("operations: draft invoices: %w", err)
+	}
+	bills, err := s.repo.ListUninvoicedJobBilling(ctx, jobID)
+	if err != nil {
+		logger.Error("failed to list uninvoiced billing", slog.Any("error", err))
+		return operationsdto.DraftInvoices{}, fmt.Errorf("operations: draft invoices: %w", err)
+	}
+
+	var (
+		result = operationsdto.DraftInvoices{}
+		keys   []invoiceKey
+		groups = map[invoiceKey][]sqlc.OpsBilling{}
+	)
+	for _, bill := range bills {
+		currency := strings.ToUpper(strings.TrimSpace(bill.CurrencyCode.String))
+		if !bill.BillingPartyID.Valid || currency == "" {
+			result.Skipped = append(result.Skipped, bill.ID)
+			continue
+		}
+		key := invoiceKey{partyID: uuid.UUID(bill.BillingPartyID.Bytes), currency: currency}
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], bill)
+	}
+	if len(keys) == 0 {
+		return result, fmt.Errorf("%w: job %s has no uninvoiced billing line with a party and currency", ErrNothingToInvoice, job.JobCode)
+	}
+
+	rates := &chargePricer{s: s, taxRates: map[string]decimal.Decimal{}}
+	drafts := make([]repository.InvoiceDraft, 0, len(keys))
+	result.Invoices = make([]operationsdto.Invoice, 0, len(keys))
+	for _, key := range keys {
+		partyID := key.partyID
+		invoice := operationsdto.Invoice{
+			JobID:          jobID,
+			JobCode:        job.JobCode,
+			BillingPartyID: &partyID,
+			CurrencyCode:   key.currency,
+			Status:         invoiceStatusDraft,
+			CreatedBy:      &actor,
+		}
+		if invoice.Lines, invoice.Taxes, err = invoiceLines(ctx, rates, groups[key]); err != nil {
+			return operationsdto.DraftInvoices{}, err
+		}
+		for _, line := range invoice.Lines {
+			invoice.Subtotal = invoice.Subtotal.Add(line.AmountWithoutTax)
+			invoice.TaxTotal = invoice.TaxTotal.Add(line.TaxAmount)
+		}
+		invoice.Subtotal = invoice.Subtotal.RoundCurrency(key.currency)
+		invoice.TaxTotal = invoice.TaxTotal.RoundCurrency(key.currency)
+		invoice.Total = invoice.Subtotal.Add(invoice.TaxTotal)
+
+		billingIDs := make([]uuid.UUID, 0, len(invoice.Lines))
+		for _, line := range invoice.Lines {
+			billingIDs = append(billingIDs, line.BillingID)
+		}
+		message := invoiceMessageFrom(invoice)
+		drafts = append(drafts, repository.InvoiceDraft{
+			Invoice: sqlc.CreateInvoiceParams{
+				JobID:          jobID,
+				BillingPartyID: repository.NullUUIDFromUUID(&partyID),
+				CurrencyCode:   key.currency,
+				Status:         invoiceStatusDraft,
+				Subtotal:       numericFromDecimal(&invoice.Subtotal),
+				TaxTotal:       numericFromDecimal(&invoice.TaxTotal),
+				Total:          numericFromDecimal(&invoice.Total),
+				Actor:          pgtype.Text{String: actor, Valid: true},
+			},
+			BillingIDs: billingIDs,
+			Event: func(stored sqlc.OpsInvoice) (sqlc.CreateOutboxEventParams, error) {
+				message.ID = stored.ID
+				message.InvoiceNumber = stored.InvoiceNumber
+				message.CreatedAt = timeFromTimestamptz(stored.CreatedAt)
+				payload, err := json.Marshal(message)
+				if err != nil {
+					return sqlc.CreateOutboxEventParams{}, fmt.Errorf("operations: encode invoice event: %w", err)
+				}
+				return sqlc.CreateOutboxEventParams{
+					EventType:     eventInvoiceDraftCreated,
+					AggregateType: invoiceAggregateType,
+					AggregateID:   stored.ID,
+					Payload:       payload,
+					Actor:         pgtype.Text{String: actor, Valid: true},
+				}, nil
+			},
+		})
+		result.Invoices = append(result.Invoices, invoice)
+	}
+
+	prefix := fmt.Sprintf("INV-%d-", time.Now().UTC().Year())
+	stored, err := s.repo.CreateDraftInvoices(ctx, prefix, drafts)
+	if errors.Is(err, repository.ErrBillingAlreadyInvoiced) {
+		return operationsdto.DraftInvoices{}, fmt.Errorf("%w: retry to invoice the remaining lines", ErrAlreadyInvoiced)
+	}
+	if err != nil {
+		logger.Error("failed to create draft invoices", slog.Any("error", err))
+		return operationsdto.DraftInvoices{}, fmt.Errorf("operations: draft invoices: %w", err)
+	}
+	for i, row := range stored {
+		result.Invoices[i].ID = row.ID
+		result.Invoices[i].InvoiceNumber = row.InvoiceNumber
+		result.Invoices[i].CreatedAt = timeFromTimestamptz(row.CreatedAt)
+	}
+
+	logger.Info("drafted job invoices",
+		slog.String("jobCode", job.JobCode),
+		slog.Int("invoices", len(stored)),
+		slog.Int("skippedLines", len(result.Skipped)),
+	)
+	return result, nil
+}
+
+// ListJobInvoices returns the job's invoices with their lines and tax summaries.
+func (s *Service) ListJobInvoices(ctx context.Context, jobID uuid.UUID) ([]operationsdto.Invoice, error) {
+	if err := s.ensureJobVisible(ctx, jobID); err != nil {
+		return nil, err
+	}
+	invoices, bills, err := s.repo.ListJobInvoices(ctx, jobID)
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to list job invoices", slog.Any("error", err))
+		return nil, fmt.Errorf("operations: list invoices: %w", err)
+	}
+
+	byInvoice := make(map[uuid.UUID][]sqlc.OpsBilling, len(invoices))
+	for _, bill := range bills {
+		id := uuid.UUID(bill.InvoiceID.Bytes)
+		byInvoice[id] = append(byInvoice[id], bill)
+	}
+
+	rates := &chargePricer{s: s, taxRates: map[string]decimal.Decimal{}}
+	result := make([]operationsdto.Invoice, 0, len(invoices))
+	for _, row := range invoices {
+		invoice, err := invoiceFromSqlc(ctx, rates, sqlc.GetInvoiceRow(row), byInvoice[row.ID])
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, invoice)
+	}
+	return result, nil
+}
+
+// GetInvoice returns one invoice with its lines and tax summary.
+func (s *Service) GetInvoice(ctx context.Context, invoiceID uuid.UUID) (operationsdto.Invoice, error) {
+	row, bills, err := s.repo.GetInvoice(ctx, invoiceID)
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to get invoice", slog.Any("error", err))
+		return operationsdto.Invoice{}, fmt.Errorf("operations: get invoice: %w", err)
+	}
+	if err := s.ensureJobVisible(ctx, row.JobID); err != nil {
+		return operationsdto.Invoice{}, err
+	}
+	return invoiceFromSqlc(ctx, &chargePricer{s: s, taxRates: map[string]decimal.Decimal{}}, row, bills)
+}
+
+// ExportInvoiceJSON returns the invoice in the JSON form shared with the finance module.
+func (s *Service) ExportInvoiceJSON(ctx context.Context, invoiceID uuid.UUID) (operationsdto.ExportFile, error) {
+	invoice, err := s.GetInvoice(ctx, invoiceID)
+	if err != nil {
+		return operationsdto.ExportFile{}, err
+	}
+	data, err := json.MarshalIndent(invoiceMessageFrom(invoice), "", "  ")
+	if err != nil {
+		return operationsdto.ExportFile{}, fmt.Errorf("operations: encode invoice: %w", err)
+	}
+	return operationsdto.ExportFile{
+		FileName:    invoice.InvoiceNumber + ".json",
+		ContentType: "application/json",
+		Data:        data,
+	}, nil
+}
+
+// RenderInvoicePDF prints the invoice with the tenant's newest INV template or the built-in one.
+func (s *Service) RenderInvoicePDF(ctx context.Context, invoiceID uuid.UUID) (operationsdto.ExportFile, error) {
+	logger := logging.FromContext(ctx)
+
+	invoice, err := s.GetInvoice(ctx, invoiceID)
+	if err != nil {
+		return operationsdto.ExportFile{}, err
+	}
+	tpl, err := s.activeTemplate(ctx, docrender.DocInvoice)
+	if err != nil {
+		return operationsdto.ExportFile{}, err
+	}
+	job, err := s.repo.GetJob(ctx, invoice.JobID)
+	if err != nil {
+		logger.Error("failed to get job", slog.Any("error", err))
+		return operationsdto.ExportFile{}, fmt.Errorf("operations: render invoice: %w", err)
+	}
+	data, err := s.loadDocumentData(ctx, job)
+	if err != nil {
+		return operationsdto.ExportFile{}, err
+	}
+	_, data.TenantName = tenantFromContext(ctx)
+	data.DocNumber = invoice.InvoiceNumber
+	data.DocTypeCode = docrender.DocInvoice
+	data.DocTypeLabel = invoiceDocTypeLabel
+	data.IssuedAt = time.Now().UTC()
+	if invoice.CreatedAt != nil {
+		data.IssuedAt = *invoice.CreatedAt
+	}
+	data.Invoice = invoiceView(invoice)
+
+	rendered, err := docrender.Render(tpl, data)
+	if err != nil {
+		logger.Error("failed to render invoice", slog.Any("error", err))
+		return operationsdto.ExportFile{}, fmt.Errorf("operations: render invoice: %w", err)
+	}
+	return operationsdto.ExportFile{
+		FileName:    invoice.InvoiceNumber + ".pdf",
+		ContentType: "application/pdf",
+		Data:        rendered.PDF,
+	}, nil
+}
+
+// invoiceLines converts billing lines to invoice lines and totals them per tax code,
+// in order of first appearance.
+func invoiceLines(ctx context.Context, rates *chargePricer, bills []sqlc.OpsBilling) ([]operationsdto.InvoiceLine, []operationsdto.InvoiceTaxSummary, error) {
+	lines := make([]operationsdto.InvoiceLine, 0, len(bills))
+	var taxes []operationsdto.InvoiceTaxSummary
+	taxIndex := map[string]int{}
+	for _, bill := range bills {
+		line := operationsdto.InvoiceLine{
+			BillingID:        bill.ID,
+			ActivityType:     textToStringPtr(bill.ActivityType),
+			ActivityCode:     textToStringPtr(bill.ActivityCode),
+			Description:      textToStringPtr(bill.Description),
+			Quantity:         decimalFromNumeric(bill.Quantity),
+			UnitPrice:        decimalFromNumeric(bill.UnitPrice),
+			TaxCode:          textToStringPtr(bill.TaxCode),
+			AmountWithoutTax: numericOrZero(bill.AmountWithoutTax),
+			TaxAmount:        numericOrZero(bill.TaxAmount),
+		}
+		line.TotalAmount = line.AmountWithoutTax.Add(line.TaxAmount)
+		if total := decimalFromNumeric(bill.TotalAmount); total != nil {
+			line.TotalAmount = *total
+		}
+		lines = append(lines, line)
+
+		if line.TaxCode == nil || strings.TrimSpace(*line.TaxCode) == "" {
+			continue
+		}
+		code := strings.TrimSpace(*line.TaxCode)
+		i, ok := taxIndex[code]
+		if !ok {
+			summary := operationsdto.InvoiceTaxSummary{TaxCode: code}
+			rate, found, err := rates.taxRate(ctx, code)
+			if err != nil {
+				return nil, nil, err
+			}
+			if found {
+				summary.RatePercent = &rate
+			}
+			i = len(taxes)
+			taxIndex[code] = i
+			taxes = append(taxes, summary)
+		}
+		taxes[i].Taxable = taxes[i].Taxable.Add(line.AmountWithoutTax)
+		taxes[i].Tax = taxes[i].Tax.Add(line.TaxAmount)
+	}
+	return lines, taxes, nil
+}
+
+func invoiceFromSqlc(ctx context.Context, rates *chargePricer, row sqlc.GetInvoiceRow, bills []sqlc.OpsBilling) (operationsdto.Invoice, error) {
+	invoice := operationsdto.Invoice{
+		ID:               row.ID,
+		InvoiceNumber:    row.InvoiceNumber,
+		JobID:            row.JobID,
+		JobCode:          row.JobCode,
+		BillingPartyID:   uuidFromPgtype(row.BillingPartyID),
+		BillingPartyName: textToStringPtr(row.BillingPartyName),
+		CurrencyCode:     row.CurrencyCode,
+		Status:           row.Status,
+		Subtotal:         numericOrZero(row.Subtotal),
+		TaxTotal:         numericOrZero(row.TaxTotal),
+		Total:            numericOrZero(row.Total),
+		CreatedAt:        timeFromTimestamptz(row.CreatedAt),
+		CreatedBy:        textToStringPtr(row.CreatedBy),
+	}
+	var err error
+	invoice.Lines, invoice.Taxes, err = invoiceLines(ctx, rates, bills)
+	return invoice, err
+}
+
+func invoiceMessageFrom(invoice operationsdto.Invoice) invoiceMessage {
+	message := invoiceMessage{
+		ID:               invoice.ID,
+		InvoiceNumber:    invoice.InvoiceNumber,
+		Status:           invoice.Status,
+		JobID:            invoice.JobID,
+		JobCode:          invoice.JobCode,
+		BillingPartyID:   invoice.BillingPartyID,
+		BillingPartyName: invoice.BillingPartyName,
+		Currency:         invoice.CurrencyCode,
+		Subtotal:         invoice.Subtotal,
+		TaxTotal:         invoice.TaxTotal,
+		Total:            invoice.Total,
+		Lines:            make([]invoiceMessageLine, 0, len(invoice.Lines)),
+		Taxes:            make([]invoiceMessageTax, 0, len(invoice.Taxes)),
+		CreatedAt:        invoice.CreatedAt,
+		CreatedBy:        invoice.CreatedBy,
+	}
+	for _, line := range invoice.Lines {
+		message.Lines = append(message.Lines, invoiceMessageLine(line))
+	}
+	for _, tax := range invoice.Taxes {
+		message.Taxes = append(message.Taxes, invoiceMessageTax(tax))
+	}
+	return message
+}
+
+// invoiceView formats an invoice for templates, amounts to the currency's minor units.
+func invoiceView(invoice operationsdto.Invoice) docrender.Invoice {
+	places := decimal.MinorUnits(invoice.CurrencyCode)
+	money := func(d decimal.Decimal) string { return d.RoundCurrency(invoice.CurrencyCode).StringFixed(places) }
+	optional := func(d *decimal.Decimal) string {
+		if d == nil {
+			return ""
+		}
+		return d.String()
+	}
+
+	view := docrender.Invoice{
+		Number:   invoice.InvoiceNumber,
+		Status:   invoice.Status,
+		Currency: invoice.CurrencyCode,
+		BillTo:   docrender.Party{Name: derefString(invoice.BillingPartyName)},
+		Subtotal: money(invoice.Subtotal),
+		TaxTotal: money(invoice.TaxTotal),
+		Total:    money(invoice.Total),
+	}
+	for _, line := range invoice.Lines {
+		view.Lines = append(view.Lines, docrender.InvoiceLine{
+			Description: derefString(line.Description),
+			Activity:    joinNonEmpty(derefString(line.ActivityType), derefString(line.ActivityCode)),
+			Quantity:    optional(line.Quantity),
+			UnitPrice:   optional(line.UnitPrice),
+			TaxCode:     derefString(line.TaxCode),
+			Amount:      money(line.AmountWithoutTax),
+			TaxAmount:   money(line.TaxAmount),
+			Total:       money(line.TotalAmount),
+		})
+	}
+	for _, tax := range invoice.Taxes {
+		view.Taxes = append(view.Taxes, docrender.TaxSummary{
+			TaxCode: tax.TaxCode,
+			Rate:    optional(tax.RatePercent),
+			Taxable: money(tax.Taxable),
+			Tax:     money(tax.Tax),
+		})
+	}
+	return view
+}
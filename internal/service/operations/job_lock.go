@@ -0,0 +1,183 @@
+package operations
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"frego-operations/internal/common"
+	sqlc "frego-operations/internal/db/sqlc"
+	operationsdto "frego-operations/internal/dto/operations"
+	"frego-operations/internal/logging"
+	repository "frego-operations/internal/repository/operations"
+)
+
+// JobReopenRole is the role a principal needs to reopen a closed job.
+const JobReopenRole = "ops-supervisor"
+
+const (
+	jobStatusClosed = "Closed"
+
+	jobAggregateType = "job"
+	eventJobReopened = "job.reopened"
+)
+
+var (
+	// ErrJobClosed indicates a change to a closed job, which is read-only until reopened.
+	ErrJobClosed = errors.New("operations: job is closed")
+	// ErrJobNotClosed indicates a reopen of a job that is not closed.
+	ErrJobNotClosed = errors.New("operations: job is not closed")
+	// ErrReopenForbidden indicates the caller lacks the role to reopen a job.
+	ErrReopenForbidden = errors.New("operations: reopen forbidden")
+	// ErrInvalidReopen indicates a reopen request without a reason.
+	ErrInvalidReopen = errors.New("operations: invalid reopen")
+)
+
+// jobReopenedMessage is the payload of the job.reopened event. Finance reverses anything posted
+// for the job when it sees one.
+type jobReopenedMessage struct {
+	JobID      uuid.UUID  `json:"jobId"`
+	JobCode    string     `json:"jobCode"`
+	FromStatus string     `json:"fromStatus"`
+	ToStatus   string     `json:"toStatus"`
+	Reason     string     `json:"reason"`
+	ReopenedBy string     `json:"reopenedBy"`
+	ReopenedAt *time.Time `json:"reopenedAt,omitempty"`
+}
+
+// ensureJobOpen returns the job, or ErrJobClosed when its status is Closed. It locks the job row,
+// so callers run it inside repo.InTx together with their writes: a concurrent close then waits
+// for them, or they see it. Finance work on closed jobs (rate locking, invoicing, supplier
+// invoice reconciliation and approval decisions) does not go through it.
+func (s *Service) ensureJobOpen(ctx context.Context, jobID uuid.UUID) (sqlc.GetJobRow, error) {
+	job, err := s.repo.GetJob(ctx, jobID)
+	if err != nil {
+		return sqlc.GetJobRow{}, fmt.Errorf("operations: get job: %w", err)
+	}
+	status, err := s.repo.LockJob(ctx, jobID)
+	if err != nil {
+		return sqlc.GetJobRow{}, fmt.Errorf("operations: lock job: %w", err)
+	}
+	if status == jobStatusClosed {
+		return sqlc.GetJobRow{}, fmt.Errorf("%w: %s must be reopened before it can be changed", ErrJobClosed, job.JobCode)
+	}
+	job.Status = pgtype.Text{String: status, Valid: status != ""}
+	return job, nil
+}
+
+// jobWriteError maps the repository's closed-job guard to ErrJobClosed.
+func jobWriteError(op string, err error) error {
+	if errors.Is(err, repository.ErrJobClosed) {
+		return fmt.Errorf("%w: it must be reopened before it can be changed", ErrJobClosed)
+	}
+	return fmt.Errorf("operations: %s: %w", op, err)
+}
+
+// recordJobStatusChange writes a status transition made through UpdateJob to the job's history,
+// in the update's transaction.
+func (s *Service) recordJobStatusChange(ctx context.Context, jobID uuid.UUID, from pgtype.Text, to *string, actor string) error {
+	if to == nil || *to == from.String {
+		return nil
+	}
+	_, err := s.repo.CreateJobStatusHistory(ctx, sqlc.CreateJobStatusHistoryParams{
+		JobID:      jobID,
+		FromStatus: from,
+		ToStatus:   pgtype.Text{String: *to, Valid: true},
+		Actor:      pgtype.Text{String: actor, Valid: true},
+	})
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to record job status change", slog.Any("error", err))
+		return fmt.Errorf("operations: record job status change: %w", err)
+	}
+	return nil
+}
+
+// ReopenJob moves a closed job back to Active so it can be changed again. The caller needs
+// JobReopenRole and a reason; the transition is kept in the job's status history and announced
+// with a job.reopened event.
+func (s *Service) ReopenJob(ctx context.Context, jobID uuid.UUID, reason, actor string) (operationsdto.JobStatusChange, error) {
+	logger := logging.FromContext(ctx)
+	logger.Info("reopening job", slog.String("jobID", jobID.String()))
+
+	principal, _ := common.PrincipalFromContext(ctx)
+	if !principal.HasRole(JobReopenRole) {
+		return operationsdto.JobStatusChange{}, fmt.Errorf("%w: role %q is required", ErrReopenForbidden, JobReopenRole)
+	}
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return operationsdto.JobStatusChange{}, fmt.Errorf("%w: a reason is required", ErrInvalidReopen)
+	}
+
+	job, err := s.repo.GetJob(ctx, jobID)
+	if err != nil {
+		return operationsdto.JobStatusChange{}, fmt.Errorf("operations: reopen job: %w", err)
+	}
+
+	history, err := s.repo.ReopenJob(ctx, jobID, reason, actor, func(history sqlc.OpsJobStatusHistory) (sqlc.CreateOutboxEventParams, error) {
+		payload, err := json.Marshal(jobReopenedMessage{
+			JobID:      jobID,
+			JobCode:    job.JobCode,
+			FromStatus: history.FromStatus.String,
+			ToStatus:   history.ToStatus.String,
+			Reason:     reason,
+			ReopenedBy: actor,
+			ReopenedAt: timeFromTimestamptz(history.ChangedAt),
+		})
+		if err != nil {
+			return sqlc.CreateOutboxEventParams{}, fmt.Errorf("operations: encode job event: %w", err)
+		}
+		return sqlc.CreateOutboxEventParams{
+			EventType:     eventJobReopened,
+			AggregateType: jobAggregateType,
+			AggregateID:   jobID,
+			Payload:       payload,
+			Actor:         pgtype.Text{String: actor, Valid: true},
+		}, nil
+	})
+	if errors.Is(err, repository.ErrJobNotClosed) {
+		return operationsdto.JobStatusChange{}, fmt.Errorf("%w: %s", ErrJobNotClosed, job.JobCode)
+	}
+	if err != nil {
+		logger.Error("failed to reopen job", slog.Any("error", err))
+		return operationsdto.JobStatusChange{}, fmt.Errorf("operations: reopen job: %w", err)
+	}
+
+	logger.Info("reopened job", slog.String("jobCode", job.JobCode), slog.String("reason", reason))
+	return jobStatusChangeFromSqlc(history), nil
+}
+
+// ListJobStatusHistory returns the job's status transitions, oldest first.
+func (s *Service) ListJobStatusHistory(ctx context.Context, jobID uuid.UUID) ([]operationsdto.JobStatusChange, error) {
+	if err := s.ensureJobVisible(ctx, jobID); err != nil {
+		return nil, err
+	}
+	rows, err := s.repo.ListJobStatusHistory(ctx, jobID)
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to list job status history", slog.Any("error", err))
+		return nil, fmt.Errorf("operations: list job status history: %w", err)
+	}
+	result := make([]operationsdto.JobStatusChange, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, jobStatusChangeFromSqlc(row))
+	}
+	return result, nil
+}
+
+func jobStatusChangeFromSqlc(row sqlc.OpsJobStatusHistory) operationsdto.JobStatusChange {
+	return operationsdto.JobStatusChange{
+		ID:         row.ID,
+		JobID:      row.JobID,
+		FromStatus: textToStringPtr(row.FromStatus),
+		ToStatus:   textToStringPtr(row.ToStatus),
+		Reason:     textToStringPtr(row.Reason),
+		ChangedAt:  timeFromTimestamptz(row.ChangedAt),
+		ChangedBy:  textToStringPtr(row.ChangedBy),
+	}
+}
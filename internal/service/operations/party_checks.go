@@ -0,0 +1,325 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"frego-operations/internal/common"
+	sqlc "frego-operations/internal/db/sqlc"
+	"frego-operations/internal/decimal"
+	operationsdto "frego-operations/internal/dto/operations"
+	"frego-operations/internal/logging"
+)
+
+// CreditOverrideRole lets a principal create or activate a job past the customer's credit limit.
+const CreditOverrideRole = "ops-credit-override"
+
+const jobStatusActive = "Active"
+
+var (
+	// ErrIneligibleParty indicates a job names a blacklisted or inactive party.
+	ErrIneligibleParty = errors.New("operations: ineligible party")
+	// ErrCreditLimitExceeded indicates a job would take the customer past their credit limit.
+	ErrCreditLimitExceeded = errors.New("operations: credit limit exceeded")
+	// ErrInvalidCreditLimit indicates a credit limit failed validation.
+	ErrInvalidCreditLimit = errors.New("operations: invalid credit limit")
+)
+
+// PartyEligibilityError lists every blacklisted or inactive party on a job.
+type PartyEligibilityError struct {
+	Problems []string
+}
+
+func (e *PartyEligibilityError) Error() string {
+	return fmt.Sprintf("operations: ineligible party: %s", strings.Join(e.Problems, "; "))
+}
+
+func (e *PartyEligibilityError) Unwrap() error {
+	return ErrIneligibleParty
+}
+
+// CreditLimitError reports the customer's exposure, including the job, against their limit.
+type CreditLimitError struct {
+	PartyID     uuid.UUID
+	Currency    string
+	CreditLimit decimal.Decimal
+	Exposure    decimal.Decimal
+}
+
+func (e *CreditLimitError) Error() string {
+	return fmt.Sprintf("operations: credit limit exceeded: exposure %s %s is over the limit of %s %s",
+		e.Exposure.String(), e.Currency, e.CreditLimit.String(), e.Currency)
+}
+
+func (e *CreditLimitError) Unwrap() error {
+	return ErrCreditLimitExceeded
+}
+
+// jobParty is a party a job refers to, with the role it plays on the job.
+type jobParty struct {
+	role string
+	id   uuid.UUID
+}
+
+// partyStatus is a referenced party's master data status.
+type partyStatus struct {
+	role   string
+	name   string
+	status string
+}
+
+// jobInputParties collects the parties named in a job create or update request.
+func jobInputParties(customerID, agentID *uuid.UUID, carrier *operationsdto.CarrierInput, billing []operationsdto.BillingInput, provisions []operationsdto.ProvisionInput) []jobParty {
+	var parties []jobParty
+	add := func(role string, id *uuid.UUID) {
+		if id != nil {
+			parties = append(parties, jobParty{role: role, id: *id})
+		}
+	}
+	add("customer", customerID)
+	add("agent", agentID)
+	if carrier != nil {
+		add("carrier", carrier.CarrierPartyID)
+	}
+	for _, b := range billing {
+		add("billing party", b.BillingPartyID)
+	}
+	for _, p := range provisions {
+		add("cost party", p.CostPartyID)
+	}
+	return parties
+}
+
+// partyStatuses looks up the status of each party. Parties missing from party_master are skipped,
+// as job party references are soft.
+func (s *Service) partyStatuses(ctx context.Context, parties []jobParty) ([]partyStatus, error) {
+	if len(parties) == 0 {
+		return nil, nil
+	}
+	ids := make([]uuid.UUID, 0, len(parties))
+	seen := make(map[uuid.UUID]bool, len(parties))
+	for _, p := range parties {
+		if !seen[p.id] {
+			seen[p.id] = true
+			ids = append(ids, p.id)
+		}
+	}
+	rows, err := s.repo.ListPartyStatuses(ctx, ids)
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to list party statuses", slog.Any("error", err))
+		return nil, fmt.Errorf("operations: check parties: %w", err)
+	}
+	byID := make(map[uuid.UUID]sqlc.ListPartyStatusesRow, len(rows))
+	for _, row := range rows {
+		byID[row.ID] = row
+	}
+	statuses := make([]partyStatus, 0, len(parties))
+	for _, p := range parties {
+		if row, ok := byID[p.id]; ok {
+			statuses = append(statuses, partyStatus{role: p.role, name: row.Name, status: row.Status.String})
+		}
+	}
+	return statuses, nil
+}
+
+// jobPartyStatuses returns the status of every party already on the job.
+func (s *Service) jobPartyStatuses(ctx context.Context, jobID uuid.UUID) ([]partyStatus, error) {
+	rows, err := s.repo.ListJobPartyStatuses(ctx, jobID)
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to list job party statuses", slog.Any("error", err))
+		return nil, fmt.Errorf("operations: check parties: %w", err)
+	}
+	statuses := make([]partyStatus, 0, len(rows))
+	for _, row := range rows {
+		statuses = append(statuses, partyStatus{role: row.PartyRole, name: row.Name, status: row.Status.String})
+	}
+	return statuses, nil
+}
+
+// ineligibleParties refuses blacklisted and inactive parties, naming each once per role.
+func ineligibleParties(statuses []partyStatus) error {
+	var problems []string
+	seen := make(map[partyStatus]bool, len(statuses))
+	for _, p := range statuses {
+		if p.status != "Blacklisted" && p.status != "Inactive" {
+			continue
+		}
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+		problems = append(problems, fmt.Sprintf("%s %s is %s", p.role, p.name, strings.ToLower(p.status)))
+	}
+	if len(problems) > 0 {
+		return &PartyEligibilityError{Problems: problems}
+	}
+	return nil
+}
+
+// checkCreditLimit compares the customer's open billed and unbilled amounts on active jobs, plus
+// added, with their credit limit. Over the limit it fails unless the caller holds
+// CreditOverrideRole, in which case it returns the override to audit once the job is saved.
+func (s *Service) checkCreditLimit(ctx context.Context, customerID, jobID *uuid.UUID, added decimal.Decimal) (*sqlc.CreateCreditOverrideParams, error) {
+	if customerID == nil {
+		return nil, nil
+	}
+	logger := logging.FromContext(ctx)
+	limit, err := s.repo.GetPartyCreditLimit(ctx, *customerID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		logger.Error("failed to get credit limit", slog.Any("error", err))
+		return nil, fmt.Errorf("operations: check credit limit: %w", err)
+	}
+	row, err := s.repo.GetCustomerCreditExposure(ctx, *customerID, jobID)
+	if err != nil {
+		logger.Error("failed to get credit exposure", slog.Any("error", err))
+		return nil, fmt.Errorf("operations: check credit limit: %w", err)
+	}
+	exposure := numericOrZero(row.OpenBilled).Add(numericOrZero(row.Unbilled)).Add(added)
+	creditLimit := numericOrZero(limit.CreditLimit)
+	if exposure.Cmp(creditLimit) <= 0 {
+		return nil, nil
+	}
+
+	currency, err := s.primaryCurrency(ctx)
+	if err != nil {
+		return nil, err
+	}
+	principal, _ := common.PrincipalFromContext(ctx)
+	if !principal.HasRole(CreditOverrideRole) {
+		return nil, &CreditLimitError{PartyID: *customerID, Currency: currency, CreditLimit: creditLimit, Exposure: exposure}
+	}
+	logger.Warn("credit limit overridden",
+		slog.String("partyID", customerID.String()),
+		slog.String("exposure", exposure.String()),
+		slog.String("creditLimit", creditLimit.String()),
+	)
+	return &sqlc.CreateCreditOverrideParams{
+		PartyID:      *customerID,
+		CreditLimit:  limit.CreditLimit,
+		Exposure:     numericFromDecimal(&exposure),
+		CurrencyCode: currency,
+	}, nil
+}
+
+// recordCreditOverride writes the audit entry of a credit limit override on a saved job. It runs
+// in the job's write transaction: an override that cannot be recorded fails the request.
+func (s *Service) recordCreditOverride(ctx context.Context, jobID uuid.UUID, override *sqlc.CreateCreditOverrideParams, actor string) error {
+	if override == nil {
+		return nil
+	}
+	override.JobID = jobID
+	override.Actor = pgtype.Text{String: actor, Valid: true}
+	if err := s.repo.CreateCreditOverride(ctx, *override); err != nil {
+		logging.FromContext(ctx).Error("failed to record credit override", slog.Any("error", err))
+		return fmt.Errorf("operations: record credit override: %w", err)
+	}
+	return nil
+}
+
+// billedTotal sums priced billing lines in the primary currency.
+func billedTotal(billed []chargeAmounts) decimal.Decimal {
+	var total decimal.Decimal
+	for _, b := range billed {
+		total = total.Add(b.AmountPrimaryCurrency)
+	}
+	return total
+}
+
+// GetCustomerCredit returns the customer's credit limit and current exposure.
+func (s *Service) GetCustomerCredit(ctx context.Context, partyID uuid.UUID) (operationsdto.CustomerCredit, error) {
+	logger := logging.FromContext(ctx)
+	currency, err := s.primaryCurrency(ctx)
+	if err != nil {
+		return operationsdto.CustomerCredit{}, err
+	}
+	row, err := s.repo.GetCustomerCreditExposure(ctx, partyID, nil)
+	if err != nil {
+		logger.Error("failed to get credit exposure", slog.Any("error", err))
+		return operationsdto.CustomerCredit{}, fmt.Errorf("operations: get customer credit: %w", err)
+	}
+	credit := operationsdto.CustomerCredit{
+		PartyID:    partyID,
+		Currency:   currency,
+		OpenBilled: numericOrZero(row.OpenBilled),
+		Unbilled:   numericOrZero(row.Unbilled),
+	}
+	credit.Exposure = credit.OpenBilled.Add(credit.Unbilled)
+
+	limit, err := s.repo.GetPartyCreditLimit(ctx, partyID)
+	switch {
+	case err == nil:
+		credit.CreditLimit = decimalFromNumeric(limit.CreditLimit)
+		if credit.CreditLimit != nil {
+			available := credit.CreditLimit.Sub(credit.Exposure)
+			credit.Available = &available
+		}
+	case !errors.Is(err, pgx.ErrNoRows):
+		logger.Error("failed to get credit limit", slog.Any("error", err))
+		return operationsdto.CustomerCredit{}, fmt.Errorf("operations: get customer credit: %w", err)
+	}
+	return credit, nil
+}
+
+// SetCreditLimit sets the customer's credit limit in the tenant's primary currency.
+func (s *Service) SetCreditLimit(ctx context.Context, partyID uuid.UUID, limit decimal.Decimal, actor string) (operationsdto.CustomerCredit, error) {
+	if limit.Sign() < 0 {
+		return operationsdto.CustomerCredit{}, fmt.Errorf("%w: credit limit must not be negative", ErrInvalidCreditLimit)
+	}
+	_, err := s.repo.UpsertPartyCreditLimit(ctx, sqlc.UpsertPartyCreditLimitParams{
+		PartyID:     partyID,
+		CreditLimit: numericFromDecimal(&limit),
+		Actor:       pgtype.Text{String: actor, Valid: true},
+	})
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to set credit limit", slog.Any("error", err))
+		return operationsdto.CustomerCredit{}, fmt.Errorf("operations: set credit limit: %w", err)
+	}
+	return s.GetCustomerCredit(ctx, partyID)
+}
+
+// RemoveCreditLimit lifts the customer's credit limit.
+func (s *Service) RemoveCreditLimit(ctx context.Context, partyID uuid.UUID) error {
+	n, err := s.repo.DeletePartyCreditLimit(ctx, partyID)
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to remove credit limit", slog.Any("error", err))
+		return fmt.Errorf("operations: remove credit limit: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("operations: remove credit limit: %w", pgx.ErrNoRows)
+	}
+	return nil
+}
+
+// ListCreditOverrides returns the jobs created or activated past the customer's limit, newest first.
+func (s *Service) ListCreditOverrides(ctx context.Context, partyID uuid.UUID) ([]operationsdto.CreditOverride, error) {
+	rows, err := s.repo.ListPartyCreditOverrides(ctx, partyID)
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to list credit overrides", slog.Any("error", err))
+		return nil, fmt.Errorf("operations: list credit overrides: %w", err)
+	}
+	result := make([]operationsdto.CreditOverride, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, operationsdto.CreditOverride{
+			ID:           row.ID,
+			JobID:        row.JobID,
+			JobCode:      row.JobCode,
+			PartyID:      row.PartyID,
+			CreditLimit:  numericOrZero(row.CreditLimit),
+			Exposure:     numericOrZero(row.Exposure),
+			Currency:     row.CurrencyCode,
+			OverriddenAt: timeFromTimestamptz(row.OverriddenAt),
+			OverriddenBy: textToStringPtr(row.OverriddenBy),
+		})
+	}
+	return result, nil
+}
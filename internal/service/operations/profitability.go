@@ -0,0 +1,182 @@
+package operations
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	sqlc "frego-operations/internal/db/sqlc"
+	"frego-operations/internal/decimal"
+	operationsdto "frego-operations/internal/dto/operations"
+	"frego-operations/internal/logging"
+	repository "frego-operations/internal/repository/operations"
+)
+
+// marginPlaces is the precision margin percentages are reported with.
+const marginPlaces = 2
+
+// ProfitabilityReport reports revenue, cost, gross profit and margin in the tenant's primary
+// currency for the jobs matching the filter. Totals and the activity breakdown cover every
+// matching job; the job list is capped at filter.Limit, newest first.
+func (s *Service) ProfitabilityReport(ctx context.Context, filter operationsdto.ProfitabilityFilter) (operationsdto.ProfitabilityReport, error) {
+	logger := logging.FromContext(ctx)
+	logger.Info("building profitability report",
+		slog.Any("branchID", filter.BranchID),
+		slog.Any("salesExecutiveID", filter.SalesExecutiveID),
+		slog.Any("customerID", filter.CustomerID),
+		slog.Any("transportMode", filter.TransportMode),
+		slog.Any("periodFrom", filter.PeriodFrom),
+		slog.Any("periodTo", filter.PeriodTo))
+
+	currency, err := s.primaryCurrency(ctx)
+	if err != nil {
+		return operationsdto.ProfitabilityReport{}, err
+	}
+
+	jobs, err := s.repo.ListJobProfitability(ctx, jobProfitabilityParams(filter))
+	if err != nil {
+		logger.Error("failed to list job profitability", slog.Any("error", err))
+		return operationsdto.ProfitabilityReport{}, fmt.Errorf("operations: profitability report: %w", err)
+	}
+	activities, err := s.activityProfitability(ctx, filter, currency)
+	if err != nil {
+		return operationsdto.ProfitabilityReport{}, err
+	}
+
+	report := operationsdto.ProfitabilityReport{
+		Currency:   currency,
+		Jobs:       make([]operationsdto.JobProfitability, 0, len(jobs)),
+		Activities: activities,
+	}
+	for _, row := range jobs {
+		report.Jobs = append(report.Jobs, jobProfitabilityFromRow(row, currency))
+	}
+
+	revenue, cost := decimal.Zero, decimal.Zero
+	for _, activity := range activities {
+		revenue = revenue.Add(activity.Revenue)
+		cost = cost.Add(activity.Cost)
+	}
+	report.Totals = profitabilityFigures(revenue, cost, currency)
+
+	logger.Info("built profitability report", slog.Int("jobs", len(report.Jobs)), slog.Int("activities", len(activities)))
+	return report, nil
+}
+
+// GetJobProfitability reports the profitability of one job broken down by activity.
+func (s *Service) GetJobProfitability(ctx context.Context, jobID uuid.UUID) (operationsdto.JobProfitability, error) {
+	logger := logging.FromContext(ctx)
+	logger.Info("getting job profitability", slog.String("jobID", jobID.String()))
+
+	currency, err := s.primaryCurrency(ctx)
+	if err != nil {
+		return operationsdto.JobProfitability{}, err
+	}
+
+	filter := operationsdto.ProfitabilityFilter{JobID: &jobID, Limit: 1}
+	rows, err := s.repo.ListJobProfitability(ctx, jobProfitabilityParams(filter))
+	if err != nil {
+		logger.Error("failed to get job profitability", slog.Any("error", err))
+		return operationsdto.JobProfitability{}, fmt.Errorf("operations: job profitability: %w", err)
+	}
+	if len(rows) == 0 {
+		return operationsdto.JobProfitability{}, fmt.Errorf("operations: job profitability: %w", pgx.ErrNoRows)
+	}
+
+	result := jobProfitabilityFromRow(rows[0], currency)
+	result.Activities, err = s.activityProfitability(ctx, filter, currency)
+	if err != nil {
+		return operationsdto.JobProfitability{}, err
+	}
+	return result, nil
+}
+
+func (s *Service) activityProfitability(ctx context.Context, filter operationsdto.ProfitabilityFilter, currency string) ([]operationsdto.ActivityProfitability, error) {
+	rows, err := s.repo.ListActivityProfitability(ctx, sqlc.ListActivityProfitabilityParams{
+		JobID:            repository.NullUUIDFromUUID(filter.JobID),
+		BranchID:         repository.NullUUIDFromUUID(filter.BranchID),
+		SalesExecutiveID: repository.NullUUIDFromUUID(filter.SalesExecutiveID),
+		CustomerID:       repository.NullUUIDFromUUID(filter.CustomerID),
+		TransportMode:    textFromString(filter.TransportMode),
+		PeriodFrom:       timestampFromTime(filter.PeriodFrom),
+		PeriodTo:         timestampFromTime(filter.PeriodTo),
+	})
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to list activity profitability", slog.Any("error", err))
+		return nil, fmt.Errorf("operations: activity profitability: %w", err)
+	}
+
+	result := make([]operationsdto.ActivityProfitability, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, operationsdto.ActivityProfitability{
+			ActivityType:         textToStringPtr(row.ActivityType),
+			ActivityCode:         textToStringPtr(row.ActivityCode),
+			JobCount:             row.JobCount,
+			ProfitabilityFigures: profitabilityFigures(numericOrZero(row.Revenue), numericOrZero(row.Cost), currency),
+		})
+	}
+	return result, nil
+}
+
+func jobProfitabilityParams(filter operationsdto.ProfitabilityFilter) sqlc.ListJobProfitabilityParams {
+	return sqlc.ListJobProfitabilityParams{
+		JobID:            repository.NullUUIDFromUUID(filter.JobID),
+		BranchID:         repository.NullUUIDFromUUID(filter.BranchID),
+		SalesExecutiveID: repository.NullUUIDFromUUID(filter.SalesExecutiveID),
+		CustomerID:       repository.NullUUIDFromUUID(filter.CustomerID),
+		TransportMode:    textFromString(filter.TransportMode),
+		PeriodFrom:       timestampFromTime(filter.PeriodFrom),
+		PeriodTo:         timestampFromTime(filter.PeriodTo),
+		RowLimit:         filter.Limit,
+	}
+}
+
+func jobProfitabilityFromRow(row sqlc.ListJobProfitabilityRow, currency string) operationsdto.JobProfitability {
+	return operationsdto.JobProfitability{
+		JobID:                row.ID,
+		JobCode:              row.JobCode,
+		JobType:              textToStringPtr(row.JobType),
+		TransportMode:        textToStringPtr(row.TransportMode),
+		Status:               textToStringPtr(row.Status),
+		CustomerID:           uuidFromPgtype(row.CustomerID),
+		CustomerName:         textToStringPtr(row.CustomerName),
+		BranchID:             uuidFromPgtype(row.BranchID),
+		BranchName:           textToStringPtr(row.BranchName),
+		SalesExecutiveID:     uuidFromPgtype(row.SalesExecutiveID),
+		SalesExecutiveName:   textToStringPtr(row.SalesExecutiveName),
+		CreatedAt:            timeFromTimestamptz(row.CreatedAt),
+		Currency:             currency,
+		ProfitabilityFigures: profitabilityFigures(numericOrZero(row.Revenue), numericOrZero(row.Cost), currency),
+	}
+}
+
+// profitabilityFigures derives gross profit, margin and the loss flag from revenue and cost.
+// Margin is gross profit as a percentage of revenue and is left nil when there is no revenue.
+func profitabilityFigures(revenue, cost decimal.Decimal, currency string) operationsdto.ProfitabilityFigures {
+	revenue = revenue.RoundCurrency(currency)
+	cost = cost.RoundCurrency(currency)
+	profit := revenue.Sub(cost)
+
+	figures := operationsdto.ProfitabilityFigures{
+		Revenue:     revenue,
+		Cost:        cost,
+		GrossProfit: profit,
+		Loss:        cost.Cmp(revenue) > 0,
+	}
+	if !revenue.IsZero() {
+		margin := profit.Mul(decimalHundred).Div(revenue, marginPlaces)
+		figures.MarginPercent = &margin
+	}
+	return figures
+}
+
+func numericOrZero(n pgtype.Numeric) decimal.Decimal {
+	if d := decimalFromNumeric(n); d != nil {
+		return *d
+	}
+	return decimal.Zero
+}
@@ -0,0 +1,408 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	sqlc "frego-operations/internal/db/sqlc"
+	"frego-operations/internal/decimal"
+	operationsdto "frego-operations/internal/dto/operations"
+	"frego-operations/internal/logging"
+	repository "frego-operations/internal/repository/operations"
+)
+
+// Reconciliation states, as constrained in ops_provision_reconciliation.status.
+const (
+	ReconciliationMatched         = "Matched"
+	ReconciliationPendingApproval = "PendingApproval"
+	ReconciliationApproved        = "Approved"
+	ReconciliationRejected        = "Rejected"
+)
+
+const variancePercentScale = 4
+
+// ErrInvalidReconciliation indicates a supplier invoice or tolerance setting failed validation.
+var ErrInvalidReconciliation = errors.New("operations: invalid reconciliation")
+
+// ReconciliationError lists every problem found in a supplier invoice or tolerance setting.
+type ReconciliationError struct {
+	Problems []string
+}
+
+func (e *ReconciliationError) Error() string {
+	return fmt.Sprintf("operations: invalid reconciliation: %s", strings.Join(e.Problems, "; "))
+}
+
+func (e *ReconciliationError) Unwrap() error {
+	return ErrInvalidReconciliation
+}
+
+// GetAccrualTolerance returns the tenant's supplier invoice variance tolerance.
+func (s *Service) GetAccrualTolerance(ctx context.Context) (operationsdto.AccrualTolerance, error) {
+	row, err := s.repo.GetAccrualTolerance(ctx)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return operationsdto.AccrualTolerance{}, &ReconciliationError{Problems: []string{"tenant has no primary currency configured"}}
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to get accrual tolerance", slog.Any("error", err))
+		return operationsdto.AccrualTolerance{}, fmt.Errorf("operations: get accrual tolerance: %w", err)
+	}
+	return operationsdto.AccrualTolerance{
+		Currency: row.PrimaryCurrencyCode,
+		Percent:  decimalFromNumeric(row.AccrualTolerancePercent),
+		Amount:   decimalFromNumeric(row.AccrualToleranceAmount),
+	}, nil
+}
+
+// UpdateAccrualTolerance replaces the tenant's supplier invoice variance tolerance.
+// Existing reconciliations keep the status they were recorded with.
+func (s *Service) UpdateAccrualTolerance(ctx context.Context, input operationsdto.AccrualToleranceInput) (operationsdto.AccrualTolerance, error) {
+	logger := logging.FromContext(ctx)
+
+	var problems []string
+	if input.Percent != nil && input.Percent.Sign() < 0 {
+		problems = append(problems, "tolerance percent must not be negative")
+	}
+	if input.Amount != nil && input.Amount.Sign() < 0 {
+		problems = append(problems, "tolerance amount must not be negative")
+	}
+	if len(problems) > 0 {
+		return operationsdto.AccrualTolerance{}, &ReconciliationError{Problems: problems}
+	}
+
+	row, err := s.repo.UpdateAccrualTolerance(ctx, sqlc.UpdateAccrualToleranceParams{
+		AccrualTolerancePercent: numericFromDecimal(input.Percent),
+		AccrualToleranceAmount:  numericFromDecimal(input.Amount),
+		Actor:                   pgtype.Text{String: input.UpdatedBy, Valid: true},
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return operationsdto.AccrualTolerance{}, &ReconciliationError{Problems: []string{"tenant has no primary currency configured"}}
+	}
+	if err != nil {
+		logger.Error("failed to update accrual tolerance", slog.Any("error", err))
+		return operationsdto.AccrualTolerance{}, fmt.Errorf("operations: update accrual tolerance: %w", err)
+	}
+
+	logger.Info("updated accrual tolerance")
+	return operationsdto.AccrualTolerance{
+		Currency: row.PrimaryCurrencyCode,
+		Percent:  decimalFromNumeric(row.AccrualTolerancePercent),
+		Amount:   decimalFromNumeric(row.AccrualToleranceAmount),
+	}, nil
+}
+
+// RecordSupplierInvoice records the supplier's invoice against a provision and computes the
+// variance from the accrued amount. Variances outside the tenant's tolerance are flagged
+// PendingApproval; recording again replaces the previous reconciliation.
+func (s *Service) RecordSupplierInvoice(ctx context.Context, provisionID uuid.UUID, input operationsdto.SupplierInvoiceInput) (operationsdto.ProvisionReconciliation, error) {
+	logger := logging.FromContext(ctx)
+	logger.Info("recording supplier invoice", slog.String("provisionID", provisionID.String()))
+
+	provision, err := s.repo.GetProvision(ctx, provisionID)
+	if err != nil {
+		logger.Error("failed to get provision", slog.Any("error", err))
+		return operationsdto.ProvisionReconciliation{}, fmt.Errorf("operations: record supplier invoice: %w", err)
+	}
+	if err := s.ensureJobVisible(ctx, provision.JobID.Bytes); err != nil {
+		return operationsdto.ProvisionReconciliation{}, err
+	}
+
+	var problems []string
+	invoiceNumber := strings.TrimSpace(input.InvoiceNumber)
+	if invoiceNumber == "" {
+		problems = append(problems, "supplier invoice number is required")
+	}
+	if input.AmountWithoutTax.Sign() < 0 {
+		problems = append(problems, "supplier invoice amount must not be negative")
+	}
+	provisionCurrency := strings.ToUpper(strings.TrimSpace(provision.CurrencyCode.String))
+	expected := decimalFromNumeric(provision.AmountWithoutTax)
+	if provisionCurrency == "" || expected == nil {
+		problems = append(problems, "provision has no currency or amount to reconcile against")
+	}
+	currency := provisionCurrency
+	if input.CurrencyCode != nil && strings.TrimSpace(*input.CurrencyCode) != "" {
+		currency = strings.ToUpper(strings.TrimSpace(*input.CurrencyCode))
+	}
+	if currency != "" && !isCurrencyCode(currency) {
+		problems = append(problems, fmt.Sprintf("supplier invoice currency %q is not an ISO 4217 code", currency))
+	}
+	if len(problems) > 0 {
+		return operationsdto.ProvisionReconciliation{}, &ReconciliationError{Problems: problems}
+	}
+
+	tolerance, err := s.GetAccrualTolerance(ctx)
+	if err != nil {
+		return operationsdto.ProvisionReconciliation{}, err
+	}
+	on := rateDate(input.InvoiceDate)
+
+	// Compare in the provision's currency, converting the invoice when it was issued in another one.
+	var rate *decimal.Decimal
+	actual := input.AmountWithoutTax
+	if currency != provisionCurrency {
+		quote, found, err := s.exchangeRate(ctx, currency, provisionCurrency, on)
+		if err != nil {
+			return operationsdto.ProvisionReconciliation{}, err
+		}
+		if !found {
+			return operationsdto.ProvisionReconciliation{}, &ReconciliationError{Problems: []string{
+				fmt.Sprintf("no %s/%s exchange rate in force on %s", currency, provisionCurrency, on.Format("2006-01-02")),
+			}}
+		}
+		rate = &quote.Rate
+		actual = actual.Mul(quote.Rate).RoundCurrency(provisionCurrency)
+	}
+
+	variance := actual.Sub(*expected)
+	var variancePercent *decimal.Decimal
+	if !expected.IsZero() {
+		pct := variance.Mul(decimalHundred).Div(*expected, variancePercentScale)
+		variancePercent = &pct
+	}
+	variancePrimary, err := s.varianceInPrimary(ctx, provision, provisionCurrency, tolerance.Currency, variance, on)
+	if err != nil {
+		return operationsdto.ProvisionReconciliation{}, err
+	}
+
+	status := ReconciliationMatched
+	var approval *sqlc.CreateApprovalParams
+	if !withinTolerance(variance, variancePercent, variancePrimary, tolerance) {
+		status = ReconciliationPendingApproval
+		approval = &sqlc.CreateApprovalParams{
+			SubjectType:  repository.ApprovalSubjectReconciliation,
+			JobID:        uuid.UUID(provision.JobID.Bytes),
+			RequiredRole: DefaultApproverRole,
+			Reason:       fmt.Sprintf("supplier invoice %s varies from the accrual by %s %s", invoiceNumber, variance.String(), provisionCurrency),
+			Actor:        pgtype.Text{String: input.RecordedBy, Valid: true},
+		}
+	}
+
+	row, err := s.repo.RecordProvisionReconciliation(ctx, sqlc.CreateProvisionReconciliationParams{
+		ProvisionID:             provision.ID,
+		JobID:                   uuid.UUID(provision.JobID.Bytes),
+		SupplierInvoiceNumber:   invoiceNumber,
+		SupplierInvoiceDate:     timestampFromTime(input.InvoiceDate),
+		CurrencyCode:            currency,
+		AmountWithoutTax:        numericFromDecimal(&input.AmountWithoutTax),
+		TaxAmount:               numericFromDecimal(input.TaxAmount),
+		TotalAmount:             numericFromDecimal(input.TotalAmount),
+		ExchangeRate:            numericFromDecimal(rate),
+		ExpectedAmount:          numericFromDecimal(expected),
+		Variance:                numericFromDecimal(&variance),
+		VariancePercent:         numericFromDecimal(variancePercent),
+		VariancePrimaryCurrency: numericFromDecimal(variancePrimary),
+		Status:                  status,
+		Notes:                   textFromString(input.Notes),
+		Actor:                   pgtype.Text{String: input.RecordedBy, Valid: true},
+	}, approval)
+	if err != nil {
+		logger.Error("failed to record supplier invoice", slog.Any("error", err))
+		return operationsdto.ProvisionReconciliation{}, fmt.Errorf("operations: record supplier invoice: %w", err)
+	}
+
+	logger.Info("recorded supplier invoice",
+		slog.String("jobCode", provision.JobCode),
+		slog.String("variance", variance.String()),
+		slog.String("status", status),
+	)
+	stored, err := s.repo.GetProvisionReconciliation(ctx, row.ID)
+	if err != nil {
+		logger.Error("failed to get reconciliation", slog.Any("error", err))
+		return operationsdto.ProvisionReconciliation{}, fmt.Errorf("operations: record supplier invoice: %w", err)
+	}
+	return reconciliationFromSqlc(stored), nil
+}
+
+// ListJobReconciliations returns the supplier invoices recorded against the job's provisions.
+func (s *Service) ListJobReconciliations(ctx context.Context, jobID uuid.UUID) ([]operationsdto.ProvisionReconciliation, error) {
+	if err := s.ensureJobVisible(ctx, jobID); err != nil {
+		return nil, err
+	}
+	rows, err := s.repo.ListJobReconciliations(ctx, jobID)
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to list job reconciliations", slog.Any("error", err))
+		return nil, fmt.Errorf("operations: list reconciliations: %w", err)
+	}
+	result := make([]operationsdto.ProvisionReconciliation, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, reconciliationFromSqlc(sqlc.GetProvisionReconciliationRow(row)))
+	}
+	return result, nil
+}
+
+// ListReconciliationsByStatus returns reconciliations in one state across jobs, oldest first.
+func (s *Service) ListReconciliationsByStatus(ctx context.Context, status string, limit int32) ([]operationsdto.ProvisionReconciliation, error) {
+	switch status {
+	case ReconciliationMatched, ReconciliationPendingApproval, ReconciliationApproved, ReconciliationRejected:
+	default:
+		return nil, &ReconciliationError{Problems: []string{fmt.Sprintf("unknown reconciliation status %q", status)}}
+	}
+
+	rows, err := s.repo.ListReconciliationsByStatus(ctx, sqlc.ListReconciliationsByStatusParams{Status: status, RowLimit: limit})
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to list reconciliations", slog.Any("error", err))
+		return nil, fmt.Errorf("operations: list reconciliations: %w", err)
+	}
+	result := make([]operationsdto.ProvisionReconciliation, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, reconciliationFromSqlc(sqlc.GetProvisionReconciliationRow(row)))
+	}
+	return result, nil
+}
+
+// OpenAccruals lists provisions still awaiting a supplier invoice, oldest first, and ages
+// them per vendor in the primary currency.
+func (s *Service) OpenAccruals(ctx context.Context, filter operationsdto.OpenAccrualFilter) (operationsdto.OpenAccrualReport, error) {
+	primary, err := s.primaryCurrency(ctx)
+	if err != nil {
+		return operationsdto.OpenAccrualReport{}, err
+	}
+
+	params := sqlc.ListOpenAccrualsParams{
+		CostPartyID: repository.NullUUIDFromUUID(filter.CostPartyID),
+		RowLimit:    filter.Limit,
+	}
+	if filter.MinAgeDays != nil {
+		params.MinAgeDays = pgtype.Int4{Int32: *filter.MinAgeDays, Valid: true}
+	}
+	rows, err := s.repo.ListOpenAccruals(ctx, params)
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to list open accruals", slog.Any("error", err))
+		return operationsdto.OpenAccrualReport{}, fmt.Errorf("operations: list open accruals: %w", err)
+	}
+
+	report := operationsdto.OpenAccrualReport{
+		Currency: primary,
+		Accruals: make([]operationsdto.OpenAccrual, 0, len(rows)),
+	}
+	vendors := map[uuid.UUID]int{}
+	for _, row := range rows {
+		accrual := operationsdto.OpenAccrual{
+			ProvisionID:           row.ID,
+			JobID:                 uuidFromPgtype(row.JobID),
+			JobCode:               row.JobCode,
+			ActivityType:          textToStringPtr(row.ActivityType),
+			ActivityCode:          textToStringPtr(row.ActivityCode),
+			CostPartyID:           uuidFromPgtype(row.CostPartyID),
+			CostPartyName:         textToStringPtr(row.CostPartyName),
+			CurrencyCode:          textToStringPtr(row.CurrencyCode),
+			AmountWithoutTax:      decimalFromNumeric(row.AmountWithoutTax),
+			AmountPrimaryCurrency: decimalFromNumeric(row.AmountPrimaryCurrency),
+			PONumber:              textToStringPtr(row.PoNumber),
+			CreatedAt:             timeFromTimestamptz(row.CreatedAt),
+			AgeDays:               row.AgeDays,
+		}
+		report.Accruals = append(report.Accruals, accrual)
+
+		// Accruals without a vendor are aged together under the nil vendor.
+		key := uuid.Nil
+		if accrual.CostPartyID != nil {
+			key = *accrual.CostPartyID
+		}
+		i, ok := vendors[key]
+		if !ok {
+			i = len(report.Vendors)
+			vendors[key] = i
+			report.Vendors = append(report.Vendors, operationsdto.VendorAccrualAging{
+				CostPartyID:   accrual.CostPartyID,
+				CostPartyName: accrual.CostPartyName,
+			})
+		}
+		ageAccrual(&report.Vendors[i], accrual)
+	}
+	return report, nil
+}
+
+// varianceInPrimary converts a variance to the primary currency with the provision's own rate,
+// falling back to the rate in force on the invoice date. It is nil when no rate is known.
+func (s *Service) varianceInPrimary(ctx context.Context, provision sqlc.GetProvisionRow, currency, primary string, variance decimal.Decimal, on time.Time) (*decimal.Decimal, error) {
+	if currency == primary {
+		return &variance, nil
+	}
+	if rate := decimalFromNumeric(provision.ExchangeRate); rate != nil && rate.Sign() > 0 {
+		converted := variance.Mul(*rate).RoundCurrency(primary)
+		return &converted, nil
+	}
+	quote, found, err := s.exchangeRate(ctx, currency, primary, on)
+	if err != nil || !found {
+		return nil, err
+	}
+	converted := variance.Mul(quote.Rate).RoundCurrency(primary)
+	return &converted, nil
+}
+
+// withinTolerance reports whether a variance may be accepted without approval. Every configured
+// tolerance must hold; with none configured only an exact match is accepted.
+func withinTolerance(variance decimal.Decimal, percent, primary *decimal.Decimal, tolerance operationsdto.AccrualTolerance) bool {
+	if variance.IsZero() {
+		return true
+	}
+	if tolerance.Percent == nil && tolerance.Amount == nil {
+		return false
+	}
+	if tolerance.Percent != nil && (percent == nil || percent.Abs().Cmp(*tolerance.Percent) > 0) {
+		return false
+	}
+	if tolerance.Amount != nil && (primary == nil || primary.Abs().Cmp(*tolerance.Amount) > 0) {
+		return false
+	}
+	return true
+}
+
+func ageAccrual(vendor *operationsdto.VendorAccrualAging, accrual operationsdto.OpenAccrual) {
+	vendor.Count++
+	if accrual.AmountPrimaryCurrency == nil {
+		vendor.Unpriced++
+		return
+	}
+	amount := *accrual.AmountPrimaryCurrency
+	switch {
+	case accrual.AgeDays <= 30:
+		vendor.Days0To30 = vendor.Days0To30.Add(amount)
+	case accrual.AgeDays <= 60:
+		vendor.Days31To60 = vendor.Days31To60.Add(amount)
+	case accrual.AgeDays <= 90:
+		vendor.Days61To90 = vendor.Days61To90.Add(amount)
+	default:
+		vendor.Over90Days = vendor.Over90Days.Add(amount)
+	}
+	vendor.Total = vendor.Total.Add(amount)
+}
+
+func reconciliationFromSqlc(row sqlc.GetProvisionReconciliationRow) operationsdto.ProvisionReconciliation {
+	return operationsdto.ProvisionReconciliation{
+		ID:                      row.ID,
+		ProvisionID:             row.ProvisionID,
+		JobID:                   row.JobID,
+		JobCode:                 &row.JobCode,
+		ActivityType:            textToStringPtr(row.ActivityType),
+		ActivityCode:            textToStringPtr(row.ActivityCode),
+		CostPartyID:             uuidFromPgtype(row.CostPartyID),
+		CostPartyName:           textToStringPtr(row.CostPartyName),
+		SupplierInvoiceNumber:   row.SupplierInvoiceNumber,
+		SupplierInvoiceDate:     timeFromTimestamptz(row.SupplierInvoiceDate),
+		CurrencyCode:            row.CurrencyCode,
+		AmountWithoutTax:        numericOrZero(row.AmountWithoutTax),
+		TaxAmount:               decimalFromNumeric(row.TaxAmount),
+		TotalAmount:             decimalFromNumeric(row.TotalAmount),
+		ExchangeRate:            decimalFromNumeric(row.ExchangeRate),
+		ProvisionCurrency:       textToStringPtr(row.ProvisionCurrencyCode),
+		ExpectedAmount:          numericOrZero(row.ExpectedAmount),
+		Variance:                numericOrZero(row.Variance),
+		VariancePercent:         decimalFromNumeric(row.VariancePercent),
+		VariancePrimaryCurrency: decimalFromNumeric(row.VariancePrimaryCurrency),
+		Status:                  row.Status,
+		Notes:                   textToStringPtr(row.Notes),
+		CreatedAt:               timeFromTimestamptz(row.CreatedAt),
+		CreatedBy:               textToStringPtr(row.CreatedBy),
+	}
+}
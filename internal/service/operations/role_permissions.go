@@ -0,0 +1,106 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"frego-operations/internal/authz"
+	"frego-operations/internal/common"
+	operationsdto "frego-operations/internal/dto/operations"
+	"frego-operations/internal/logging"
+)
+
+// ErrInvalidRolePermissions indicates a role grant naming an unknown permission.
+var ErrInvalidRolePermissions = errors.New("operations: invalid role permissions")
+
+// RoleGrants returns the tenant's role grants as permissions keyed by role name, for the
+// authorizer.
+func (s *Service) RoleGrants(ctx context.Context) (map[string][]string, error) {
+	rows, err := s.repo.ListRolePermissions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("operations: list role permissions: %w", err)
+	}
+	grants := make(map[string][]string)
+	for _, row := range rows {
+		if name := strings.TrimSpace(row.RoleName.String); name != "" {
+			grants[name] = append(grants[name], row.Permission)
+		}
+	}
+	return grants, nil
+}
+
+// ListRolePermissions returns every role_details_lu role with the permissions granted to it.
+func (s *Service) ListRolePermissions(ctx context.Context) ([]operationsdto.RolePermissions, error) {
+	logger := logging.FromContext(ctx)
+
+	roles, err := s.repo.ListRoleDetailsLookups(ctx)
+	if err != nil {
+		logger.Error("failed to list role details lookups", slog.Any("error", err))
+		return nil, fmt.Errorf("operations: list role details: %w", err)
+	}
+	rows, err := s.repo.ListRolePermissions(ctx)
+	if err != nil {
+		logger.Error("failed to list role permissions", slog.Any("error", err))
+		return nil, fmt.Errorf("operations: list role permissions: %w", err)
+	}
+	granted := make(map[int16][]string)
+	for _, row := range rows {
+		granted[row.RoleID] = append(granted[row.RoleID], row.Permission)
+	}
+
+	result := make([]operationsdto.RolePermissions, 0, len(roles))
+	for _, role := range roles {
+		perms := granted[role.RoleID]
+		if perms == nil {
+			perms = []string{}
+		}
+		result = append(result, operationsdto.RolePermissions{
+			RoleID:      role.RoleID,
+			RoleName:    common.PgtypeTextToString(role.RoleName),
+			RoleDesc:    common.PgtypeTextToStringPtr(role.RoleDesc),
+			Permissions: perms,
+		})
+	}
+	return result, nil
+}
+
+// SetRolePermissions replaces the permissions granted to a role. Callers holding a cached policy
+// must drop it for the tenant afterwards.
+func (s *Service) SetRolePermissions(ctx context.Context, roleID int16, permissions []string, actor string) (operationsdto.RolePermissions, error) {
+	logger := logging.FromContext(ctx)
+	logger.Info("setting role permissions", slog.Int("roleID", int(roleID)))
+
+	seen := make(map[string]bool, len(permissions))
+	var problems []string
+	cleaned := make([]string, 0, len(permissions))
+	for _, p := range permissions {
+		p = strings.TrimSpace(p)
+		switch {
+		case !authz.Known(authz.Permission(p)):
+			problems = append(problems, fmt.Sprintf("unknown permission %q", p))
+		case !seen[p]:
+			seen[p] = true
+			cleaned = append(cleaned, p)
+		}
+	}
+	if len(problems) > 0 {
+		return operationsdto.RolePermissions{}, fmt.Errorf("%w: %s", ErrInvalidRolePermissions, strings.Join(problems, "; "))
+	}
+	sort.Strings(cleaned)
+
+	role, err := s.repo.ReplaceRolePermissions(ctx, roleID, cleaned, actor)
+	if err != nil {
+		logger.Error("failed to set role permissions", slog.Any("error", err))
+		return operationsdto.RolePermissions{}, fmt.Errorf("operations: set role permissions: %w", err)
+	}
+	return operationsdto.RolePermissions{
+		RoleID:      role.RoleID,
+		RoleName:    common.PgtypeTextToString(role.RoleName),
+		RoleDesc:    common.PgtypeTextToStringPtr(role.RoleDesc),
+		Permissions: cleaned,
+	}, nil
+}
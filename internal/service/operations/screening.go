@@ -0,0 +1,312 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"frego-operations/internal/common"
+	sqlc "frego-operations/internal/db/sqlc"
+	"frego-operations/internal/decimal"
+	operationsdto "frego-operations/internal/dto/operations"
+	"frego-operations/internal/logging"
+	repository "frego-operations/internal/repository/operations"
+	"frego-operations/internal/screening"
+)
+
+// ScreeningReviewerRole is the role a principal needs to clear or confirm a screening match.
+const ScreeningReviewerRole = "ops-compliance"
+
+// Screening result statuses. Clear and PotentialMatch come from screening; a reviewer moves a
+// potential match to Cleared or Confirmed, and rescreening supersedes the open results.
+const (
+	ScreeningClear          = "Clear"
+	ScreeningPotentialMatch = "PotentialMatch"
+	ScreeningCleared        = "Cleared"
+	ScreeningConfirmed      = "Confirmed"
+)
+
+var (
+	// ErrScreeningUnavailable indicates screening is not configured.
+	ErrScreeningUnavailable = errors.New("operations: screening unavailable")
+	// ErrScreeningUnresolved indicates a job with potential or confirmed denied-party matches.
+	ErrScreeningUnresolved = errors.New("operations: unresolved screening match")
+	// ErrScreeningForbidden indicates the caller lacks the role to resolve a screening match.
+	ErrScreeningForbidden = errors.New("operations: screening resolution forbidden")
+	// ErrScreeningResolved indicates a screening result that is not an open potential match.
+	ErrScreeningResolved = errors.New("operations: screening match already resolved")
+	// ErrInvalidScreening indicates a screening resolution without a note.
+	ErrInvalidScreening = errors.New("operations: invalid screening resolution")
+)
+
+// screeningKey identifies a listed entry matched against a party, so a reviewer's decision on it
+// carries over to later screenings.
+type screeningKey struct {
+	partyID uuid.UUID
+	list    string
+	entryID string
+}
+
+// ScreenJob screens the job's customer, agent and participants against every loaded list and
+// records the results, replacing the job's open ones. Matches a reviewer already cleared or
+// confirmed are not raised again.
+func (s *Service) ScreenJob(ctx context.Context, jobID uuid.UUID, actor string) ([]operationsdto.PartyScreening, error) {
+	logger := logging.FromContext(ctx)
+	logger.Info("screening job parties", slog.String("jobID", jobID.String()))
+
+	if s.screener == nil {
+		return nil, fmt.Errorf("%w: no denied-party lists are configured", ErrScreeningUnavailable)
+	}
+	if _, err := s.repo.GetJob(ctx, jobID); err != nil {
+		return nil, fmt.Errorf("operations: screen job: %w", err)
+	}
+	parties, err := s.repo.ListJobScreeningParties(ctx, jobID)
+	if err != nil {
+		logger.Error("failed to list job parties for screening", slog.Any("error", err))
+		return nil, fmt.Errorf("operations: screen job: %w", err)
+	}
+	resolvedRows, err := s.repo.ListResolvedScreeningMatches(ctx, jobID)
+	if err != nil {
+		logger.Error("failed to list resolved screening matches", slog.Any("error", err))
+		return nil, fmt.Errorf("operations: screen job: %w", err)
+	}
+	resolved := make(map[screeningKey]bool, len(resolvedRows))
+	for _, row := range resolvedRows {
+		resolved[screeningKey{row.PartyID, row.ListName, row.EntryID.String}] = true
+	}
+
+	actorText := pgtype.Text{String: actor, Valid: actor != ""}
+	var results []sqlc.CreatePartyScreeningParams
+	for _, party := range parties {
+		lists, matches := s.screener.Screen(party.Name)
+		for _, list := range lists {
+			raised := false
+			for _, m := range matches {
+				if m.List != list.Name {
+					continue
+				}
+				raised = true
+				if resolved[screeningKey{party.ID, m.List, m.EntryID}] {
+					continue
+				}
+				results = append(results, sqlc.CreatePartyScreeningParams{
+					JobID:       jobID,
+					PartyID:     party.ID,
+					PartyRole:   party.PartyRole,
+					PartyName:   party.Name,
+					ListName:    list.Name,
+					ListVersion: list.Version,
+					EntryID:     pgtype.Text{String: m.EntryID, Valid: m.EntryID != ""},
+					EntryName:   pgtype.Text{String: m.EntryName, Valid: m.EntryName != ""},
+					MatchedName: pgtype.Text{String: m.MatchedName, Valid: m.MatchedName != ""},
+					Program:     pgtype.Text{String: m.Program, Valid: m.Program != ""},
+					Score:       screeningScore(m.Score),
+					Status:      ScreeningPotentialMatch,
+					Actor:       actorText,
+				})
+			}
+			if !raised {
+				results = append(results, sqlc.CreatePartyScreeningParams{
+					JobID:       jobID,
+					PartyID:     party.ID,
+					PartyRole:   party.PartyRole,
+					PartyName:   party.Name,
+					ListName:    list.Name,
+					ListVersion: list.Version,
+					Status:      ScreeningClear,
+					Actor:       actorText,
+				})
+			}
+		}
+	}
+
+	rows, err := s.repo.RecordJobScreening(ctx, jobID, actor, results)
+	if err != nil {
+		logger.Error("failed to record job screening", slog.Any("error", err))
+		return nil, fmt.Errorf("operations: screen job: %w", err)
+	}
+
+	result := make([]operationsdto.PartyScreening, 0, len(rows))
+	potential := 0
+	for _, row := range rows {
+		if row.Status == ScreeningPotentialMatch {
+			potential++
+		}
+		result = append(result, partyScreeningFromSqlc(row))
+	}
+	logger.Info("screened job parties", slog.Int("parties", len(parties)), slog.Int("potentialMatches", potential))
+	return result, nil
+}
+
+// ListJobScreenings returns the job's current screening results.
+func (s *Service) ListJobScreenings(ctx context.Context, jobID uuid.UUID) ([]operationsdto.PartyScreening, error) {
+	if err := s.ensureJobVisible(ctx, jobID); err != nil {
+		return nil, err
+	}
+	rows, err := s.repo.ListJobScreenings(ctx, jobID)
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to list job screenings", slog.Any("error", err))
+		return nil, fmt.Errorf("operations: list job screenings: %w", err)
+	}
+	result := make([]operationsdto.PartyScreening, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, partyScreeningFromSqlc(row))
+	}
+	return result, nil
+}
+
+// ResolveScreening records a reviewer's decision on a potential match: cleared as a false
+// positive, or confirmed as the listed party. The caller needs ScreeningReviewerRole and a note.
+// A confirmed match keeps the job from being activated.
+func (s *Service) ResolveScreening(ctx context.Context, id uuid.UUID, confirm bool, note, actor string) (operationsdto.PartyScreening, error) {
+	logger := logging.FromContext(ctx)
+
+	principal, _ := common.PrincipalFromContext(ctx)
+	if !principal.HasRole(ScreeningReviewerRole) {
+		return operationsdto.PartyScreening{}, fmt.Errorf("%w: role %q is required", ErrScreeningForbidden, ScreeningReviewerRole)
+	}
+	note = strings.TrimSpace(note)
+	if note == "" {
+		return operationsdto.PartyScreening{}, fmt.Errorf("%w: a note is required", ErrInvalidScreening)
+	}
+
+	current, err := s.repo.GetPartyScreening(ctx, id)
+	if err != nil {
+		return operationsdto.PartyScreening{}, fmt.Errorf("operations: resolve screening: %w", err)
+	}
+	if err := s.ensureJobVisible(ctx, current.JobID); err != nil {
+		return operationsdto.PartyScreening{}, err
+	}
+
+	status := ScreeningCleared
+	if confirm {
+		status = ScreeningConfirmed
+	}
+	err = s.repo.ResolvePartyScreening(ctx, sqlc.ResolvePartyScreeningParams{
+		Status: status,
+		Actor:  pgtype.Text{String: actor, Valid: actor != ""},
+		Note:   pgtype.Text{String: note, Valid: true},
+		ID:     id,
+	})
+	if errors.Is(err, repository.ErrScreeningResolved) {
+		return operationsdto.PartyScreening{}, ErrScreeningResolved
+	}
+	if err != nil {
+		logger.Error("failed to resolve screening match", slog.Any("error", err))
+		return operationsdto.PartyScreening{}, fmt.Errorf("operations: resolve screening: %w", err)
+	}
+
+	row, err := s.repo.GetPartyScreening(ctx, id)
+	if err != nil {
+		return operationsdto.PartyScreening{}, fmt.Errorf("operations: resolve screening: %w", err)
+	}
+	logger.Info("resolved screening match", slog.String("screeningID", id.String()), slog.String("status", status))
+	return partyScreeningFromSqlc(row), nil
+}
+
+// ScreeningLists returns the loaded denied-party lists.
+func (s *Service) ScreeningLists() ([]operationsdto.ScreeningList, error) {
+	if s.screener == nil {
+		return nil, fmt.Errorf("%w: no denied-party lists are configured", ErrScreeningUnavailable)
+	}
+	return screeningListsToDTO(s.screener.Lists()), nil
+}
+
+// ReloadScreeningLists re-reads the denied-party lists from disk. Results already recorded keep
+// the list version they were made against.
+func (s *Service) ReloadScreeningLists(ctx context.Context) ([]operationsdto.ScreeningList, error) {
+	if s.screener == nil {
+		return nil, fmt.Errorf("%w: no denied-party lists are configured", ErrScreeningUnavailable)
+	}
+	lists, err := s.screener.Reload()
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to reload denied-party lists", slog.Any("error", err))
+		return nil, fmt.Errorf("operations: reload screening lists: %w", err)
+	}
+	logging.FromContext(ctx).Info("reloaded denied-party lists", slog.Int("lists", len(lists)))
+	return screeningListsToDTO(lists), nil
+}
+
+// ensureScreeningClear refuses the job while any match is unresolved or confirmed. Callers
+// screen the job again first. Without configured lists there is nothing to check.
+func (s *Service) ensureScreeningClear(ctx context.Context, jobID uuid.UUID) error {
+	if s.screener == nil {
+		return nil
+	}
+	blocking, err := s.repo.CountBlockingScreenings(ctx, jobID)
+	if err != nil {
+		return fmt.Errorf("operations: check screening: %w", err)
+	}
+	if blocking > 0 {
+		return fmt.Errorf("%w: %d denied-party match(es) must be cleared before the job can be activated", ErrScreeningUnresolved, blocking)
+	}
+	return nil
+}
+
+// screenPartyNames checks parties not yet stored on a job, such as those of a job created as
+// Active. Any potential match refuses the request: the job must be saved first so the match can
+// be recorded and reviewed.
+func (s *Service) screenPartyNames(statuses []partyStatus) error {
+	if s.screener == nil {
+		return nil
+	}
+	var problems []string
+	for _, p := range statuses {
+		if _, matches := s.screener.Screen(p.name); len(matches) > 0 {
+			problems = append(problems, fmt.Sprintf("%s %q resembles %q on %s", p.role, p.name, matches[0].MatchedName, matches[0].List))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s; save the job as a draft and screen it for review", ErrScreeningUnresolved, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func screeningScore(score float64) pgtype.Numeric {
+	d, err := decimal.Parse(strconv.FormatFloat(score, 'f', 4, 64))
+	if err != nil {
+		return pgtype.Numeric{}
+	}
+	return numericFromDecimal(&d)
+}
+
+func screeningListsToDTO(lists []screening.List) []operationsdto.ScreeningList {
+	result := make([]operationsdto.ScreeningList, 0, len(lists))
+	for _, list := range lists {
+		result = append(result, operationsdto.ScreeningList{
+			Name:    list.Name,
+			Version: list.Version,
+			Entries: len(list.Entries),
+		})
+	}
+	return result
+}
+
+func partyScreeningFromSqlc(row sqlc.OpsPartyScreening) operationsdto.PartyScreening {
+	return operationsdto.PartyScreening{
+		ID:             row.ID,
+		JobID:          row.JobID,
+		PartyID:        row.PartyID,
+		PartyRole:      row.PartyRole,
+		PartyName:      row.PartyName,
+		ListName:       row.ListName,
+		ListVersion:    row.ListVersion,
+		EntryID:        textToStringPtr(row.EntryID),
+		EntryName:      textToStringPtr(row.EntryName),
+		MatchedName:    textToStringPtr(row.MatchedName),
+		Program:        textToStringPtr(row.Program),
+		Score:          decimalFromNumeric(row.Score),
+		Status:         row.Status,
+		ScreenedAt:     timeFromTimestamptz(row.ScreenedAt),
+		ScreenedBy:     textToStringPtr(row.ScreenedBy),
+		ResolvedAt:     timeFromTimestamptz(row.ResolvedAt),
+		ResolvedBy:     textToStringPtr(row.ResolvedBy),
+		ResolutionNote: textToStringPtr(row.ResolutionNote),
+	}
+}
@@ -14,16 +14,23 @@ import (
 	operationsdto "frego-operations/internal/dto/operations"
 	"frego-operations/internal/logging"
 	repository "frego-operations/internal/repository/operations"
+	"frego-operations/internal/screening"
+	"frego-operations/internal/storage"
 )
 
 // Service orchestrates business logic for operations.
 type Service struct {
-	repo *repository.Repository
+	repo      *repository.Repository
+	documents storage.DocumentUploader
+	screener  *screening.Screener
 }
 
-func New(repo *repository.Repository) *Service {
+// New creates the operations service. A nil screener disables restricted-party screening.
+func New(repo *repository.Repository, documents storage.DocumentUploader, screener *screening.Screener) *Service {
 	return &Service{
-		repo: repo,
+		repo:      repo,
+		documents: documents,
+		screener:  screener,
 	}
 }
 
@@ -159,11 +166,21 @@ func (s *Service) GetLookups(ctx context.Context) (operationsdto.OperationsLooku
 	}
 	for _, row := range activityRows {
 		result.Activities = append(result.Activities, operationsdto.ActivityLookup{
-			ActivityType: row.ActivityType,
-			ActivityCode: row.ActivityCode,
+			ActivityType:          row.ActivityType,
+			ActivityCode:          row.ActivityCode,
+			CostSegment:           textToStringPtr(row.CostSegment),
+			IATAChargeCode:        textToStringPtr(row.IataChargeCode),
+			IATAChargeEntitlement: textToStringPtr(row.IataChargeEntitlement),
+			IATARateClass:         textToStringPtr(row.IataRateClass),
 		})
 	}
 
+	// Fetch incoterms
+	result.Incoterms, err = s.ListIncoterms(ctx)
+	if err != nil {
+		return result, err
+	}
+
 	logger.Info("fetched operations lookups",
 		slog.Int("transport_modes", len(result.TransportModes)),
 		slog.Int("incoterms", len(result.Incoterms)),
@@ -265,12 +282,13 @@ func (s *Service) GetJob(ctx context.Context, jobID uuid.UUID) (operationsdto.Jo
 	logger := logging.FromContext(ctx)
 	logger.Info("fetching job", slog.String("jobID", jobID.String()))
 
-	// Fetch job
-	job, err := s.repo.GetJob(ctx, jobID)
+	// Fetch the job and its related records from one snapshot
+	loaded, err := s.repo.GetJobDetail(ctx, jobID)
 	if err != nil {
 		logger.Error("failed to get job", slog.Any("error", err))
 		return operationsdto.JobDetail{}, fmt.Errorf("operations: get job: %w", err)
 	}
+	job := loaded.Job
 
 	detail := operationsdto.JobDetail{
 		ID:                 job.ID,
@@ -330,41 +348,24 @@ func (s *Service) GetJob(ctx context.Context, jobID uuid.UUID) (operationsdto.Jo
 		Provisions:        []operationsdto.Provision{},
 	}
 
-	// Fetch packages
-	packages, _ := s.repo.ListJobPackages(ctx, jobID)
-	for _, pkg := range packages {
+	for _, pkg := range loaded.Packages {
 		detail.Packages = append(detail.Packages, packageFromSqlc(pkg))
 	}
-
-	// Fetch carriers
-	carriers, err := s.repo.GetJobCarriers(ctx, jobID)
-	if err == nil && len(carriers) > 0 {
-		c := carrierFromSqlc(carriers[0])
+	if len(loaded.Carriers) > 0 {
+		c := carrierFromSqlc(loaded.Carriers[0])
 		detail.Carrier = &c
 	}
-
-	// Fetch documents
-	docs, _ := s.repo.ListJobDocuments(ctx, jobID)
-	for _, doc := range docs {
+	for _, doc := range loaded.Documents {
 		detail.Documents = append(detail.Documents, documentFromSqlc(doc))
 	}
-
-	// Fetch billing
-	billings, _ := s.repo.ListJobBilling(ctx, jobID)
-	for _, b := range billings {
+	for _, b := range loaded.Billing {
 		detail.Billing = append(detail.Billing, billingFromSqlc(b))
 	}
-
-	// Fetch provisions
-	provisions, _ := s.repo.ListJobProvisions(ctx, jobID)
-	for _, p := range provisions {
+	for _, p := range loaded.Provisions {
 		detail.Provisions = append(detail.Provisions, provisionFromSqlc(p))
 	}
-
-	// Fetch tracking
-	tracking, err := s.repo.GetJobTracking(ctx, jobID)
-	if err == nil {
-		t := trackingFromSqlc(tracking)
+	if loaded.Tracking != nil {
+		t := trackingFromSqlc(*loaded.Tracking)
 		detail.Tracking = &t
 	}
 
@@ -376,6 +377,41 @@ func (s *Service) GetJob(ctx context.Context, jobID uuid.UUID) (operationsdto.Jo
 func (s *Service) CreateJob(ctx context.Context, input operationsdto.CreateJobInput) (operationsdto.JobDetail, error) {
 	logger := logging.FromContext(ctx)
 
+	if err := checkJobScope(ctx, input.BranchID, input.SalesExecutiveID, true); err != nil {
+		return operationsdto.JobDetail{}, err
+	}
+
+	// Jobs created without charges start from their service's charge template; the lines it
+	// cannot complete come back with the job as a proposal
+	var proposal *operationsdto.ChargeProposal
+	if len(input.Billing) == 0 && len(input.Provisions) == 0 {
+		proposal = s.applyChargeTemplate(ctx, &input)
+	}
+
+	billed, provisioned, err := s.priceCharges(ctx, input.Billing, input.Provisions)
+	if err != nil {
+		return operationsdto.JobDetail{}, err
+	}
+
+	// Refuse blacklisted or inactive parties and check the customer's credit
+	statuses, err := s.partyStatuses(ctx, jobInputParties(input.CustomerID, input.AgentID, input.Carrier, input.Billing, input.Provisions))
+	if err != nil {
+		return operationsdto.JobDetail{}, err
+	}
+	if err := ineligibleParties(statuses); err != nil {
+		return operationsdto.JobDetail{}, err
+	}
+	creditOverride, err := s.checkCreditLimit(ctx, input.CustomerID, nil, billedTotal(billed))
+	if err != nil {
+		return operationsdto.JobDetail{}, err
+	}
+	activating := input.Status != nil && *input.Status == jobStatusActive
+	if activating {
+		if err := s.screenPartyNames(statuses); err != nil {
+			return operationsdto.JobDetail{}, err
+		}
+	}
+
 	// Generate job code (always auto-generated)
 	jobCode, err := s.generateJobCode(ctx)
 	if err != nil {
@@ -419,10 +455,44 @@ func (s *Service) CreateJob(ctx context.Context, input operationsdto.CreateJobIn
 		Actor:              pgtype.Text{String: input.CreatedBy, Valid: true},
 	}
 
-	job, err := s.repo.CreateJob(ctx, jobParams)
+	var jobID uuid.UUID
+	err = s.repo.InTx(ctx, func(ctx context.Context) error {
+		var err error
+		jobID, err = s.createJob(ctx, jobParams, input, billed, provisioned, creditOverride)
+		return err
+	})
+	if err != nil {
+		return operationsdto.JobDetail{}, err
+	}
+
+	// Keep a screening record for a job that starts out Active
+	if activating && s.screener != nil {
+		if _, err := s.ScreenJob(ctx, jobID, input.CreatedBy); err != nil {
+			logger.Warn("failed to record job screening", slog.Any("error", err))
+		}
+	}
+
+	logger.Info("created job", slog.String("jobID", jobID.String()))
+	detail, err := s.GetJob(ctx, jobID)
+	if err != nil {
+		return operationsdto.JobDetail{}, err
+	}
+	detail.ChargeProposal = proposal
+	return detail, nil
+}
+
+// createJob writes a new job and everything attached to it inside the caller's transaction, so a
+// failed write leaves no partial job behind.
+func (s *Service) createJob(ctx context.Context, params sqlc.CreateJobParams, input operationsdto.CreateJobInput, billed, provisioned []chargeAmounts, creditOverride *sqlc.CreateCreditOverrideParams) (uuid.UUID, error) {
+	logger := logging.FromContext(ctx)
+
+	job, err := s.repo.CreateJob(ctx, params)
 	if err != nil {
 		logger.Error("failed to create job", slog.Any("error", err))
-		return operationsdto.JobDetail{}, fmt.Errorf("operations: create job: %w", err)
+		return uuid.Nil, fmt.Errorf("operations: create job: %w", err)
+	}
+	if err := s.recordCreditOverride(ctx, job.ID, creditOverride, input.CreatedBy); err != nil {
+		return uuid.Nil, err
 	}
 
 	// Create packages
@@ -432,22 +502,22 @@ func (s *Service) CreateJob(ctx context.Context, input operationsdto.CreateJobIn
 			ContainerNo:               repository.NullTextFromString(pkgInput.ContainerNo),
 			ContainerType:             repository.NullTextFromString(pkgInput.ContainerType),
 			ContainerSize:             repository.NullTextFromString(pkgInput.ContainerSize),
-			GrossWeightKg:             numericFromFloat64(pkgInput.GrossWeightKg),
-			NetWeightKg:               numericFromFloat64(pkgInput.NetWeightKg),
-			Volume:                    numericFromFloat64(pkgInput.Volume),
+			GrossWeightKg:             numericFromDecimal(pkgInput.GrossWeightKg),
+			NetWeightKg:               numericFromDecimal(pkgInput.NetWeightKg),
+			Volume:                    numericFromDecimal(pkgInput.Volume),
 			CarrierSealNo:             repository.NullTextFromString(pkgInput.CarrierSealNo),
 			CommodityCargoDescription: repository.NullTextFromString(pkgInput.CommodityCargoDescription),
 			PackageType:               repository.NullTextFromString(pkgInput.PackageType),
 			CargoType:                 repository.NullTextFromString(pkgInput.CargoType),
-			NoOfPackages:              numericFromFloat64(pkgInput.NoOfPackages),
-			ChargeableWeight:          numericFromFloat64(pkgInput.ChargeableWeight),
+			NoOfPackages:              numericFromDecimal(pkgInput.NoOfPackages),
+			ChargeableWeight:          numericFromDecimal(pkgInput.ChargeableWeight),
 			HsCode:                    repository.NullTextFromString(pkgInput.HSCode),
 			TemperatureControl:        pgtype.Bool{Bool: pkgInput.TemperatureControl, Valid: true},
 			Actor:                     pgtype.Text{String: input.CreatedBy, Valid: true},
 		}
-		_, err = s.repo.CreateJobPackage(ctx, pkgParams)
-		if err != nil {
-			logger.Warn("failed to create package", slog.Any("error", err))
+		if _, err := s.repo.CreateJobPackage(ctx, pkgParams); err != nil {
+			logger.Error("failed to create package", slog.Any("error", err))
+			return uuid.Nil, fmt.Errorf("operations: create job package: %w", err)
 		}
 	}
 
@@ -474,15 +544,16 @@ func (s *Service) CreateJob(ctx context.Context, input operationsdto.CreateJobIn
 			DestinationCountry:         repository.NullTextFromString(input.Carrier.DestinationCountry),
 			AccountingInfo:             repository.NullTextFromString(input.Carrier.AccountingInfo),
 			HandlingInfo:               repository.NullTextFromString(input.Carrier.HandlingInfo),
+			ChargesCode:                chargesCode(input.Carrier.ChargesCode),
 			TransportDocumentReference: repository.NullTextFromString(input.Carrier.TransportDocumentRef),
 			DocUrls:                    input.Carrier.SupportingDocURLs,
 			FileRegion:                 repository.NullTextFromString(input.Carrier.FileRegion),
 			Description:                repository.NullTextFromString(input.Carrier.Description),
 			Actor:                      pgtype.Text{String: input.CreatedBy, Valid: true},
 		}
-		_, err = s.repo.CreateJobCarrier(ctx, carrierParams)
-		if err != nil {
-			logger.Warn("failed to create carrier", slog.Any("error", err))
+		if _, err := s.repo.CreateJobCarrier(ctx, carrierParams); err != nil {
+			logger.Error("failed to create carrier", slog.Any("error", err))
+			return uuid.Nil, fmt.Errorf("operations: create job carrier: %w", err)
 		}
 	}
 
@@ -499,14 +570,15 @@ func (s *Service) CreateJob(ctx context.Context, input operationsdto.CreateJobIn
 			FileRegion:  repository.NullTextFromString(docInput.FileRegion),
 			Actor:       pgtype.Text{String: input.CreatedBy, Valid: true},
 		}
-		_, err = s.repo.CreateJobDocument(ctx, docParams)
-		if err != nil {
-			logger.Warn("failed to create document", slog.Any("error", err))
+		if _, err := s.repo.CreateJobDocument(ctx, docParams); err != nil {
+			logger.Error("failed to create document", slog.Any("error", err))
+			return uuid.Nil, fmt.Errorf("operations: create job document: %w", err)
 		}
 	}
 
 	// Create billing entries
-	for _, billInput := range input.Billing {
+	for i, billInput := range input.Billing {
+		amounts := billed[i]
 		billParams := sqlc.CreateJobBillingParams{
 			JobID:                 uuidToPgtype(job.ID),
 			ActivityType:          repository.NullTextFromString(billInput.ActivityType),
@@ -515,28 +587,30 @@ func (s *Service) CreateJob(ctx context.Context, input operationsdto.CreateJobIn
 			PoNumber:              repository.NullTextFromString(billInput.PONumber),
 			PoDate:                timestampFromTime(billInput.PODate),
 			CurrencyCode:          repository.NullTextFromString(billInput.CurrencyCode),
-			Quantity:              numericFromFloat64(billInput.Quantity),
-			UnitPrice:             numericFromFloat64(billInput.UnitPrice),
-			AmountWithoutTax:      numericFromFloat64(billInput.AmountWithoutTax),
+			Quantity:              numericFromDecimal(&amounts.Quantity),
+			UnitPrice:             numericFromDecimal(billInput.UnitPrice),
+			AmountWithoutTax:      numericFromDecimal(&amounts.AmountWithoutTax),
 			TaxCode:               repository.NullTextFromString(billInput.TaxCode),
-			TaxAmount:             numericFromFloat64(billInput.TaxAmount),
-			ExchangeRate:          numericFromFloat64(billInput.ExchangeRate),
-			TotalAmount:           numericFromFloat64(billInput.TotalAmount),
+			TaxAmount:             numericFromDecimal(&amounts.TaxAmount),
+			ExchangeRate:          numericFromDecimal(&amounts.ExchangeRate),
+			TotalAmount:           numericFromDecimal(&amounts.TotalAmount),
 			Description:           repository.NullTextFromString(billInput.Description),
 			Notes:                 repository.NullTextFromString(billInput.Notes),
 			DocUrls:               billInput.SupportingDocURLs,
 			FileRegion:            repository.NullTextFromString(billInput.FileRegion),
-			AmountPrimaryCurrency: numericFromFloat64(billInput.AmountPrimaryCurrency),
+			AmountPrimaryCurrency: numericFromDecimal(&amounts.AmountPrimaryCurrency),
 			Actor:                 pgtype.Text{String: input.CreatedBy, Valid: true},
 		}
-		_, err = s.repo.CreateJobBilling(ctx, billParams)
-		if err != nil {
-			logger.Warn("failed to create billing", slog.Any("error", err))
+		if _, err := s.repo.CreateJobBilling(ctx, billParams); err != nil {
+			logger.Error("failed to create billing", slog.Any("error", err))
+			return uuid.Nil, fmt.Errorf("operations: create job billing: %w", err)
 		}
 	}
 
 	// Create provision entries
-	for _, provInput := range input.Provisions {
+	var provisions []sqlc.OpsProvision
+	for i, provInput := range input.Provisions {
+		amounts := provisioned[i]
 		provParams := sqlc.CreateJobProvisionParams{
 			JobID:                 uuidToPgtype(job.ID),
 			ActivityType:          repository.NullTextFromString(provInput.ActivityType),
@@ -545,26 +619,39 @@ func (s *Service) CreateJob(ctx context.Context, input operationsdto.CreateJobIn
 			InvoiceNumber:         repository.NullTextFromString(provInput.InvoiceNumber),
 			InvoiceDate:           timestampFromTime(provInput.InvoiceDate),
 			CurrencyCode:          repository.NullTextFromString(provInput.CurrencyCode),
-			Quantity:              numericFromFloat64(provInput.Quantity),
-			UnitPrice:             numericFromFloat64(provInput.UnitPrice),
-			AmountWithoutTax:      numericFromFloat64(provInput.AmountWithoutTax),
+			Quantity:              numericFromDecimal(&amounts.Quantity),
+			UnitPrice:             numericFromDecimal(provInput.UnitPrice),
+			AmountWithoutTax:      numericFromDecimal(&amounts.AmountWithoutTax),
 			TaxCode:               repository.NullTextFromString(provInput.TaxCode),
-			TaxAmount:             numericFromFloat64(provInput.TaxAmount),
-			TotalAmount:           numericFromFloat64(provInput.TotalAmount),
+			TaxAmount:             numericFromDecimal(&amounts.TaxAmount),
+			TotalAmount:           numericFromDecimal(&amounts.TotalAmount),
 			PoNumber:              repository.NullTextFromString(provInput.PONumber),
 			PoDate:                timestampFromTime(provInput.PODate),
-			ExchangeRate:          numericFromFloat64(provInput.ExchangeRate),
+			ExchangeRate:          numericFromDecimal(&amounts.ExchangeRate),
 			PaymentPriority:       repository.NullTextFromString(provInput.PaymentPriority),
 			Notes:                 repository.NullTextFromString(provInput.Notes),
 			DocUrls:               provInput.SupportingDocURLs,
 			FileRegion:            repository.NullTextFromString(provInput.FileRegion),
-			AmountPrimaryCurrency: numericFromFloat64(provInput.AmountPrimaryCurrency),
-			Profit:                numericFromFloat64(provInput.Profit),
+			AmountPrimaryCurrency: numericFromDecimal(&amounts.AmountPrimaryCurrency),
 			Actor:                 pgtype.Text{String: input.CreatedBy, Valid: true},
 		}
-		_, err = s.repo.CreateJobProvision(ctx, provParams)
+		provision, err := s.repo.CreateJobProvision(ctx, provParams)
 		if err != nil {
-			logger.Warn("failed to create provision", slog.Any("error", err))
+			logger.Error("failed to create provision", slog.Any("error", err))
+			return uuid.Nil, fmt.Errorf("operations: create job provision: %w", err)
+		}
+		provisions = append(provisions, provision)
+	}
+	if err := s.routeProvisionApprovals(ctx, provisions, input.CreatedBy); err != nil {
+		logger.Error("failed to route provision approvals", slog.Any("error", err))
+		return uuid.Nil, err
+	}
+
+	// Derive provision profit against the job's billing
+	if len(input.Billing) > 0 || len(input.Provisions) > 0 {
+		if err := s.recalculateProfit(ctx, job.ID, input.CreatedBy); err != nil {
+			logger.Error("failed to recalculate profit", slog.Any("error", err))
+			return uuid.Nil, err
 		}
 	}
 
@@ -581,14 +668,13 @@ func (s *Service) CreateJob(ctx context.Context, input operationsdto.CreateJobIn
 			Notes:          repository.NullTextFromString(input.Tracking.Notes),
 			Actor:          pgtype.Text{String: input.CreatedBy, Valid: true},
 		}
-		_, err = s.repo.UpsertJobTracking(ctx, trackParams)
-		if err != nil {
-			logger.Warn("failed to create tracking", slog.Any("error", err))
+		if _, err := s.repo.UpsertJobTracking(ctx, trackParams); err != nil {
+			logger.Error("failed to create tracking", slog.Any("error", err))
+			return uuid.Nil, fmt.Errorf("operations: create job tracking: %w", err)
 		}
 	}
 
-	logger.Info("created job", slog.String("jobID", job.ID.String()))
-	return s.GetJob(ctx, job.ID)
+	return job.ID, nil
 }
 
 // UpdateJob updates an existing job.
@@ -596,6 +682,79 @@ func (s *Service) UpdateJob(ctx context.Context, jobID uuid.UUID, input operatio
 	logger := logging.FromContext(ctx)
 	logger.Info("updating job", slog.String("jobID", jobID.String()))
 
+	// Activation screens the job again. The results are kept even when the update is refused, so
+	// they are recorded outside its transaction
+	if input.Status != nil && *input.Status == jobStatusActive && s.screener != nil {
+		if _, err := s.ScreenJob(ctx, jobID, input.ModifiedBy); err != nil {
+			return operationsdto.JobDetail{}, err
+		}
+	}
+
+	err := s.repo.InTx(ctx, func(ctx context.Context) error {
+		return s.updateJob(ctx, jobID, input)
+	})
+	if err != nil {
+		return operationsdto.JobDetail{}, err
+	}
+
+	logger.Info("updated job", slog.String("jobID", jobID.String()))
+	return s.GetJob(ctx, jobID)
+}
+
+// updateJob writes an update inside the caller's transaction, which holds the job's row lock
+// from ensureJobOpen until every change is committed.
+func (s *Service) updateJob(ctx context.Context, jobID uuid.UUID, input operationsdto.UpdateJobInput) error {
+	logger := logging.FromContext(ctx)
+
+	current, err := s.ensureJobOpen(ctx, jobID)
+	if err != nil {
+		return err
+	}
+	if err := checkJobScope(ctx, input.BranchID, input.SalesExecutiveID, false); err != nil {
+		return err
+	}
+
+	billed, provisioned, err := s.priceCharges(ctx, input.Billing, input.Provisions)
+	if err != nil {
+		return err
+	}
+
+	// Refuse blacklisted or inactive parties being added; activating the job also rechecks the
+	// parties already on it and the customer's credit
+	statuses, err := s.partyStatuses(ctx, jobInputParties(input.CustomerID, input.AgentID, input.Carrier, input.Billing, input.Provisions))
+	if err != nil {
+		return err
+	}
+	activating := input.Status != nil && *input.Status == jobStatusActive && current.Status.String != jobStatusActive
+	var creditOverride *sqlc.CreateCreditOverrideParams
+	if activating {
+		existing, err := s.jobPartyStatuses(ctx, jobID)
+		if err != nil {
+			return err
+		}
+		statuses = append(statuses, existing...)
+	}
+	if err := ineligibleParties(statuses); err != nil {
+		return err
+	}
+	if activating {
+		customerID := input.CustomerID
+		if customerID == nil {
+			customerID = uuidFromPgtype(current.CustomerID)
+		}
+		creditOverride, err = s.checkCreditLimit(ctx, customerID, &jobID, billedTotal(billed))
+		if err != nil {
+			return err
+		}
+		// Parties named in this request are not on the job yet, so they are screened by name
+		if err := s.screenPartyNames(statuses); err != nil {
+			return err
+		}
+		if err := s.ensureScreeningClear(ctx, jobID); err != nil {
+			return err
+		}
+	}
+
 	params := sqlc.UpdateJobParams{
 		ID:                 jobID,
 		EnquiryNumber:      repository.NullTextFromString(input.EnquiryNumber),
@@ -631,10 +790,16 @@ func (s *Service) UpdateJob(ctx context.Context, jobID uuid.UUID, input operatio
 		Actor:              pgtype.Text{String: input.ModifiedBy, Valid: true},
 	}
 
-	_, err := s.repo.UpdateJob(ctx, params)
+	_, err = s.repo.UpdateJob(ctx, params)
 	if err != nil {
 		logger.Error("failed to update job", slog.Any("error", err))
-		return operationsdto.JobDetail{}, fmt.Errorf("operations: update job: %w", err)
+		return jobWriteError("update job", err)
+	}
+	if err := s.recordJobStatusChange(ctx, jobID, current.Status, input.Status, input.ModifiedBy); err != nil {
+		return err
+	}
+	if err := s.recordCreditOverride(ctx, jobID, creditOverride, input.ModifiedBy); err != nil {
+		return err
 	}
 
 	// Update packages if provided
@@ -644,22 +809,22 @@ func (s *Service) UpdateJob(ctx context.Context, jobID uuid.UUID, input operatio
 			ContainerNo:               repository.NullTextFromString(pkgInput.ContainerNo),
 			ContainerType:             repository.NullTextFromString(pkgInput.ContainerType),
 			ContainerSize:             repository.NullTextFromString(pkgInput.ContainerSize),
-			GrossWeightKg:             numericFromFloat64(pkgInput.GrossWeightKg),
-			NetWeightKg:               numericFromFloat64(pkgInput.NetWeightKg),
-			Volume:                    numericFromFloat64(pkgInput.Volume),
+			GrossWeightKg:             numericFromDecimal(pkgInput.GrossWeightKg),
+			NetWeightKg:               numericFromDecimal(pkgInput.NetWeightKg),
+			Volume:                    numericFromDecimal(pkgInput.Volume),
 			CarrierSealNo:             repository.NullTextFromString(pkgInput.CarrierSealNo),
 			CommodityCargoDescription: repository.NullTextFromString(pkgInput.CommodityCargoDescription),
 			PackageType:               repository.NullTextFromString(pkgInput.PackageType),
 			CargoType:                 repository.NullTextFromString(pkgInput.CargoType),
-			NoOfPackages:              numericFromFloat64(pkgInput.NoOfPackages),
-			ChargeableWeight:          numericFromFloat64(pkgInput.ChargeableWeight),
+			NoOfPackages:              numericFromDecimal(pkgInput.NoOfPackages),
+			ChargeableWeight:          numericFromDecimal(pkgInput.ChargeableWeight),
 			HsCode:                    repository.NullTextFromString(pkgInput.HSCode),
 			TemperatureControl:        pgtype.Bool{Bool: pkgInput.TemperatureControl, Valid: true},
 			Actor:                     pgtype.Text{String: input.ModifiedBy, Valid: true},
 		}
-		_, err = s.repo.CreateJobPackage(ctx, pkgParams)
-		if err != nil {
-			logger.Warn("failed to create package", slog.Any("error", err))
+		if _, err := s.repo.CreateJobPackage(ctx, pkgParams); err != nil {
+			logger.Error("failed to create package", slog.Any("error", err))
+			return fmt.Errorf("operations: update job package: %w", err)
 		}
 	}
 
@@ -687,6 +852,7 @@ func (s *Service) UpdateJob(ctx context.Context, jobID uuid.UUID, input operatio
 			DestinationCountry:         repository.NullTextFromString(input.Carrier.DestinationCountry),
 			AccountingInfo:             repository.NullTextFromString(input.Carrier.AccountingInfo),
 			HandlingInfo:               repository.NullTextFromString(input.Carrier.HandlingInfo),
+			ChargesCode:                chargesCode(input.Carrier.ChargesCode),
 			TransportDocumentReference: repository.NullTextFromString(input.Carrier.TransportDocumentRef),
 			DocUrls:                    input.Carrier.SupportingDocURLs,
 			FileRegion:                 repository.NullTextFromString(input.Carrier.FileRegion),
@@ -696,12 +862,16 @@ func (s *Service) UpdateJob(ctx context.Context, jobID uuid.UUID, input operatio
 		// We need the carrier ID to update. Since we don't have it in input, we assume single carrier per job.
 		// But UpdateJobCarrier requires carrier_id.
 		// Let's fetch existing carrier first.
-		carriers, _ := s.repo.GetJobCarriers(ctx, jobID)
+		carriers, err := s.repo.GetJobCarriers(ctx, jobID)
+		if err != nil {
+			logger.Error("failed to get carriers", slog.Any("error", err))
+			return fmt.Errorf("operations: update job carrier: %w", err)
+		}
 		if len(carriers) > 0 {
 			updateParams.CarrierID = carriers[0].ID
-			_, err = s.repo.UpdateJobCarrier(ctx, updateParams)
-			if err != nil {
-				logger.Warn("failed to update carrier", slog.Any("error", err))
+			if _, err := s.repo.UpdateJobCarrier(ctx, updateParams); err != nil {
+				logger.Error("failed to update carrier", slog.Any("error", err))
+				return fmt.Errorf("operations: update job carrier: %w", err)
 			}
 		} else {
 			// Create new carrier
@@ -726,15 +896,16 @@ func (s *Service) UpdateJob(ctx context.Context, jobID uuid.UUID, input operatio
 				DestinationCountry:         repository.NullTextFromString(input.Carrier.DestinationCountry),
 				AccountingInfo:             repository.NullTextFromString(input.Carrier.AccountingInfo),
 				HandlingInfo:               repository.NullTextFromString(input.Carrier.HandlingInfo),
+				ChargesCode:                chargesCode(input.Carrier.ChargesCode),
 				TransportDocumentReference: repository.NullTextFromString(input.Carrier.TransportDocumentRef),
 				DocUrls:                    input.Carrier.SupportingDocURLs,
 				FileRegion:                 repository.NullTextFromString(input.Carrier.FileRegion),
 				Description:                repository.NullTextFromString(input.Carrier.Description),
 				Actor:                      pgtype.Text{String: input.ModifiedBy, Valid: true},
 			}
-			_, err = s.repo.CreateJobCarrier(ctx, createParams)
-			if err != nil {
-				logger.Warn("failed to create carrier", slog.Any("error", err))
+			if _, err := s.repo.CreateJobCarrier(ctx, createParams); err != nil {
+				logger.Error("failed to create carrier", slog.Any("error", err))
+				return fmt.Errorf("operations: update job carrier: %w", err)
 			}
 		}
 	}
@@ -752,14 +923,15 @@ func (s *Service) UpdateJob(ctx context.Context, jobID uuid.UUID, input operatio
 			FileRegion:  repository.NullTextFromString(docInput.FileRegion),
 			Actor:       pgtype.Text{String: input.ModifiedBy, Valid: true},
 		}
-		_, err = s.repo.CreateJobDocument(ctx, docParams)
-		if err != nil {
-			logger.Warn("failed to create document", slog.Any("error", err))
+		if _, err := s.repo.CreateJobDocument(ctx, docParams); err != nil {
+			logger.Error("failed to create document", slog.Any("error", err))
+			return fmt.Errorf("operations: update job document: %w", err)
 		}
 	}
 
 	// Update billing if provided
-	for _, billInput := range input.Billing {
+	for i, billInput := range input.Billing {
+		amounts := billed[i]
 		billParams := sqlc.CreateJobBillingParams{
 			JobID:                 uuidToPgtype(jobID),
 			ActivityType:          repository.NullTextFromString(billInput.ActivityType),
@@ -768,28 +940,30 @@ func (s *Service) UpdateJob(ctx context.Context, jobID uuid.UUID, input operatio
 			PoNumber:              repository.NullTextFromString(billInput.PONumber),
 			PoDate:                timestampFromTime(billInput.PODate),
 			CurrencyCode:          repository.NullTextFromString(billInput.CurrencyCode),
-			Quantity:              numericFromFloat64(billInput.Quantity),
-			UnitPrice:             numericFromFloat64(billInput.UnitPrice),
-			AmountWithoutTax:      numericFromFloat64(billInput.AmountWithoutTax),
+			Quantity:              numericFromDecimal(&amounts.Quantity),
+			UnitPrice:             numericFromDecimal(billInput.UnitPrice),
+			AmountWithoutTax:      numericFromDecimal(&amounts.AmountWithoutTax),
 			TaxCode:               repository.NullTextFromString(billInput.TaxCode),
-			TaxAmount:             numericFromFloat64(billInput.TaxAmount),
-			ExchangeRate:          numericFromFloat64(billInput.ExchangeRate),
-			TotalAmount:           numericFromFloat64(billInput.TotalAmount),
+			TaxAmount:             numericFromDecimal(&amounts.TaxAmount),
+			ExchangeRate:          numericFromDecimal(&amounts.ExchangeRate),
+			TotalAmount:           numericFromDecimal(&amounts.TotalAmount),
 			Description:           repository.NullTextFromString(billInput.Description),
 			Notes:                 repository.NullTextFromString(billInput.Notes),
 			DocUrls:               billInput.SupportingDocURLs,
 			FileRegion:            repository.NullTextFromString(billInput.FileRegion),
-			AmountPrimaryCurrency: numericFromFloat64(billInput.AmountPrimaryCurrency),
+			AmountPrimaryCurrency: numericFromDecimal(&amounts.AmountPrimaryCurrency),
 			Actor:                 pgtype.Text{String: input.ModifiedBy, Valid: true},
 		}
-		_, err = s.repo.CreateJobBilling(ctx, billParams)
-		if err != nil {
-			logger.Warn("failed to create billing", slog.Any("error", err))
+		if _, err := s.repo.CreateJobBilling(ctx, billParams); err != nil {
+			logger.Error("failed to create billing", slog.Any("error", err))
+			return fmt.Errorf("operations: update job billing: %w", err)
 		}
 	}
 
 	// Update provisions if provided
-	for _, provInput := range input.Provisions {
+	var provisions []sqlc.OpsProvision
+	for i, provInput := range input.Provisions {
+		amounts := provisioned[i]
 		provParams := sqlc.CreateJobProvisionParams{
 			JobID:                 uuidToPgtype(jobID),
 			ActivityType:          repository.NullTextFromString(provInput.ActivityType),
@@ -798,26 +972,47 @@ func (s *Service) UpdateJob(ctx context.Context, jobID uuid.UUID, input operatio
 			InvoiceNumber:         repository.NullTextFromString(provInput.InvoiceNumber),
 			InvoiceDate:           timestampFromTime(provInput.InvoiceDate),
 			CurrencyCode:          repository.NullTextFromString(provInput.CurrencyCode),
-			Quantity:              numericFromFloat64(provInput.Quantity),
-			UnitPrice:             numericFromFloat64(provInput.UnitPrice),
-			AmountWithoutTax:      numericFromFloat64(provInput.AmountWithoutTax),
+			Quantity:              numericFromDecimal(&amounts.Quantity),
+			UnitPrice:             numericFromDecimal(provInput.UnitPrice),
+			AmountWithoutTax:      numericFromDecimal(&amounts.AmountWithoutTax),
 			TaxCode:               repository.NullTextFromString(provInput.TaxCode),
-			TaxAmount:             numericFromFloat64(provInput.TaxAmount),
-			TotalAmount:           numericFromFloat64(provInput.TotalAmount),
+			TaxAmount:             numericFromDecimal(&amounts.TaxAmount),
+			TotalAmount:           numericFromDecimal(&amounts.TotalAmount),
 			PoNumber:              repository.NullTextFromString(provInput.PONumber),
 			PoDate:                timestampFromTime(provInput.PODate),
-			ExchangeRate:          numericFromFloat64(provInput.ExchangeRate),
+			ExchangeRate:          numericFromDecimal(&amounts.ExchangeRate),
 			PaymentPriority:       repository.NullTextFromString(provInput.PaymentPriority),
 			Notes:                 repository.NullTextFromString(provInput.Notes),
 			DocUrls:               provInput.SupportingDocURLs,
 			FileRegion:            repository.NullTextFromString(provInput.FileRegion),
-			AmountPrimaryCurrency: numericFromFloat64(provInput.AmountPrimaryCurrency),
-			Profit:                numericFromFloat64(provInput.Profit),
+			AmountPrimaryCurrency: numericFromDecimal(&amounts.AmountPrimaryCurrency),
 			Actor:                 pgtype.Text{String: input.ModifiedBy, Valid: true},
 		}
-		_, err = s.repo.CreateJobProvision(ctx, provParams)
+		provision, err := s.repo.CreateJobProvision(ctx, provParams)
 		if err != nil {
-			logger.Warn("failed to create provision", slog.Any("error", err))
+			logger.Error("failed to create provision", slog.Any("error", err))
+			return fmt.Errorf("operations: update job provision: %w", err)
+		}
+		provisions = append(provisions, provision)
+	}
+	if err := s.routeProvisionApprovals(ctx, provisions, input.ModifiedBy); err != nil {
+		logger.Error("failed to route provision approvals", slog.Any("error", err))
+		return err
+	}
+
+	// Derive provision profit against the job's billing
+	if len(input.Billing) > 0 || len(input.Provisions) > 0 {
+		if err := s.recalculateProfit(ctx, jobID, input.ModifiedBy); err != nil {
+			logger.Error("failed to recalculate profit", slog.Any("error", err))
+			return err
+		}
+	}
+
+	// Closing the job freezes its exchange rates, including those of lines added above
+	if input.Status != nil && *input.Status == jobStatusClosed {
+		if _, err := s.repo.LockJobRates(ctx, jobID, input.ModifiedBy); err != nil {
+			logger.Error("failed to lock job exchange rates", slog.Any("error", err))
+			return fmt.Errorf("operations: lock job rates: %w", err)
 		}
 	}
 
@@ -834,14 +1029,13 @@ func (s *Service) UpdateJob(ctx context.Context, jobID uuid.UUID, input operatio
 			Notes:          repository.NullTextFromString(input.Tracking.Notes),
 			Actor:          pgtype.Text{String: input.ModifiedBy, Valid: true},
 		}
-		_, err = s.repo.UpsertJobTracking(ctx, trackParams)
-		if err != nil {
-			logger.Warn("failed to update tracking", slog.Any("error", err))
+		if _, err := s.repo.UpsertJobTracking(ctx, trackParams); err != nil {
+			logger.Error("failed to update tracking", slog.Any("error", err))
+			return fmt.Errorf("operations: update job tracking: %w", err)
 		}
 	}
 
-	logger.Info("updated job", slog.String("jobID", jobID.String()))
-	return s.GetJob(ctx, jobID)
+	return nil
 }
 
 // ArchiveJob soft deletes a job.
@@ -849,10 +1043,13 @@ func (s *Service) ArchiveJob(ctx context.Context, jobID uuid.UUID, actor string)
 	logger := logging.FromContext(ctx)
 	logger.Info("archiving job", slog.String("jobID", jobID.String()))
 
+	if err := s.ensureJobVisible(ctx, jobID); err != nil {
+		return err
+	}
 	err := s.repo.ArchiveJob(ctx, jobID, actor)
 	if err != nil {
 		logger.Error("failed to archive job", slog.Any("error", err))
-		return fmt.Errorf("operations: archive job: %w", err)
+		return jobWriteError("archive job", err)
 	}
 
 	logger.Info("archived job", slog.String("jobID", jobID.String()))
@@ -869,15 +1066,15 @@ func packageFromSqlc(pkg sqlc.OpsPackage) operationsdto.Package {
 		ContainerNo:               common.PgtypeTextToStringPtr(pkg.ContainerNo),
 		ContainerType:             common.PgtypeTextToStringPtr(pkg.ContainerType),
 		ContainerSize:             common.PgtypeTextToStringPtr(pkg.ContainerSize),
-		GrossWeightKg:             float64FromNumeric(pkg.GrossWeightKg),
-		NetWeightKg:               float64FromNumeric(pkg.NetWeightKg),
-		Volume:                    float64FromNumeric(pkg.Volume),
+		GrossWeightKg:             decimalFromNumeric(pkg.GrossWeightKg),
+		NetWeightKg:               decimalFromNumeric(pkg.NetWeightKg),
+		Volume:                    decimalFromNumeric(pkg.Volume),
 		CarrierSealNo:             common.PgtypeTextToStringPtr(pkg.CarrierSealNo),
 		CommodityCargoDescription: common.PgtypeTextToStringPtr(pkg.CommodityCargoDescription),
 		PackageType:               common.PgtypeTextToStringPtr(pkg.PackageType),
 		CargoType:                 common.PgtypeTextToStringPtr(pkg.CargoType),
-		NoOfPackages:              float64FromNumeric(pkg.NoOfPackages),
-		ChargeableWeight:          float64FromNumeric(pkg.ChargeableWeight),
+		NoOfPackages:              decimalFromNumeric(pkg.NoOfPackages),
+		ChargeableWeight:          decimalFromNumeric(pkg.ChargeableWeight),
 		HSCode:                    common.PgtypeTextToStringPtr(pkg.HsCode),
 		TemperatureControl:        boolFromPgtype(pkg.TemperatureControl),
 	}
@@ -913,6 +1110,7 @@ func carrierFromSqlc(carrier sqlc.OpsCarrier) operationsdto.Carrier {
 		DestinationCountry:     common.PgtypeTextToStringPtr(carrier.DestinationCountry),
 		AccountingInfo:         common.PgtypeTextToStringPtr(carrier.AccountingInfo),
 		HandlingInfo:           common.PgtypeTextToStringPtr(carrier.HandlingInfo),
+		ChargesCode:            common.PgtypeTextToStringPtr(carrier.ChargesCode),
 		TransportDocumentRef:   common.PgtypeTextToStringPtr(carrier.TransportDocumentReference),
 		SupportingDocURLs:      stringsFromStringArray(carrier.SupportingDocUrl),
 		FileRegion:             common.PgtypeTextToStringPtr(carrier.FileRegion),
@@ -947,18 +1145,19 @@ func billingFromSqlc(b sqlc.ListJobBillingRow) operationsdto.Billing {
 		PONumber:              common.PgtypeTextToStringPtr(b.PoNumber),
 		PODate:                poDate,
 		CurrencyCode:          common.PgtypeTextToStringPtr(b.CurrencyCode),
-		Quantity:              float64FromNumeric(b.Quantity),
-		UnitPrice:             float64FromNumeric(b.UnitPrice),
-		AmountWithoutTax:      float64FromNumeric(b.AmountWithoutTax),
+		Quantity:              decimalFromNumeric(b.Quantity),
+		UnitPrice:             decimalFromNumeric(b.UnitPrice),
+		AmountWithoutTax:      decimalFromNumeric(b.AmountWithoutTax),
 		TaxCode:               common.PgtypeTextToStringPtr(b.TaxCode),
-		TaxAmount:             float64FromNumeric(b.TaxAmount),
-		ExchangeRate:          float64FromNumeric(b.ExchangeRate),
-		TotalAmount:           float64FromNumeric(b.TotalAmount),
+		TaxAmount:             decimalFromNumeric(b.TaxAmount),
+		ExchangeRate:          decimalFromNumeric(b.ExchangeRate),
+		TotalAmount:           decimalFromNumeric(b.TotalAmount),
 		Description:           common.PgtypeTextToStringPtr(b.Description),
 		Notes:                 common.PgtypeTextToStringPtr(b.Notes),
 		SupportingDocURLs:     stringsFromStringArray(b.SupportingDocUrl),
 		FileRegion:            common.PgtypeTextToStringPtr(b.FileRegion),
-		AmountPrimaryCurrency: float64FromNumeric(b.AmountPrimaryCurrency),
+		AmountPrimaryCurrency: decimalFromNumeric(b.AmountPrimaryCurrency),
+		InvoiceID:             uuidFromPgtype(b.InvoiceID),
 	}
 }
 
@@ -980,21 +1179,22 @@ func provisionFromSqlc(p sqlc.ListJobProvisionsRow) operationsdto.Provision {
 		InvoiceNumber:         common.PgtypeTextToStringPtr(p.InvoiceNumber),
 		InvoiceDate:           invDate,
 		CurrencyCode:          common.PgtypeTextToStringPtr(p.CurrencyCode),
-		Quantity:              float64FromNumeric(p.Quantity),
-		UnitPrice:             float64FromNumeric(p.UnitPrice),
-		AmountWithoutTax:      float64FromNumeric(p.AmountWithoutTax),
+		Quantity:              decimalFromNumeric(p.Quantity),
+		UnitPrice:             decimalFromNumeric(p.UnitPrice),
+		AmountWithoutTax:      decimalFromNumeric(p.AmountWithoutTax),
 		TaxCode:               common.PgtypeTextToStringPtr(p.TaxCode),
-		TaxAmount:             float64FromNumeric(p.TaxAmount),
-		TotalAmount:           float64FromNumeric(p.TotalAmount),
+		TaxAmount:             decimalFromNumeric(p.TaxAmount),
+		TotalAmount:           decimalFromNumeric(p.TotalAmount),
 		PONumber:              common.PgtypeTextToStringPtr(p.PoNumber),
 		PODate:                poDate,
-		ExchangeRate:          float64FromNumeric(p.ExchangeRate),
+		ExchangeRate:          decimalFromNumeric(p.ExchangeRate),
 		PaymentPriority:       common.PgtypeTextToStringPtr(p.PaymentPriority),
 		Notes:                 common.PgtypeTextToStringPtr(p.Notes),
 		SupportingDocURLs:     stringsFromStringArray(p.SupportingDocUrl),
 		FileRegion:            common.PgtypeTextToStringPtr(p.FileRegion),
-		AmountPrimaryCurrency: float64FromNumeric(p.AmountPrimaryCurrency),
-		Profit:                float64FromNumeric(p.Profit),
+		AmountPrimaryCurrency: decimalFromNumeric(p.AmountPrimaryCurrency),
+		Profit:                decimalFromNumeric(p.Profit),
+		ApprovalStatus:        p.ApprovalStatus,
 	}
 }
 
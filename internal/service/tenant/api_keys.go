@@ -0,0 +1,328 @@
+package tenant
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"frego-operations/internal/auth"
+	"frego-operations/internal/authz"
+	"frego-operations/internal/common"
+	tenantdto "frego-operations/internal/dto/tenant"
+	"frego-operations/internal/logging"
+	"frego-operations/internal/repository/tenant"
+)
+
+// apiKeyMarker starts every API key, so keys are recognisable in logs and secret scanners.
+const apiKeyMarker = "frk_"
+
+// DefaultAPIKeyGrace is how long a rotated key keeps working when no grace period is given.
+const DefaultAPIKeyGrace = 24 * time.Hour
+
+var (
+	// ErrInvalidAPIKeyInput indicates an API key request with a missing name, unknown scopes, no
+	// branch scope or a past expiry.
+	ErrInvalidAPIKeyInput = errors.New("tenant: invalid api key request")
+	// ErrAPIKeyInactive indicates the API key was already revoked or rotated.
+	ErrAPIKeyInactive = errors.New("tenant: api key is revoked or already rotated")
+	// ErrAPIKeyScopeForbidden indicates an API key scope or branch granting more than the issuer
+	// holds.
+	ErrAPIKeyScopeForbidden = errors.New("tenant: api key scope forbidden")
+)
+
+// IssueAPIKey creates an API key for the tenant. The returned secret is not stored and cannot be
+// shown again. The caller must hold every permission the key's scopes grant and see every branch
+// the key does.
+func (s *Service) IssueAPIKey(ctx context.Context, tenantID uuid.UUID, input tenantdto.APIKeyInput, actor string) (tenantdto.IssuedAPIKey, error) {
+	record, err := validateAPIKeyInput(input)
+	if err != nil {
+		return tenantdto.IssuedAPIKey{}, err
+	}
+	if err := s.authorizeAPIKey(ctx, record); err != nil {
+		return tenantdto.IssuedAPIKey{}, err
+	}
+	secret, record, err := newAPIKey(tenantID, record, actor)
+	if err != nil {
+		return tenantdto.IssuedAPIKey{}, err
+	}
+
+	created, err := s.repo.CreateAPIKey(ctx, record)
+	if err != nil {
+		return tenantdto.IssuedAPIKey{}, fmt.Errorf("tenant: create api key: %w", err)
+	}
+	logging.FromContext(ctx).Info("api key issued",
+		slog.String("api_key_id", created.ID.String()),
+		slog.String("prefix", created.KeyPrefix),
+		slog.String("actor", actor),
+	)
+	return tenantdto.IssuedAPIKey{APIKey: apiKeyDTO(created), Key: secret}, nil
+}
+
+// ListAPIKeys returns the tenant's API keys, newest first.
+func (s *Service) ListAPIKeys(ctx context.Context, tenantID uuid.UUID) ([]tenantdto.APIKey, error) {
+	keys, err := s.repo.ListAPIKeys(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("tenant: list api keys: %w", err)
+	}
+	result := make([]tenantdto.APIKey, 0, len(keys))
+	for _, k := range keys {
+		result = append(result, apiKeyDTO(k))
+	}
+	return result, nil
+}
+
+// RevokeAPIKey stops one of the tenant's keys from authenticating.
+func (s *Service) RevokeAPIKey(ctx context.Context, tenantID, keyID uuid.UUID, actor string) (tenantdto.APIKey, error) {
+	key, err := s.repo.RevokeAPIKey(ctx, tenantID, keyID, actor)
+	if errors.Is(err, tenant.ErrAPIKeyInactive) {
+		return tenantdto.APIKey{}, ErrAPIKeyInactive
+	}
+	if err != nil {
+		return tenantdto.APIKey{}, fmt.Errorf("tenant: revoke api key: %w", err)
+	}
+	logging.FromContext(ctx).Info("api key revoked",
+		slog.String("api_key_id", key.ID.String()),
+		slog.String("actor", actor),
+	)
+	return apiKeyDTO(key), nil
+}
+
+// RotateAPIKey issues a replacement for one of the tenant's keys with the same name, scopes,
+// branches and expiry. The old key keeps working for grace so clients can switch over. As when
+// issuing, the caller must hold every permission the scopes grant and see every branch.
+func (s *Service) RotateAPIKey(ctx context.Context, tenantID, keyID uuid.UUID, grace time.Duration, actor string) (tenantdto.IssuedAPIKey, error) {
+	if grace < 0 {
+		return tenantdto.IssuedAPIKey{}, fmt.Errorf("%w: grace period cannot be negative", ErrInvalidAPIKeyInput)
+	}
+	old, err := s.repo.GetAPIKey(ctx, tenantID, keyID)
+	if err != nil {
+		return tenantdto.IssuedAPIKey{}, fmt.Errorf("tenant: rotate api key: %w", err)
+	}
+	if err := s.authorizeAPIKey(ctx, old); err != nil {
+		return tenantdto.IssuedAPIKey{}, err
+	}
+
+	secret, record, err := newAPIKey(tenantID, old, actor)
+	if err != nil {
+		return tenantdto.IssuedAPIKey{}, err
+	}
+	created, err := s.repo.RotateAPIKey(ctx, tenantID, keyID, record, time.Now().Add(grace))
+	if errors.Is(err, tenant.ErrAPIKeyInactive) {
+		return tenantdto.IssuedAPIKey{}, ErrAPIKeyInactive
+	}
+	if err != nil {
+		return tenantdto.IssuedAPIKey{}, fmt.Errorf("tenant: rotate api key: %w", err)
+	}
+	logging.FromContext(ctx).Info("api key rotated",
+		slog.String("api_key_id", keyID.String()),
+		slog.String("replaced_by", created.ID.String()),
+		slog.Duration("grace", grace),
+		slog.String("actor", actor),
+	)
+	return tenantdto.IssuedAPIKey{APIKey: apiKeyDTO(created), Key: secret}, nil
+}
+
+// AuthenticateAPIKey resolves a presented API key to a principal of its tenant carrying the key's
+// scopes and branches. Unknown, revoked and expired keys fail with auth.ErrAPIKeyInvalid.
+func (s *Service) AuthenticateAPIKey(ctx context.Context, presented string) (common.Principal, error) {
+	prefix, secret, ok := splitAPIKey(presented)
+	if !ok {
+		return common.Principal{}, fmt.Errorf("%w: malformed key", auth.ErrAPIKeyInvalid)
+	}
+	key, err := s.repo.GetAPIKeyByPrefix(ctx, prefix)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return common.Principal{}, fmt.Errorf("%w: unknown key", auth.ErrAPIKeyInvalid)
+	}
+	if err != nil {
+		return common.Principal{}, fmt.Errorf("tenant: look up api key: %w", err)
+	}
+	hash := sha256.Sum256([]byte(secret))
+	if subtle.ConstantTimeCompare(hash[:], key.KeyHash) != 1 {
+		return common.Principal{}, fmt.Errorf("%w: unknown key", auth.ErrAPIKeyInvalid)
+	}
+	if key.RevokedAt != nil {
+		return common.Principal{}, fmt.Errorf("%w: key %s is revoked", auth.ErrAPIKeyInvalid, key.KeyPrefix)
+	}
+	if key.ExpiresAt != nil && !time.Now().Before(*key.ExpiresAt) {
+		return common.Principal{}, fmt.Errorf("%w: key %s has expired", auth.ErrAPIKeyInvalid, key.KeyPrefix)
+	}
+
+	if err := s.repo.TouchAPIKey(ctx, key.ID); err != nil {
+		logging.FromContext(ctx).Warn("failed to record api key use", slog.Any("error", err))
+	}
+	branches := make([]string, 0, len(key.BranchIDs))
+	for _, id := range key.BranchIDs {
+		branches = append(branches, id.String())
+	}
+	return common.Principal{
+		Username: "api-key:" + key.Name,
+		Subject:  "api-key:" + key.ID.String(),
+		TenantID: key.TenantID.String(),
+		Scopes:   append([]string(nil), key.Scopes...),
+		Claims: map[string]any{
+			"auth":                       "api_key",
+			"api_key_id":                 key.ID.String(),
+			authz.APIKeyBranchesClaim:    branches,
+			authz.APIKeyAllBranchesClaim: key.AllBranches,
+		},
+	}, nil
+}
+
+// authorizeAPIKey refuses scopes that would give a key permissions its issuer lacks, and branches
+// outside the issuer's data scope.
+func (s *Service) authorizeAPIKey(ctx context.Context, key tenant.APIKey) error {
+	if err := s.authorizer.AuthorizeScopes(ctx, key.Scopes); err != nil {
+		return fmt.Errorf("%w: %w", ErrAPIKeyScopeForbidden, err)
+	}
+	scope := common.DataScopeFromContext(ctx)
+	if scope.Unrestricted() {
+		return nil
+	}
+	// A key has no sales executive limit, so a principal held to its own jobs cannot pass one on.
+	if key.AllBranches || scope.SalesExecutiveID != nil {
+		return fmt.Errorf("%w: the key would see branches outside your data scope", ErrAPIKeyScopeForbidden)
+	}
+	visible := make(map[uuid.UUID]bool, len(scope.BranchIDs))
+	for _, id := range scope.BranchIDs {
+		visible[id] = true
+	}
+	for _, id := range key.BranchIDs {
+		if !visible[id] {
+			return fmt.Errorf("%w: branch %s is outside your data scope", ErrAPIKeyScopeForbidden, id)
+		}
+	}
+	return nil
+}
+
+// validateAPIKeyInput checks an issue request and returns the key's name, scopes, branches and
+// expiry as a record to complete with newAPIKey.
+func validateAPIKeyInput(input tenantdto.APIKeyInput) (tenant.APIKey, error) {
+	var problems []string
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		problems = append(problems, "name is required")
+	}
+	seen := make(map[string]bool, len(input.Scopes))
+	scopes := make([]string, 0, len(input.Scopes))
+	for _, scope := range input.Scopes {
+		scope = strings.TrimSpace(scope)
+		switch {
+		case seen[scope]:
+		case !authz.KnownScope(scope):
+			problems = append(problems, fmt.Sprintf("unknown scope %q (known: %s)", scope, strings.Join(authz.Scopes(), ", ")))
+		default:
+			scopes = append(scopes, scope)
+		}
+		seen[scope] = true
+	}
+	if len(input.Scopes) == 0 {
+		problems = append(problems, "at least one scope is required")
+	}
+	branches := make([]uuid.UUID, 0, len(input.BranchIDs))
+	seenBranch := make(map[uuid.UUID]bool, len(input.BranchIDs))
+	for _, id := range input.BranchIDs {
+		switch {
+		case id == uuid.Nil:
+			problems = append(problems, "branch IDs cannot be the nil UUID")
+		case !seenBranch[id]:
+			branches = append(branches, id)
+		}
+		seenBranch[id] = true
+	}
+	switch {
+	case input.AllBranches && len(input.BranchIDs) > 0:
+		problems = append(problems, "set either branch IDs or all branches, not both")
+	case !input.AllBranches && len(input.BranchIDs) == 0:
+		problems = append(problems, "branch IDs or all branches is required")
+	}
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(time.Now()) {
+		problems = append(problems, "expiry must be in the future")
+	}
+	if len(problems) > 0 {
+		return tenant.APIKey{}, fmt.Errorf("%w: %s", ErrInvalidAPIKeyInput, strings.Join(problems, "; "))
+	}
+	return tenant.APIKey{
+		Name:        name,
+		Scopes:      scopes,
+		BranchIDs:   branches,
+		AllBranches: input.AllBranches,
+		ExpiresAt:   input.ExpiresAt,
+	}, nil
+}
+
+// newAPIKey generates a key of the form frk_<prefix>_<secret> and the record storing its hash,
+// with the name, scopes, branches and expiry of from.
+func newAPIKey(tenantID uuid.UUID, from tenant.APIKey, actor string) (string, tenant.APIKey, error) {
+	prefixBytes := make([]byte, 6)
+	secretBytes := make([]byte, 32)
+	if _, err := rand.Read(prefixBytes); err != nil {
+		return "", tenant.APIKey{}, fmt.Errorf("tenant: generate api key: %w", err)
+	}
+	if _, err := rand.Read(secretBytes); err != nil {
+		return "", tenant.APIKey{}, fmt.Errorf("tenant: generate api key: %w", err)
+	}
+	prefix := hex.EncodeToString(prefixBytes)
+	secret := base64.RawURLEncoding.EncodeToString(secretBytes)
+	hash := sha256.Sum256([]byte(secret))
+
+	return apiKeyMarker + prefix + "_" + secret, tenant.APIKey{
+		TenantID:    tenantID,
+		Name:        from.Name,
+		KeyPrefix:   prefix,
+		KeyHash:     hash[:],
+		Scopes:      from.Scopes,
+		BranchIDs:   from.BranchIDs,
+		AllBranches: from.AllBranches,
+		ExpiresAt:   from.ExpiresAt,
+		CreatedBy:   &actor,
+	}, nil
+}
+
+func splitAPIKey(key string) (prefix, secret string, ok bool) {
+	rest, found := strings.CutPrefix(strings.TrimSpace(key), apiKeyMarker)
+	if !found {
+		return "", "", false
+	}
+	prefix, secret, found = strings.Cut(rest, "_")
+	if !found || prefix == "" || secret == "" {
+		return "", "", false
+	}
+	return prefix, secret, true
+}
+
+func apiKeyDTO(k tenant.APIKey) tenantdto.APIKey {
+	scopes := k.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+	branches := k.BranchIDs
+	if branches == nil {
+		branches = []uuid.UUID{}
+	}
+	return tenantdto.APIKey{
+		ID:          k.ID,
+		Name:        k.Name,
+		Prefix:      apiKeyMarker + k.KeyPrefix,
+		Scopes:      scopes,
+		BranchIDs:   branches,
+		AllBranches: k.AllBranches,
+		ExpiresAt:   k.ExpiresAt,
+		LastUsedAt:  k.LastUsedAt,
+		ReplacedBy:  k.ReplacedBy,
+		CreatedAt:   k.CreatedAt,
+		CreatedBy:   k.CreatedBy,
+		RevokedAt:   k.RevokedAt,
+		RevokedBy:   k.RevokedBy,
+	}
+}
@@ -0,0 +1,29 @@
+package tenant
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"frego-operations/internal/ratelimit"
+)
+
+// RateLimitRules returns the rate limit rules that apply to a tenant, for the limiter.
+func (s *Service) RateLimitRules(ctx context.Context, tenantID uuid.UUID) ([]ratelimit.Rule, error) {
+	rows, err := s.repo.ListRateLimits(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("tenant: list rate limits: %w", err)
+	}
+	rules := make([]ratelimit.Rule, 0, len(rows))
+	for _, row := range rows {
+		rules = append(rules, ratelimit.Rule{
+			TenantID: row.TenantID,
+			APIKeyID: row.APIKeyID,
+			Class:    ratelimit.Class(row.RouteClass),
+			Level:    ratelimit.Level(row.LimitLevel),
+			Limit:    ratelimit.Limit{PerMinute: int(row.RequestsPerMinute), Burst: int(row.Burst)},
+		})
+	}
+	return rules, nil
+}
@@ -3,6 +3,7 @@ package tenant
 import (
 	"context"
 
+	"frego-operations/internal/authz"
 	"frego-operations/internal/repository/tenant"
 
 	"github.com/google/uuid"
@@ -10,13 +11,16 @@ import (
 
 // Service handles tenant business logic
 type Service struct {
-	repo *tenant.Repository
+	repo       *tenant.Repository
+	authorizer *authz.Authorizer
 }
 
-// New creates a new tenant service
-func New(repo *tenant.Repository) *Service {
+// New creates a new tenant service. The authorizer limits the scopes of issued API keys to those
+// the issuing principal holds.
+func New(repo *tenant.Repository, authorizer *authz.Authorizer) *Service {
 	return &Service{
-		repo: repo,
+		repo:       repo,
+		authorizer: authorizer,
 	}
 }
 
@@ -20,6 +20,14 @@ func (noopUploader) UploadPartyDocument(ctx context.Context, tenantID, tenantNam
 	return DocumentLocation{}, ErrUploaderDisabled
 }
 
+func (noopUploader) UploadJobDocument(ctx context.Context, tenantID, tenantName string, jobID uuid.UUID, jobCode, docType string, payload DocumentPayload) (DocumentLocation, error) {
+	return DocumentLocation{}, ErrUploaderDisabled
+}
+
 func (noopUploader) DownloadDocument(ctx context.Context, region, key string) (DocumentDownload, error) {
 	return DocumentDownload{}, ErrUploaderDisabled
 }
+
+func (noopUploader) DeleteDocument(ctx context.Context, region, key string) error {
+	return ErrUploaderDisabled
+}
@@ -101,8 +101,25 @@ func (u *S3Uploader) UploadPartyDocument(ctx context.Context, tenantID, tenantNa
 		fileName = "document.bin"
 	}
 
-	key := u.buildObjectKey(tenantID, tenantName, partyID, partyName, docType, fileName)
+	key := u.buildObjectKey(tenantID, tenantName, "parties", partyID, partyName, docType, fileName)
+	return u.put(ctx, key, docType, payload)
+}
+
+// UploadJobDocument uploads a document generated for or attached to a job.
+func (u *S3Uploader) UploadJobDocument(ctx context.Context, tenantID, tenantName string, jobID uuid.UUID, jobCode, docType string, payload DocumentPayload) (DocumentLocation, error) {
+	if len(payload.Data) == 0 {
+		return DocumentLocation{}, fmt.Errorf("storage: s3 uploader: payload is empty")
+	}
+	fileName := sanitizeFileName(payload.FileName)
+	if fileName == "" {
+		fileName = "document.pdf"
+	}
+
+	key := u.buildObjectKey(tenantID, tenantName, "jobs", jobID, jobCode, docType, fileName)
+	return u.put(ctx, key, docType, payload)
+}
 
+func (u *S3Uploader) put(ctx context.Context, key, docType string, payload DocumentPayload) (DocumentLocation, error) {
 	contentType := strings.TrimSpace(payload.ContentType)
 	if contentType == "" {
 		contentType = "application/octet-stream"
@@ -174,9 +191,30 @@ func (u *S3Uploader) DownloadDocument(ctx context.Context, region, key string) (
 	}, nil
 }
 
-func (u *S3Uploader) buildObjectKey(tenantID, tenantName string, partyID uuid.UUID, partyName, docType, fileName string) string {
+// DeleteDocument removes a document by key ensuring it resides in the configured region.
+func (u *S3Uploader) DeleteDocument(ctx context.Context, region, key string) error {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return fmt.Errorf("storage: s3 uploader: key is required")
+	}
+
+	if trimmed := strings.TrimSpace(region); trimmed != "" && !strings.EqualFold(trimmed, strings.TrimSpace(u.region)) {
+		return fmt.Errorf("storage: s3 uploader: mismatched region '%s'", trimmed)
+	}
+
+	_, err := u.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(u.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("storage: s3 uploader: delete object: %w", err)
+	}
+	return nil
+}
+
+func (u *S3Uploader) buildObjectKey(tenantID, tenantName, ownerKind string, ownerID uuid.UUID, ownerName, docType, fileName string) string {
 	tenantSlug := slugifyName(tenantName, tenantID)
-	partySlug := slugifyName(partyName, partyID.String())
+	ownerSlug := slugifyName(ownerName, ownerID.String())
 	docSlug := slugifyName(docType, "document")
 
 	unique := uuid.New().String()
@@ -190,8 +228,8 @@ func (u *S3Uploader) buildObjectKey(tenantID, tenantName string, partyID uuid.UU
 	parts := []string{
 		"tenants",
 		fmt.Sprintf("%s-%s", tenantSlug, tenantID),
-		"parties",
-		fmt.Sprintf("%s-%s", partySlug, partyID.String()),
+		ownerKind,
+		fmt.Sprintf("%s-%s", ownerSlug, ownerID.String()),
 		docSlug,
 		fmt.Sprintf("%04d", now.Year()),
 		fmt.Sprintf("%02d", now.Month()),
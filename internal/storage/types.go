@@ -26,8 +26,10 @@ type DocumentDownload struct {
 	Size        int64
 }
 
-// DocumentUploader persists and retrieves documents for parties.
+// DocumentUploader persists, retrieves and removes documents for parties and jobs.
 type DocumentUploader interface {
 	UploadPartyDocument(ctx context.Context, tenantID, tenantName string, partyID uuid.UUID, partyName, docType string, payload DocumentPayload) (DocumentLocation, error)
+	UploadJobDocument(ctx context.Context, tenantID, tenantName string, jobID uuid.UUID, jobCode, docType string, payload DocumentPayload) (DocumentLocation, error)
 	DownloadDocument(ctx context.Context, region, key string) (DocumentDownload, error)
+	DeleteDocument(ctx context.Context, region, key string) error
 }